@@ -0,0 +1,312 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sarah/sarah"
+)
+
+// GetAlertRules handles GET requests to retrieve the alert rules of an organization, oldest first.
+//
+// HTTP Method: GET
+// Endpoint: /alerts/org
+//
+// Response:
+//   - 200 OK: Returns an array of alert rules with their trigger counts
+//   - 405 Method Not Allowed: If not using GET method
+//   - 500 Internal Server Error: If database operation fails
+//
+// Example Response:
+//
+//	[
+//	  {
+//	    "id": "65a1b2c3d4e5f6a7b8c9d0e3",
+//	    "name": "Legal threat",
+//	    "enabled": true,
+//	    "condition": { "type": "keyword", "keywords": ["lawyer", "complaint", "cancel"], "role": "user", "threshold": 0 },
+//	    "actions": [
+//	      { "type": "webhook", "url": "https://example.com/hooks/sarah-alerts", "secret": "s3cret" },
+//	      { "type": "email", "recipients": ["support-lead@example.com"] },
+//	      { "type": "flag" }
+//	    ],
+//	    "trigger_count": 12,
+//	    "last_triggered_at": "2024-01-02T15:04:05Z",
+//	    "created_at": "2024-01-01T12:00:00Z",
+//	    "updated_at": "2024-01-01T12:00:00Z"
+//	  }
+//	]
+//
+// The organization ID is obtained from the auth bearer token.
+func GetAlertRules(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"GET"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	orgId := ExtractOrgId(r)
+
+	rules, err := sarah.GetAlertRules(orgId)
+	if err != nil {
+		http.Error(w, "Failed to get alert rules", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(rules)
+}
+
+// CreateAlertRule handles POST requests to create an alert rule of an organization.
+// The rule is checked against the live transcripts and end-of-call reports of the organization's calls,
+// and triggers at most once per call.
+//
+// HTTP Method: POST
+// Endpoint: /alerts/create
+//
+// Request Body:
+//
+//	{
+//	  "rule": {
+//	    "name": "Legal threat",
+//	    "enabled": true,
+//	    "condition": { "type": "keyword", "keywords": ["lawyer", "complaint", "cancel"], "role": "user" },
+//	    "actions": [
+//	      { "type": "webhook", "url": "https://example.com/hooks/sarah-alerts", "secret": "s3cret" },
+//	      { "type": "email", "recipients": ["support-lead@example.com"] },
+//	      { "type": "flag" }
+//	    ]
+//	  }
+//	}
+//
+// Condition types are keyword (whole words or phrases, ignoring case), regex (RE2 syntax, e.g. "(?i)charge ?back"),
+// and negative_score, matching when the analysis field (success_evaluation or structured_data.<key>) is at or below
+// threshold. Rules are enabled unless enabled is false.
+//
+// Response:
+//   - 200 OK: Rule created successfully, returns the insert result
+//   - 400 Bad Request: If the request body is invalid, or a JSON list of the invalid fields
+//   - 405 Method Not Allowed: If not using POST method
+//   - 500 Internal Server Error: If database operation fails
+//
+// Example Response:
+//
+//	{
+//	  "InsertedID": "65a1b2c3d4e5f6a7b8c9d0e3"
+//	}
+//
+// The organization ID is obtained from the auth bearer token.
+func CreateAlertRule(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"POST"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rule := ExtractAlertRule(r)
+	if rule == nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	caller := ExtractCaller(r)
+
+	result, err := sarah.CreateAlertRule(caller, *rule)
+	if WriteValidationError(w, err) {
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to create alert rule", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// UpdateAlertRule handles PATCH requests to replace the name, enabled flag, condition and actions of an alert rule.
+// The trigger count of the rule is kept.
+//
+// HTTP Method: PATCH
+// Endpoint: /alerts/update
+//
+// Query Parameters:
+//   - ruleId: The hex ObjectID of the rule (required)
+//
+// Request Body:
+//
+//	{
+//	  "rule": {
+//	    "name": "Unhappy customer",
+//	    "enabled": true,
+//	    "condition": { "type": "negative_score", "field": "structured_data.sentiment", "threshold": -0.5 },
+//	    "actions": [{ "type": "flag" }]
+//	  }
+//	}
+//
+// Response:
+//   - 200 OK: Rule updated successfully, returns the update result
+//   - 400 Bad Request: If ruleId is missing, the request body is invalid, or a JSON list of the invalid fields
+//   - 404 Not Found: If the rule does not belong to the organization
+//   - 405 Method Not Allowed: If not using PATCH method
+//   - 500 Internal Server Error: If database operation fails
+//
+// Example Response:
+//
+//	{
+//	  "MatchedCount": 1,
+//	  "ModifiedCount": 1,
+//	  "UpsertedCount": 0,
+//	  "UpsertedID": nil,
+//	  "Acknowledged": true
+//	}
+//
+// The organization ID is obtained from the auth bearer token.
+func UpdateAlertRule(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"PATCH"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ruleId := ExtractAlertRuleId(r)
+	if ruleId == "" {
+		http.Error(w, "Missing ruleId", http.StatusBadRequest)
+		return
+	}
+
+	rule := ExtractAlertRule(r)
+	if rule == nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	caller := ExtractCaller(r)
+
+	result, err := sarah.UpdateAlertRule(caller, ruleId, *rule)
+	if errors.Is(err, sarah.ErrNotFound) {
+		http.Error(w, "Alert rule not found", http.StatusNotFound)
+		return
+	} else if WriteValidationError(w, err) {
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to update alert rule", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// DeleteAlertRule handles DELETE requests to delete an alert rule of an organization.
+// Flags the rule added to calls are kept.
+//
+// HTTP Method: DELETE
+// Endpoint: /alerts/delete
+//
+// Query Parameters:
+//   - ruleId: The hex ObjectID of the rule (required)
+//
+// Response:
+//   - 200 OK: Rule deleted successfully, returns the delete result
+//   - 400 Bad Request: If ruleId is missing
+//   - 404 Not Found: If the rule does not belong to the organization
+//   - 405 Method Not Allowed: If not using DELETE method
+//   - 500 Internal Server Error: If database operation fails
+//
+// Example Response:
+//
+//	{
+//	  "DeletedCount": 1,
+//	  "Acknowledged": true
+//	}
+//
+// The organization ID is obtained from the auth bearer token.
+func DeleteAlertRule(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"DELETE"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ruleId := ExtractAlertRuleId(r)
+	if ruleId == "" {
+		http.Error(w, "Missing ruleId", http.StatusBadRequest)
+		return
+	}
+	caller := ExtractCaller(r)
+
+	result, err := sarah.DeleteAlertRule(caller, ruleId)
+	if errors.Is(err, sarah.ErrNotFound) {
+		http.Error(w, "Alert rule not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to delete alert rule", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// TestAlertRule handles POST requests to check whether an alert rule triggers on a sample transcript or analysis.
+// Testing doesn't count a trigger. The rule is a stored rule given by ruleId, or the "rule" of the body,
+// so a rule can be tried before it is created. With "deliver" set, a matching rule's webhook and email
+// actions are sent a test alert, and the outcome of each is returned; flag actions are skipped.
+//
+// HTTP Method: POST
+// Endpoint: /alerts/test
+//
+// Query Parameters:
+//   - ruleId: The hex ObjectID of a stored rule (optional, the body's rule is tested without it)
+//
+// Request Body:
+//
+//	{
+//	  "sample": {
+//	    "transcript": "If this isn't fixed I'm calling my lawyer",
+//	    "role": "user",
+//	    "analysis": { "successEvaluation": "2", "structuredData": { "sentiment": -0.8 } }
+//	  },
+//	  "deliver": true
+//	}
+//
+// Response:
+//   - 200 OK: Returns whether the rule matched and, if delivered, the outcome of each action
+//   - 400 Bad Request: If the request body is invalid, or a JSON list of the invalid fields
+//   - 404 Not Found: If the rule does not belong to the organization
+//   - 405 Method Not Allowed: If not using POST method
+//   - 500 Internal Server Error: If database operation fails
+//
+// Example Response:
+//
+//	{
+//	  "matched": true,
+//	  "match": "lawyer",
+//	  "deliveries": [
+//	    { "type": "webhook" },
+//	    { "type": "email", "error": "dial tcp: lookup smtp.example.com: no such host" }
+//	  ]
+//	}
+//
+// The organization ID is obtained from the auth bearer token.
+func TestAlertRule(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"POST"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rule, sample, deliver := ExtractAlertTest(r)
+	if sample == nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	caller := ExtractCaller(r)
+
+	result, err := sarah.TestAlertRule(caller, ExtractAlertRuleId(r), rule, *sample, deliver)
+	if errors.Is(err, sarah.ErrNotFound) {
+		http.Error(w, "Alert rule not found", http.StatusNotFound)
+		return
+	} else if WriteValidationError(w, err) {
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to test alert rule", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
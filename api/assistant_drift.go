@@ -0,0 +1,133 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sarah/sarah"
+	"strings"
+)
+
+// GetAssistantDrift handles GET requests to compare the organization's assistant records with VapiAI.
+// Returns the latest report of the hourly check, or checks the assistants now when refresh is set
+// or they were never checked. Assistants deleted or renamed in VapiAI, and assistants used by campaigns
+// without being registered (orphans), are reported with the repairs that fix them.
+//
+// HTTP Method: GET
+// Endpoint: /assistants/drift
+//
+// Query Parameters:
+//   - refresh: Set to true to check the assistants now (optional)
+//
+// Response:
+//   - 200 OK: Returns the drift report
+//   - 405 Method Not Allowed: If not using GET method
+//   - 500 Internal Server Error: If VapiAI or database operations fail
+//   - 503 Service Unavailable: If VapiAI is unavailable and its circuit is open
+//
+// Example Response:
+//
+//	{
+//	  "trigger": "scheduled",
+//	  "checked": 3,
+//	  "issues": [
+//	    {
+//	      "kind": "name",
+//	      "vapi_assistant_id": "asst_1234567890abcdef",
+//	      "name": "Insurance Reminder Assistant",
+//	      "vapi_name": "Insurance Renewals",
+//	      "campaign_ids": ["507f1f77bcf86cd799439011"],
+//	      "repairs": ["pull_name", "push_name"]
+//	    },
+//	    {
+//	      "kind": "deleted",
+//	      "vapi_assistant_id": "asst_0987654321fedcba",
+//	      "name": "Old Support Assistant",
+//	      "vapi_name": "",
+//	      "campaign_ids": [],
+//	      "repairs": ["unregister"]
+//	    }
+//	  ],
+//	  "checked_at": "2024-01-01T12:00:00Z"
+//	}
+//
+// The organization ID is obtained from the auth bearer token.
+func GetAssistantDrift(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"GET"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	refresh := strings.TrimSpace(r.URL.Query().Get("refresh")) == "true"
+	caller := ExtractCaller(r)
+
+	report, err := sarah.GetAssistantDrift(caller, refresh)
+	if errors.Is(err, sarah.ErrCircuitOpen) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to check assistants", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
+
+// RepairAssistantDrift handles POST requests to repair a difference between an assistant record and VapiAI.
+// The assistant is looked up in VapiAI again before repairing it, and removed from the drift report once repaired.
+//
+// HTTP Method: POST
+// Endpoint: /assistants/drift/repair
+//
+// Query Parameters:
+//   - assistantId: The VapiAI assistant ID (required)
+//   - action: The repair, one of (required):
+//   - pull_name: Copy the VapiAI name into the assistant record
+//   - push_name: Rename the assistant in VapiAI after its record
+//   - unregister: Delete the record of an assistant deleted in VapiAI
+//   - register: Register an orphan assistant with its VapiAI name
+//
+// Response:
+//   - 200 OK: Returns the drift report without the repaired assistant
+//   - 400 Bad Request: If assistantId is missing, or a JSON error if the action is unknown
+//   - 404 Not Found: If the assistant is not registered by the organization, or is registered by another one
+//   - 405 Method Not Allowed: If not using POST method
+//   - 409 Conflict: If the repair doesn't apply to the assistant's state in VapiAI, e.g. unregistering an assistant that still exists
+//   - 500 Internal Server Error: If VapiAI or database operations fail
+//   - 503 Service Unavailable: If VapiAI is unavailable and its circuit is open
+//
+// The organization ID is obtained from the auth bearer token.
+func RepairAssistantDrift(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"POST"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	assistantId := ExtractAssistantId(r)
+	if assistantId == "" {
+		http.Error(w, "Missing assistantId", http.StatusBadRequest)
+		return
+	}
+	caller := ExtractCaller(r)
+
+	report, err := sarah.RepairAssistantDrift(caller, assistantId, ExtractAssistantRepair(r))
+	if errors.Is(err, sarah.ErrNotFound) {
+		http.Error(w, "Assistant not found", http.StatusNotFound)
+		return
+	} else if errors.Is(err, sarah.ErrRepairNotApplicable) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if errors.Is(err, sarah.ErrCircuitOpen) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	} else if WriteValidationError(w, err) {
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to repair assistant", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,308 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sarah/sarah"
+	"strings"
+)
+
+// GetAssistantTemplates handles GET requests to list the assistant templates available to an organization:
+// the built-in templates, followed by the organization's own templates sorted by name.
+//
+// HTTP Method: GET
+// Endpoint: /assistants/templates
+//
+// Response:
+//   - 200 OK: Returns an array of templates
+//   - 405 Method Not Allowed: If not using GET method
+//   - 500 Internal Server Error: If database operation fails
+//
+// Example Response:
+//
+//	[
+//	  {
+//	    "id": "insurance-renewal-reminder",
+//	    "name": "Insurance renewal reminder",
+//	    "description": "Reminds customers that their policy is up for renewal and offers to have an agent call them back.",
+//	    "parameters": [
+//	      { "name": "company_name", "description": "Name of the insurance company" },
+//	      { "name": "voice_id", "description": "VapiAI voice, e.g. Elliot, Kylie, ...", "default": "Savannah" },
+//	      { "name": "language", "description": "ISO 639-1 code of the language the customer speaks", "default": "en" }
+//	    ],
+//	    "assistant": { "name": "{{company_name}} renewals", "voice": { "provider": "vapi", "voiceId": "{{voice_id}}" } },
+//	    "builtin": true
+//	  }
+//	]
+//
+// The organization ID is obtained from the auth bearer token.
+func GetAssistantTemplates(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"GET"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	orgId := ExtractOrgId(r)
+
+	templates, err := sarah.GetAssistantTemplates(orgId)
+	if err != nil {
+		http.Error(w, "Failed to get assistant templates", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(templates)
+}
+
+// CreateAssistantTemplate handles POST requests to create an assistant template of an organization.
+// Strings of the assistant can contain {{placeholders}} naming the template's parameters; other placeholders
+// are left for VapiAI's dynamic variables. Every parameter must be used, and the assistant must be a valid
+// VapiAI create assistant request once the parameters' defaults are applied.
+//
+// HTTP Method: POST
+// Endpoint: /assistants/templates/create
+//
+// Request Body:
+//
+//	{
+//	  "template": {
+//	    "name": "Policy lapse follow-up",
+//	    "description": "Follows up on policies that lapsed last month",
+//	    "parameters": [
+//	      { "name": "company_name", "description": "Name of the insurance company" },
+//	      { "name": "voice_id", "description": "VapiAI voice", "default": "Kylie" }
+//	    ],
+//	    "assistant": {
+//	      "name": "{{company_name}} follow-ups",
+//	      "firstMessage": "Hi {{customer.name}}, this is {{company_name}} calling about your policy.",
+//	      "voice": { "provider": "vapi", "voiceId": "{{voice_id}}" }
+//	    }
+//	  }
+//	}
+//
+// Response:
+//   - 200 OK: Template created successfully, returns the insert result
+//   - 400 Bad Request: If the request body is invalid, or a JSON list of the invalid fields
+//   - 405 Method Not Allowed: If not using POST method
+//   - 500 Internal Server Error: If database operation fails
+//
+// Example Response:
+//
+//	{
+//	  "InsertedID": "65a1b2c3d4e5f6a7b8c9d0e4"
+//	}
+//
+// The organization ID is obtained from the auth bearer token.
+func CreateAssistantTemplate(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"POST"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	template := ExtractAssistantTemplate(r)
+	if template == nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	caller := ExtractCaller(r)
+
+	result, err := sarah.CreateAssistantTemplate(caller, *template)
+	if WriteValidationError(w, err) {
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to create assistant template", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// UpdateAssistantTemplate handles PATCH requests to replace the name, description, parameters and assistant
+// of an assistant template of an organization. Assistants already created from the template are unchanged.
+//
+// HTTP Method: PATCH
+// Endpoint: /assistants/templates/update
+//
+// Query Parameters:
+//   - templateId: The ID of the template (required)
+//
+// Request Body:
+//
+//	{
+//	  "template": { ...template as in /assistants/templates/create... }
+//	}
+//
+// Response:
+//   - 200 OK: Template updated successfully, returns the update result
+//   - 400 Bad Request: If templateId is missing, the request body is invalid, or a JSON list of the invalid fields
+//   - 403 Forbidden: If the template is built-in
+//   - 404 Not Found: If the template does not belong to the organization
+//   - 405 Method Not Allowed: If not using PATCH method
+//   - 500 Internal Server Error: If database operation fails
+//
+// The organization ID is obtained from the auth bearer token.
+func UpdateAssistantTemplate(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"PATCH"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	templateId := ExtractAssistantTemplateId(r)
+	if templateId == "" {
+		http.Error(w, "Missing templateId", http.StatusBadRequest)
+		return
+	}
+
+	template := ExtractAssistantTemplate(r)
+	if template == nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	caller := ExtractCaller(r)
+
+	result, err := sarah.UpdateAssistantTemplate(caller, templateId, *template)
+	if errors.Is(err, sarah.ErrBuiltinTemplate) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	} else if errors.Is(err, sarah.ErrNotFound) {
+		http.Error(w, "Assistant template not found", http.StatusNotFound)
+		return
+	} else if WriteValidationError(w, err) {
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to update assistant template", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// DeleteAssistantTemplate handles DELETE requests to delete an assistant template of an organization.
+// Assistants already created from the template are kept.
+//
+// HTTP Method: DELETE
+// Endpoint: /assistants/templates/delete
+//
+// Query Parameters:
+//   - templateId: The ID of the template (required)
+//
+// Response:
+//   - 200 OK: Template deleted successfully, returns the delete result
+//   - 400 Bad Request: If templateId is missing
+//   - 403 Forbidden: If the template is built-in
+//   - 404 Not Found: If the template does not belong to the organization
+//   - 405 Method Not Allowed: If not using DELETE method
+//   - 500 Internal Server Error: If database operation fails
+//
+// The organization ID is obtained from the auth bearer token.
+func DeleteAssistantTemplate(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"DELETE"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	templateId := ExtractAssistantTemplateId(r)
+	if templateId == "" {
+		http.Error(w, "Missing templateId", http.StatusBadRequest)
+		return
+	}
+	caller := ExtractCaller(r)
+
+	result, err := sarah.DeleteAssistantTemplate(caller, templateId)
+	if errors.Is(err, sarah.ErrBuiltinTemplate) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	} else if errors.Is(err, sarah.ErrNotFound) {
+		http.Error(w, "Assistant template not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to delete assistant template", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// RenderAssistantTemplate handles POST requests to create an assistant from a built-in template or a template
+// of the organization. The placeholders of the template are replaced by the given values, falling back to the
+// parameters' defaults, and the assistant is created like with /assistants/create. With preview set, the
+// rendered create assistant request is returned instead and nothing is created.
+//
+// HTTP Method: POST
+// Endpoint: /assistants/templates/render
+//
+// Query Parameters:
+//   - templateId: The ID of the template (required)
+//   - preview: Set to true to only return the rendered create assistant request (optional)
+//
+// Request Body:
+//
+//	{
+//	  "values": { "company_name": "Acme Insurance", "voice_id": "Kylie", "language": "es" }
+//	}
+//
+// Response:
+//   - 200 OK: Returns the insert result of the assistant record, or the rendered request with preview
+//   - 400 Bad Request: If templateId is missing, the request body is invalid, or a JSON list of the missing
+//     and unknown values, or of the rendered assistant's errors
+//   - 404 Not Found: If the template is not built-in and does not belong to the organization
+//   - 405 Method Not Allowed: If not using POST method
+//   - 500 Internal Server Error: If VapiAI or database operations fail
+//   - 503 Service Unavailable: If VapiAI is unavailable and its circuit is open
+//
+// Example Response:
+//
+//	{
+//	  "InsertedID": "507f1f77bcf86cd799439011",
+//	  "Acknowledged": true
+//	}
+//
+// The organization ID is obtained from the auth bearer token.
+func RenderAssistantTemplate(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"POST"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	templateId := ExtractAssistantTemplateId(r)
+	if templateId == "" {
+		http.Error(w, "Missing templateId", http.StatusBadRequest)
+		return
+	}
+
+	values, ok := ExtractTemplateValues(r)
+	if !ok {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	preview := strings.TrimSpace(r.URL.Query().Get("preview")) == "true"
+	caller := ExtractCaller(r)
+
+	var result interface{}
+	var err error
+	if preview {
+		result, err = sarah.RenderAssistantTemplate(caller, templateId, values)
+	} else {
+		result, err = sarah.CreateAssistantFromTemplate(caller, templateId, values)
+	}
+
+	if errors.Is(err, sarah.ErrNotFound) {
+		http.Error(w, "Assistant template not found", http.StatusNotFound)
+		return
+	} else if errors.Is(err, sarah.ErrCircuitOpen) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	} else if WriteValidationError(w, err) {
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to create assistant from template", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,223 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sarah/sarah"
+)
+
+// GetAssistantVersions handles GET requests to list the versions of an assistant, newest first.
+// A version is stored every time the assistant is created, registered, updated or rolled back.
+//
+// HTTP Method: GET
+// Endpoint: /assistants/versions
+//
+// Query Parameters:
+//   - assistantId: The VapiAI assistant ID (required)
+//   - limit: The maximum number of versions to return (optional, defaults to 50, maximum 200)
+//
+// Response:
+//   - 200 OK: Returns an array of versions
+//   - 400 Bad Request: If assistantId is missing or limit is invalid
+//   - 404 Not Found: If the assistant is not registered by the organization
+//   - 405 Method Not Allowed: If not using GET method
+//   - 500 Internal Server Error: If database operation fails
+//
+// Example Response:
+//
+//	[
+//	  {
+//	    "id": "507f1f77bcf86cd799439011",
+//	    "vapi_assistant_id": "asst_1234567890abcdef",
+//	    "version": 2,
+//	    "action": "update",
+//	    "config": {
+//	      "id": "asst_1234567890abcdef",
+//	      "name": "Insurance Reminder Assistant",
+//	      "firstMessage": "Hi, this is Sarah from Acme Insurance.",
+//	      "model": { "provider": "openai", "model": "gpt-4o" }
+//	    },
+//	    "author_id": "user_1234567890abcdef",
+//	    "created_at": "2024-01-01T12:00:00Z"
+//	  }
+//	]
+//
+// The organization ID is obtained from the auth bearer token.
+func GetAssistantVersions(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"GET"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	assistantId := ExtractAssistantId(r)
+	if assistantId == "" {
+		http.Error(w, "Missing assistantId", http.StatusBadRequest)
+		return
+	}
+
+	_, limit, err := ExtractPagination(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	caller := ExtractCaller(r)
+
+	versions, err := sarah.GetAssistantVersions(caller, assistantId, limit)
+	if errors.Is(err, sarah.ErrNotFound) {
+		http.Error(w, "Assistant not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to get assistant versions", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(versions)
+}
+
+// DiffAssistantVersions handles GET requests to compare two versions of an assistant.
+// Fields set by VapiAI, such as id and updatedAt, are not compared.
+//
+// HTTP Method: GET
+// Endpoint: /assistants/versions/diff
+//
+// Query Parameters:
+//   - assistantId: The VapiAI assistant ID (required)
+//   - from: The version to compare from (required)
+//   - to: The version to compare to (optional, defaults to the latest version)
+//
+// Response:
+//   - 200 OK: Returns the differing fields
+//   - 400 Bad Request: If assistantId or from is missing, or a version is invalid
+//   - 404 Not Found: If the assistant is not registered by the organization, or either version doesn't exist
+//   - 405 Method Not Allowed: If not using GET method
+//   - 500 Internal Server Error: If database operation fails
+//
+// Example Response:
+//
+//	{
+//	  "vapi_assistant_id": "asst_1234567890abcdef",
+//	  "from": 1,
+//	  "to": 2,
+//	  "changes": [
+//	    {
+//	      "path": "firstMessage",
+//	      "old": "Hello!",
+//	      "new": "Hi, this is Sarah from Acme Insurance."
+//	    },
+//	    {
+//	      "path": "model.temperature",
+//	      "new": 0.3
+//	    }
+//	  ]
+//	}
+//
+// The organization ID is obtained from the auth bearer token.
+func DiffAssistantVersions(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"GET"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	assistantId := ExtractAssistantId(r)
+	if assistantId == "" {
+		http.Error(w, "Missing assistantId", http.StatusBadRequest)
+		return
+	}
+
+	from, err := ExtractVersionParam(r, "from")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if from == 0 {
+		http.Error(w, "Missing from", http.StatusBadRequest)
+		return
+	}
+
+	to, err := ExtractVersionParam(r, "to")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	caller := ExtractCaller(r)
+
+	diff, err := sarah.DiffAssistantVersions(caller, assistantId, from, to)
+	if errors.Is(err, sarah.ErrNotFound) {
+		http.Error(w, "Assistant version not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to compare assistant versions", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(diff)
+}
+
+// RollbackAssistant handles POST requests to re-apply an earlier version of an assistant in VapiAI.
+// The configuration of the version is sent to VapiAI as an update, and the result is stored as a new
+// version. VapiAI only updates the fields sent, so the rollback is refused, leaving the assistant
+// unchanged, if fields were added to the assistant after the version was taken or changed fields
+// can't be sent in an update.
+//
+// HTTP Method: POST
+// Endpoint: /assistants/versions/rollback
+//
+// Query Parameters:
+//   - assistantId: The VapiAI assistant ID (required)
+//   - version: The version to re-apply (required)
+//
+// Response:
+//   - 200 OK: Returns the new version, with restored_version set to the re-applied version
+//   - 400 Bad Request: If assistantId or version is missing or invalid
+//   - 404 Not Found: If the assistant is not registered by the organization, or the version doesn't exist
+//   - 405 Method Not Allowed: If not using POST method
+//   - 409 Conflict: If the version can't be fully restored, listing the fields that would keep their current value
+//   - 500 Internal Server Error: If VapiAI or database operations fail
+//   - 503 Service Unavailable: If VapiAI is unavailable and its circuit is open
+//
+// The organization ID is obtained from the auth bearer token.
+func RollbackAssistant(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"POST"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	assistantId := ExtractAssistantId(r)
+	if assistantId == "" {
+		http.Error(w, "Missing assistantId", http.StatusBadRequest)
+		return
+	}
+
+	version, err := ExtractVersionParam(r, "version")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if version == 0 {
+		http.Error(w, "Missing version", http.StatusBadRequest)
+		return
+	}
+
+	caller := ExtractCaller(r)
+
+	result, err := sarah.RollbackAssistant(caller, assistantId, version)
+	if errors.Is(err, sarah.ErrNotFound) {
+		http.Error(w, "Assistant version not found", http.StatusNotFound)
+		return
+	} else if errors.Is(err, sarah.ErrRollbackIncomplete) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if errors.Is(err, sarah.ErrCircuitOpen) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to roll back assistant", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,308 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sarah/sarah"
+)
+
+// UpdateCallTags handles PATCH requests to add and remove tags of a call.
+// Tags are lowercased and their spaces collapsed. Agents tagging the same call at once don't overwrite each other.
+//
+// HTTP Method: PATCH
+// Endpoint: /calls/tags
+//
+// Query Parameters:
+//   - callId: The VapiAI call ID (required)
+//
+// Request Body:
+//
+//	{
+//	  "tags": { "add": ["vip", "follow-up"], "remove": ["new"] }
+//	}
+//
+// Response:
+//   - 200 OK: Returns the call record with its new tags
+//   - 400 Bad Request: If callId is missing, the request body is invalid, or a JSON list of the invalid tags
+//   - 404 Not Found: If the call does not belong to the organization
+//   - 405 Method Not Allowed: If not using PATCH method
+//   - 500 Internal Server Error: If database operation fails
+//   - 503 Service Unavailable: If the call isn't recorded yet and VapiAI is unavailable
+//
+// Example Response:
+//
+//	{
+//	  "id": "507f1f77bcf86cd799439011",
+//	  "vapi_call_id": "call_abc123def456",
+//	  "status": "ended",
+//	  "tags": ["follow-up", "vip"],
+//	  "disposition": "resolved",
+//	  "disposition_by": "user_1234567890",
+//	  "disposition_at": "2024-01-01T12:10:00Z",
+//	  "note_count": 2,
+//	  ...
+//	}
+//
+// The organization ID is obtained from the auth bearer token.
+func UpdateCallTags(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"PATCH"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	callId := ExtractCallId(r)
+	if callId == "" {
+		http.Error(w, "Missing callId", http.StatusBadRequest)
+		return
+	}
+
+	add, remove, ok := ExtractTagChanges(r)
+	if !ok {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	caller := ExtractCaller(r)
+
+	call, err := sarah.UpdateCallTags(caller, callId, add, remove)
+	writeCallAnnotation(w, call, err, "Failed to update call tags")
+}
+
+// SetCallDisposition handles PATCH requests to set the disposition of a call.
+// The disposition must be a code of the organization's "dispositions" setting; an empty or null
+// disposition clears it. The user from the auth bearer token is recorded as who set it.
+//
+// HTTP Method: PATCH
+// Endpoint: /calls/disposition
+//
+// Query Parameters:
+//   - callId: The VapiAI call ID (required)
+//
+// Request Body:
+//
+//	{
+//	  "disposition": "resolved"
+//	}
+//
+// Response:
+//   - 200 OK: Returns the updated call record
+//   - 400 Bad Request: If callId is missing, the request body is invalid, or a JSON error if the code is unknown
+//   - 404 Not Found: If the call does not belong to the organization
+//   - 405 Method Not Allowed: If not using PATCH method
+//   - 500 Internal Server Error: If database operation fails
+//   - 503 Service Unavailable: If the call isn't recorded yet and VapiAI is unavailable
+//
+// The organization ID is obtained from the auth bearer token.
+func SetCallDisposition(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"PATCH"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	callId := ExtractCallId(r)
+	if callId == "" {
+		http.Error(w, "Missing callId", http.StatusBadRequest)
+		return
+	}
+
+	disposition, ok := ExtractDisposition(r)
+	if !ok {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	caller := ExtractCaller(r)
+
+	call, err := sarah.SetCallDisposition(caller, callId, disposition)
+	writeCallAnnotation(w, call, err, "Failed to set call disposition")
+}
+
+// GetCallNotes handles GET requests to retrieve the notes written on a call, oldest first.
+//
+// HTTP Method: GET
+// Endpoint: /calls/notes
+//
+// Query Parameters:
+//   - callId: The VapiAI call ID (required)
+//
+// Response:
+//   - 200 OK: Returns an array of notes
+//   - 400 Bad Request: If callId is missing
+//   - 404 Not Found: If the call does not belong to the organization
+//   - 405 Method Not Allowed: If not using GET method
+//   - 500 Internal Server Error: If database operation fails
+//   - 503 Service Unavailable: If the call isn't recorded yet and VapiAI is unavailable
+//
+// Example Response:
+//
+//	[
+//	  {
+//	    "id": "65a1b2c3d4e5f6a7b8c9d0e4",
+//	    "vapi_call_id": "call_abc123def456",
+//	    "author_id": "user_1234567890",
+//	    "text": "Customer asked to be called back after the holidays",
+//	    "created_at": "2024-01-01T12:10:00Z"
+//	  }
+//	]
+//
+// The organization ID is obtained from the auth bearer token.
+func GetCallNotes(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"GET"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	callId := ExtractCallId(r)
+	if callId == "" {
+		http.Error(w, "Missing callId", http.StatusBadRequest)
+		return
+	}
+	caller := ExtractCaller(r)
+
+	notes, err := sarah.GetCallNotes(caller, callId)
+	if errors.Is(err, sarah.ErrNotFound) {
+		http.Error(w, "Call not found", http.StatusNotFound)
+		return
+	} else if errors.Is(err, sarah.ErrCircuitOpen) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to get call notes", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(notes)
+}
+
+// CreateCallNote handles POST requests to write a note on a call.
+// The user from the auth bearer token is recorded as the author of the note.
+//
+// HTTP Method: POST
+// Endpoint: /calls/notes/create
+//
+// Query Parameters:
+//   - callId: The VapiAI call ID (required)
+//
+// Request Body:
+//
+//	{
+//	  "note": { "text": "Customer asked to be called back after the holidays" }
+//	}
+//
+// Response:
+//   - 200 OK: Returns the created note
+//   - 400 Bad Request: If callId is missing, the request body is invalid, or a JSON error if the text is empty or too long
+//   - 404 Not Found: If the call does not belong to the organization
+//   - 405 Method Not Allowed: If not using POST method
+//   - 500 Internal Server Error: If database operation fails
+//   - 503 Service Unavailable: If the call isn't recorded yet and VapiAI is unavailable
+//
+// The organization ID is obtained from the auth bearer token.
+func CreateCallNote(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"POST"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	callId := ExtractCallId(r)
+	if callId == "" {
+		http.Error(w, "Missing callId", http.StatusBadRequest)
+		return
+	}
+
+	note := ExtractCallNote(r)
+	if note == nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	caller := ExtractCaller(r)
+
+	created, err := sarah.CreateCallNote(caller, callId, note.Text)
+	if errors.Is(err, sarah.ErrNotFound) {
+		http.Error(w, "Call not found", http.StatusNotFound)
+		return
+	} else if errors.Is(err, sarah.ErrCircuitOpen) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	} else if WriteValidationError(w, err) {
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to create call note", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(created)
+}
+
+// DeleteCallNote handles DELETE requests to delete a note of a call. Only the author of a note can delete it.
+//
+// HTTP Method: DELETE
+// Endpoint: /calls/notes/delete
+//
+// Query Parameters:
+//   - noteId: The hex ObjectID of the note (required)
+//
+// Response:
+//   - 200 OK: Note deleted successfully, returns the delete result
+//   - 400 Bad Request: If noteId is missing
+//   - 403 Forbidden: If the note was written by another user
+//   - 404 Not Found: If the note does not belong to the organization
+//   - 405 Method Not Allowed: If not using DELETE method
+//   - 500 Internal Server Error: If database operation fails
+//
+// Example Response:
+//
+//	{
+//	  "DeletedCount": 1,
+//	  "Acknowledged": true
+//	}
+//
+// The organization ID is obtained from the auth bearer token.
+func DeleteCallNote(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"DELETE"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	noteId := ExtractCallNoteId(r)
+	if noteId == "" {
+		http.Error(w, "Missing noteId", http.StatusBadRequest)
+		return
+	}
+	caller := ExtractCaller(r)
+
+	result, err := sarah.DeleteCallNote(caller, noteId)
+	if errors.Is(err, sarah.ErrNotFound) {
+		http.Error(w, "Note not found", http.StatusNotFound)
+		return
+	} else if errors.Is(err, sarah.ErrNotNoteAuthor) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to delete call note", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// writeCallAnnotation writes the call record returned by a tag or disposition change, or the error that prevented it.
+func writeCallAnnotation(w http.ResponseWriter, call interface{}, err error, failure string) {
+	if errors.Is(err, sarah.ErrNotFound) {
+		http.Error(w, "Call not found", http.StatusNotFound)
+		return
+	} else if errors.Is(err, sarah.ErrCircuitOpen) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	} else if WriteValidationError(w, err) {
+		return
+	} else if err != nil {
+		http.Error(w, failure, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(call)
+}
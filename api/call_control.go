@@ -0,0 +1,258 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sarah/sarah"
+)
+
+// CancelCall handles POST requests to cancel a call. A scheduled or queued call is deleted in VapiAI
+// before it is dialed. A ringing or in-progress call is hung up through its VapiAI control URL, like
+// with /calls/end, so VapiAI keeps its record. The call must belong to the organization from the auth
+// bearer token. Cancellations are recorded in the audit log and in the run history of the campaign that placed the call.
+//
+// HTTP Method: POST
+// Endpoint: /calls/cancel
+//
+// Query Parameters:
+//   - callId: The VapiAI call ID to cancel (required)
+//
+// Response:
+//   - 200 OK: Call cancelled successfully, returns the call as it was before cancelling
+//   - 400 Bad Request: If callId is missing
+//   - 404 Not Found: If the call does not belong to the organization
+//   - 405 Method Not Allowed: If not using POST method
+//   - 409 Conflict: If the call already ended, or is active and its assistant doesn't allow call control
+//   - 500 Internal Server Error: If VapiAI API call fails
+//   - 503 Service Unavailable: If VapiAI is unavailable and its circuit is open
+//
+// Example Response:
+//
+//	{
+//	  "id": "call_abc123def456",
+//	  "assistantId": "asst_1234567890abcdef",
+//	  "status": "queued",
+//	  "createdAt": "2024-01-01T12:00:00Z"
+//	}
+func CancelCall(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"POST"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	callId := ExtractCallId(r)
+	if callId == "" {
+		http.Error(w, "Missing callId", http.StatusBadRequest)
+		return
+	}
+
+	caller := ExtractCaller(r)
+
+	call, err := sarah.CancelCall(caller, callId)
+	if errors.Is(err, sarah.ErrNotFound) {
+		http.Error(w, "Call not found", http.StatusNotFound)
+		return
+	} else if errors.Is(err, sarah.ErrCircuitOpen) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	} else if errors.Is(err, sarah.ErrCallState) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if errors.Is(err, sarah.ErrCallNotControllable) {
+		http.Error(w, "Call control is not enabled for this assistant, set monitorPlan.controlEnabled", http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to cancel call", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Call cancelled successfully: %s\n", callId)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(call)
+}
+
+// EndCall handles POST requests to hang up a ringing or in-progress call.
+// The call is ended through its VapiAI control URL, which requires the assistant to have
+// monitorPlan.controlEnabled set. The call must belong to the organization from the auth bearer token.
+//
+// HTTP Method: POST
+// Endpoint: /calls/end
+//
+// Query Parameters:
+//   - callId: The VapiAI call ID to end (required)
+//
+// Response:
+//   - 200 OK: End request accepted by VapiAI, returns the call as it was before ending
+//   - 400 Bad Request: If callId is missing
+//   - 404 Not Found: If the call does not belong to the organization
+//   - 405 Method Not Allowed: If not using POST method
+//   - 409 Conflict: If the call is not active or its assistant doesn't allow call control
+//   - 500 Internal Server Error: If VapiAI API call fails
+//   - 503 Service Unavailable: If VapiAI is unavailable and its circuit is open
+//
+// Example Response:
+//
+//	{
+//	  "id": "call_abc123def456",
+//	  "assistantId": "asst_1234567890abcdef",
+//	  "status": "in-progress",
+//	  "createdAt": "2024-01-01T12:00:00Z"
+//	}
+func EndCall(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"POST"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	callId := ExtractCallId(r)
+	if callId == "" {
+		http.Error(w, "Missing callId", http.StatusBadRequest)
+		return
+	}
+
+	caller := ExtractCaller(r)
+
+	call, err := sarah.EndCall(caller, callId)
+	if errors.Is(err, sarah.ErrNotFound) {
+		http.Error(w, "Call not found", http.StatusNotFound)
+		return
+	} else if errors.Is(err, sarah.ErrCircuitOpen) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	} else if errors.Is(err, sarah.ErrCallState) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if errors.Is(err, sarah.ErrCallNotControllable) {
+		http.Error(w, "Call control is not enabled for this assistant, set monitorPlan.controlEnabled", http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to end call", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Call ended successfully: %s\n", callId)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(call)
+}
+
+// CancelCampaignCalls handles POST requests to cancel every scheduled or queued call of a campaign.
+// Calls still waiting in the call queue are removed from it, and calls already sent to VapiAI are cancelled.
+// Calls that started in the meantime are skipped rather than failing the request.
+// The campaign must belong to the organization from the auth bearer token.
+//
+// HTTP Method: POST
+// Endpoint: /campaigns/cancel
+//
+// Query Parameters:
+//   - campaignId: The hex ObjectID of the campaign (required)
+//
+// Response:
+//   - 200 OK: Returns the number of dequeued calls and the cancelled, skipped and failed calls
+//   - 400 Bad Request: If campaignId is missing
+//   - 404 Not Found: If the campaign does not belong to the organization
+//   - 405 Method Not Allowed: If not using POST method
+//   - 500 Internal Server Error: If database operation fails
+//
+// Example Response:
+//
+//	{
+//	  "dequeued": 120,
+//	  "cancelled": ["call_abc123def456"],
+//	  "skipped": { "call_0987654321fedcba": "call is not in a state that allows this action: call is in-progress" },
+//	  "failed": {}
+//	}
+func CancelCampaignCalls(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"POST"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	campaignId := ExtractCampaignIdParam(r)
+	if campaignId == "" {
+		http.Error(w, "Missing campaignId", http.StatusBadRequest)
+		return
+	}
+
+	caller := ExtractCaller(r)
+
+	result, err := sarah.CancelCampaignCalls(caller, campaignId)
+	if errors.Is(err, sarah.ErrNotFound) {
+		http.Error(w, "Campaign not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to cancel campaign calls", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// GetCampaignRuns handles GET requests to retrieve the run history of a campaign, newest first.
+// Each run lists the calls it placed and the ones that were cancelled or ended afterwards.
+//
+// HTTP Method: GET
+// Endpoint: /campaigns/runs
+//
+// Query Parameters:
+//   - campaignId: The hex ObjectID of the campaign (required)
+//   - limit: The maximum number of runs to return (optional, defaults to 50, maximum 200)
+//
+// Response:
+//   - 200 OK: Returns the campaign runs
+//   - 400 Bad Request: If campaignId is missing or limit is invalid
+//   - 404 Not Found: If the campaign does not belong to the organization
+//   - 405 Method Not Allowed: If not using GET method
+//   - 500 Internal Server Error: If database operation fails
+//
+// Example Response:
+//
+//	[
+//	  {
+//	    "id": "65a1b2c3d4e5f6a7b8c9d0e1",
+//	    "campaign_id": "507f1f77bcf86cd799439011",
+//	    "customers_targeted": 2,
+//	    "queued_calls": 2,
+//	    "call_ids": ["call_abc123def456", "call_0987654321fedcba"],
+//	    "cancelled_call_ids": ["call_abc123def456"],
+//	    "status": "partially_cancelled",
+//	    "error": "",
+//	    "started_at": "2024-01-01T12:00:00Z",
+//	    "cancelled_at": "2024-01-01T12:01:00Z",
+//	    "cancelled_by": "user_1234567890abcdef"
+//	  }
+//	]
+func GetCampaignRuns(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"GET"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	campaignId := ExtractCampaignIdParam(r)
+	if campaignId == "" {
+		http.Error(w, "Missing campaignId", http.StatusBadRequest)
+		return
+	}
+
+	_, limit, err := ExtractPagination(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	caller := ExtractCaller(r)
+
+	runs, err := sarah.GetCampaignRuns(caller, campaignId, limit)
+	if errors.Is(err, sarah.ErrNotFound) {
+		http.Error(w, "Campaign not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to get campaign runs", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(runs)
+}
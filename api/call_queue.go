@@ -0,0 +1,165 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sarah/sarah"
+)
+
+// GetCallQueue handles GET requests to retrieve the call queue of an organization, oldest first.
+// Calls stay in the queue once they leave it, with their final status, so the queue doubles as a dispatch log.
+//
+// HTTP Method: GET
+// Endpoint: /queue/org
+//
+// Query Parameters:
+//   - status: Only return calls in this status: queued, dispatching, dispatched, failed or cancelled (optional)
+//   - page: The 1-based page number (optional, defaults to 1)
+//   - limit: The number of calls per page (optional, defaults to 50, maximum 200)
+//
+// Response:
+//   - 200 OK: Returns a page of the queue
+//   - 400 Bad Request: If the status or pagination parameters are invalid
+//   - 405 Method Not Allowed: If not using GET method
+//   - 500 Internal Server Error: If database operation fails
+//
+// Example Response:
+//
+//	{
+//	  "calls": [
+//	    {
+//	      "id": "65a1b2c3d4e5f6a7b8c9d0e1",
+//	      "assistant_id": "asst_1234567890abcdef",
+//	      "phone_number_id": "phone_0987654321fedcba",
+//	      "customer": { "phone_number": "+1234567890", "phone_number_type": "mobile" },
+//	      "campaign_id": "507f1f77bcf86cd799439011",
+//	      "status": "dispatched",
+//	      "vapi_call_id": "call_abc123def456",
+//	      "attempts": 1,
+//	      "error": "",
+//	      "enqueued_at": "2024-01-01T12:00:00Z",
+//	      "dispatched_at": "2024-01-01T12:00:05Z"
+//	    }
+//	  ],
+//	  "total": 1,
+//	  "page": 1,
+//	  "limit": 50
+//	}
+func GetCallQueue(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"GET"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	status, err := ExtractQueueStatus(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, limit, err := ExtractPagination(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	orgId := ExtractOrgId(r)
+
+	calls, err := sarah.GetCallQueue(orgId, status, page, limit)
+	if err != nil {
+		http.Error(w, "Failed to get call queue", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(calls)
+}
+
+// GetCallQueueStats handles GET requests to retrieve the depth of an organization's call queue,
+// its calls in flight and the concurrency limits that apply to it.
+//
+// HTTP Method: GET
+// Endpoint: /queue/stats
+//
+// The organization ID is obtained from the auth bearer token.
+//
+// Response:
+//   - 200 OK: Returns the queue stats
+//   - 405 Method Not Allowed: If not using GET method
+//   - 500 Internal Server Error: If database operation fails
+//
+// Example Response:
+//
+//	{
+//	  "depth": 118,
+//	  "by_status": { "queued": 118, "dispatched": 382, "failed": 2 },
+//	  "depth_by_phone_number": { "phone_0987654321fedcba": 118 },
+//	  "oldest_enqueued_at": "2024-01-01T12:00:00Z",
+//	  "active": 10,
+//	  "active_by_phone_number": { "phone_0987654321fedcba": 10 },
+//	  "limits": { "global": 50, "organization": 10, "phone_numbers": { "phone_0987654321fedcba": 10 } }
+//	}
+func GetCallQueueStats(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"GET"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	orgId := ExtractOrgId(r)
+
+	stats, err := sarah.GetCallQueueStats(orgId)
+	if err != nil {
+		http.Error(w, "Failed to get call queue stats", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(stats)
+}
+
+// CancelQueuedCall handles POST requests to remove a call from the call queue before it is dispatched.
+// Calls already sent to VapiAI are cancelled with /calls/cancel instead.
+//
+// HTTP Method: POST
+// Endpoint: /queue/cancel
+//
+// Query Parameters:
+//   - queuedCallId: The hex ObjectID of the queued call (required)
+//
+// Response:
+//   - 200 OK: Returns the cancelled queued call
+//   - 400 Bad Request: If queuedCallId is missing
+//   - 404 Not Found: If the queued call does not belong to the organization
+//   - 405 Method Not Allowed: If not using POST method
+//   - 409 Conflict: If the call already left the queue
+//   - 500 Internal Server Error: If database operation fails
+func CancelQueuedCall(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"POST"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	queuedCallId := ExtractQueuedCallId(r)
+	if queuedCallId == "" {
+		http.Error(w, "Missing queuedCallId", http.StatusBadRequest)
+		return
+	}
+
+	caller := ExtractCaller(r)
+
+	call, err := sarah.CancelQueuedCall(caller, queuedCallId)
+	if errors.Is(err, sarah.ErrNotFound) {
+		http.Error(w, "Queued call not found", http.StatusNotFound)
+		return
+	} else if errors.Is(err, sarah.ErrCallState) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to cancel queued call", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(call)
+}
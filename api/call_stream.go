@@ -0,0 +1,121 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sarah/sarah"
+	"time"
+)
+
+// streamRetry is the reconnection delay suggested to clients of the call stream, in milliseconds
+const streamRetry = 5000
+
+// StreamCalls handles GET requests to stream the organization's call lifecycle events as Server-Sent Events.
+// Events come from the VapiAI webhook receiver: a call status change (queued, ringing, in-progress, forwarding
+// or ended), the end-of-call report (sent as a second ended event with the ended reason), and every final
+// transcript. Each event's ID is the stored call event's ID: a client reconnecting with the Last-Event-ID
+// header receives the events it missed first. Without it, only new events are sent.
+// A comment is sent as a heartbeat while no event is sent for 15 seconds.
+//
+// HTTP Method: GET
+// Endpoint: /calls/stream
+//
+// Headers:
+//   - Last-Event-ID: The ID of the last event received, to resume a stream (optional)
+//
+// Query Parameters:
+//   - campaignId: Only stream events of calls placed by this campaign (optional)
+//   - assistantId: Only stream events of calls handled by this assistant (optional)
+//
+// Response:
+//   - 200 OK: An event stream (text/event-stream) that stays open until the client disconnects
+//   - 404 Not Found: If the campaign or assistant does not belong to the organization
+//   - 405 Method Not Allowed: If not using GET method
+//   - 500 Internal Server Error: If the events can't be read when the stream starts
+//
+// Example Response:
+//
+//	retry: 5000
+//
+//	id: 65a1b2c3d4e5f6a7b8c9d0e3
+//	event: ringing
+//	data: {"id":"65a1b2c3d4e5f6a7b8c9d0e3","event":"ringing","vapi_call_id":"call_abc123def456","assistant_id":"asst_1234567890abcdef","campaign_id":"507f1f77bcf86cd799439011","occurred_at":"2024-01-01T12:00:05Z"}
+//
+//	id: 65a1b2c3d4e5f6a7b8c9d0e4
+//	event: transcript
+//	data: {"id":"65a1b2c3d4e5f6a7b8c9d0e4","event":"transcript","vapi_call_id":"call_abc123def456","assistant_id":"asst_1234567890abcdef","campaign_id":"507f1f77bcf86cd799439011","role":"assistant","transcript":"Hi, this is Sarah from Acme.","occurred_at":"2024-01-01T12:00:09Z"}
+func StreamCalls(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"GET"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	controller := http.NewResponseController(w)
+
+	filter := sarah.CallStreamFilter{
+		CampaignId:  ExtractCampaignIdParam(r),
+		AssistantId: ExtractAssistantId(r),
+	}
+	caller := ExtractCaller(r)
+	writer := &eventStreamWriter{w: w, controller: controller}
+
+	err := sarah.StreamCallEvents(r.Context(), caller, filter, r.Header.Get("Last-Event-ID"), writer)
+	if errors.Is(err, sarah.ErrNotFound) {
+		http.Error(w, "Campaign or assistant not found", http.StatusNotFound)
+		return
+	} else if err != nil && !writer.opened {
+		http.Error(w, "Failed to stream call events", http.StatusInternalServerError)
+		return
+	} else if err != nil {
+		// The stream already started, the client reconnects and resumes with Last-Event-ID
+		log.Printf("Call stream for organization %s closed: %v", caller.OrgId, err)
+	}
+}
+
+// eventStreamWriter writes a call stream as Server-Sent Events.
+type eventStreamWriter struct {
+	w          http.ResponseWriter
+	controller *http.ResponseController
+	opened     bool
+}
+
+func (s *eventStreamWriter) Open() error {
+	// The stream stays open well past the server write timeout
+	if err := s.controller.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+
+	s.w.Header().Set("Content-Type", "text/event-stream")
+	s.w.Header().Set("Cache-Control", "no-cache")
+	s.w.Header().Set("Connection", "keep-alive")
+	s.w.Header().Set("X-Accel-Buffering", "no")
+	s.w.WriteHeader(http.StatusOK)
+	s.opened = true
+
+	if _, err := fmt.Fprintf(s.w, "retry: %d\n\n", streamRetry); err != nil {
+		return err
+	}
+	return s.controller.Flush()
+}
+
+func (s *eventStreamWriter) Send(event sarah.CallStreamEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(s.w, "id: %s\nevent: %s\ndata: %s\n\n", event.Id, event.Event, data); err != nil {
+		return err
+	}
+	return s.controller.Flush()
+}
+
+func (s *eventStreamWriter) Heartbeat() error {
+	if _, err := fmt.Fprint(s.w, ": heartbeat\n\n"); err != nil {
+		return err
+	}
+	return s.controller.Flush()
+}
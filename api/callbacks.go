@@ -0,0 +1,212 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sarah/sarah"
+	vapiTypes "sarah/types/vapi"
+)
+
+// CallbackTool handles POST requests sent by VapiAI when an assistant invokes the callback tool during a call.
+// Configure `https://<your-host>/tools/callback` as the server URL of a function tool, with
+// VAPI_WEBHOOK_SECRET as its secret. Every tool call registers a callback to the customer of the call,
+// placed with the same assistant and phone number once it is due.
+// It is authenticated with the shared VapiAI secret instead of a Clerk token.
+//
+// HTTP Method: POST
+// Endpoint: /tools/callback
+//
+// Headers:
+//   - X-Vapi-Secret: The shared secret (or X-Vapi-Signature: hex HMAC-SHA256 of the body)
+//
+// Request Body:
+//
+//	{
+//	  "message": {
+//	    "type": "tool-calls",
+//	    "toolCallList": [
+//	      {
+//	        "id": "toolu_01DTPAzUm5Gk3zxrpJ969oMF",
+//	        "type": "function",
+//	        "function": {
+//	          "name": "schedule_callback",
+//	          "arguments": { "time": "2024-01-02T15:00:00", "timeZone": "America/New_York", "reason": "Wants to discuss the renewal" }
+//	        }
+//	      }
+//	    ],
+//	    "call": {
+//	      "id": "call_abc123def456",
+//	      "assistantId": "asst_1234567890abcdef",
+//	      "phoneNumberId": "phone_0987654321fedcba",
+//	      "customer": { "number": "+1234567890" }
+//	    }
+//	  }
+//	}
+//
+// Response:
+//   - 200 OK: Returns one result per tool call; invalid arguments are reported in the result's error
+//   - 400 Bad Request: If the body is not a valid server message or has no call
+//   - 401 Unauthorized: If the secret or signature is invalid
+//   - 404 Not Found: If the call's assistant and phone number are not registered by any organization
+//   - 405 Method Not Allowed: If not using POST method
+//
+// Example Response:
+//
+//	{
+//	  "results": [
+//	    { "toolCallId": "toolu_01DTPAzUm5Gk3zxrpJ969oMF", "result": "Callback scheduled for 2024-01-02T20:00:00Z" }
+//	  ]
+//	}
+func CallbackTool(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"POST"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request vapiTypes.ServerMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid server message", http.StatusBadRequest)
+		return
+	}
+
+	if request.Message.Call == nil || request.Message.Call.Id == "" {
+		http.Error(w, "Server message has no call", http.StatusBadRequest)
+		return
+	}
+
+	_, results, err := sarah.HandleCallbackToolCalls(request.Message)
+	if errors.Is(err, sarah.ErrOrganizationNotFound) {
+		http.Error(w, "Call does not belong to any organization", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error handling callback tool calls: %v", err)
+		http.Error(w, "Failed to handle tool calls", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(vapiTypes.ToolCallsResponse{Results: results})
+}
+
+// GetCallbacks handles GET requests to retrieve the callbacks of an organization, soonest first.
+//
+// HTTP Method: GET
+// Endpoint: /callbacks/org
+//
+// Query Parameters:
+//   - status: Only return callbacks in this status: scheduled, queued, failed or cancelled (optional)
+//   - contactId: Only return callbacks of this contact (optional)
+//   - page: The 1-based page number (optional, defaults to 1)
+//   - limit: The number of callbacks per page (optional, defaults to 50, maximum 200)
+//
+// Response:
+//   - 200 OK: Returns a page of callbacks
+//   - 400 Bad Request: If the status, contact ID or pagination parameters are invalid
+//   - 405 Method Not Allowed: If not using GET method
+//   - 500 Internal Server Error: If database operation fails
+//
+// Example Response:
+//
+//	{
+//	  "callbacks": [
+//	    {
+//	      "id": "65a1b2c3d4e5f6a7b8c9d0e2",
+//	      "tool_call_id": "toolu_01DTPAzUm5Gk3zxrpJ969oMF",
+//	      "vapi_call_id": "call_abc123def456",
+//	      "contact_id": "507f1f77bcf86cd799439011",
+//	      "assistant_id": "asst_1234567890abcdef",
+//	      "phone_number_id": "phone_0987654321fedcba",
+//	      "customer": { "phone_number": "+1234567890", "phone_number_type": "mobile" },
+//	      "customer_name": "John Doe",
+//	      "scheduled_at": "2024-01-02T20:00:00Z",
+//	      "reason": "Wants to discuss the renewal",
+//	      "status": "scheduled",
+//	      "queued_call_id": null,
+//	      "error": "",
+//	      "created_at": "2024-01-01T12:01:30Z",
+//	      "updated_at": "2024-01-01T12:01:30Z"
+//	    }
+//	  ],
+//	  "total": 1,
+//	  "page": 1,
+//	  "limit": 50
+//	}
+func GetCallbacks(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"GET"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	status, err := ExtractCallbackStatus(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, limit, err := ExtractPagination(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	orgId := ExtractOrgId(r)
+
+	callbacks, err := sarah.GetCallbacks(orgId, status, ExtractContactId(r), page, limit)
+	if WriteValidationError(w, err) {
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to get callbacks", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(callbacks)
+}
+
+// CancelCallback handles POST requests to cancel a scheduled callback before it is queued.
+// Callbacks already queued are removed from the queue with /queue/cancel and their queued_call_id instead.
+//
+// HTTP Method: POST
+// Endpoint: /callbacks/cancel
+//
+// Query Parameters:
+//   - callbackId: The hex ObjectID of the callback (required)
+//
+// Response:
+//   - 200 OK: Returns the cancelled callback
+//   - 400 Bad Request: If callbackId is missing
+//   - 404 Not Found: If the callback does not belong to the organization
+//   - 405 Method Not Allowed: If not using POST method
+//   - 409 Conflict: If the callback is no longer scheduled
+//   - 500 Internal Server Error: If database operation fails
+func CancelCallback(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"POST"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	callbackId := ExtractCallbackId(r)
+	if callbackId == "" {
+		http.Error(w, "Missing callbackId", http.StatusBadRequest)
+		return
+	}
+
+	caller := ExtractCaller(r)
+
+	callback, err := sarah.CancelCallback(caller, callbackId)
+	if errors.Is(err, sarah.ErrNotFound) {
+		http.Error(w, "Callback not found", http.StatusNotFound)
+		return
+	} else if errors.Is(err, sarah.ErrCallState) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to cancel callback", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(callback)
+}
@@ -0,0 +1,214 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sarah/sarah"
+)
+
+// MAX_DNC_IMPORT_BYTES is the maximum size of a Do-Not-Call CSV import
+const MAX_DNC_IMPORT_BYTES = 20 << 20
+
+// GetOrganizationDnc handles GET requests to retrieve the Do-Not-Call list of an organization, newest first.
+// The global Do-Not-Call list loaded from DNC_GLOBAL_FILE is not included.
+//
+// HTTP Method: GET
+// Endpoint: /dnc/org
+//
+// Query Parameters:
+//   - page: The 1-based page number (optional, defaults to 1)
+//   - limit: The number of entries per page (optional, defaults to 50, maximum 200)
+//
+// Response:
+//   - 200 OK: Returns a page of the list
+//   - 400 Bad Request: If the pagination parameters are invalid
+//   - 405 Method Not Allowed: If not using GET method
+//   - 500 Internal Server Error: If database operation fails
+//
+// Example Response:
+//
+//	{
+//	  "entries": [
+//	    {
+//	      "id": "65a1b2c3d4e5f6a7b8c9d0e1",
+//	      "phone_number": "+1234567890",
+//	      "reason": "customer opted out",
+//	      "source": "manual",
+//	      "added_by": "user_1234567890abcdef",
+//	      "created_at": "2024-01-01T12:00:00Z"
+//	    }
+//	  ],
+//	  "total": 1,
+//	  "page": 1,
+//	  "limit": 50
+//	}
+func GetOrganizationDnc(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"GET"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	page, limit, err := ExtractPagination(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	orgId := ExtractOrgId(r)
+
+	entries, err := sarah.GetDncList(orgId, page, limit)
+	if err != nil {
+		http.Error(w, "Failed to get do-not-call list", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(entries)
+}
+
+// CreateDncEntry handles POST requests to add a phone number to the organization's Do-Not-Call list.
+// Adding a number that is already on the list keeps the existing entry.
+//
+// HTTP Method: POST
+// Endpoint: /dnc/create
+//
+// Request Body:
+//
+//	{
+//	  "entry": {
+//	    "phone_number": "+1234567890",
+//	    "reason": "customer opted out"
+//	  }
+//	}
+//
+// Response:
+//   - 201 Created: Number added, returns the entry
+//   - 400 Bad Request: If the request body is invalid or the value is not a phone number
+//   - 405 Method Not Allowed: If not using POST method
+//   - 500 Internal Server Error: If database operation fails
+func CreateDncEntry(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"POST"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	entry := ExtractDncEntry(r)
+	if entry == nil {
+		http.Error(w, "Invalid entry", http.StatusBadRequest)
+		return
+	}
+
+	caller := ExtractCaller(r)
+
+	created, err := sarah.AddDncNumber(caller, entry.PhoneNumber, entry.Reason)
+	if errors.Is(err, sarah.ErrInvalidDnc) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to add number to the do-not-call list", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// ImportDnc handles POST requests to add the numbers of a CSV file to the organization's Do-Not-Call list.
+// The file is sent either as the raw request body or as the "file" field of a multipart form.
+// It either has a header row with a phone number column ("phone_number", "phone" or "number")
+// and an optional "reason" column, or lists one number per line.
+//
+// HTTP Method: POST
+// Endpoint: /dnc/import
+//
+// Response:
+//   - 200 OK: Returns the number of imported and duplicate entries and the invalid rows by line
+//   - 400 Bad Request: If the file is missing, is not valid CSV or has more than 500,000 rows
+//   - 405 Method Not Allowed: If not using POST method
+//   - 413 Request Entity Too Large: If the file is larger than 20 MB
+//   - 500 Internal Server Error: If database operation fails
+//
+// Example Response:
+//
+//	{
+//	  "imported": 1250,
+//	  "duplicates": 12,
+//	  "invalid": { "7": "n/a" }
+//	}
+func ImportDnc(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"POST"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	file, err := ExtractCsvUpload(w, r, MAX_DNC_IMPORT_BYTES)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, "File too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	caller := ExtractCaller(r)
+
+	result, err := sarah.ImportDncCsv(caller, file)
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		http.Error(w, "File too large", http.StatusRequestEntityTooLarge)
+		return
+	} else if errors.Is(err, sarah.ErrInvalidDnc) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to import do-not-call list", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// DeleteDncEntry handles DELETE requests to remove a phone number from the organization's Do-Not-Call list.
+// Numbers on the global Do-Not-Call list stay suppressed.
+//
+// HTTP Method: DELETE
+// Endpoint: /dnc/delete
+//
+// Query Parameters:
+//   - phoneNumber: The phone number to remove, URL-encoded (required)
+//
+// Response:
+//   - 200 OK: Number removed, returns the mongodb delete result object
+//   - 400 Bad Request: If the value is not a phone number
+//   - 404 Not Found: If the number is not on the list
+//   - 405 Method Not Allowed: If not using DELETE method
+//   - 500 Internal Server Error: If database operation fails
+func DeleteDncEntry(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"DELETE"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	phoneNumber := ExtractPhoneNumberParam(r)
+	caller := ExtractCaller(r)
+
+	result, err := sarah.RemoveDncNumber(caller, phoneNumber)
+	if errors.Is(err, sarah.ErrInvalidDnc) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if errors.Is(err, sarah.ErrNotFound) {
+		http.Error(w, "Number is not on the do-not-call list", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to remove number from the do-not-call list", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
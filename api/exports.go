@@ -0,0 +1,115 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sarah/sarah"
+	"strings"
+	"time"
+)
+
+// exportWriteWindow is how long each batch of an export has to be written. The write deadline
+// is pushed back by this much after every batch, so exports only time out if the client stalls.
+const exportWriteWindow = 30 * time.Second
+
+// ExportCalls handles GET requests to export the organization's calls as a file.
+// This endpoint streams the calls stored in the organization's calls collection, oldest first,
+// so exports of any size complete without hitting the server write timeout.
+//
+// HTTP Method: GET
+// Endpoint: /calls/export
+//
+// Query Parameters:
+//   - format: "csv" or "jsonl" (optional, defaults to "csv")
+//   - columns: Comma-separated columns to export, in order (optional, defaults to
+//     vapi_call_id,created_at,customer_number,duration_seconds,ended_reason,cost,summary).
+//     Available: vapi_call_id, created_at, started_at, ended_at, assistant_id, phone_number_id,
+//     campaign_id, customer_number, customer_name, status, ended_reason, duration_seconds, cost,
+//     success_evaluation, summary, tags (";"-separated in CSV), disposition, note_count
+//   - assistantId: Only export calls handled by this assistant (optional)
+//   - campaignId: Only export calls placed by this campaign (optional)
+//   - from: Only export calls created at or after this date (optional, RFC 3339 or YYYY-MM-DD)
+//   - to: Only export calls created before this date (optional, RFC 3339 or YYYY-MM-DD)
+//
+// The other /calls/org filters (phoneNumberId, customerNumber, status, endedReason, flagged, tag,
+// disposition, hasNotes) are also supported.
+// The organization ID is obtained from the auth bearer token.
+//
+// Response:
+//   - 200 OK: The export file, sent as an attachment
+//   - 400 Bad Request: If the format, columns or filters are invalid
+//   - 405 Method Not Allowed: If not using GET method
+//
+// Example Response (format=csv&columns=customer_number,duration_seconds,ended_reason):
+//
+//	customer_number,duration_seconds,ended_reason
+//	+1234567890,95.4,customer-ended-call
+//	+1987654321,0,customer-did-not-answer
+func ExportCalls(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"GET"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	orgId := ExtractOrgId(r)
+
+	format := sarah.ExportFormat(strings.ToLower(strings.TrimSpace(r.URL.Query().Get("format"))))
+	if format == "" {
+		format = sarah.EXPORT_CSV
+	}
+
+	var contentType string
+	switch format {
+	case sarah.EXPORT_CSV:
+		contentType = "text/csv; charset=utf-8"
+	case sarah.EXPORT_JSONL:
+		contentType = "application/x-ndjson"
+	default:
+		http.Error(w, "Invalid format, expected csv or jsonl", http.StatusBadRequest)
+		return
+	}
+
+	columns, err := sarah.ParseExportColumns(r.URL.Query().Get("columns"))
+	if errors.Is(err, sarah.ErrInvalidExport) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter, err := ExtractCallFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	controller := http.NewResponseController(w)
+	extendDeadline := func() error {
+		if err := controller.SetWriteDeadline(time.Now().Add(exportWriteWindow)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+		return nil
+	}
+	if err := extendDeadline(); err != nil {
+		log.Printf("Could not extend write deadline for call export: %v", err)
+	}
+
+	filename := fmt.Sprintf("calls-%s.%s", time.Now().UTC().Format("2006-01-02"), format)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+
+	exported, err := sarah.ExportCalls(orgId, filter, format, columns, w, func() error {
+		if err := controller.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+		return extendDeadline()
+	})
+	if err != nil {
+		// The status line is already sent, the client sees a truncated file
+		log.Printf("Call export for organization %s aborted after %d calls: %v", orgId, exported, err)
+		return
+	}
+
+	log.Printf("Exported %d calls for organization %s", exported, orgId)
+}
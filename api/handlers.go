@@ -2,17 +2,18 @@ package api
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
 	"log"
 	"net/http"
 	"sarah/mongodb"
 	"sarah/sarah"
 	mongodbTypes "sarah/types/mongodb"
-	"sort"
+	"strings"
 )
 
-// CreateCall handles POST requests to create a new call using VapiAI.
-// This endpoint creates outbound calls to multiple phone numbers using a specified assistant.
+// CreateCall handles POST requests to create a batch of outbound calls using VapiAI.
+// This endpoint queues a call to every customer of the batch using a specified assistant, and returns
+// the outcome of each customer instead of failing the whole batch.
 //
 // HTTP Method: POST
 // Endpoint: /calls/create
@@ -24,23 +25,55 @@ import (
 // Request Body:
 //
 //	{
-//	  "phoneNumbers": ["+1234567890", "+1987654321"]
+//	  "customers": [
+//	    {
+//	      "phone_number": "+1234567890",
+//	      "name": "John Doe",
+//	      "timezone": "America/New_York",
+//	      "variables": { "plan": "gold", "renewal_date": "2024-02-01" }
+//	    }
+//	  ],
+//	  "phoneNumbers": ["+1987654321"]
 //	}
 //
+// A batch has up to 1000 customers; numbers of the flat "phoneNumbers" array are called without name,
+// time zone or variables. The name is sent to VapiAI, and the variables and time zone (as "timezone")
+// are given to the assistant as template variables, e.g. {{plan}}.
+//
+// Phone numbers are converted to E.164, reading national numbers in the organization's default country.
+// Each customer is "accepted" and its call added to the organization's call queue, "suppressed" if its
+// number is on the organization's or the global Do-Not-Call list, "invalid" if a field is invalid or its
+// number is repeated, or "failed" if the call couldn't be queued, e.g. beyond the daily cap of the phone number.
+// Queued calls get their VapiAI call ID once they are dispatched (see /queue/org).
+//
 // Response:
-//   - 201 Created: Call created successfully, returns the call details
-//   - 400 Bad Request: If no phone numbers are provided
+//   - 202 Accepted: At least one call was queued, returns the queued calls and the result of every customer
+//   - 400 Bad Request: If the request body is invalid, or a JSON error if the batch is empty or too large
+//   - 404 Not Found: If the assistant or phone number does not belong to the organization
 //   - 405 Method Not Allowed: If not using POST method
-//   - 500 Internal Server Error: If VapiAI API call fails
+//   - 422 Unprocessable Entity: If no call was queued, returns the result of every customer
+//   - 500 Internal Server Error: If database operation fails
 //
 // Example Response:
 //
 //	{
-//	  "id": "call_abc123def456",
-//	  "assistantId": "asst_1234567890abcdef",
-//	  "phoneNumberId": "phone_0987654321fedcba",
-//	  "status": "queued",
-//	  "createdAt": "2024-01-01T12:00:00Z"
+//	  "calls": [
+//	    {
+//	      "id": "65a1b2c3d4e5f6a7b8c9d0e1",
+//	      "assistant_id": "asst_1234567890abcdef",
+//	      "phone_number_id": "phone_0987654321fedcba",
+//	      "customer": { "phone_number": "+1234567890", "phone_number_type": "mobile", "name": "John Doe", ... },
+//	      "status": "queued",
+//	      "vapi_call_id": "",
+//	      "enqueued_at": "2024-01-01T12:00:00Z"
+//	    }
+//	  ],
+//	  "suppressed": ["+1987654321"],
+//	  "results": [
+//	    { "index": 0, "phone_number": "+1234567890", "status": "accepted", "queued_call_id": "65a1b2c3d4e5f6a7b8c9d0e1" },
+//	    { "index": 1, "phone_number": "+1987654321", "status": "suppressed" }
+//	  ],
+//	  "counts": { "accepted": 1, "suppressed": 1 }
 //	}
 func CreateCall(w http.ResponseWriter, r *http.Request) {
 	if !VerifyMethod(r, []string{"POST"}) {
@@ -49,33 +82,37 @@ func CreateCall(w http.ResponseWriter, r *http.Request) {
 	}
 
 	assistantId := ExtractAssistantId(r)
-	phoneNumbers := ExtractPhoneNumbers(r)
 	assistantNumberId := ExtractAssistantNumberId(r)
-
-	customers := []mongodbTypes.Customer{}
-	for _, phoneNumber := range phoneNumbers {
-		customers = append(customers, mongodbTypes.Customer{
-			PhoneNumber: phoneNumber,
-		})
+	customers := ExtractCallCustomers(r)
+	if customers == nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
 	}
+	caller := ExtractCaller(r)
 
-	resp, err := sarah.CreateCall(assistantId, assistantNumberId, customers)
-
-	if resp == nil {
-		http.Error(w, "Failed to create call", http.StatusInternalServerError)
+	batch, err := sarah.CreateCallBatch(caller, assistantId, assistantNumberId, customers)
+	if errors.Is(err, sarah.ErrNotFound) {
+		http.Error(w, "Assistant or phone number not found", http.StatusNotFound)
+		return
+	} else if WriteValidationError(w, err) {
 		return
 	} else if err != nil {
 		http.Error(w, "Failed to create call", http.StatusInternalServerError)
 		return
 	}
 
-	fmt.Printf("Call created successfully: %+v\n", resp)
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(resp)
+	status := http.StatusAccepted
+	if batch.Counts[sarah.CALL_BATCH_ACCEPTED] == 0 {
+		status = http.StatusUnprocessableEntity
+	}
+
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(batch)
 }
 
 // GetCall handles GET requests to retrieve a specific call by its ID.
 // This endpoint fetches detailed information about a single call from VapiAI.
+// The call is only returned if its assistant or phone number belongs to the organization from the auth bearer token.
 //
 // HTTP Method: GET
 // Endpoint: /calls/call
@@ -85,8 +122,10 @@ func CreateCall(w http.ResponseWriter, r *http.Request) {
 //
 // Response:
 //   - 200 OK: Call retrieved successfully, returns the call details
+//   - 404 Not Found: If the call does not belong to the organization
 //   - 405 Method Not Allowed: If not using GET method
 //   - 500 Internal Server Error: If VapiAI API call fails
+//   - 503 Service Unavailable: If VapiAI is unavailable and its circuit is open
 //
 // Example Response:
 //
@@ -106,10 +145,17 @@ func GetCall(w http.ResponseWriter, r *http.Request) {
 	}
 
 	callId := ExtractCallId(r)
+	caller := ExtractCaller(r)
 
-	resp, err := sarah.GetCall(callId)
+	resp, err := sarah.GetCall(caller, callId)
 
-	if resp == nil {
+	if errors.Is(err, sarah.ErrNotFound) {
+		http.Error(w, "Call not found", http.StatusNotFound)
+		return
+	} else if errors.Is(err, sarah.ErrCircuitOpen) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	} else if resp == nil {
 		http.Error(w, "Failed to get call", http.StatusInternalServerError)
 		return
 	} else if err != nil {
@@ -124,6 +170,8 @@ func GetCall(w http.ResponseWriter, r *http.Request) {
 
 // ListCalls handles GET requests to list calls based on specified criteria.
 // This endpoint retrieves a list of calls from VapiAI using optional filtering parameters.
+// Results are restricted to the assistants of the organization from the auth bearer token:
+// a foreign assistantId is rejected, and without one every organization assistant is listed.
 //
 // HTTP Method: POST
 // Endpoint: /calls/list
@@ -141,8 +189,10 @@ func GetCall(w http.ResponseWriter, r *http.Request) {
 //
 // Response:
 //   - 200 OK: Calls retrieved successfully, returns an array of calls
+//   - 404 Not Found: If the assistantId does not belong to the organization
 //   - 405 Method Not Allowed: If not using GET method
 //   - 500 Internal Server Error: If VapiAI API call fails
+//   - 503 Service Unavailable: If VapiAI is unavailable and its circuit is open
 //
 // Example Response:
 //
@@ -161,10 +211,17 @@ func ListCalls(w http.ResponseWriter, r *http.Request) {
 	}
 
 	callListRequest := ExtractCallListRequest(r)
+	caller := ExtractCaller(r)
 
-	calls, err := sarah.ListCalls(callListRequest)
+	calls, err := sarah.ListCalls(caller, callListRequest)
 
-	if calls == nil {
+	if errors.Is(err, sarah.ErrNotFound) {
+		http.Error(w, "Assistant not found", http.StatusNotFound)
+		return
+	} else if errors.Is(err, sarah.ErrCircuitOpen) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	} else if calls == nil {
 		http.Error(w, "Failed to list calls", http.StatusInternalServerError)
 		return
 	} else if err != nil {
@@ -176,27 +233,53 @@ func ListCalls(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(calls)
 }
 
-// GetCallListByOrgId handles GET requests to retrieve all calls for an organization.
-// This endpoint aggregates calls from all assistants belonging to the organization from the auth bearer token.
+// GetCallListByOrgId handles GET requests to retrieve the calls of an organization.
+// This endpoint serves the calls stored in the organization's calls collection, newest first,
+// for the organization from the auth bearer token.
 //
 // HTTP Method: GET
 // Endpoint: /calls/org
 //
+// Query Parameters:
+//   - page: The 1-based page number (optional, defaults to 1)
+//   - limit: The number of calls per page (optional, defaults to 50, maximum 200)
+//   - assistantId: Only return calls handled by this assistant (optional)
+//   - phoneNumberId: Only return calls placed from this phone number (optional)
+//   - campaignId: Only return calls placed by this campaign (optional)
+//   - customerNumber: Only return calls to this customer number (optional)
+//   - status: Only return calls in this status (optional)
+//   - endedReason: Only return calls that ended for this reason (optional)
+//   - flagged: Set to true to only return calls flagged by an alert rule (optional)
+//   - tag: Only return calls with this tag (optional, repeated or comma-separated to require every tag)
+//   - disposition: Only return calls with this disposition code (optional)
+//   - hasNotes: Set to true to only return calls with notes (optional)
+//   - from: Only return calls created at or after this date (optional, RFC 3339 or YYYY-MM-DD)
+//   - to: Only return calls created before this date (optional, RFC 3339 or YYYY-MM-DD)
+//
 // Response:
-//   - 200 OK: Calls retrieved successfully, returns an array of calls sorted by creation date
+//   - 200 OK: Calls retrieved successfully, returns a page of calls sorted by creation date
+//   - 400 Bad Request: If the pagination or filter parameters are invalid
 //   - 405 Method Not Allowed: If not using GET method
-//   - 500 Internal Server Error: If database or VapiAI API calls fail
+//   - 500 Internal Server Error: If database operation fails
 //
 // Example Response:
 //
-//	[
-//	  {
-//	    "id": "call_abc123def456",
-//	    "assistantId": "asst_1234567890abcdef",
-//	    "status": "completed",
-//	    "createdAt": "2024-01-01T12:00:00Z"
-//	  }
-//	]
+//	{
+//	  "calls": [
+//	    {
+//	      "id": "507f1f77bcf86cd799439011",
+//	      "vapi_call_id": "call_abc123def456",
+//	      "assistant_id": "asst_1234567890abcdef",
+//	      "customer_number": "+1234567890",
+//	      "status": "ended",
+//	      "ended_reason": "customer-ended-call",
+//	      "created_at": "2024-01-01T12:00:00Z"
+//	    }
+//	  ],
+//	  "total": 1,
+//	  "page": 1,
+//	  "limit": 50
+//	}
 func GetCallListByOrgId(w http.ResponseWriter, r *http.Request) {
 	if !VerifyMethod(r, []string{"GET"}) {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -205,11 +288,19 @@ func GetCallListByOrgId(w http.ResponseWriter, r *http.Request) {
 
 	orgID := ExtractOrgId(r)
 
-	calls, err := sarah.GetOrganizationCalls(orgID)
+	page, limit, err := ExtractPagination(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter, err := ExtractCallFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	sort.Slice(calls, func(i, j int) bool {
-		return calls[i].CreatedAt.After(calls[j].CreatedAt)
-	})
+	calls, err := sarah.GetOrganizationCalls(orgID, filter, page, limit)
 	if err != nil {
 		http.Error(w, "Failed to get organization calls", http.StatusInternalServerError)
 		return
@@ -219,6 +310,138 @@ func GetCallListByOrgId(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(calls)
 }
 
+// SearchCalls handles GET requests to search the transcripts of the organization's calls.
+// This endpoint runs a full-text search over the transcripts stored at the end of every call and
+// returns the matching calls, most relevant first, for the organization from the auth bearer token.
+// Words are matched with English stemming, so "cancel" also finds "cancelled".
+//
+// HTTP Method: GET
+// Endpoint: /calls/search
+//
+// Query Parameters:
+//   - q: The search text; words, "quoted phrases" and -excluded words are supported (required)
+//   - assistantId: Only search calls handled by this assistant (optional)
+//   - campaignId: Only search calls placed by this campaign (optional)
+//   - from: Only search calls created at or after this date (optional, RFC 3339 or YYYY-MM-DD)
+//   - to: Only search calls created before this date (optional, RFC 3339 or YYYY-MM-DD)
+//   - page: The 1-based page number (optional, defaults to 1)
+//   - limit: The number of calls per page (optional, defaults to 50, maximum 200)
+//
+// Response:
+//   - 200 OK: Returns a page of matching calls with up to three highlighted snippets each
+//   - 400 Bad Request: If q is missing or the pagination or filter parameters are invalid
+//   - 405 Method Not Allowed: If not using GET method
+//   - 500 Internal Server Error: If database operation fails
+//
+// Snippets are HTML-escaped, with the matched terms wrapped in <mark></mark>.
+//
+// Example Response:
+//
+//	{
+//	  "matches": [
+//	    {
+//	      "vapi_call_id": "call_abc123def456",
+//	      "assistant_id": "asst_1234567890abcdef",
+//	      "campaign_id": "507f1f77bcf86cd799439011",
+//	      "customer_number": "+1234567890",
+//	      "call_created_at": "2024-01-01T12:00:00Z",
+//	      "score": 2.25,
+//	      "snippets": [
+//	        { "role": "user", "seconds_from_start": 42.1, "text": "I want to <mark>cancel</mark> my <mark>policy</mark>" }
+//	      ]
+//	    }
+//	  ],
+//	  "total": 1,
+//	  "page": 1,
+//	  "limit": 50
+//	}
+func SearchCalls(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"GET"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	orgId := ExtractOrgId(r)
+
+	search := strings.TrimSpace(r.URL.Query().Get("q"))
+	if search == "" {
+		http.Error(w, "Missing q", http.StatusBadRequest)
+		return
+	}
+
+	page, limit, err := ExtractPagination(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter, err := ExtractTranscriptFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	results, err := sarah.SearchTranscripts(orgId, search, filter, page, limit)
+	if err != nil {
+		http.Error(w, "Failed to search calls", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(results)
+}
+
+// SyncCalls handles POST requests to sync the organization's calls from VapiAI.
+// This endpoint copies every call of the organization's assistants into the calls collection,
+// which is useful right after registering an assistant that already placed calls.
+//
+// HTTP Method: POST
+// Endpoint: /calls/sync
+//
+// Query Parameters:
+//   - from: Only sync calls updated at or after this date (optional, RFC 3339 or YYYY-MM-DD)
+//
+// The organization ID is obtained from the auth bearer token.
+//
+// Response:
+//   - 200 OK: Calls synced, returns the number of synced calls
+//   - 400 Bad Request: If the from parameter is invalid
+//   - 405 Method Not Allowed: If not using POST method
+//   - 500 Internal Server Error: If VapiAI or database operations fail
+//   - 503 Service Unavailable: If VapiAI is unavailable and its circuit is open
+//
+// Example Response:
+//
+//	{
+//	  "synced": 42
+//	}
+func SyncCalls(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"POST"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	orgId := ExtractOrgId(r)
+
+	since, _, err := ExtractDateRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	synced, err := sarah.SyncOrganizationCalls(orgId, since)
+	if errors.Is(err, sarah.ErrCircuitOpen) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to sync calls", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]int{"synced": synced})
+}
+
 // GetOrganizationAssistants handles GET requests to retrieve all assistants for an organization.
 // This endpoint returns all VapiAI assistants that belong to the organization from the auth bearer token.
 //
@@ -313,6 +536,7 @@ func GetOrganizationAssistants(w http.ResponseWriter, r *http.Request) {
 //   - 200 OK: Assistant created successfully, returns the created assistant object
 //   - 405 Method Not Allowed: If not using POST method
 //   - 400 Bad Request: If the request body is invalid
+//   - 503 Service Unavailable: If VapiAI is unavailable and its circuit is open
 //
 // Example Request:
 //
@@ -333,11 +557,14 @@ func CreateAssistant(w http.ResponseWriter, r *http.Request) {
 	}
 
 	assistantCreateDto := ExtractAssistantCreateDto(r)
-	orgId := ExtractOrgId(r)
+	caller := ExtractCaller(r)
 
-	result, err := sarah.CreateAsisstant(orgId, *assistantCreateDto)
+	result, err := sarah.CreateAsisstant(caller, *assistantCreateDto)
 
-	if result == nil {
+	if errors.Is(err, sarah.ErrCircuitOpen) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	} else if result == nil {
 		http.Error(w, "Failed to create assistant", http.StatusInternalServerError)
 		return
 	} else if err != nil {
@@ -364,6 +591,12 @@ func CreateAssistant(w http.ResponseWriter, r *http.Request) {
 //	}
 //
 // The organization ID is obtained from the auth bearer token.
+//
+// Response:
+//   - 200 OK: Assistant registered successfully, returns the mongodb insert one result object
+//   - 404 Not Found: If the assistant does not exist in VapiAI or is registered by another organization
+//   - 405 Method Not Allowed: If not using POST method
+//   - 500 Internal Server Error: If database operation fails
 func RegisterAssistant(w http.ResponseWriter, r *http.Request) {
 	if !VerifyMethod(r, []string{"POST"}) {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -371,16 +604,14 @@ func RegisterAssistant(w http.ResponseWriter, r *http.Request) {
 	}
 
 	assistant := ExtractAssistant(r)
-	orgId := ExtractOrgId(r)
+	caller := ExtractCaller(r)
 
-	if !sarah.ExistsAssistant(assistant.VapiAssistantId) {
-		http.Error(w, "Assistant does not exist in VapiAI", http.StatusBadRequest)
-		return
-	}
-
-	result, err := mongodb.CreateAssistant(orgId, *assistant)
+	result, err := sarah.RegisterAssistant(caller, *assistant)
 
-	if result == nil {
+	if errors.Is(err, sarah.ErrNotFound) {
+		http.Error(w, "Assistant not found", http.StatusNotFound)
+		return
+	} else if result == nil {
 		http.Error(w, "Failed to create assistant", http.StatusInternalServerError)
 		return
 	} else if err != nil {
@@ -448,8 +679,10 @@ func RegisterAssistant(w http.ResponseWriter, r *http.Request) {
 //
 // Response:
 //   - 200 OK: Assistant updated successfully, returns the updated assistant object
+//   - 404 Not Found: If the assistant does not belong to the organization
 //   - 405 Method Not Allowed: If not using PUT method
 //   - 400 Bad Request: If the request body is invalid
+//   - 503 Service Unavailable: If VapiAI is unavailable and its circuit is open
 //
 // Example Request:
 //
@@ -472,10 +705,17 @@ func UpdateAssistant(w http.ResponseWriter, r *http.Request) {
 
 	assistantUpdateDto := ExtractAssistantUpdateDto(r)
 	assistantId := ExtractAssistantId(r)
+	caller := ExtractCaller(r)
 
-	result, err := sarah.UpdateAssistant(assistantId, *assistantUpdateDto)
+	result, err := sarah.UpdateAssistant(caller, assistantId, *assistantUpdateDto)
 
-	if result == nil {
+	if errors.Is(err, sarah.ErrNotFound) {
+		http.Error(w, "Assistant not found", http.StatusNotFound)
+		return
+	} else if errors.Is(err, sarah.ErrCircuitOpen) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	} else if result == nil {
 		http.Error(w, "Failed to update assistant", http.StatusInternalServerError)
 		return
 	} else if err != nil {
@@ -500,8 +740,10 @@ func UpdateAssistant(w http.ResponseWriter, r *http.Request) {
 //
 // Response:
 //   - 200 OK: Assistant deleted successfully, returns the deleted assistant object
+//   - 404 Not Found: If the assistant does not belong to the organization
 //   - 405 Method Not Allowed: If not using DELETE method
 //   - 500 Internal Server Error: If database operation fails
+//   - 503 Service Unavailable: If VapiAI is unavailable and its circuit is open
 //
 // Example Request:
 //
@@ -523,11 +765,17 @@ func DeleteAssistant(w http.ResponseWriter, r *http.Request) {
 	}
 
 	assistantId := ExtractAssistantId(r)
-	orgId := ExtractOrgId(r)
+	caller := ExtractCaller(r)
 
-	result, err := sarah.DeleteAssistant(orgId, assistantId)
+	result, err := sarah.DeleteAssistant(caller, assistantId)
 
-	if result == nil {
+	if errors.Is(err, sarah.ErrNotFound) {
+		http.Error(w, "Assistant not found", http.StatusNotFound)
+		return
+	} else if errors.Is(err, sarah.ErrCircuitOpen) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	} else if result == nil {
 		http.Error(w, "Failed to delete assistant", http.StatusInternalServerError)
 		return
 	} else if err != nil {
@@ -552,6 +800,8 @@ func DeleteAssistant(w http.ResponseWriter, r *http.Request) {
 //	    "name": "Weekly Insurance Reminders",
 //	    "assistant_id": "asst_1234567890abcdef",
 //	    "phone_number_id": "phone_0987654321fedcba",
+//	    "phone_number_pool": ["phone_0987654321fedcba", "phone_1122334455aabbcc"],
+//	    "caller_id_strategy": "local_presence",
 //	    "schedule_plan": {
 //	      "before_day": 3,
 //	      "after_day": 0,
@@ -577,10 +827,15 @@ func DeleteAssistant(w http.ResponseWriter, r *http.Request) {
 //	  }
 //	}
 //
+// When phone_number_pool is set, calls are rotated across its numbers instead of phone_number_id,
+// with caller_id_strategy "round_robin" (default), "least_used" or "local_presence".
+//
 // The organization ID is obtained from the auth bearer token.
 //
 // Response:
 //   - 200 OK: Campaign created successfully, returns the created campaign
+//   - 400 Bad Request: If a customer phone number or the caller ID strategy is invalid, returns a JSON list of the invalid fields
+//   - 404 Not Found: If the assistant or a phone number does not belong to the organization
 //   - 405 Method Not Allowed: If not using POST method
 //   - 500 Internal Server Error: If database operation fails
 func CreateCampaign(w http.ResponseWriter, r *http.Request) {
@@ -590,12 +845,17 @@ func CreateCampaign(w http.ResponseWriter, r *http.Request) {
 	}
 
 	campaignCreateDto := ExtractCampaignCreateDto(r)
-	orgId := ExtractOrgId(r)
+	caller := ExtractCaller(r)
 
 	// Adds the campaign to the database
-	campaign, err := sarah.CreateCampaign(*campaignCreateDto, orgId)
+	campaign, err := sarah.CreateCampaign(caller, *campaignCreateDto)
 
-	if campaign == nil {
+	if errors.Is(err, sarah.ErrNotFound) {
+		http.Error(w, "Assistant or phone number not found", http.StatusNotFound)
+		return
+	} else if WriteValidationError(w, err) {
+		return
+	} else if campaign == nil {
 		http.Error(w, "Failed to create campaign", http.StatusInternalServerError)
 		return
 	} else if err != nil {
@@ -649,6 +909,8 @@ func CreateCampaign(w http.ResponseWriter, r *http.Request) {
 //
 // Response:
 //   - 200 OK: Campaign updated successfully, returns the updated campaign
+//   - 400 Bad Request: If a customer phone number or the caller ID strategy is invalid, returns a JSON list of the invalid fields
+//   - 404 Not Found: If the assistant or a phone number does not belong to the organization
 //   - 405 Method Not Allowed: If not using PATCH method
 //   - 500 Internal Server Error: If database operation fails
 
@@ -668,11 +930,16 @@ func UpdateCampaign(w http.ResponseWriter, r *http.Request) {
 	}
 
 	campaignUpdateDto := ExtractCampaignUpdateDto(r)
-	orgId := ExtractOrgId(r)
+	caller := ExtractCaller(r)
 
-	result, err := mongodb.UpdateCampaign(orgId, *campaignUpdateDto)
+	result, err := sarah.UpdateCampaign(caller, *campaignUpdateDto)
 
-	if result == nil {
+	if errors.Is(err, sarah.ErrNotFound) {
+		http.Error(w, "Assistant or phone number not found", http.StatusNotFound)
+		return
+	} else if WriteValidationError(w, err) {
+		return
+	} else if result == nil {
 		http.Error(w, "Failed to update campaign", http.StatusInternalServerError)
 		return
 	} else if err != nil {
@@ -775,6 +1042,91 @@ func GetCampaignViaOrgID(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(campaigns)
 }
 
+// GetCampaignAnalytics handles GET requests to retrieve the progress metrics of a campaign.
+// This endpoint aggregates the calls stored for the campaign so dashboards don't need to
+// fetch and crunch every call client-side.
+//
+// HTTP Method: GET
+// Endpoint: /campaigns/analytics
+//
+// Query Parameters:
+//   - campaignId: The campaign ID to compute analytics for (required)
+//   - from: Only include calls created at or after this date (optional, RFC 3339 or YYYY-MM-DD)
+//   - to: Only include calls created before this date (optional, RFC 3339 or YYYY-MM-DD)
+//   - interval: Bucket size for the time series, "day" or "week" (optional, defaults to "day")
+//
+// The organization ID is obtained from the auth bearer token.
+//
+// Response:
+//   - 200 OK: Returns the campaign analytics
+//   - 400 Bad Request: If the campaign ID is missing or the range/interval is invalid
+//   - 404 Not Found: If the organization has no such campaign
+//   - 405 Method Not Allowed: If not using GET method
+//   - 500 Internal Server Error: If database operation fails
+//
+// Example Response:
+//
+//	{
+//	  "campaign_id": "507f1f77bcf86cd799439011",
+//	  "from": "2024-01-01T00:00:00Z",
+//	  "to": null,
+//	  "interval": "day",
+//	  "totals": {
+//	    "customers_called": 120,
+//	    "calls_placed": 140,
+//	    "calls_answered": 96,
+//	    "successful_calls": 61,
+//	    "success_rate": 0.635,
+//	    "total_duration_seconds": 10450.5,
+//	    "average_duration_seconds": 108.8,
+//	    "total_cost": 23.41
+//	  },
+//	  "ended_reasons": [
+//	    { "reason": "customer-ended-call", "count": 80 }
+//	  ],
+//	  "buckets": [
+//	    { "start": "2024-01-01T05:00:00Z", "customers_called": 40, "calls_placed": 45, ... }
+//	  ]
+//	}
+func GetCampaignAnalytics(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"GET"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	campaignId := ExtractCampaignIdParam(r)
+	if campaignId == "" {
+		http.Error(w, "Missing campaignId", http.StatusBadRequest)
+		return
+	}
+
+	from, to, err := ExtractDateRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	interval, ok := ExtractAnalyticsInterval(r)
+	if !ok {
+		http.Error(w, "Invalid interval, expected day or week", http.StatusBadRequest)
+		return
+	}
+
+	caller := ExtractCaller(r)
+
+	analytics, err := sarah.GetCampaignAnalytics(caller, campaignId, from, to, interval)
+	if errors.Is(err, sarah.ErrNotFound) {
+		http.Error(w, "Campaign not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to get campaign analytics", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(analytics)
+}
+
 // GetOrganizationContacts handles GET requests to retrieve all contacts for an organization.
 // This endpoint returns all customer contacts that belong to the organization from the auth bearer token.
 //
@@ -838,6 +1190,7 @@ func GetOrganizationContacts(w http.ResponseWriter, r *http.Request) {
 //
 // Response:
 //   - 200 OK: Contact created successfully, returns the created contact
+//   - 400 Bad Request: If the request body is invalid, or a JSON list of the invalid phone numbers
 //   - 405 Method Not Allowed: If not using POST method
 //   - 500 Internal Server Error: If database operation fails
 //
@@ -856,11 +1209,18 @@ func CreateContact(w http.ResponseWriter, r *http.Request) {
 	}
 
 	contact := ExtractContact(r)
+	if contact == nil {
+		http.Error(w, "Invalid contact", http.StatusBadRequest)
+		return
+	}
+
 	orgId := ExtractOrgId(r)
 
-	result, err := mongodb.CreateContact(orgId, *contact)
+	result, err := sarah.CreateContact(orgId, *contact)
 
-	if result == nil {
+	if WriteValidationError(w, err) {
+		return
+	} else if result == nil {
 		http.Error(w, "Failed to create contact", http.StatusInternalServerError)
 		return
 	} else if err != nil {
@@ -894,6 +1254,7 @@ func CreateContact(w http.ResponseWriter, r *http.Request) {
 //
 // Response:
 //   - 200 OK: Contact updated successfully, returns the updated contact
+//   - 400 Bad Request: If the request body is invalid, or a JSON list of the invalid phone numbers
 //   - 405 Method Not Allowed: If not using PATCH method
 //   - 500 Internal Server Error: If database operation fails
 //
@@ -913,11 +1274,18 @@ func UpdateContact(w http.ResponseWriter, r *http.Request) {
 	}
 
 	contact := ExtractContact(r)
+	if contact == nil {
+		http.Error(w, "Invalid contact", http.StatusBadRequest)
+		return
+	}
+
 	orgId := ExtractOrgId(r)
 
-	result, err := mongodb.UpdateContact(orgId, *contact)
+	result, err := sarah.UpdateContact(orgId, *contact)
 
-	if result == nil {
+	if WriteValidationError(w, err) {
+		return
+	} else if result == nil {
 		http.Error(w, "Failed to update contact", http.StatusInternalServerError)
 		return
 	} else if err != nil {
@@ -974,6 +1342,77 @@ func DeleteContact(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(result)
 }
 
+// GetContactUpdates handles GET requests to retrieve the metadata updates written to a contact
+// from the analysis of its calls, newest first.
+//
+// HTTP Method: GET
+// Endpoint: /contacts/updates
+//
+// Query Parameters:
+//   - contactId: The contact ID (required)
+//   - page: The 1-based page number (optional, defaults to 1)
+//   - limit: The number of updates per page (optional, defaults to 50, maximum 200)
+//
+// The organization ID is obtained from the auth bearer token.
+//
+// Response:
+//   - 200 OK: Returns a page of the contact's update history
+//   - 400 Bad Request: If contactId is missing or the pagination parameters are invalid
+//   - 404 Not Found: If the contact does not belong to the organization
+//   - 405 Method Not Allowed: If not using GET method
+//   - 500 Internal Server Error: If database operation fails
+//
+// Example Response:
+//
+//	{
+//	  "updates": [
+//	    {
+//	      "id": "65a1b2c3d4e5f6a7b8c9d0e3",
+//	      "contact_id": "507f1f77bcf86cd799439011",
+//	      "vapi_call_id": "call_abc123def456",
+//	      "changes": [
+//	        { "field": "structured_data.renewal_confirmed", "metadata_key": "renewed", "old_value": false, "new_value": true }
+//	      ],
+//	      "created_at": "2024-01-01T12:02:10Z"
+//	    }
+//	  ],
+//	  "total": 1,
+//	  "page": 1,
+//	  "limit": 50
+//	}
+func GetContactUpdates(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"GET"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	contactId := ExtractContactId(r)
+	if contactId == "" {
+		http.Error(w, "Missing contactId", http.StatusBadRequest)
+		return
+	}
+
+	page, limit, err := ExtractPagination(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	caller := ExtractCaller(r)
+
+	updates, err := sarah.GetContactUpdates(caller, contactId, page, limit)
+	if errors.Is(err, sarah.ErrNotFound) {
+		http.Error(w, "Contact not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to get contact updates", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(updates)
+}
+
 // GetOrganizationPhoneNumbers handles GET requests to retrieve all phone numbers for an organization.
 // This endpoint returns all VapiAI phone numbers that belong to the organization from the auth bearer token.
 //
@@ -1026,12 +1465,18 @@ func GetOrganizationPhoneNumbers(w http.ResponseWriter, r *http.Request) {
 //	  "phoneNumber": {
 //	    "name": "Main Office Line",
 //	    "phoneNumber": "+1987654321",
-//	    "vapiPhoneNumberId": "phone_0987654321fedcba"
+//	    "vapiPhoneNumberId": "phone_0987654321fedcba",
+//	    "daily_cap": 200
 //	  }
 //	}
 //
+// The phone number is converted to E.164, reading a national number in the organization's default country.
+// A daily_cap of 0 places calls from the number without a limit.
+//
 // Response:
 //   - 200 OK: Phone number created successfully, returns the created phone number
+//   - 400 Bad Request: If the phone number or daily cap is invalid, returns a JSON list of the invalid fields
+//   - 404 Not Found: If the phone number does not exist in VapiAI or is registered by another organization
 //   - 405 Method Not Allowed: If not using POST method
 //   - 500 Internal Server Error: If database operation fails
 //
@@ -1050,11 +1495,16 @@ func CreatePhoneNumber(w http.ResponseWriter, r *http.Request) {
 	}
 
 	phoneNumber := ExtractPhoneNumber(r)
-	orgId := ExtractOrgId(r)
+	caller := ExtractCaller(r)
 
-	result, err := mongodb.CreatePhoneNumber(orgId, *phoneNumber)
+	result, err := sarah.CreatePhoneNumber(caller, *phoneNumber)
 
-	if result == nil {
+	if errors.Is(err, sarah.ErrNotFound) {
+		http.Error(w, "Phone number not found", http.StatusNotFound)
+		return
+	} else if WriteValidationError(w, err) {
+		return
+	} else if result == nil {
 		http.Error(w, "Failed to create phone number", http.StatusInternalServerError)
 		return
 	} else if err != nil {
@@ -1066,6 +1516,75 @@ func CreatePhoneNumber(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(result)
 }
 
+// UpdatePhoneNumber handles PATCH requests to update the name, daily cap and concurrency limit of a phone number.
+//
+// HTTP Method: PATCH
+// Endpoint: /phone_numbers/update
+//
+// Request Body:
+//
+//	{
+//	  "phoneNumber": {
+//	    "name": "Main Office Line",
+//	    "phone_number_id": "phone_0987654321fedcba",
+//	    "daily_cap": 200,
+//	    "max_concurrent_calls": 5
+//	  }
+//	}
+//
+// A daily_cap of 0 places calls from the number without a limit. Caps count calls per UTC day.
+// max_concurrent_calls limits the calls in flight from the number at once, 0 meaning no limit of its own.
+//
+// Response:
+//   - 200 OK: Phone number updated successfully, returns the update result
+//   - 400 Bad Request: If the request body is invalid, or a JSON list of the invalid fields
+//   - 404 Not Found: If the phone number does not belong to the organization
+//   - 405 Method Not Allowed: If not using PATCH method
+//   - 500 Internal Server Error: If database operation fails
+//
+// Example Response:
+//
+//	{
+//	  "MatchedCount": 1,
+//	  "ModifiedCount": 1,
+//	  "UpsertedCount": 0,
+//	  "UpsertedID": nil,
+//	  "Acknowledged": true
+//	}
+//
+// The organization ID is obtained from the auth bearer token.
+func UpdatePhoneNumber(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"PATCH"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	phoneNumber := ExtractPhoneNumber(r)
+	if phoneNumber == nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	caller := ExtractCaller(r)
+
+	result, err := sarah.UpdatePhoneNumber(caller, *phoneNumber)
+
+	if errors.Is(err, sarah.ErrNotFound) {
+		http.Error(w, "Phone number not found", http.StatusNotFound)
+		return
+	} else if WriteValidationError(w, err) {
+		return
+	} else if result == nil {
+		http.Error(w, "Failed to update phone number", http.StatusInternalServerError)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to update phone number", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
 // DeletePhoneNumber handles DELETE requests to delete an existing phone number.
 // This endpoint accepts a phone number deletion request and deletes the phone number from the database.
 //
@@ -1079,6 +1598,7 @@ func CreatePhoneNumber(w http.ResponseWriter, r *http.Request) {
 //
 // Response:
 //   - 200 OK: Phone number deleted successfully, returns the deleted phone number object
+//   - 404 Not Found: If the phone number does not belong to the organization
 //   - 405 Method Not Allowed: If not using DELETE method
 //   - 500 Internal Server Error: If database operation fails
 //
@@ -1097,11 +1617,14 @@ func DeletePhoneNumber(w http.ResponseWriter, r *http.Request) {
 	}
 
 	phoneNumberId := ExtractPhoneNumberId(r)
-	orgId := ExtractOrgId(r)
+	caller := ExtractCaller(r)
 
-	result, err := mongodb.DeletePhoneNumber(orgId, phoneNumberId)
+	result, err := sarah.DeletePhoneNumber(caller, phoneNumberId)
 
-	if result == nil {
+	if errors.Is(err, sarah.ErrNotFound) {
+		http.Error(w, "Phone number not found", http.StatusNotFound)
+		return
+	} else if result == nil {
 		http.Error(w, "Failed to delete phone number", http.StatusInternalServerError)
 		return
 	} else if err != nil {
@@ -1112,3 +1635,59 @@ func DeletePhoneNumber(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(result)
 }
+
+// GetAuditLog handles GET requests to retrieve the audit log of an organization.
+// This endpoint returns the attempts to act on resources the organization doesn't own,
+// newest first, for the organization from the auth bearer token.
+//
+// HTTP Method: GET
+// Endpoint: /audit/org
+//
+// Query Parameters:
+//   - outcome: Only return entries with this outcome, e.g. "denied" (optional)
+//   - limit: The maximum number of entries to return (optional, defaults to 50, maximum 200)
+//
+// Response:
+//   - 200 OK: Returns an array of audit entries
+//   - 400 Bad Request: If the limit is invalid
+//   - 405 Method Not Allowed: If not using GET method
+//   - 500 Internal Server Error: If database operation fails
+//
+// Example Response:
+//
+//	[
+//	  {
+//	    "id": "507f1f77bcf86cd799439011",
+//	    "action": "assistants.update",
+//	    "resource_type": "assistant",
+//	    "resource_id": "asst_1234567890abcdef",
+//	    "user_id": "user_1234567890",
+//	    "outcome": "denied",
+//	    "reason": "resource not found",
+//	    "created_at": "2024-01-01T12:00:00Z"
+//	  }
+//	]
+func GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"GET"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	orgId := ExtractOrgId(r)
+	outcome := mongodbTypes.AuditOutcome(r.URL.Query().Get("outcome"))
+
+	_, limit, err := ExtractPagination(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	entries, err := sarah.GetAuditLog(orgId, outcome, limit)
+	if err != nil {
+		http.Error(w, "Failed to get audit log", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(entries)
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"sarah/sarah"
+)
+
+// Health handles GET requests for the health of Sarah and VapiAI. It is not authenticated, so load
+// balancers and monitoring can poll it. A degraded status still returns 200 OK: the API keeps serving
+// requests while VapiAI is unavailable, and queued calls are dispatched once it recovers.
+//
+// HTTP Method: GET
+// Endpoint: /health
+//
+// Response:
+//   - 200 OK: Returns the overall status and the state of the VapiAI circuit breaker
+//   - 405 Method Not Allowed: If not using GET method
+//
+// Example Response:
+//
+//	{
+//	  "status": "degraded",
+//	  "vapi": {
+//	    "state": "open",
+//	    "consecutive_failures": 5,
+//	    "last_error": "503: Service Unavailable",
+//	    "opened_at": "2024-01-01T12:00:00Z",
+//	    "retry_at": "2024-01-01T12:00:30Z"
+//	  }
+//	}
+func Health(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"GET"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(sarah.GetHealth())
+}
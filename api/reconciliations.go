@@ -0,0 +1,116 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sarah/sarah"
+)
+
+// ReconcileCalls handles POST requests to reconcile the organization's stale calls now.
+// Calls that have not ended and were not updated for 30 minutes are fetched from VapiAI and what VapiAI
+// reports is applied as if the webhook had arrived. Ended calls get their end-of-call report, calls VapiAI
+// has no record of are ended locally and their queued call is retried if they were never dialed, or failed for review
+// otherwise. The pass checks up to 20 calls for at most 5 seconds, leaving the other calls to the scheduled
+// reconciler. The report of the pass is stored and always returned, even if the pass stopped early, in which
+// case its error explains why.
+//
+// HTTP Method: POST
+// Endpoint: /calls/reconcile
+//
+// Response:
+//   - 200 OK: Returns the report of the pass
+//   - 405 Method Not Allowed: If not using POST method
+//   - 409 Conflict: If the organization's calls are already being reconciled
+//   - 500 Internal Server Error: If database operation fails
+//
+// Example Response:
+//
+//	{
+//	  "id": "65a1b2c3d4e5f6a7b8c9d0e5",
+//	  "trigger": "manual",
+//	  "triggered_by": "user_1234567890",
+//	  "stale_before": "2024-01-01T11:30:00Z",
+//	  "checked": 2,
+//	  "fixed": 1,
+//	  "failed": 0,
+//	  "calls": [
+//	    {
+//	      "vapi_call_id": "call_abc123def456",
+//	      "outcome": "ended",
+//	      "previous_status": "in-progress",
+//	      "status": "ended",
+//	      "ended_reason": "customer-ended-call"
+//	    },
+//	    {
+//	      "vapi_call_id": "call_0987654321fedcba",
+//	      "outcome": "unchanged",
+//	      "previous_status": "scheduled",
+//	      "status": "scheduled"
+//	    }
+//	  ],
+//	  "started_at": "2024-01-01T12:00:00Z",
+//	  "finished_at": "2024-01-01T12:00:02Z"
+//	}
+//
+// The organization ID is obtained from the auth bearer token.
+func ReconcileCalls(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"POST"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	caller := ExtractCaller(r)
+
+	report, err := sarah.ReconcileCalls(caller)
+	if errors.Is(err, sarah.ErrReconcileRunning) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to reconcile calls", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
+
+// GetReconciliations handles GET requests to retrieve the latest reports of the call reconciler, newest first.
+// Scheduled passes are only reported when they checked a call.
+//
+// HTTP Method: GET
+// Endpoint: /calls/reconciliations
+//
+// Query Parameters:
+//   - limit: The maximum number of reports to return (optional, defaults to 50, maximum 200)
+//
+// Response:
+//   - 200 OK: Returns an array of reconciliation reports, in the format of POST /calls/reconcile
+//   - 400 Bad Request: If limit is invalid
+//   - 405 Method Not Allowed: If not using GET method
+//   - 500 Internal Server Error: If database operation fails
+//
+// The organization ID is obtained from the auth bearer token.
+func GetReconciliations(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"GET"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	_, limit, err := ExtractPagination(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	orgId := ExtractOrgId(r)
+
+	reconciliations, err := sarah.GetReconciliations(orgId, limit)
+	if err != nil {
+		http.Error(w, "Failed to get reconciliations", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(reconciliations)
+}
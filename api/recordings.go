@@ -0,0 +1,78 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"path"
+	"sarah/sarah"
+	"time"
+)
+
+// recordingWriteTimeout replaces the server write timeout while a recording is streamed,
+// since recordings can take much longer to send than regular API responses.
+const recordingWriteTimeout = 30 * time.Minute
+
+// GetCallRecording handles GET requests to stream the archived recording of a call.
+// Recordings are downloaded from VapiAI when the call ends and served from Sarah's own storage,
+// so they stay available after the VapiAI URL expires. Range requests are supported, so
+// audio players can seek without downloading the whole file.
+//
+// HTTP Method: GET (HEAD is also accepted)
+// Endpoint: /recordings/call
+//
+// Query Parameters:
+//   - callId: The VapiAI call ID whose recording to stream (required)
+//
+// Headers:
+//   - Range: The byte range to return, e.g. "bytes=0-1023" (optional)
+//
+// The organization ID is obtained from the auth bearer token.
+//
+// Response:
+//   - 200 OK: The full recording
+//   - 206 Partial Content: The requested range of the recording
+//   - 400 Bad Request: If callId is missing
+//   - 404 Not Found: If the call has no archived recording in the organization
+//   - 405 Method Not Allowed: If not using GET or HEAD method
+//   - 416 Range Not Satisfiable: If the range is outside of the recording
+//   - 500 Internal Server Error: If the recording could not be read
+func GetCallRecording(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"GET", "HEAD"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	callId := ExtractCallId(r)
+	if callId == "" {
+		http.Error(w, "Missing callId", http.StatusBadRequest)
+		return
+	}
+
+	orgId := ExtractOrgId(r)
+
+	recording, blob, err := sarah.OpenRecording(orgId, callId)
+	if errors.Is(err, sarah.ErrNotFound) {
+		http.Error(w, "Recording not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to get recording", http.StatusInternalServerError)
+		return
+	}
+	defer blob.Close()
+
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(recordingWriteTimeout)); err != nil {
+		log.Printf("Could not extend write deadline for recording of call %s: %v", callId, err)
+	}
+
+	contentType := recording.ContentType
+	if contentType == "" {
+		contentType = blob.ContentType()
+	}
+	if contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+
+	http.ServeContent(w, r, path.Base(recording.StorageKey), blob.ModTime(), blob)
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sarah/sarah"
+)
+
+// GetOrganizationSettings handles GET requests to retrieve the settings of an organization.
+// Organizations that never saved their settings get the defaults.
+//
+// HTTP Method: GET
+// Endpoint: /settings/org
+//
+// The organization ID is obtained from the auth bearer token.
+//
+// Response:
+//   - 200 OK: Returns the organization settings
+//   - 405 Method Not Allowed: If not using GET method
+//   - 500 Internal Server Error: If database operation fails
+//
+// Example Response:
+//
+//	{
+//	  "recording_retention_days": 90,
+//	  "default_country": "US",
+//	  "max_concurrent_calls": 20,
+//	  "analysis_mappings": [
+//	    { "field": "structured_data.renewal_confirmed", "metadata_key": "renewed" }
+//	  ],
+//	  "dispositions": [
+//	    { "code": "resolved", "label": "Resolved on call" }
+//	  ],
+//	  "updated_at": "2024-01-01T12:00:00Z"
+//	}
+func GetOrganizationSettings(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"GET"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	orgId := ExtractOrgId(r)
+
+	settings, err := sarah.GetOrganizationSettings(orgId)
+	if err != nil {
+		http.Error(w, "Failed to get settings", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(settings)
+}
+
+// UpdateOrganizationSettings handles PATCH requests to update the settings of an organization.
+// Only the settings sent are changed; settings missing from the request keep their value.
+//
+// HTTP Method: PATCH
+// Endpoint: /settings/update
+//
+// Request Body:
+//
+//	{
+//	  "settings": {
+//	    "recording_retention_days": 90,
+//	    "default_country": "US",
+//	    "max_concurrent_calls": 20,
+//	    "analysis_mappings": [
+//	      { "field": "structured_data.renewal_confirmed", "metadata_key": "renewed" },
+//	      { "field": "success_evaluation", "metadata_key": "last_call.success" }
+//	    ],
+//	    "dispositions": [
+//	      { "code": "resolved", "label": "Resolved on call" },
+//	      { "code": "escalated", "label": "Escalated to a manager" }
+//	    ]
+//	  }
+//	}
+//
+// The organization ID is obtained from the auth bearer token.
+//
+// Response:
+//   - 200 OK: Settings updated successfully, returns the mongodb update result object
+//   - 400 Bad Request: If the request body is invalid or a setting is out of range
+//   - 405 Method Not Allowed: If not using PATCH method
+//   - 500 Internal Server Error: If database operation fails
+func UpdateOrganizationSettings(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"PATCH"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	settings := ExtractOrganizationSettings(r)
+	if settings == nil {
+		http.Error(w, "Invalid settings", http.StatusBadRequest)
+		return
+	}
+
+	orgId := ExtractOrgId(r)
+
+	result, err := sarah.UpdateOrganizationSettings(orgId, *settings)
+	if errors.Is(err, sarah.ErrInvalidSettings) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to update settings", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
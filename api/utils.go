@@ -1,12 +1,18 @@
 package api
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"sarah/auth"
+	"sarah/sarah"
 	mongodbTypes "sarah/types/mongodb"
+	"strconv"
 	"strings"
+	"time"
 
 	vapiApi "github.com/VapiAI/server-sdk-go"
 )
@@ -29,39 +35,53 @@ func ExtractAuthHeader(r *http.Request) string {
 	return strings.TrimPrefix(authHeader, "Bearer ")
 }
 
-// ExtractPhoneNumbers extracts phone numbers from the request body.
-// The function expects a JSON body with a "phoneNumbers" array field.
+// ExtractCallCustomers extracts the customers to call from the request body.
+// The function expects a JSON body with a "customers" array field, each customer with a phone number and
+// an optional name, time zone and template variables. Numbers of a flat "phoneNumbers" array are added
+// as customers without name, time zone or variables.
 //
 // Parameters:
-//   - r: HTTP request containing the phone numbers in the request body
+//   - r: HTTP request containing the customers in the request body
 //
 // Returns:
-//   - []string: Array of phone numbers with whitespace trimmed
+//   - []mongodb.Customer: The customers to call, in the order of the request, or nil if extraction fails
 //
 // Request Body Format:
 //
 //	{
-//	  "phoneNumbers": ["+1234567890", "+1987654321"]
+//	  "customers": [
+//	    {
+//	      "phone_number": "+1234567890",
+//	      "name": "John Doe",
+//	      "timezone": "America/New_York",
+//	      "variables": { "plan": "gold", "renewal_date": "2024-02-01" }
+//	    }
+//	  ],
+//	  "phoneNumbers": ["+1987654321"]
 //	}
-func ExtractPhoneNumbers(r *http.Request) []string {
-	var phoneNumbers []string
-
+func ExtractCallCustomers(r *http.Request) []mongodbTypes.Customer {
 	type requestBody struct {
-		PhoneNumbers []string `json:"phoneNumbers"`
+		Customers    []mongodbTypes.Customer `json:"customers"`
+		PhoneNumbers []string                `json:"phoneNumbers"`
 	}
 
 	var body requestBody
 	decoder := json.NewDecoder(r.Body)
 	err := decoder.Decode(&body)
 	if err != nil {
-		return []string{}
+		return nil
 	}
 
+	customers := []mongodbTypes.Customer{}
+	for _, customer := range body.Customers {
+		customer.PhoneNumber = strings.TrimSpace(customer.PhoneNumber)
+		customers = append(customers, customer)
+	}
 	for _, phone := range body.PhoneNumbers {
-		phoneNumbers = append(phoneNumbers, strings.TrimSpace(phone))
+		customers = append(customers, mongodbTypes.Customer{PhoneNumber: strings.TrimSpace(phone)})
 	}
 
-	return phoneNumbers
+	return customers
 }
 
 // ExtractAssistantId extracts the assistant ID from the request query parameters.
@@ -117,6 +137,35 @@ func VerifyMethod(r *http.Request, allowedMethods []string) bool {
 	return false
 }
 
+// WriteValidationError writes a 400 Bad Request listing the invalid fields if err is a *sarah.ValidationError.
+// Unlike other errors, which are plain text, the body is JSON so clients can map each error to its field.
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - err: The error returned by the sarah package
+//
+// Returns:
+//   - bool: True if err was a validation error and the response was written, false otherwise
+//
+// Example Response:
+//
+//	{
+//	  "errors": [
+//	    { "field": "phoneNumbers[1]", "value": "555-0100", "message": "\"555-0100\" is too short" }
+//	  ]
+//	}
+func WriteValidationError(w http.ResponseWriter, err error) bool {
+	var validationErr *sarah.ValidationError
+	if !errors.As(err, &validationErr) {
+		return false
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(validationErr)
+	return true
+}
+
 // ExtractCallId extracts the call ID from the request query parameters.
 // The function looks for the "callId" query parameter.
 //
@@ -207,6 +256,23 @@ func ExtractOrgId(r *http.Request) string {
 	return strings.TrimSpace(orgId)
 }
 
+// ExtractCaller extracts the caller of the request from the auth context.
+// The caller carries the organization ID and the Clerk user ID, which are used
+// to authorize access to organization resources and to audit denied attempts.
+//
+// Parameters:
+//   - r: HTTP request that went through the auth middleware
+//
+// Returns:
+//   - sarah.Caller: The organization and user the request was made by
+func ExtractCaller(r *http.Request) sarah.Caller {
+	userId, _ := auth.GetUserID(r)
+	return sarah.Caller{
+		OrgId:  ExtractOrgId(r),
+		UserId: strings.TrimSpace(userId),
+	}
+}
+
 // ExtractCampaignCreateDto extracts a campaign creation DTO from the request body.
 // The function expects a JSON body with a "campaignCreateRequest" object field.
 // This matches the structure shown in sample_campaigns.json.
@@ -412,3 +478,729 @@ func ExtractPhoneNumberId(r *http.Request) string {
 	phoneNumberId := r.URL.Query().Get("phoneNumberId")
 	return strings.TrimSpace(phoneNumberId)
 }
+
+// ExtractCampaignIdParam extracts the campaign ID from the request query parameters.
+// The function looks for the "campaignId" query parameter.
+//
+// Parameters:
+//   - r: HTTP request containing the campaignId query parameter
+//
+// Returns:
+//   - string: The campaign ID with whitespace trimmed
+//
+// Example URL: /campaigns/analytics?campaignId=507f1f77bcf86cd799439011
+func ExtractCampaignIdParam(r *http.Request) string {
+	campaignId := r.URL.Query().Get("campaignId")
+	return strings.TrimSpace(campaignId)
+}
+
+// ExtractDateRange extracts an optional date range from the "from" and "to" query parameters.
+// Both parameters accept either an RFC 3339 timestamp or a plain date (YYYY-MM-DD, interpreted as UTC midnight).
+//
+// Parameters:
+//   - r: HTTP request containing the from/to query parameters
+//
+// Returns:
+//   - *time.Time: The lower bound, or nil if "from" is not set
+//   - *time.Time: The upper bound, or nil if "to" is not set
+//   - error: If either parameter cannot be parsed or from is not before to
+//
+// Example URL: /campaigns/analytics?from=2024-01-01&to=2024-02-01T00:00:00Z
+func ExtractDateRange(r *http.Request) (*time.Time, *time.Time, error) {
+	from, err := parseDateParam(r.URL.Query().Get("from"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid from: %v", err)
+	}
+
+	to, err := parseDateParam(r.URL.Query().Get("to"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid to: %v", err)
+	}
+
+	if from != nil && to != nil && !from.Before(*to) {
+		return nil, nil, fmt.Errorf("from must be before to")
+	}
+
+	return from, to, nil
+}
+
+// parseDateParam parses a query parameter as an RFC 3339 timestamp or a YYYY-MM-DD date.
+func parseDateParam(value string) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return &parsed, nil
+	}
+
+	parsed, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &parsed, nil
+}
+
+// ExtractAnalyticsInterval extracts the analytics bucket size from the "interval" query parameter.
+// Defaults to "day" when the parameter is not set.
+//
+// Parameters:
+//   - r: HTTP request containing the interval query parameter
+//
+// Returns:
+//   - mongodb.AnalyticsInterval: The requested interval
+//   - bool: False if the interval is not "day" or "week"
+//
+// Example URL: /campaigns/analytics?interval=week
+func ExtractAnalyticsInterval(r *http.Request) (mongodbTypes.AnalyticsInterval, bool) {
+	interval := mongodbTypes.AnalyticsInterval(strings.TrimSpace(r.URL.Query().Get("interval")))
+
+	switch interval {
+	case "":
+		return mongodbTypes.INTERVAL_DAY, true
+	case mongodbTypes.INTERVAL_DAY, mongodbTypes.INTERVAL_WEEK:
+		return interval, true
+	default:
+		return "", false
+	}
+}
+
+// ExtractPagination extracts the "page" and "limit" query parameters.
+// The page defaults to 1 and the limit to 50, with a maximum of 200.
+//
+// Parameters:
+//   - r: HTTP request containing the page and limit query parameters
+//
+// Returns:
+//   - int: The 1-based page number
+//   - int: The maximum number of items per page
+//   - error: If either parameter is not a positive integer
+//
+// Example URL: /calls/org?page=2&limit=25
+func ExtractPagination(r *http.Request) (int, int, error) {
+	page, limit := 1, 50
+
+	if value := strings.TrimSpace(r.URL.Query().Get("page")); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			return 0, 0, fmt.Errorf("invalid page: %s", value)
+		}
+		page = parsed
+	}
+
+	if value := strings.TrimSpace(r.URL.Query().Get("limit")); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			return 0, 0, fmt.Errorf("invalid limit: %s", value)
+		}
+		limit = min(parsed, 200)
+	}
+
+	return page, limit, nil
+}
+
+// ExtractCallFilter extracts the call listing filters from the request query parameters.
+// The supported parameters are assistantId, phoneNumberId, campaignId, customerNumber,
+// status, endedReason, flagged, tag, disposition, hasNotes, from and to. Missing parameters don't filter.
+// tag can be repeated or comma-separated, matching calls with every tag.
+//
+// Parameters:
+//   - r: HTTP request containing the filter query parameters
+//
+// Returns:
+//   - mongodb.CallFilter: The extracted filter
+//   - error: If the date range is invalid
+//
+// Example URL: /calls/org?assistantId=asst_1234567890abcdef&status=ended&from=2024-01-01
+func ExtractCallFilter(r *http.Request) (mongodbTypes.CallFilter, error) {
+	query := r.URL.Query()
+
+	from, to, err := ExtractDateRange(r)
+	if err != nil {
+		return mongodbTypes.CallFilter{}, err
+	}
+
+	return mongodbTypes.CallFilter{
+		AssistantId:    strings.TrimSpace(query.Get("assistantId")),
+		PhoneNumberId:  strings.TrimSpace(query.Get("phoneNumberId")),
+		CampaignId:     strings.TrimSpace(query.Get("campaignId")),
+		CustomerNumber: strings.TrimSpace(query.Get("customerNumber")),
+		Status:         mongodbTypes.CallStatus(strings.TrimSpace(query.Get("status"))),
+		EndedReason:    strings.TrimSpace(query.Get("endedReason")),
+		From:           from,
+		To:             to,
+		Flagged:        strings.TrimSpace(query.Get("flagged")) == "true",
+		Tags:           ExtractTags(r),
+		Disposition:    strings.TrimSpace(query.Get("disposition")),
+		HasNotes:       strings.TrimSpace(query.Get("hasNotes")) == "true",
+	}, nil
+}
+
+// ExtractTranscriptFilter extracts the transcript search filters from the request query parameters.
+// The supported parameters are assistantId, campaignId, from and to. Missing parameters don't filter.
+//
+// Parameters:
+//   - r: HTTP request containing the filter query parameters
+//
+// Returns:
+//   - mongodb.TranscriptFilter: The extracted filter
+//   - error: If the date range is invalid
+//
+// Example URL: /calls/search?q=cancel+my+policy&campaignId=507f1f77bcf86cd799439011&from=2024-01-01
+func ExtractTranscriptFilter(r *http.Request) (mongodbTypes.TranscriptFilter, error) {
+	query := r.URL.Query()
+
+	from, to, err := ExtractDateRange(r)
+	if err != nil {
+		return mongodbTypes.TranscriptFilter{}, err
+	}
+
+	return mongodbTypes.TranscriptFilter{
+		AssistantId: strings.TrimSpace(query.Get("assistantId")),
+		CampaignId:  strings.TrimSpace(query.Get("campaignId")),
+		From:        from,
+		To:          to,
+	}, nil
+}
+
+// ExtractOrganizationSettings extracts an update of the organization settings from the request body.
+// The function expects a JSON body with a "settings" object field. Settings missing from the object,
+// or null, are left out of the update.
+//
+// Parameters:
+//   - r: HTTP request containing the settings in the request body
+//
+// Returns:
+//   - *mongodb.OrganizationSettingsUpdate: The extracted update, or nil if extraction fails
+//
+// Request Body Format:
+//
+//	{
+//	  "settings": {
+//	    "recording_retention_days": 90
+//	  }
+//	}
+func ExtractOrganizationSettings(r *http.Request) *mongodbTypes.OrganizationSettingsUpdate {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil
+	}
+
+	var requestBody struct {
+		Settings *mongodbTypes.OrganizationSettingsUpdate `json:"settings"`
+	}
+
+	if err := json.Unmarshal(body, &requestBody); err != nil {
+		return nil
+	}
+
+	return requestBody.Settings
+}
+
+// ExtractDncEntry extracts a Do-Not-Call entry from the request body.
+// The function expects a JSON body with an "entry" object field.
+//
+// Parameters:
+//   - r: HTTP request containing the entry in the request body
+//
+// Returns:
+//   - *mongodb.DncEntry: The extracted entry, or nil if extraction fails
+//
+// Request Body Format:
+//
+//	{
+//	  "entry": {
+//	    "phone_number": "+1234567890",
+//	    "reason": "customer opted out"
+//	  }
+//	}
+func ExtractDncEntry(r *http.Request) *mongodbTypes.DncEntry {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil
+	}
+
+	var requestBody struct {
+		Entry *mongodbTypes.DncEntry `json:"entry"`
+	}
+
+	if err := json.Unmarshal(body, &requestBody); err != nil {
+		return nil
+	}
+
+	return requestBody.Entry
+}
+
+// ExtractPhoneNumberParam extracts a phone number from the "phoneNumber" query parameter.
+// A "+" sent unencoded is decoded as a space, so a leading space is restored to "+".
+//
+// Parameters:
+//   - r: HTTP request containing the phoneNumber query parameter
+//
+// Returns:
+//   - string: The phone number with whitespace trimmed
+//
+// Example URL: /dnc/delete?phoneNumber=%2B1234567890
+func ExtractPhoneNumberParam(r *http.Request) string {
+	phoneNumber := r.URL.Query().Get("phoneNumber")
+	if strings.HasPrefix(phoneNumber, " ") {
+		phoneNumber = "+" + phoneNumber[1:]
+	}
+	return strings.TrimSpace(phoneNumber)
+}
+
+// ExtractCsvUpload returns the CSV file of an upload request. The file is either the "file" field
+// of a multipart form or the raw request body. The request body is limited to maxBytes, and multipart
+// forms are parsed with the same limit. The caller must close the returned reader.
+//
+// Parameters:
+//   - w: HTTP response writer, used to close the connection when the body is too large
+//   - r: HTTP request containing the CSV file
+//   - maxBytes: The maximum size of the request body
+//
+// Returns:
+//   - io.ReadCloser: The CSV content, failing with an *http.MaxBytesError past maxBytes
+//   - error: An *http.MaxBytesError if the multipart form is too large, or an error if it has no "file" field
+func ExtractCsvUpload(w http.ResponseWriter, r *http.Request, maxBytes int64) (io.ReadCloser, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(maxBytes); err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("invalid multipart form: %v", err)
+		}
+
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			return nil, fmt.Errorf("missing file: %v", err)
+		}
+		return file, nil
+	}
+
+	return r.Body, nil
+}
+
+// ExtractQueuedCallId extracts the queued call ID from the "queuedCallId" query parameter.
+//
+// Parameters:
+//   - r: HTTP request containing the queuedCallId query parameter
+//
+// Returns:
+//   - string: The hex ObjectID of the queued call with whitespace trimmed
+//
+// Example URL: /queue/cancel?queuedCallId=65a1b2c3d4e5f6a7b8c9d0e1
+func ExtractQueuedCallId(r *http.Request) string {
+	return strings.TrimSpace(r.URL.Query().Get("queuedCallId"))
+}
+
+// ExtractQueueStatus extracts the queued call status filter from the "status" query parameter.
+//
+// Parameters:
+//   - r: HTTP request containing the status query parameter
+//
+// Returns:
+//   - mongodb.QueueStatus: The status, empty if the parameter is missing
+//   - error: If the status is not a queue status
+//
+// Example URL: /queue/org?status=queued
+func ExtractQueueStatus(r *http.Request) (mongodbTypes.QueueStatus, error) {
+	status := mongodbTypes.QueueStatus(strings.TrimSpace(r.URL.Query().Get("status")))
+
+	switch status {
+	case "", mongodbTypes.QUEUE_STATUS_QUEUED, mongodbTypes.QUEUE_STATUS_DISPATCHING, mongodbTypes.QUEUE_STATUS_DISPATCHED,
+		mongodbTypes.QUEUE_STATUS_FAILED, mongodbTypes.QUEUE_STATUS_CANCELLED:
+		return status, nil
+	}
+
+	return "", fmt.Errorf("invalid status: %s", status)
+}
+
+// ExtractCallbackId extracts the callback ID from the "callbackId" query parameter.
+//
+// Parameters:
+//   - r: HTTP request containing the callbackId query parameter
+//
+// Returns:
+//   - string: The hex ObjectID of the callback with whitespace trimmed
+//
+// Example URL: /callbacks/cancel?callbackId=65a1b2c3d4e5f6a7b8c9d0e2
+func ExtractCallbackId(r *http.Request) string {
+	return strings.TrimSpace(r.URL.Query().Get("callbackId"))
+}
+
+// ExtractCallbackStatus extracts the callback status filter from the "status" query parameter.
+//
+// Parameters:
+//   - r: HTTP request containing the status query parameter
+//
+// Returns:
+//   - mongodb.CallbackStatus: The status, empty if the parameter is missing
+//   - error: If the status is not a callback status
+//
+// Example URL: /callbacks/org?status=scheduled
+func ExtractCallbackStatus(r *http.Request) (mongodbTypes.CallbackStatus, error) {
+	status := mongodbTypes.CallbackStatus(strings.TrimSpace(r.URL.Query().Get("status")))
+
+	switch status {
+	case "", mongodbTypes.CALLBACK_STATUS_SCHEDULED, mongodbTypes.CALLBACK_STATUS_QUEUED,
+		mongodbTypes.CALLBACK_STATUS_FAILED, mongodbTypes.CALLBACK_STATUS_CANCELLED:
+		return status, nil
+	}
+
+	return "", fmt.Errorf("invalid status: %s", status)
+}
+
+// ExtractAlertRuleId extracts the alert rule ID from the "ruleId" query parameter.
+//
+// Parameters:
+//   - r: HTTP request containing the ruleId query parameter
+//
+// Returns:
+//   - string: The hex ObjectID of the rule with whitespace trimmed
+//
+// Example URL: /alerts/delete?ruleId=65a1b2c3d4e5f6a7b8c9d0e3
+func ExtractAlertRuleId(r *http.Request) string {
+	return strings.TrimSpace(r.URL.Query().Get("ruleId"))
+}
+
+// ExtractAlertRule extracts an alert rule from the request body.
+// The function expects a JSON body with a "rule" object field. Rules are enabled unless "enabled" is false.
+//
+// Parameters:
+//   - r: HTTP request containing the rule in the request body
+//
+// Returns:
+//   - *mongodb.AlertRule: The extracted rule, or nil if extraction fails
+//
+// Request Body Format:
+//
+//	{
+//	  "rule": {
+//	    "name": "Legal threat",
+//	    "condition": { "type": "keyword", "keywords": ["lawyer", "complaint", "cancel"], "role": "user" },
+//	    "actions": [{ "type": "flag" }]
+//	  }
+//	}
+func ExtractAlertRule(r *http.Request) *mongodbTypes.AlertRule {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil
+	}
+
+	var requestBody struct {
+		Rule *mongodbTypes.AlertRule `json:"rule"`
+	}
+	requestBody.Rule = &mongodbTypes.AlertRule{Enabled: true}
+
+	if err := json.Unmarshal(body, &requestBody); err != nil {
+		return nil
+	}
+
+	return requestBody.Rule
+}
+
+// ExtractAlertTest extracts an alert rule test from the request body.
+// The function expects a JSON body with a "sample" object field, an optional "rule" object field
+// for rules that aren't stored, and an optional "deliver" boolean field.
+//
+// Parameters:
+//   - r: HTTP request containing the test in the request body
+//
+// Returns:
+//   - *mongodb.AlertRule: The rule to test, nil if the body has none
+//   - *sarah.AlertSample: The sample to test the rule against, or nil if extraction fails
+//   - bool: Whether the rule's webhook and email actions should be run
+//
+// Request Body Format:
+//
+//	{
+//	  "sample": { "transcript": "I'm calling my lawyer about this", "role": "user" },
+//	  "deliver": false
+//	}
+func ExtractAlertTest(r *http.Request) (*mongodbTypes.AlertRule, *sarah.AlertSample, bool) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, nil, false
+	}
+
+	var requestBody struct {
+		Rule    *mongodbTypes.AlertRule `json:"rule"`
+		Sample  *sarah.AlertSample      `json:"sample"`
+		Deliver bool                    `json:"deliver"`
+	}
+
+	if err := json.Unmarshal(body, &requestBody); err != nil {
+		return nil, nil, false
+	}
+
+	return requestBody.Rule, requestBody.Sample, requestBody.Deliver
+}
+
+// ExtractTags extracts the call tags to filter by from the "tag" query parameter.
+// The parameter can be repeated or hold comma-separated tags, which are lowercased like stored tags.
+//
+// Parameters:
+//   - r: HTTP request containing the tag query parameters
+//
+// Returns:
+//   - []string: The tags, nil if the parameter is missing
+//
+// Example URL: /calls/org?tag=vip&tag=follow-up
+func ExtractTags(r *http.Request) []string {
+	var tags []string
+	for _, value := range r.URL.Query()["tag"] {
+		for _, tag := range strings.Split(value, ",") {
+			tag = strings.ToLower(strings.Join(strings.Fields(tag), " "))
+			if tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
+
+// ExtractTagChanges extracts the tags to add to and remove from a call from the request body.
+// The function expects a JSON body with a "tags" object field holding "add" and "remove" lists.
+//
+// Parameters:
+//   - r: HTTP request containing the tag changes in the request body
+//
+// Returns:
+//   - []string: The tags to add
+//   - []string: The tags to remove
+//   - bool: False if extraction fails or the body has no "tags" object
+//
+// Request Body Format:
+//
+//	{
+//	  "tags": { "add": ["vip", "follow-up"], "remove": ["new"] }
+//	}
+func ExtractTagChanges(r *http.Request) ([]string, []string, bool) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, nil, false
+	}
+
+	var requestBody struct {
+		Tags *struct {
+			Add    []string `json:"add"`
+			Remove []string `json:"remove"`
+		} `json:"tags"`
+	}
+
+	if err := json.Unmarshal(body, &requestBody); err != nil || requestBody.Tags == nil {
+		return nil, nil, false
+	}
+
+	return requestBody.Tags.Add, requestBody.Tags.Remove, true
+}
+
+// ExtractDisposition extracts the disposition code of a call from the request body.
+// The function expects a JSON body with a "disposition" string field, empty or null to clear the disposition.
+//
+// Parameters:
+//   - r: HTTP request containing the disposition in the request body
+//
+// Returns:
+//   - string: The disposition code, empty to clear it
+//   - bool: False if extraction fails or the body has no "disposition" field
+//
+// Request Body Format:
+//
+//	{
+//	  "disposition": "resolved"
+//	}
+func ExtractDisposition(r *http.Request) (string, bool) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return "", false
+	}
+
+	var requestBody map[string]*string
+	if err := json.Unmarshal(body, &requestBody); err != nil {
+		return "", false
+	}
+
+	disposition, ok := requestBody["disposition"]
+	if !ok {
+		return "", false
+	} else if disposition == nil {
+		return "", true
+	}
+
+	return *disposition, true
+}
+
+// ExtractCallNote extracts a call note from the request body.
+// The function expects a JSON body with a "note" object field.
+//
+// Parameters:
+//   - r: HTTP request containing the note in the request body
+//
+// Returns:
+//   - *mongodb.CallNote: The extracted note, or nil if extraction fails
+//
+// Request Body Format:
+//
+//	{
+//	  "note": { "text": "Customer asked to be called back after the holidays" }
+//	}
+func ExtractCallNote(r *http.Request) *mongodbTypes.CallNote {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil
+	}
+
+	var requestBody struct {
+		Note *mongodbTypes.CallNote `json:"note"`
+	}
+
+	if err := json.Unmarshal(body, &requestBody); err != nil {
+		return nil
+	}
+
+	return requestBody.Note
+}
+
+// ExtractCallNoteId extracts the call note ID from the "noteId" query parameter.
+//
+// Parameters:
+//   - r: HTTP request containing the noteId query parameter
+//
+// Returns:
+//   - string: The hex ObjectID of the note with whitespace trimmed
+//
+// Example URL: /calls/notes/delete?noteId=65a1b2c3d4e5f6a7b8c9d0e4
+func ExtractCallNoteId(r *http.Request) string {
+	return strings.TrimSpace(r.URL.Query().Get("noteId"))
+}
+
+// ExtractAssistantRepair extracts an assistant drift repair from the "action" query parameter.
+// The repair is checked by the business logic, so an unknown repair is reported with the others' names.
+//
+// Parameters:
+//   - r: HTTP request containing the action query parameter
+//
+// Returns:
+//   - mongodbTypes.AssistantRepair: The repair with whitespace trimmed
+//
+// Example URL: /assistants/drift/repair?assistantId=asst_1234567890abcdef&action=pull_name
+func ExtractAssistantRepair(r *http.Request) mongodbTypes.AssistantRepair {
+	return mongodbTypes.AssistantRepair(strings.TrimSpace(r.URL.Query().Get("action")))
+}
+
+// ExtractVersionParam extracts an assistant version number from a query parameter.
+// A missing parameter is returned as 0.
+//
+// Parameters:
+//   - r: HTTP request containing the query parameter
+//   - name: The name of the query parameter, e.g. "version", "from" or "to"
+//
+// Returns:
+//   - int: The version number, or 0 if the parameter is missing
+//   - error: If the parameter is not a positive integer
+//
+// Example URL: /assistants/versions/diff?assistantId=asst_1234567890abcdef&from=3&to=5
+func ExtractVersionParam(r *http.Request, name string) (int, error) {
+	value := strings.TrimSpace(r.URL.Query().Get(name))
+	if value == "" {
+		return 0, nil
+	}
+
+	version, err := strconv.Atoi(value)
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("invalid %s: %s", name, value)
+	}
+
+	return version, nil
+}
+
+// ExtractAssistantTemplateId extracts the assistant template ID from the request query parameters.
+// The function looks for the "templateId" query parameter.
+//
+// Parameters:
+//   - r: HTTP request containing the templateId query parameter
+//
+// Returns:
+//   - string: The template ID with whitespace trimmed
+//
+// Example URL: /assistants/templates/render?templateId=insurance-renewal-reminder
+func ExtractAssistantTemplateId(r *http.Request) string {
+	return strings.TrimSpace(r.URL.Query().Get("templateId"))
+}
+
+// ExtractAssistantTemplate extracts an assistant template from the request body.
+// The function expects a JSON body with a "template" object field.
+//
+// Parameters:
+//   - r: HTTP request containing the template in the request body
+//
+// Returns:
+//   - *mongodb.AssistantTemplate: The extracted template, or nil if extraction fails
+//
+// Request Body Format:
+//
+//	{
+//	  "template": {
+//	    "name": "Policy lapse follow-up",
+//	    "parameters": [{ "name": "company_name", "description": "Name of the insurance company" }],
+//	    "assistant": { "name": "{{company_name}} follow-ups", "firstMessage": "Hi, this is {{company_name}}." }
+//	  }
+//	}
+func ExtractAssistantTemplate(r *http.Request) *mongodbTypes.AssistantTemplate {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil
+	}
+
+	var requestBody struct {
+		Template *mongodbTypes.AssistantTemplate `json:"template"`
+	}
+
+	if err := json.Unmarshal(body, &requestBody); err != nil {
+		return nil
+	}
+
+	return requestBody.Template
+}
+
+// ExtractTemplateValues extracts the values of an assistant template's parameters from the request body.
+// The function expects a JSON body with a "values" object field of strings; an empty body has no values.
+//
+// Parameters:
+//   - r: HTTP request containing the values in the request body
+//
+// Returns:
+//   - map[string]string: The values by parameter name
+//   - bool: Whether the body could be read
+//
+// Request Body Format:
+//
+//	{
+//	  "values": { "company_name": "Acme Insurance", "voice_id": "Kylie", "language": "es" }
+//	}
+func ExtractTemplateValues(r *http.Request) (map[string]string, bool) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, false
+	}
+
+	var requestBody struct {
+		Values map[string]string `json:"values"`
+	}
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err := json.Unmarshal(body, &requestBody); err != nil {
+			return nil, false
+		}
+	}
+	if requestBody.Values == nil {
+		requestBody.Values = map[string]string{}
+	}
+
+	return requestBody.Values, true
+}
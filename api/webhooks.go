@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"sarah/sarah"
+	vapiTypes "sarah/types/vapi"
+)
+
+// VapiWebhook handles POST requests sent by VapiAI to the server URL.
+// This endpoint receives server messages about calls (status-update, end-of-call-report,
+// hang, transcript), maps each call back to its organization and stores the events.
+// It is authenticated with the shared VapiAI secret instead of a Clerk token.
+//
+// HTTP Method: POST
+// Endpoint: /webhooks/vapi
+//
+// Headers:
+//   - X-Vapi-Secret: The shared secret (or X-Vapi-Signature: hex HMAC-SHA256 of the body)
+//
+// Request Body:
+//
+//	{
+//	  "message": {
+//	    "type": "status-update",
+//	    "status": "in-progress",
+//	    "timestamp": 1704110400000,
+//	    "call": {
+//	      "id": "call_abc123def456",
+//	      "assistantId": "asst_1234567890abcdef",
+//	      "phoneNumberId": "phone_0987654321fedcba",
+//	      "customer": { "number": "+1234567890" }
+//	    }
+//	  }
+//	}
+//
+// Response:
+//   - 200 OK: Message processed
+//   - 400 Bad Request: If the body is not a valid server message or has no call
+//   - 401 Unauthorized: If the secret or signature is invalid
+//   - 404 Not Found: If the call's assistant and phone number are not registered by any organization
+//   - 405 Method Not Allowed: If not using POST method
+//   - 500 Internal Server Error: If the event could not be stored
+func VapiWebhook(w http.ResponseWriter, r *http.Request) {
+	if !VerifyMethod(r, []string{"POST"}) {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	var request vapiTypes.ServerMessageRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		http.Error(w, "Invalid server message", http.StatusBadRequest)
+		return
+	}
+
+	if request.Message.Call == nil || request.Message.Call.Id == "" {
+		http.Error(w, "Server message has no call", http.StatusBadRequest)
+		return
+	}
+
+	var raw struct {
+		Message map[string]interface{} `json:"message"`
+	}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		http.Error(w, "Invalid server message", http.StatusBadRequest)
+		return
+	}
+
+	_, err = sarah.HandleServerMessage(request.Message, raw.Message)
+	if errors.Is(err, sarah.ErrOrganizationNotFound) {
+		http.Error(w, "Call does not belong to any organization", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error handling %s server message: %v", request.Message.Type, err)
+		http.Error(w, "Failed to handle server message", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
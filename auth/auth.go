@@ -22,6 +22,15 @@ func GetOrganizationID(r *http.Request) (string, bool) {
 	return organizationID, ok
 }
 
+// UserIDKey is the context key for storing the Clerk user ID
+type UserIDKey struct{}
+
+// GetUserID retrieves the Clerk user ID from the request context
+func GetUserID(r *http.Request) (string, bool) {
+	userID, ok := r.Context().Value(UserIDKey{}).(string)
+	return userID, ok
+}
+
 // VerifyingMiddleware is the general middleware that verifies the passed JWT Token from clerk and extracts the user ID and organization ID to pass it to the next handler
 func VerifyingMiddleware(next http.Handler) http.Handler {
 	return clerkhttp.RequireHeaderAuthorization()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -53,8 +62,9 @@ func VerifyingMiddleware(next http.Handler) http.Handler {
 		}
 		log.Printf("[AUTH] Successfully retrieved organization ID: %s for user %s on %s %s", organizationID, userID, r.Method, r.URL.Path)
 
-		// Add organization ID to request context
+		// Add organization ID and user ID to request context
 		ctx := context.WithValue(r.Context(), OrganizationIDKey{}, organizationID)
+		ctx = context.WithValue(ctx, UserIDKey{}, userID)
 		r = r.WithContext(ctx)
 
 		next.ServeHTTP(w, r)
@@ -81,9 +91,11 @@ func TestingMiddleware(next http.Handler) http.Handler {
 		// }
 
 		organizationID := "org_1234567890"
+		userID := "user_1234567890"
 
-		// Add organization ID to request context
+		// Add organization ID and user ID to request context
 		ctx := context.WithValue(r.Context(), OrganizationIDKey{}, organizationID)
+		ctx = context.WithValue(ctx, UserIDKey{}, userID)
 		r = r.WithContext(ctx)
 
 		next.ServeHTTP(w, r)
@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// MAX_WEBHOOK_BODY_SIZE is the maximum size of a VapiAI server message, read before it is authenticated.
+// End-of-call reports carry the whole transcript and message history, so the limit leaves room for long calls.
+const MAX_WEBHOOK_BODY_SIZE = 5 << 20
+
+// VapiWebhookMiddleware verifies that a request was sent by VapiAI before passing it to the next handler.
+// VapiAI server messages don't carry a Clerk token, so they are authenticated with the shared
+// secret configured on the assistant's server URL (VAPI_WEBHOOK_SECRET). Either form is accepted:
+//   - X-Vapi-Secret: the shared secret itself
+//   - X-Vapi-Signature: the hex HMAC-SHA256 of the raw request body keyed with the secret (optionally prefixed with "sha256=")
+//
+// Requests are rejected with 401 when neither header matches, or when no secret is configured,
+// and with 413 when the body is larger than MAX_WEBHOOK_BODY_SIZE.
+func VapiWebhookMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("[WEBHOOK] Request: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+		startTime := time.Now()
+
+		secret := os.Getenv("VAPI_WEBHOOK_SECRET")
+		if secret == "" {
+			log.Printf("[WEBHOOK] ERROR: VAPI_WEBHOOK_SECRET is not configured, rejecting %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MAX_WEBHOOK_BODY_SIZE))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			log.Printf("[WEBHOOK] ERROR: Body of %s %s is larger than %d bytes", r.Method, r.URL.Path, MAX_WEBHOOK_BODY_SIZE)
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		} else if err != nil {
+			log.Printf("[WEBHOOK] ERROR: Failed to read body for %s %s: %v", r.Method, r.URL.Path, err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		if !verifyVapiRequest(r, body, secret) {
+			log.Printf("[WEBHOOK] ERROR: Invalid secret or signature for %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+		log.Printf("[WEBHOOK] Response: %s %s completed in %v", r.Method, r.URL.Path, time.Since(startTime))
+	})
+}
+
+// verifyVapiRequest checks the X-Vapi-Secret or X-Vapi-Signature header against the shared secret.
+func verifyVapiRequest(r *http.Request, body []byte, secret string) bool {
+	if provided := r.Header.Get("X-Vapi-Secret"); provided != "" {
+		return subtle.ConstantTimeCompare([]byte(provided), []byte(secret)) == 1
+	}
+
+	signature := strings.TrimPrefix(r.Header.Get("X-Vapi-Signature"), "sha256=")
+	if signature == "" {
+		return false
+	}
+
+	provided, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return hmac.Equal(provided, mac.Sum(nil))
+}
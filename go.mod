@@ -6,6 +6,7 @@ require (
 	github.com/VapiAI/server-sdk-go v0.9.0
 	github.com/clerk/clerk-sdk-go/v2 v2.3.1
 	github.com/joho/godotenv v1.5.1
+	github.com/nyaruka/phonenumbers v1.6.12
 	go.mongodb.org/mongo-driver/v2 v2.2.2
 )
 
@@ -14,12 +15,13 @@ require (
 	github.com/golang/snappy v1.0.0 // indirect
 	github.com/google/uuid v1.6.0 // indirect
 	github.com/klauspost/compress v1.16.7 // indirect
-	github.com/stretchr/testify v1.8.4 // indirect
 	github.com/xdg-go/pbkdf2 v1.0.0 // indirect
 	github.com/xdg-go/scram v1.1.2 // indirect
 	github.com/xdg-go/stringprep v1.0.4 // indirect
 	github.com/youmark/pkcs8 v0.0.0-20240726163527-a2c0da244d78 // indirect
 	golang.org/x/crypto v0.40.0 // indirect
+	golang.org/x/exp v0.0.0-20250305212735-054e65f0b394 // indirect
 	golang.org/x/sync v0.16.0 // indirect
 	golang.org/x/text v0.27.0 // indirect
+	google.golang.org/protobuf v1.36.11 // indirect
 )
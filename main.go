@@ -3,46 +3,131 @@ package main
 import (
 	"log"
 	"net/http"
+	"os"
 	"sarah/api"
 	"sarah/auth"
+	"sarah/mongodb"
 	"sarah/sarah"
 	"time"
 )
 
 func main() {
+	if err := mongodb.Connect(os.Getenv("MONGO_URI")); err != nil {
+		log.Fatalf("Error connecting to MongoDB: %v", err)
+	}
+	log.Println("Pinged deployment. Successfully connected to MongoDB!")
+
 	campaignScheduler := sarah.CampaignScheduler{}
 	campaignScheduler.Start()
 
+	callSyncer := sarah.CallSyncer{Interval: 15 * time.Minute}
+	callSyncer.Start()
+
+	recordingArchiver := sarah.RecordingArchiver{Interval: time.Hour}
+	recordingArchiver.Start()
+
+	callDispatcher := sarah.CallDispatcher{Interval: 30 * time.Second}
+	callDispatcher.Start()
+
+	callbackScheduler := sarah.CallbackScheduler{Interval: time.Minute}
+	callbackScheduler.Start()
+
+	callReconciler := sarah.CallReconciler{Interval: 5 * time.Minute, StaleAfter: sarah.RECONCILE_STALE_AFTER}
+	callReconciler.Start()
+
+	assistantDriftChecker := sarah.AssistantDriftChecker{Interval: time.Hour}
+	assistantDriftChecker.Start()
+
 	http.HandleFunc("/", welcome)
+	http.HandleFunc("/health", api.Health) // GET: Get the health of Sarah and VapiAI
 
 	// Call management endpoints
-	http.Handle("/calls/create", auth.VerifyingMiddleware(http.HandlerFunc(api.CreateCall)))      // POST: Create a new call
-	http.Handle("/calls/list", auth.VerifyingMiddleware(http.HandlerFunc(api.ListCalls)))         // GET: List all calls
-	http.Handle("/calls/call", auth.VerifyingMiddleware(http.HandlerFunc(api.GetCall)))           // GET: Get specific call by ID
-	http.Handle("/calls/org", auth.VerifyingMiddleware(http.HandlerFunc(api.GetCallListByOrgId))) // GET: Get calls by organization ID
+	http.Handle("/calls/create", auth.VerifyingMiddleware(http.HandlerFunc(api.CreateCall)))                  // POST: Create a new call
+	http.Handle("/calls/list", auth.VerifyingMiddleware(http.HandlerFunc(api.ListCalls)))                     // GET: List all calls
+	http.Handle("/calls/call", auth.VerifyingMiddleware(http.HandlerFunc(api.GetCall)))                       // GET: Get specific call by ID
+	http.Handle("/calls/org", auth.VerifyingMiddleware(http.HandlerFunc(api.GetCallListByOrgId)))             // GET: Get calls by organization ID
+	http.Handle("/calls/search", auth.VerifyingMiddleware(http.HandlerFunc(api.SearchCalls)))                 // GET: Search call transcripts
+	http.Handle("/calls/export", auth.VerifyingMiddleware(http.HandlerFunc(api.ExportCalls)))                 // GET: Export calls as CSV or JSONL
+	http.Handle("/calls/sync", auth.VerifyingMiddleware(http.HandlerFunc(api.SyncCalls)))                     // POST: Sync organization calls from VapiAI
+	http.Handle("/calls/cancel", auth.VerifyingMiddleware(http.HandlerFunc(api.CancelCall)))                  // POST: Cancel a call, hanging it up if it started
+	http.Handle("/calls/end", auth.VerifyingMiddleware(http.HandlerFunc(api.EndCall)))                        // POST: End an active call
+	http.Handle("/calls/stream", auth.VerifyingMiddleware(http.HandlerFunc(api.StreamCalls)))                 // GET: Stream call lifecycle events (SSE)
+	http.Handle("/calls/tags", auth.VerifyingMiddleware(http.HandlerFunc(api.UpdateCallTags)))                // PATCH: Add and remove tags of a call
+	http.Handle("/calls/disposition", auth.VerifyingMiddleware(http.HandlerFunc(api.SetCallDisposition)))     // PATCH: Set the disposition of a call
+	http.Handle("/calls/notes", auth.VerifyingMiddleware(http.HandlerFunc(api.GetCallNotes)))                 // GET: Get the notes of a call
+	http.Handle("/calls/notes/create", auth.VerifyingMiddleware(http.HandlerFunc(api.CreateCallNote)))        // POST: Write a note on a call
+	http.Handle("/calls/notes/delete", auth.VerifyingMiddleware(http.HandlerFunc(api.DeleteCallNote)))        // DELETE: Delete a note of a call
+	http.Handle("/calls/reconcile", auth.VerifyingMiddleware(http.HandlerFunc(api.ReconcileCalls)))           // POST: Reconcile stale calls with VapiAI
+	http.Handle("/calls/reconciliations", auth.VerifyingMiddleware(http.HandlerFunc(api.GetReconciliations))) // GET: Get the call reconciliation reports
+
+	// Call queue endpoints
+	http.Handle("/queue/org", auth.VerifyingMiddleware(http.HandlerFunc(api.GetCallQueue)))        // GET: Get the organization call queue
+	http.Handle("/queue/stats", auth.VerifyingMiddleware(http.HandlerFunc(api.GetCallQueueStats))) // GET: Get the call queue depth, active calls and limits
+	http.Handle("/queue/cancel", auth.VerifyingMiddleware(http.HandlerFunc(api.CancelQueuedCall))) // POST: Remove a call from the queue
+
+	// Callback endpoints
+	http.Handle("/callbacks/org", auth.VerifyingMiddleware(http.HandlerFunc(api.GetCallbacks)))      // GET: Get the organization callbacks
+	http.Handle("/callbacks/cancel", auth.VerifyingMiddleware(http.HandlerFunc(api.CancelCallback))) // POST: Cancel a scheduled callback
 
 	// Campaign management endpoints
-	http.Handle("/campaigns/org", auth.VerifyingMiddleware(http.HandlerFunc(api.GetCampaignViaOrgID))) // GET: Get campaigns by organization ID
-	http.Handle("/campaigns/create", auth.VerifyingMiddleware(http.HandlerFunc(api.CreateCampaign)))   // POST: Create a new campaign
-	http.Handle("/campaigns/update", auth.VerifyingMiddleware(http.HandlerFunc(api.UpdateCampaign)))   // PATCH: Update an existing campaign
-	http.Handle("/campaigns/delete", auth.VerifyingMiddleware(http.HandlerFunc(api.DeleteCampaign)))   // DELETE: Delete an existing campaign
+	http.Handle("/campaigns/org", auth.VerifyingMiddleware(http.HandlerFunc(api.GetCampaignViaOrgID)))        // GET: Get campaigns by organization ID
+	http.Handle("/campaigns/create", auth.VerifyingMiddleware(http.HandlerFunc(api.CreateCampaign)))          // POST: Create a new campaign
+	http.Handle("/campaigns/update", auth.VerifyingMiddleware(http.HandlerFunc(api.UpdateCampaign)))          // PATCH: Update an existing campaign
+	http.Handle("/campaigns/delete", auth.VerifyingMiddleware(http.HandlerFunc(api.DeleteCampaign)))          // DELETE: Delete an existing campaign
+	http.Handle("/campaigns/analytics", auth.VerifyingMiddleware(http.HandlerFunc(api.GetCampaignAnalytics))) // GET: Get campaign progress and analytics
+	http.Handle("/campaigns/runs", auth.VerifyingMiddleware(http.HandlerFunc(api.GetCampaignRuns)))           // GET: Get the run history of a campaign
+	http.Handle("/campaigns/cancel", auth.VerifyingMiddleware(http.HandlerFunc(api.CancelCampaignCalls)))     // POST: Cancel the pending calls of a campaign
 
 	// Organization resource endpoints
-	http.Handle("/assistants/org", auth.VerifyingMiddleware(http.HandlerFunc(api.GetOrganizationAssistants))) // GET: Get assistants by organization ID
-	http.Handle("/assistants/create", auth.VerifyingMiddleware(http.HandlerFunc(api.CreateAssistant)))        // POST: Create a new assistant
-	http.Handle("/assistants/update", auth.VerifyingMiddleware(http.HandlerFunc(api.UpdateAssistant)))        // PATCH: Update an assistant
-	http.Handle("/assistants/delete", auth.VerifyingMiddleware(http.HandlerFunc(api.DeleteAssistant)))        // DELETE: Delete an assistant
-	http.Handle("/assistants/register", auth.VerifyingMiddleware(http.HandlerFunc(api.RegisterAssistant)))    // POST: Register an existing assistant
+	http.Handle("/assistants/org", auth.VerifyingMiddleware(http.HandlerFunc(api.GetOrganizationAssistants)))            // GET: Get assistants by organization ID
+	http.Handle("/assistants/create", auth.VerifyingMiddleware(http.HandlerFunc(api.CreateAssistant)))                   // POST: Create a new assistant
+	http.Handle("/assistants/update", auth.VerifyingMiddleware(http.HandlerFunc(api.UpdateAssistant)))                   // PATCH: Update an assistant
+	http.Handle("/assistants/delete", auth.VerifyingMiddleware(http.HandlerFunc(api.DeleteAssistant)))                   // DELETE: Delete an assistant
+	http.Handle("/assistants/register", auth.VerifyingMiddleware(http.HandlerFunc(api.RegisterAssistant)))               // POST: Register an existing assistant
+	http.Handle("/assistants/drift", auth.VerifyingMiddleware(http.HandlerFunc(api.GetAssistantDrift)))                  // GET: Compare assistant records with VapiAI
+	http.Handle("/assistants/drift/repair", auth.VerifyingMiddleware(http.HandlerFunc(api.RepairAssistantDrift)))        // POST: Repair an assistant drifted from VapiAI
+	http.Handle("/assistants/versions", auth.VerifyingMiddleware(http.HandlerFunc(api.GetAssistantVersions)))            // GET: List the versions of an assistant
+	http.Handle("/assistants/versions/diff", auth.VerifyingMiddleware(http.HandlerFunc(api.DiffAssistantVersions)))      // GET: Compare two versions of an assistant
+	http.Handle("/assistants/versions/rollback", auth.VerifyingMiddleware(http.HandlerFunc(api.RollbackAssistant)))      // POST: Re-apply an earlier version of an assistant
+	http.Handle("/assistants/templates", auth.VerifyingMiddleware(http.HandlerFunc(api.GetAssistantTemplates)))          // GET: List the built-in and organization assistant templates
+	http.Handle("/assistants/templates/create", auth.VerifyingMiddleware(http.HandlerFunc(api.CreateAssistantTemplate))) // POST: Create an assistant template
+	http.Handle("/assistants/templates/update", auth.VerifyingMiddleware(http.HandlerFunc(api.UpdateAssistantTemplate))) // PATCH: Update an assistant template
+	http.Handle("/assistants/templates/delete", auth.VerifyingMiddleware(http.HandlerFunc(api.DeleteAssistantTemplate))) // DELETE: Delete an assistant template
+	http.Handle("/assistants/templates/render", auth.VerifyingMiddleware(http.HandlerFunc(api.RenderAssistantTemplate))) // POST: Create an assistant from a template
 
 	http.Handle("/contacts/create", auth.VerifyingMiddleware(http.HandlerFunc(api.CreateContact)))        // POST: Create a new contact
 	http.Handle("/contacts/update", auth.VerifyingMiddleware(http.HandlerFunc(api.UpdateContact)))        // PATCH: Update an existing contact
 	http.Handle("/contacts/org", auth.VerifyingMiddleware(http.HandlerFunc(api.GetOrganizationContacts))) // GET: Get contacts by organization ID
 	http.Handle("/contacts/delete", auth.VerifyingMiddleware(http.HandlerFunc(api.DeleteContact)))        // DELETE: Delete an existing contact
+	http.Handle("/contacts/updates", auth.VerifyingMiddleware(http.HandlerFunc(api.GetContactUpdates)))   // GET: Get the metadata updates written from call analyses
 
 	http.Handle("/phone_numbers/org", auth.VerifyingMiddleware(http.HandlerFunc(api.GetOrganizationPhoneNumbers))) // GET: Get phone numbers by organization ID
 	http.Handle("/phone_numbers/create", auth.VerifyingMiddleware(http.HandlerFunc(api.CreatePhoneNumber)))        // POST: Create a new phone number
+	http.Handle("/phone_numbers/update", auth.VerifyingMiddleware(http.HandlerFunc(api.UpdatePhoneNumber)))        // PATCH: Update the name and daily cap of a phone number
 	http.Handle("/phone_numbers/delete", auth.VerifyingMiddleware(http.HandlerFunc(api.DeletePhoneNumber)))        // DELETE: Delete an existing phone number
 
+	http.Handle("/dnc/org", auth.VerifyingMiddleware(http.HandlerFunc(api.GetOrganizationDnc))) // GET: Get the organization Do-Not-Call list
+	http.Handle("/dnc/create", auth.VerifyingMiddleware(http.HandlerFunc(api.CreateDncEntry)))  // POST: Add a number to the Do-Not-Call list
+	http.Handle("/dnc/import", auth.VerifyingMiddleware(http.HandlerFunc(api.ImportDnc)))       // POST: Import Do-Not-Call numbers from CSV
+	http.Handle("/dnc/delete", auth.VerifyingMiddleware(http.HandlerFunc(api.DeleteDncEntry)))  // DELETE: Remove a number from the Do-Not-Call list
+
+	http.Handle("/recordings/call", auth.VerifyingMiddleware(http.HandlerFunc(api.GetCallRecording))) // GET: Stream the archived recording of a call
+
+	http.Handle("/alerts/org", auth.VerifyingMiddleware(http.HandlerFunc(api.GetAlertRules)))      // GET: Get the organization alert rules
+	http.Handle("/alerts/create", auth.VerifyingMiddleware(http.HandlerFunc(api.CreateAlertRule))) // POST: Create an alert rule
+	http.Handle("/alerts/update", auth.VerifyingMiddleware(http.HandlerFunc(api.UpdateAlertRule))) // PATCH: Update an alert rule
+	http.Handle("/alerts/delete", auth.VerifyingMiddleware(http.HandlerFunc(api.DeleteAlertRule))) // DELETE: Delete an alert rule
+	http.Handle("/alerts/test", auth.VerifyingMiddleware(http.HandlerFunc(api.TestAlertRule)))     // POST: Test an alert rule against a sample
+
+	http.Handle("/settings/org", auth.VerifyingMiddleware(http.HandlerFunc(api.GetOrganizationSettings)))       // GET: Get the organization settings
+	http.Handle("/settings/update", auth.VerifyingMiddleware(http.HandlerFunc(api.UpdateOrganizationSettings))) // PATCH: Update the organization settings
+
+	http.Handle("/audit/org", auth.VerifyingMiddleware(http.HandlerFunc(api.GetAuditLog))) // GET: Get the organization audit log
+
+	// VapiAI server URL, authenticated with the shared webhook secret instead of Clerk
+	http.Handle("/webhooks/vapi", auth.VapiWebhookMiddleware(http.HandlerFunc(api.VapiWebhook)))   // POST: Receive VapiAI server messages
+	http.Handle("/tools/callback", auth.VapiWebhookMiddleware(http.HandlerFunc(api.CallbackTool))) // POST: Register a callback requested during a call
+
 	server := &http.Server{
 		Addr:         ":8080",
 		ReadTimeout:  10 * time.Second,
@@ -0,0 +1,243 @@
+package mongodb
+
+import (
+	"context"
+	"log"
+	"os"
+	"sarah/types/mongodb"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// alertTriggerIndexesEnsured records the organizations whose alert trigger indexes were created by this process
+var alertTriggerIndexesEnsured sync.Map
+
+// alertRulesCollection returns the alert rules collection of an organization.
+func alertRulesCollection(orgId string) *mongo.Collection {
+	return Client.Database(orgId).Collection(os.Getenv("MONGO_COLLECTION_ALERT_RULES"))
+}
+
+// alertTriggersCollection returns the alert triggers collection of an organization, creating its indexes
+// the first time the collection is used by this process.
+func alertTriggersCollection(orgId string) *mongo.Collection {
+	coll := Client.Database(orgId).Collection(os.Getenv("MONGO_COLLECTION_ALERT_TRIGGERS"))
+
+	if _, loaded := alertTriggerIndexesEnsured.LoadOrStore(orgId, true); !loaded {
+		_, err := coll.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+			{Keys: bson.D{{Key: "rule_id", Value: 1}, {Key: "vapi_call_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		})
+		if err != nil {
+			log.Printf("Error creating alert trigger indexes for organization %s: %v", orgId, err)
+			alertTriggerIndexesEnsured.Delete(orgId)
+		}
+	}
+
+	return coll
+}
+
+// CreateAlertRule stores a new alert rule of an organization.
+//
+// Parameters:
+//   - orgId: The organization ID the rule belongs to
+//   - rule: The rule to store
+//
+// Returns:
+//   - *mongo.InsertOneResult: The result of the insert operation
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_ALERT_RULES environment variable
+//   - Operation: Inserts a single rule document
+func CreateAlertRule(orgId string, rule mongodb.AlertRule) (*mongo.InsertOneResult, error) {
+	coll := alertRulesCollection(orgId)
+
+	result, err := coll.InsertOne(context.Background(), rule)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetAlertRules retrieves the alert rules of an organization, oldest first.
+//
+// Parameters:
+//   - orgId: The organization ID whose rules are retrieved
+//   - enabledOnly: Whether to only retrieve the enabled rules
+//
+// Returns:
+//   - []mongodb.AlertRule: The rules of the organization
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_ALERT_RULES environment variable
+//   - Query: Filters by enabled when enabledOnly is set, sorts by created_at ascending
+func GetAlertRules(orgId string, enabledOnly bool) ([]mongodb.AlertRule, error) {
+	coll := alertRulesCollection(orgId)
+
+	query := bson.M{}
+	if enabledOnly {
+		query["enabled"] = true
+	}
+
+	cursor, err := coll.Find(context.Background(), query, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	rules := []mongodb.AlertRule{}
+	if err := cursor.All(context.Background(), &rules); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+// CountAlertRules counts the alert rules of an organization.
+//
+// Parameters:
+//   - orgId: The organization ID whose rules are counted
+//
+// Returns:
+//   - int64: The number of rules
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_ALERT_RULES environment variable
+//   - Operation: Counts every rule document
+func CountAlertRules(orgId string) (int64, error) {
+	coll := alertRulesCollection(orgId)
+
+	count, err := coll.CountDocuments(context.Background(), bson.M{})
+	if err != nil {
+		log.Println(err)
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// GetAlertRuleById retrieves an alert rule of an organization.
+//
+// Parameters:
+//   - orgId: The organization ID the rule belongs to
+//   - ruleId: The ObjectID of the rule
+//
+// Returns:
+//   - *mongodb.AlertRule: The rule, or mongo.ErrNoDocuments if the organization has no such rule
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_ALERT_RULES environment variable
+//   - Query: Filters by _id
+func GetAlertRuleById(orgId string, ruleId bson.ObjectID) (*mongodb.AlertRule, error) {
+	coll := alertRulesCollection(orgId)
+
+	var rule mongodb.AlertRule
+	if err := coll.FindOne(context.Background(), bson.M{"_id": ruleId}).Decode(&rule); err != nil {
+		return nil, err
+	}
+
+	return &rule, nil
+}
+
+// UpdateAlertRule replaces the name, enabled flag, condition and actions of an alert rule.
+// The trigger count and last trigger date are kept.
+//
+// Parameters:
+//   - orgId: The organization ID the rule belongs to
+//   - rule: The rule to update, matched by its Id
+//
+// Returns:
+//   - *mongo.UpdateResult: The result of the update operation
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_ALERT_RULES environment variable
+//   - Operation: Updates a single rule document matching _id
+func UpdateAlertRule(orgId string, rule mongodb.AlertRule) (*mongo.UpdateResult, error) {
+	coll := alertRulesCollection(orgId)
+
+	update := bson.M{"$set": bson.M{
+		"name":       rule.Name,
+		"enabled":    rule.Enabled,
+		"condition":  rule.Condition,
+		"actions":    rule.Actions,
+		"updated_at": time.Now(),
+	}}
+
+	result, err := coll.UpdateOne(context.Background(), bson.M{"_id": rule.Id}, update)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// DeleteAlertRule deletes an alert rule of an organization.
+//
+// Parameters:
+//   - orgId: The organization ID the rule belongs to
+//   - ruleId: The ObjectID of the rule
+//
+// Returns:
+//   - *mongo.DeleteResult: The result of the delete operation
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_ALERT_RULES environment variable
+//   - Operation: Deletes a single rule document matching _id
+func DeleteAlertRule(orgId string, ruleId bson.ObjectID) (*mongo.DeleteResult, error) {
+	coll := alertRulesCollection(orgId)
+
+	result, err := coll.DeleteOne(context.Background(), bson.M{"_id": ruleId})
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// RecordAlertTrigger records an alert rule triggering on a call and counts it on the rule.
+// A rule only triggers once per call, so the same transcript or report sent again is not counted twice.
+//
+// Parameters:
+//   - orgId: The organization ID the rule belongs to
+//   - trigger: The trigger to record
+//
+// Returns:
+//   - bool: Whether the trigger was recorded, false if the rule already triggered on the call
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Inserts into MONGO_COLLECTION_ALERT_TRIGGERS, unique on rule_id and vapi_call_id
+//   - Operation: Then increments trigger_count and sets last_triggered_at of the rule in MONGO_COLLECTION_ALERT_RULES
+func RecordAlertTrigger(orgId string, trigger mongodb.AlertTrigger) (bool, error) {
+	_, err := alertTriggersCollection(orgId).InsertOne(context.Background(), trigger)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	} else if err != nil {
+		log.Println(err)
+		return false, err
+	}
+
+	update := bson.M{
+		"$inc": bson.M{"trigger_count": 1},
+		"$set": bson.M{"last_triggered_at": trigger.CreatedAt},
+	}
+	if _, err := alertRulesCollection(orgId).UpdateOne(context.Background(), bson.M{"_id": trigger.RuleId}, update); err != nil {
+		// The trigger is recorded, so the alert is still raised once
+		log.Println(err)
+	}
+
+	return true, nil
+}
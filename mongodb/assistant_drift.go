@@ -0,0 +1,93 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"sarah/types/mongodb"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// GetAssistantDriftReport retrieves the latest assistant drift report of an organization.
+// Returns mongo.ErrNoDocuments if the organization's assistants were never checked.
+//
+// Parameters:
+//   - orgId: The organization ID to retrieve the report for
+//
+// Returns:
+//   - *mongodb.AssistantDriftReport: The latest report
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_ASSISTANT_DRIFT environment variable
+//   - Query: Finds the single report document
+func GetAssistantDriftReport(orgId string) (*mongodb.AssistantDriftReport, error) {
+	coll := Client.Database(orgId).Collection(os.Getenv("MONGO_COLLECTION_ASSISTANT_DRIFT"))
+
+	var report mongodb.AssistantDriftReport
+	if err := coll.FindOne(context.Background(), bson.M{"_id": mongodb.ASSISTANT_DRIFT_REPORT_ID}).Decode(&report); err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+// SaveAssistantDriftReport replaces the assistant drift report of an organization.
+//
+// Parameters:
+//   - orgId: The organization ID whose assistants were checked
+//   - report: The new report
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_ASSISTANT_DRIFT environment variable
+//   - Operation: Replaces the single report document, creating it if needed
+func SaveAssistantDriftReport(orgId string, report mongodb.AssistantDriftReport) error {
+	coll := Client.Database(orgId).Collection(os.Getenv("MONGO_COLLECTION_ASSISTANT_DRIFT"))
+
+	report.Id = mongodb.ASSISTANT_DRIFT_REPORT_ID
+	_, err := coll.ReplaceOne(context.Background(), bson.M{"_id": mongodb.ASSISTANT_DRIFT_REPORT_ID}, report, options.Replace().SetUpsert(true))
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return nil
+}
+
+// RemoveAssistantDrift removes the differences about an assistant from the latest drift report, once repaired.
+//
+// Parameters:
+//   - orgId: The organization ID the assistant belongs to
+//   - vapiAssistantId: The VapiAI assistant that was repaired
+//
+// Returns:
+//   - *mongodb.AssistantDriftReport: The report without the assistant's differences
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_ASSISTANT_DRIFT environment variable
+//   - Operation: Pulls the assistant's entries from issues and returns the updated document
+func RemoveAssistantDrift(orgId string, vapiAssistantId string) (*mongodb.AssistantDriftReport, error) {
+	coll := Client.Database(orgId).Collection(os.Getenv("MONGO_COLLECTION_ASSISTANT_DRIFT"))
+
+	var report mongodb.AssistantDriftReport
+	err := coll.FindOneAndUpdate(
+		context.Background(),
+		bson.M{"_id": mongodb.ASSISTANT_DRIFT_REPORT_ID},
+		bson.M{"$pull": bson.M{"issues": bson.M{"vapi_assistant_id": vapiAssistantId}}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&report)
+	if err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			log.Println(err)
+		}
+		return nil, err
+	}
+
+	return &report, nil
+}
@@ -0,0 +1,183 @@
+package mongodb
+
+import (
+	"context"
+	"log"
+	"os"
+	"sarah/types/mongodb"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// assistantTemplatesCollection returns the assistant templates collection of an organization.
+// Nested assistant documents are decoded as maps so that templates encode back to the JSON they were created from.
+func assistantTemplatesCollection(orgId string) *mongo.Collection {
+	return Client.Database(orgId).Collection(
+		os.Getenv("MONGO_COLLECTION_ASSISTANT_TEMPLATES"),
+		options.Collection().SetBSONOptions(&options.BSONOptions{DefaultDocumentM: true}),
+	)
+}
+
+// CreateAssistantTemplate stores a new assistant template of an organization.
+//
+// Parameters:
+//   - orgId: The organization ID the template belongs to
+//   - template: The template to store
+//
+// Returns:
+//   - *mongo.InsertOneResult: The result of the insert operation
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_ASSISTANT_TEMPLATES environment variable
+//   - Operation: Inserts a single template document
+func CreateAssistantTemplate(orgId string, template mongodb.AssistantTemplate) (*mongo.InsertOneResult, error) {
+	coll := assistantTemplatesCollection(orgId)
+
+	result, err := coll.InsertOne(context.Background(), template)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetAssistantTemplates retrieves the assistant templates of an organization, sorted by name.
+//
+// Parameters:
+//   - orgId: The organization ID whose templates are retrieved
+//
+// Returns:
+//   - []mongodb.AssistantTemplate: The templates of the organization
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_ASSISTANT_TEMPLATES environment variable
+//   - Query: Retrieves all documents, sorted by name ascending
+func GetAssistantTemplates(orgId string) ([]mongodb.AssistantTemplate, error) {
+	coll := assistantTemplatesCollection(orgId)
+
+	cursor, err := coll.Find(context.Background(), bson.M{}, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	templates := []mongodb.AssistantTemplate{}
+	if err := cursor.All(context.Background(), &templates); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return templates, nil
+}
+
+// CountAssistantTemplates counts the assistant templates of an organization.
+//
+// Parameters:
+//   - orgId: The organization ID whose templates are counted
+//
+// Returns:
+//   - int64: The number of templates
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_ASSISTANT_TEMPLATES environment variable
+//   - Query: Counts all documents
+func CountAssistantTemplates(orgId string) (int64, error) {
+	coll := assistantTemplatesCollection(orgId)
+
+	count, err := coll.CountDocuments(context.Background(), bson.M{})
+	if err != nil {
+		log.Println(err)
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// GetAssistantTemplateById retrieves an assistant template of an organization.
+//
+// Parameters:
+//   - orgId: The organization ID the template belongs to
+//   - templateId: The ID of the template
+//
+// Returns:
+//   - *mongodb.AssistantTemplate: The template, or mongo.ErrNoDocuments if the organization has no such template
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_ASSISTANT_TEMPLATES environment variable
+//   - Query: Filters by _id
+func GetAssistantTemplateById(orgId string, templateId string) (*mongodb.AssistantTemplate, error) {
+	coll := assistantTemplatesCollection(orgId)
+
+	var template mongodb.AssistantTemplate
+	if err := coll.FindOne(context.Background(), bson.M{"_id": templateId}).Decode(&template); err != nil {
+		return nil, err
+	}
+
+	return &template, nil
+}
+
+// UpdateAssistantTemplate replaces the name, description, parameters and assistant of an assistant template.
+//
+// Parameters:
+//   - orgId: The organization ID the template belongs to
+//   - template: The template to update, matched by its Id
+//
+// Returns:
+//   - *mongo.UpdateResult: The result of the update operation
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_ASSISTANT_TEMPLATES environment variable
+//   - Operation: Updates a single template document matching _id
+func UpdateAssistantTemplate(orgId string, template mongodb.AssistantTemplate) (*mongo.UpdateResult, error) {
+	coll := assistantTemplatesCollection(orgId)
+
+	update := bson.M{"$set": bson.M{
+		"name":        template.Name,
+		"description": template.Description,
+		"parameters":  template.Parameters,
+		"assistant":   template.Assistant,
+		"updated_at":  time.Now(),
+	}}
+
+	result, err := coll.UpdateOne(context.Background(), bson.M{"_id": template.Id}, update)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// DeleteAssistantTemplate deletes an assistant template of an organization.
+//
+// Parameters:
+//   - orgId: The organization ID the template belongs to
+//   - templateId: The ID of the template
+//
+// Returns:
+//   - *mongo.DeleteResult: The result of the delete operation
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_ASSISTANT_TEMPLATES environment variable
+//   - Operation: Deletes a single template document matching _id
+func DeleteAssistantTemplate(orgId string, templateId string) (*mongo.DeleteResult, error) {
+	coll := assistantTemplatesCollection(orgId)
+
+	result, err := coll.DeleteOne(context.Background(), bson.M{"_id": templateId})
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,180 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"sarah/types/mongodb"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// ASSISTANT_VERSION_ATTEMPTS is how many times a version number is taken before giving up on concurrent snapshots
+const ASSISTANT_VERSION_ATTEMPTS = 5
+
+// assistantVersionIndexesEnsured records the organizations whose assistant version indexes were created by this process
+var assistantVersionIndexesEnsured sync.Map
+
+// assistantVersionsCollection returns the assistant versions collection of an organization, creating its indexes
+// the first time the collection is used by this process. Nested configuration documents are decoded as maps
+// so that snapshots encode back to the JSON VapiAI returned.
+func assistantVersionsCollection(orgId string) *mongo.Collection {
+	coll := Client.Database(orgId).Collection(
+		os.Getenv("MONGO_COLLECTION_ASSISTANT_VERSIONS"),
+		options.Collection().SetBSONOptions(&options.BSONOptions{DefaultDocumentM: true}),
+	)
+
+	if _, loaded := assistantVersionIndexesEnsured.LoadOrStore(orgId, true); !loaded {
+		_, err := coll.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+			{Keys: bson.D{{Key: "vapi_assistant_id", Value: 1}, {Key: "version", Value: -1}}, Options: options.Index().SetUnique(true)},
+		})
+		if err != nil {
+			log.Printf("Error creating assistant version indexes for organization %s: %v", orgId, err)
+			assistantVersionIndexesEnsured.Delete(orgId)
+		}
+	}
+
+	return coll
+}
+
+// CreateAssistantVersion stores a snapshot of an assistant as its next version.
+// The version number is one more than the assistant's latest version; it is taken again
+// if a concurrent snapshot of the same assistant took it first.
+//
+// Parameters:
+//   - orgId: The organization ID owning the assistant
+//   - version: The snapshot to store, its Version is set by this function
+//
+// Returns:
+//   - *mongodb.AssistantVersion: The stored version
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_ASSISTANT_VERSIONS environment variable
+//   - Query: Finds the latest version of the assistant, then inserts the snapshot after it
+func CreateAssistantVersion(orgId string, version mongodb.AssistantVersion) (*mongodb.AssistantVersion, error) {
+	coll := assistantVersionsCollection(orgId)
+
+	var err error
+	for range ASSISTANT_VERSION_ATTEMPTS {
+		latest, latestErr := GetLatestAssistantVersion(orgId, version.VapiAssistantId)
+		if latestErr != nil && !errors.Is(latestErr, mongo.ErrNoDocuments) {
+			return nil, latestErr
+		}
+
+		version.Version = 1
+		if latest != nil {
+			version.Version = latest.Version + 1
+		}
+
+		var result *mongo.InsertOneResult
+		result, err = coll.InsertOne(context.Background(), version)
+		if mongo.IsDuplicateKeyError(err) {
+			continue
+		} else if err != nil {
+			log.Println(err)
+			return nil, err
+		}
+
+		version.Id = result.InsertedID.(bson.ObjectID)
+		return &version, nil
+	}
+
+	log.Println(err)
+	return nil, err
+}
+
+// GetAssistantVersions retrieves the latest versions of an assistant, newest first.
+//
+// Parameters:
+//   - orgId: The organization ID owning the assistant
+//   - vapiAssistantId: The VapiAI assistant ID
+//   - limit: The maximum number of versions to return
+//
+// Returns:
+//   - []mongodb.AssistantVersion: The versions
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_ASSISTANT_VERSIONS environment variable
+//   - Query: Filters by vapi_assistant_id, sorts by version descending and limits
+func GetAssistantVersions(orgId string, vapiAssistantId string, limit int) ([]mongodb.AssistantVersion, error) {
+	coll := assistantVersionsCollection(orgId)
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "version", Value: -1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := coll.Find(context.Background(), bson.M{"vapi_assistant_id": vapiAssistantId}, opts)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	versions := []mongodb.AssistantVersion{}
+	if err := cursor.All(context.Background(), &versions); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+// GetAssistantVersion retrieves a version of an assistant.
+// Returns mongo.ErrNoDocuments if the assistant has no such version.
+//
+// Parameters:
+//   - orgId: The organization ID owning the assistant
+//   - vapiAssistantId: The VapiAI assistant ID
+//   - version: The version number
+//
+// Returns:
+//   - *mongodb.AssistantVersion: The version
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_ASSISTANT_VERSIONS environment variable
+//   - Query: Filters by vapi_assistant_id and version
+func GetAssistantVersion(orgId string, vapiAssistantId string, version int) (*mongodb.AssistantVersion, error) {
+	coll := assistantVersionsCollection(orgId)
+
+	var assistantVersion mongodb.AssistantVersion
+	err := coll.FindOne(context.Background(), bson.M{"vapi_assistant_id": vapiAssistantId, "version": version}).Decode(&assistantVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	return &assistantVersion, nil
+}
+
+// GetLatestAssistantVersion retrieves the latest version of an assistant.
+// Returns mongo.ErrNoDocuments if the assistant has no versions.
+//
+// Parameters:
+//   - orgId: The organization ID owning the assistant
+//   - vapiAssistantId: The VapiAI assistant ID
+//
+// Returns:
+//   - *mongodb.AssistantVersion: The latest version
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_ASSISTANT_VERSIONS environment variable
+//   - Query: Filters by vapi_assistant_id and sorts by version descending
+func GetLatestAssistantVersion(orgId string, vapiAssistantId string) (*mongodb.AssistantVersion, error) {
+	coll := assistantVersionsCollection(orgId)
+
+	opts := options.FindOne().SetSort(bson.D{{Key: "version", Value: -1}})
+
+	var assistantVersion mongodb.AssistantVersion
+	err := coll.FindOne(context.Background(), bson.M{"vapi_assistant_id": vapiAssistantId}, opts).Decode(&assistantVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	return &assistantVersion, nil
+}
@@ -19,20 +19,32 @@ func init() {
 	if err := godotenv.Load(); err != nil {
 		log.Printf("Warning: .env file not found, using system environment variables")
 	}
+}
 
+// Connect connects Client to the MongoDB deployment at uri and pings it.
+// It must be called before any other function of the package; main connects to MONGO_URI on startup.
+//
+// Parameters:
+//   - uri: The MongoDB connection string
+//
+// Returns:
+//   - error: If the connection string is invalid or the deployment can't be reached
+func Connect(uri string) error {
 	serverAPI := options.ServerAPI(options.ServerAPIVersion1)
-	opts := options.Client().ApplyURI(os.Getenv("MONGO_URI")).SetServerAPIOptions(serverAPI)
+	opts := options.Client().ApplyURI(uri).SetServerAPIOptions(serverAPI)
 
-	var err error
-	Client, err = mongo.Connect(opts)
+	client, err := mongo.Connect(opts)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
-	if err := Client.Ping(context.TODO(), readpref.Primary()); err != nil {
-		panic(err)
+	if err := client.Ping(context.TODO(), readpref.Primary()); err != nil {
+		client.Disconnect(context.TODO())
+		return err
 	}
-	log.Println("Pinged deployment. Successfully connected to MongoDB!")
+
+	Client = client
+	return nil
 }
 
 // GetOrganizationAssistants retrieves all assistants for a specific organization from the database.
@@ -75,6 +87,19 @@ func GetOrganizationAssistants(orgId string) ([]mongodb.Assistant, error) {
 	return assistants, nil
 }
 
+// GetAssistantByVapiId retrieves the assistant record of an organization matching a VapiAI assistant ID.
+// Returns mongo.ErrNoDocuments if the organization has no such assistant.
+func GetAssistantByVapiId(orgId string, vapiAssistantId string) (*mongodb.Assistant, error) {
+	coll := Client.Database(orgId).Collection(os.Getenv("MONGO_COLLECTION_ASSISTANTS"))
+
+	var assistant mongodb.Assistant
+	if err := coll.FindOne(context.Background(), bson.M{"vapi_assistant_id": vapiAssistantId}).Decode(&assistant); err != nil {
+		return nil, err
+	}
+
+	return &assistant, nil
+}
+
 func CreateAssistant(orgId string, assistant mongodb.Assistant) (*mongo.InsertOneResult, error) {
 	coll := Client.Database(orgId).Collection(os.Getenv("MONGO_COLLECTION_ASSISTANTS"))
 
@@ -87,6 +112,32 @@ func CreateAssistant(orgId string, assistant mongodb.Assistant) (*mongo.InsertOn
 	return result, nil
 }
 
+// UpdateAssistantName renames the record of an organization's assistant, e.g. after it was renamed in VapiAI.
+//
+// Parameters:
+//   - orgId: The organization ID the assistant belongs to
+//   - vapiAssistantId: The VapiAI assistant whose record is renamed
+//   - name: The new name
+//
+// Returns:
+//   - *mongo.UpdateResult: The result of the update, matching nothing if the organization has no such assistant
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_ASSISTANTS environment variable
+//   - Operation: Sets the name of the documents matching vapi_assistant_id
+func UpdateAssistantName(orgId string, vapiAssistantId string, name string) (*mongo.UpdateResult, error) {
+	coll := Client.Database(orgId).Collection(os.Getenv("MONGO_COLLECTION_ASSISTANTS"))
+
+	result, err := coll.UpdateMany(context.Background(), bson.M{"vapi_assistant_id": vapiAssistantId}, bson.M{"$set": bson.M{"name": name}})
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
 func DeleteAssistant(orgId string, assistantId string) (*mongo.DeleteResult, error) {
 	coll := Client.Database(orgId).Collection(os.Getenv("MONGO_COLLECTION_ASSISTANTS"))
 
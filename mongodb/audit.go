@@ -0,0 +1,76 @@
+package mongodb
+
+import (
+	"context"
+	"log"
+	"os"
+	"sarah/types/mongodb"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// CreateAuditEntry stores an audit entry in the organization's audit log.
+//
+// Parameters:
+//   - orgId: The organization ID of the caller
+//   - entry: The audit entry to store
+//
+// Returns:
+//   - *mongo.InsertOneResult: The result of the insertion operation
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_AUDIT_LOGS environment variable
+//   - Operation: Inserts a single audit entry document
+func CreateAuditEntry(orgId string, entry mongodb.AuditEntry) (*mongo.InsertOneResult, error) {
+	coll := Client.Database(orgId).Collection(os.Getenv("MONGO_COLLECTION_AUDIT_LOGS"))
+
+	result, err := coll.InsertOne(context.Background(), entry)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetAuditEntries retrieves the most recent entries of the organization's audit log.
+//
+// Parameters:
+//   - orgId: The organization ID to retrieve the audit log for
+//   - outcome: Only return entries with this outcome, empty for all entries
+//   - limit: The maximum number of entries to return
+//
+// Returns:
+//   - []mongodb.AuditEntry: The entries, newest first
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_AUDIT_LOGS environment variable
+//   - Query: Filters by outcome, sorts by created_at descending and limits
+func GetAuditEntries(orgId string, outcome mongodb.AuditOutcome, limit int) ([]mongodb.AuditEntry, error) {
+	coll := Client.Database(orgId).Collection(os.Getenv("MONGO_COLLECTION_AUDIT_LOGS"))
+
+	query := bson.M{}
+	if outcome != "" {
+		query["outcome"] = outcome
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(int64(limit))
+
+	cursor, err := coll.Find(context.Background(), query, opts)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	entries := []mongodb.AuditEntry{}
+	if err := cursor.All(context.Background(), &entries); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,112 @@
+package mongodb
+
+import (
+	"context"
+	"log"
+	"os"
+	"sarah/types/mongodb"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// callEventIndexesEnsured records the organizations whose call event indexes were created by this process
+var callEventIndexesEnsured sync.Map
+
+// callEventsCollection returns the call events collection of an organization, creating its indexes
+// the first time the collection is used by this process.
+func callEventsCollection(orgId string) *mongo.Collection {
+	coll := Client.Database(orgId).Collection(os.Getenv("MONGO_COLLECTION_CALL_EVENTS"))
+
+	if _, loaded := callEventIndexesEnsured.LoadOrStore(orgId, true); !loaded {
+		// Streamed events are read in _id order, optionally for a single campaign or assistant
+		_, err := coll.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+			{Keys: bson.D{{Key: "stream_event", Value: 1}, {Key: "_id", Value: 1}}},
+			{Keys: bson.D{{Key: "campaign_id", Value: 1}, {Key: "stream_event", Value: 1}, {Key: "_id", Value: 1}}},
+			{Keys: bson.D{{Key: "assistant_id", Value: 1}, {Key: "stream_event", Value: 1}, {Key: "_id", Value: 1}}},
+		})
+		if err != nil {
+			log.Printf("Error creating call event indexes for organization %s: %v", orgId, err)
+			callEventIndexesEnsured.Delete(orgId)
+		}
+	}
+
+	return coll
+}
+
+// CreateCallEvent stores a server message received from VapiAI.
+//
+// Parameters:
+//   - orgId: The organization ID the call belongs to
+//   - event: The event to store
+//
+// Returns:
+//   - *mongo.InsertOneResult: The result of the insertion operation
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CALL_EVENTS environment variable
+//   - Operation: Inserts a single event document
+func CreateCallEvent(orgId string, event mongodb.CallEvent) (*mongo.InsertOneResult, error) {
+	coll := callEventsCollection(orgId)
+
+	result, err := coll.InsertOne(context.Background(), event)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetStreamedCallEvents retrieves the streamed events of an organization's calls stored after a given event, oldest first.
+//
+// Parameters:
+//   - orgId: The organization ID whose events are retrieved
+//   - campaignId: Only return events of calls placed by this campaign, every campaign if empty
+//   - assistantId: Only return events of calls handled by this assistant, every assistant if empty
+//   - after: Only return events whose ObjectID is greater than this one
+//   - limit: The maximum number of events to return
+//
+// Returns:
+//   - []mongodb.CallEvent: The events, in _id order
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CALL_EVENTS environment variable
+//   - Query: Filters by stream_event set, campaign_id, assistant_id and _id $gt after, sorts by _id ascending and limits
+func GetStreamedCallEvents(orgId string, campaignId string, assistantId string, after bson.ObjectID, limit int) ([]mongodb.CallEvent, error) {
+	coll := callEventsCollection(orgId)
+
+	query := bson.M{
+		"stream_event": bson.M{"$exists": true},
+		"_id":          bson.M{"$gt": after},
+	}
+	if campaignId != "" {
+		query["campaign_id"] = campaignId
+	}
+	if assistantId != "" {
+		query["assistant_id"] = assistantId
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetLimit(int64(limit)).
+		SetProjection(bson.M{"payload": 0})
+
+	cursor, err := coll.Find(context.Background(), query, opts)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	events := []mongodb.CallEvent{}
+	if err := cursor.All(context.Background(), &events); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return events, nil
+}
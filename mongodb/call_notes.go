@@ -0,0 +1,139 @@
+package mongodb
+
+import (
+	"context"
+	"log"
+	"os"
+	"sarah/types/mongodb"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// callNoteIndexesEnsured records the organizations whose call note indexes were created by this process
+var callNoteIndexesEnsured sync.Map
+
+// callNotesCollection returns the call notes collection of an organization, creating its indexes
+// the first time the collection is used by this process.
+func callNotesCollection(orgId string) *mongo.Collection {
+	coll := Client.Database(orgId).Collection(os.Getenv("MONGO_COLLECTION_CALL_NOTES"))
+
+	if _, loaded := callNoteIndexesEnsured.LoadOrStore(orgId, true); !loaded {
+		_, err := coll.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+			{Keys: bson.D{{Key: "vapi_call_id", Value: 1}, {Key: "created_at", Value: 1}}},
+		})
+		if err != nil {
+			log.Printf("Error creating call note indexes for organization %s: %v", orgId, err)
+			callNoteIndexesEnsured.Delete(orgId)
+		}
+	}
+
+	return coll
+}
+
+// CreateCallNote stores a note written on a call.
+//
+// Parameters:
+//   - orgId: The organization ID the call belongs to
+//   - note: The note to store
+//
+// Returns:
+//   - *mongo.InsertOneResult: The result of the insert operation
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CALL_NOTES environment variable
+//   - Operation: Inserts a single note document
+func CreateCallNote(orgId string, note mongodb.CallNote) (*mongo.InsertOneResult, error) {
+	coll := callNotesCollection(orgId)
+
+	result, err := coll.InsertOne(context.Background(), note)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetCallNotes retrieves the notes written on a call, oldest first.
+//
+// Parameters:
+//   - orgId: The organization ID the call belongs to
+//   - vapiCallId: The VapiAI call whose notes are retrieved
+//
+// Returns:
+//   - []mongodb.CallNote: The notes of the call
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CALL_NOTES environment variable
+//   - Query: Filters by vapi_call_id, sorts by created_at ascending
+func GetCallNotes(orgId string, vapiCallId string) ([]mongodb.CallNote, error) {
+	coll := callNotesCollection(orgId)
+
+	cursor, err := coll.Find(context.Background(), bson.M{"vapi_call_id": vapiCallId}, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	notes := []mongodb.CallNote{}
+	if err := cursor.All(context.Background(), &notes); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return notes, nil
+}
+
+// GetCallNoteById retrieves a note of an organization's call.
+//
+// Parameters:
+//   - orgId: The organization ID the call belongs to
+//   - noteId: The ObjectID of the note
+//
+// Returns:
+//   - *mongodb.CallNote: The note, or mongo.ErrNoDocuments if the organization has no such note
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CALL_NOTES environment variable
+//   - Query: Filters by _id
+func GetCallNoteById(orgId string, noteId bson.ObjectID) (*mongodb.CallNote, error) {
+	coll := callNotesCollection(orgId)
+
+	var note mongodb.CallNote
+	if err := coll.FindOne(context.Background(), bson.M{"_id": noteId}).Decode(&note); err != nil {
+		return nil, err
+	}
+
+	return &note, nil
+}
+
+// DeleteCallNote deletes a note of an organization's call.
+//
+// Parameters:
+//   - orgId: The organization ID the call belongs to
+//   - noteId: The ObjectID of the note
+//
+// Returns:
+//   - *mongo.DeleteResult: The result of the delete operation
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CALL_NOTES environment variable
+//   - Operation: Deletes a single note document matching _id
+func DeleteCallNote(orgId string, noteId bson.ObjectID) (*mongo.DeleteResult, error) {
+	coll := callNotesCollection(orgId)
+
+	result, err := coll.DeleteOne(context.Background(), bson.M{"_id": noteId})
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return result, nil
+}
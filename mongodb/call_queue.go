@@ -0,0 +1,493 @@
+package mongodb
+
+import (
+	"context"
+	"log"
+	"os"
+	"sarah/types/mongodb"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// queueIndexesEnsured records the organizations whose call queue indexes were created by this process
+var queueIndexesEnsured sync.Map
+
+// queueCollection returns the call queue collection of an organization, creating its indexes
+// the first time the collection is used by this process.
+func queueCollection(orgId string) *mongo.Collection {
+	coll := Client.Database(orgId).Collection(os.Getenv("MONGO_COLLECTION_CALL_QUEUE"))
+
+	if _, loaded := queueIndexesEnsured.LoadOrStore(orgId, true); !loaded {
+		_, err := coll.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+			{Keys: bson.D{{Key: "status", Value: 1}, {Key: "enqueued_at", Value: 1}}},
+			{Keys: bson.D{{Key: "campaign_id", Value: 1}, {Key: "status", Value: 1}}},
+			{Keys: bson.D{{Key: "vapi_call_id", Value: 1}}},
+		})
+		if err != nil {
+			log.Printf("Error creating call queue indexes for organization %s: %v", orgId, err)
+			queueIndexesEnsured.Delete(orgId)
+		}
+	}
+
+	return coll
+}
+
+// EnqueueCalls adds calls to an organization's call queue.
+//
+// Parameters:
+//   - orgId: The organization ID the calls belong to
+//   - calls: The calls to queue
+//
+// Returns:
+//   - []mongodb.QueuedCall: The queued calls with their IDs
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CALL_QUEUE environment variable
+//   - Operation: Inserts the calls in order
+func EnqueueCalls(orgId string, calls []mongodb.QueuedCall) ([]mongodb.QueuedCall, error) {
+	if len(calls) == 0 {
+		return calls, nil
+	}
+
+	coll := queueCollection(orgId)
+
+	for i := range calls {
+		calls[i].Id = bson.NewObjectID()
+	}
+
+	if _, err := coll.InsertMany(context.Background(), calls); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return calls, nil
+}
+
+// GetQueuedCalls retrieves a page of an organization's call queue, oldest first.
+//
+// Parameters:
+//   - orgId: The organization ID whose queue is retrieved
+//   - status: Only return calls in this status, every status if empty
+//   - page: The 1-based page number
+//   - limit: The maximum number of calls per page
+//
+// Returns:
+//   - *mongodb.QueuedCallPage: The calls on the page and the total number of matching calls
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CALL_QUEUE environment variable
+//   - Query: Filters by status, sorts by enqueued_at ascending, then skips and limits
+func GetQueuedCalls(orgId string, status mongodb.QueueStatus, page int, limit int) (*mongodb.QueuedCallPage, error) {
+	coll := queueCollection(orgId)
+
+	query := bson.M{}
+	if status != "" {
+		query["status"] = status
+	}
+
+	total, err := coll.CountDocuments(context.Background(), query)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "enqueued_at", Value: 1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+
+	cursor, err := coll.Find(context.Background(), query, opts)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	calls := []mongodb.QueuedCall{}
+	if err := cursor.All(context.Background(), &calls); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return &mongodb.QueuedCallPage{
+		Calls: calls,
+		Total: total,
+		Page:  page,
+		Limit: limit,
+	}, nil
+}
+
+// GetQueuedCallById retrieves a single call of an organization's call queue.
+// Returns mongo.ErrNoDocuments if the organization has no such queued call.
+func GetQueuedCallById(orgId string, queuedCallId bson.ObjectID) (*mongodb.QueuedCall, error) {
+	coll := queueCollection(orgId)
+
+	var call mongodb.QueuedCall
+	if err := coll.FindOne(context.Background(), bson.M{"_id": queuedCallId}).Decode(&call); err != nil {
+		return nil, err
+	}
+
+	return &call, nil
+}
+
+// GetQueuedCallByVapiId retrieves the queued call that was dispatched as a VapiAI call.
+// Returns mongo.ErrNoDocuments if the call wasn't placed through the organization's call queue.
+func GetQueuedCallByVapiId(orgId string, vapiCallId string) (*mongodb.QueuedCall, error) {
+	coll := queueCollection(orgId)
+
+	var call mongodb.QueuedCall
+	if err := coll.FindOne(context.Background(), bson.M{"vapi_call_id": vapiCallId}).Decode(&call); err != nil {
+		return nil, err
+	}
+
+	return &call, nil
+}
+
+// GetDispatchableCalls retrieves the calls of an organization waiting to be dispatched, oldest first.
+//
+// Parameters:
+//   - orgId: The organization ID whose queue is read
+//   - excludedPhoneNumberIds: The VapiAI phone numbers whose calls are left in the queue
+//   - limit: The maximum number of calls to return
+//
+// Returns:
+//   - []mongodb.QueuedCall: The waiting calls
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CALL_QUEUE environment variable
+//   - Query: Filters by status queued and phone_number_id $nin the excluded IDs, sorts by enqueued_at ascending and limits
+func GetDispatchableCalls(orgId string, excludedPhoneNumberIds []string, limit int) ([]mongodb.QueuedCall, error) {
+	coll := queueCollection(orgId)
+
+	query := bson.M{"status": mongodb.QUEUE_STATUS_QUEUED}
+	if len(excludedPhoneNumberIds) > 0 {
+		query["phone_number_id"] = bson.M{"$nin": excludedPhoneNumberIds}
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "enqueued_at", Value: 1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := coll.Find(context.Background(), query, opts)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	calls := []mongodb.QueuedCall{}
+	if err := cursor.All(context.Background(), &calls); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return calls, nil
+}
+
+// ClaimQueuedCall moves a waiting call to the dispatching status, so no other dispatcher sends it too.
+//
+// Parameters:
+//   - orgId: The organization ID the call belongs to
+//   - queuedCallId: The ObjectID of the queued call
+//
+// Returns:
+//   - bool: Whether the call was still waiting and is now claimed
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CALL_QUEUE environment variable
+//   - Operation: Updates the call only if its status is still queued, incrementing attempts
+func ClaimQueuedCall(orgId string, queuedCallId bson.ObjectID) (bool, error) {
+	coll := queueCollection(orgId)
+
+	result, err := coll.UpdateOne(context.Background(),
+		bson.M{"_id": queuedCallId, "status": mongodb.QUEUE_STATUS_QUEUED},
+		bson.M{
+			"$set": bson.M{"status": mongodb.QUEUE_STATUS_DISPATCHING, "updated_at": time.Now()},
+			"$inc": bson.M{"attempts": 1},
+		},
+	)
+	if err != nil {
+		log.Println(err)
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+// MarkQueuedCallDispatched records the VapiAI call placed for a queued call.
+//
+// Parameters:
+//   - orgId: The organization ID the call belongs to
+//   - queuedCallId: The ObjectID of the queued call
+//   - vapiCallId: The VapiAI call that was placed
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CALL_QUEUE environment variable
+//   - Operation: Sets the dispatched status, VapiAI call ID and dispatch time
+func MarkQueuedCallDispatched(orgId string, queuedCallId bson.ObjectID, vapiCallId string) error {
+	coll := queueCollection(orgId)
+
+	now := time.Now()
+	_, err := coll.UpdateOne(context.Background(), bson.M{"_id": queuedCallId}, bson.M{"$set": bson.M{
+		"status":        mongodb.QUEUE_STATUS_DISPATCHED,
+		"vapi_call_id":  vapiCallId,
+		"error":         "",
+		"dispatched_at": now,
+		"updated_at":    now,
+	}})
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return nil
+}
+
+// ReleaseQueuedCall records a failed dispatch of a queued call, putting it back in the queue or failing it.
+//
+// Parameters:
+//   - orgId: The organization ID the call belongs to
+//   - queuedCallId: The ObjectID of the queued call
+//   - status: QUEUE_STATUS_QUEUED to retry the call, QUEUE_STATUS_FAILED to give up
+//   - reason: The error VapiAI returned
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CALL_QUEUE environment variable
+//   - Operation: Sets the status and error of the call
+func ReleaseQueuedCall(orgId string, queuedCallId bson.ObjectID, status mongodb.QueueStatus, reason string) error {
+	coll := queueCollection(orgId)
+
+	_, err := coll.UpdateOne(context.Background(), bson.M{"_id": queuedCallId}, bson.M{"$set": bson.M{
+		"status":     status,
+		"error":      reason,
+		"updated_at": time.Now(),
+	}})
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return nil
+}
+
+// RequeueQueuedCall puts a claimed call back in the queue without counting the attempt,
+// for dispatches interrupted before the call was sent, e.g. while VapiAI is unavailable.
+//
+// Parameters:
+//   - orgId: The organization ID the call belongs to
+//   - queuedCallId: The ObjectID of the queued call
+//   - reason: Why the call wasn't sent
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CALL_QUEUE environment variable
+//   - Operation: Sets the queued status and error of the call, decrementing attempts
+func RequeueQueuedCall(orgId string, queuedCallId bson.ObjectID, reason string) error {
+	coll := queueCollection(orgId)
+
+	_, err := coll.UpdateOne(context.Background(), bson.M{"_id": queuedCallId}, bson.M{
+		"$set": bson.M{"status": mongodb.QUEUE_STATUS_QUEUED, "error": reason, "updated_at": time.Now()},
+		"$inc": bson.M{"attempts": -1},
+	})
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return nil
+}
+
+// CancelQueuedCalls removes waiting calls from an organization's call queue.
+//
+// Parameters:
+//   - orgId: The organization ID whose queue is updated
+//   - filter: Restricts the cancelled calls, e.g. by _id or campaign_id
+//
+// Returns:
+//   - int64: The number of calls that were still waiting and are now cancelled
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CALL_QUEUE environment variable
+//   - Operation: Sets the cancelled status of the matching calls whose status is still queued
+func CancelQueuedCalls(orgId string, filter bson.M) (int64, error) {
+	coll := queueCollection(orgId)
+
+	query := bson.M{"status": mongodb.QUEUE_STATUS_QUEUED}
+	for key, value := range filter {
+		query[key] = value
+	}
+
+	result, err := coll.UpdateMany(context.Background(), query, bson.M{"$set": bson.M{
+		"status":     mongodb.QUEUE_STATUS_CANCELLED,
+		"updated_at": time.Now(),
+	}})
+	if err != nil {
+		log.Println(err)
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
+
+// FailInterruptedDispatches fails the calls left in the dispatching status by a dispatcher that stopped
+// before it knew whether VapiAI accepted them. They are not retried, so a customer is never called twice.
+//
+// Parameters:
+//   - orgId: The organization ID whose queue is updated
+//   - before: Only calls claimed before this time are failed
+//
+// Returns:
+//   - int64: The number of calls failed
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CALL_QUEUE environment variable
+//   - Operation: Sets the failed status of dispatching calls last updated before the given time
+func FailInterruptedDispatches(orgId string, before time.Time) (int64, error) {
+	coll := queueCollection(orgId)
+
+	result, err := coll.UpdateMany(context.Background(),
+		bson.M{"status": mongodb.QUEUE_STATUS_DISPATCHING, "updated_at": bson.M{"$lt": before}},
+		bson.M{"$set": bson.M{
+			"status":     mongodb.QUEUE_STATUS_FAILED,
+			"error":      "dispatch was interrupted before VapiAI answered",
+			"updated_at": time.Now(),
+		}},
+	)
+	if err != nil {
+		log.Println(err)
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
+
+// GetQueueStats counts an organization's queued calls by status and the waiting calls by phone number.
+//
+// Parameters:
+//   - orgId: The organization ID whose queue is counted
+//
+// Returns:
+//   - *mongodb.QueueStats: The depth of the queue, without the active calls and limits
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CALL_QUEUE environment variable
+//   - Aggregation: $facet grouping every call by status and the waiting calls by phone_number_id
+func GetQueueStats(orgId string) (*mongodb.QueueStats, error) {
+	coll := queueCollection(orgId)
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$facet", Value: bson.M{
+			"by_status": bson.A{
+				bson.M{"$group": bson.M{"_id": "$status", "count": bson.M{"$sum": 1}}},
+			},
+			"by_phone_number": bson.A{
+				bson.M{"$match": bson.M{"status": mongodb.QUEUE_STATUS_QUEUED}},
+				bson.M{"$group": bson.M{"_id": "$phone_number_id", "count": bson.M{"$sum": 1}, "oldest": bson.M{"$min": "$enqueued_at"}}},
+			},
+		}}},
+	}
+
+	cursor, err := coll.Aggregate(context.Background(), pipeline)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	var results []struct {
+		ByStatus []struct {
+			Status mongodb.QueueStatus `bson:"_id"`
+			Count  int64               `bson:"count"`
+		} `bson:"by_status"`
+		ByPhoneNumber []struct {
+			PhoneNumberId string    `bson:"_id"`
+			Count         int64     `bson:"count"`
+			Oldest        time.Time `bson:"oldest"`
+		} `bson:"by_phone_number"`
+	}
+	if err := cursor.All(context.Background(), &results); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	stats := &mongodb.QueueStats{
+		ByStatus:           map[mongodb.QueueStatus]int64{},
+		DepthByPhoneNumber: map[string]int64{},
+	}
+	if len(results) == 0 {
+		return stats, nil
+	}
+
+	for _, group := range results[0].ByStatus {
+		stats.ByStatus[group.Status] = group.Count
+	}
+	stats.Depth = stats.ByStatus[mongodb.QUEUE_STATUS_QUEUED]
+
+	for _, group := range results[0].ByPhoneNumber {
+		stats.DepthByPhoneNumber[group.PhoneNumberId] = group.Count
+		if stats.OldestEnqueuedAt == nil || group.Oldest.Before(*stats.OldestEnqueuedAt) {
+			oldest := group.Oldest
+			stats.OldestEnqueuedAt = &oldest
+		}
+	}
+
+	return stats, nil
+}
+
+// CountPendingCallsByPhoneNumber counts the calls of an organization's call queue that the given phone numbers
+// haven't placed yet: the calls waiting to be dispatched and the calls being dispatched.
+//
+// Parameters:
+//   - orgId: The organization ID whose queue is counted
+//   - phoneNumberIds: The VapiAI phone number IDs to count
+//
+// Returns:
+//   - map[string]int: The number of pending calls by phone number ID, missing for numbers with none
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CALL_QUEUE environment variable
+//   - Aggregation: Matches queued and dispatching calls of the phone numbers, grouped by phone_number_id
+func CountPendingCallsByPhoneNumber(orgId string, phoneNumberIds []string) (map[string]int, error) {
+	coll := queueCollection(orgId)
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"phone_number_id": bson.M{"$in": phoneNumberIds},
+			"status":          bson.M{"$in": bson.A{mongodb.QUEUE_STATUS_QUEUED, mongodb.QUEUE_STATUS_DISPATCHING}},
+		}}},
+		{{Key: "$group", Value: bson.M{"_id": "$phone_number_id", "count": bson.M{"$sum": 1}}}},
+	}
+
+	cursor, err := coll.Aggregate(context.Background(), pipeline)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	var groups []struct {
+		PhoneNumberId string `bson:"_id"`
+		Count         int    `bson:"count"`
+	}
+	if err := cursor.All(context.Background(), &groups); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	pending := map[string]int{}
+	for _, group := range groups {
+		pending[group.PhoneNumberId] = group.Count
+	}
+
+	return pending, nil
+}
@@ -0,0 +1,225 @@
+package mongodb
+
+import (
+	"context"
+	"log"
+	"os"
+	"sarah/types/mongodb"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// callbackIndexesEnsured records the organizations whose callback indexes were created by this process
+var callbackIndexesEnsured sync.Map
+
+// callbacksCollection returns the callbacks collection of an organization, creating its indexes
+// the first time the collection is used by this process.
+func callbacksCollection(orgId string) *mongo.Collection {
+	coll := Client.Database(orgId).Collection(os.Getenv("MONGO_COLLECTION_CALLBACKS"))
+
+	if _, loaded := callbackIndexesEnsured.LoadOrStore(orgId, true); !loaded {
+		_, err := coll.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+			{Keys: bson.D{{Key: "tool_call_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "status", Value: 1}, {Key: "scheduled_at", Value: 1}}},
+			{Keys: bson.D{{Key: "contact_id", Value: 1}, {Key: "scheduled_at", Value: 1}}},
+		})
+		if err != nil {
+			log.Printf("Error creating callback indexes for organization %s: %v", orgId, err)
+			callbackIndexesEnsured.Delete(orgId)
+		}
+	}
+
+	return coll
+}
+
+// CreateCallback stores a callback registered during a call.
+// A callback registered again by the same tool call is not duplicated; the stored one is returned.
+//
+// Parameters:
+//   - orgId: The organization ID the callback belongs to
+//   - callback: The callback to store
+//
+// Returns:
+//   - *mongodb.Callback: The stored callback with its ID
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CALLBACKS environment variable
+//   - Operation: Inserts the callback, reading the existing one back on a duplicate tool_call_id
+func CreateCallback(orgId string, callback mongodb.Callback) (*mongodb.Callback, error) {
+	coll := callbacksCollection(orgId)
+
+	callback.Id = bson.NewObjectID()
+
+	_, err := coll.InsertOne(context.Background(), callback)
+	if mongo.IsDuplicateKeyError(err) {
+		var existing mongodb.Callback
+		if err := coll.FindOne(context.Background(), bson.M{"tool_call_id": callback.ToolCallId}).Decode(&existing); err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		return &existing, nil
+	} else if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return &callback, nil
+}
+
+// GetCallbacks retrieves a page of an organization's callbacks, soonest first.
+//
+// Parameters:
+//   - orgId: The organization ID whose callbacks are retrieved
+//   - status: Only return callbacks in this status, every status if empty
+//   - contactId: Only return callbacks of this contact, every contact if nil
+//   - page: The 1-based page number
+//   - limit: The maximum number of callbacks per page
+//
+// Returns:
+//   - *mongodb.CallbackPage: The callbacks on the page and the total number of matching callbacks
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CALLBACKS environment variable
+//   - Query: Filters by status and contact_id, sorts by scheduled_at ascending, then skips and limits
+func GetCallbacks(orgId string, status mongodb.CallbackStatus, contactId *bson.ObjectID, page int, limit int) (*mongodb.CallbackPage, error) {
+	coll := callbacksCollection(orgId)
+
+	query := bson.M{}
+	if status != "" {
+		query["status"] = status
+	}
+	if contactId != nil {
+		query["contact_id"] = *contactId
+	}
+
+	total, err := coll.CountDocuments(context.Background(), query)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "scheduled_at", Value: 1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+
+	cursor, err := coll.Find(context.Background(), query, opts)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	callbacks := []mongodb.Callback{}
+	if err := cursor.All(context.Background(), &callbacks); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return &mongodb.CallbackPage{
+		Callbacks: callbacks,
+		Total:     total,
+		Page:      page,
+		Limit:     limit,
+	}, nil
+}
+
+// GetCallbackById retrieves a single callback of an organization.
+// Returns mongo.ErrNoDocuments if the organization has no such callback.
+func GetCallbackById(orgId string, callbackId bson.ObjectID) (*mongodb.Callback, error) {
+	coll := callbacksCollection(orgId)
+
+	var callback mongodb.Callback
+	if err := coll.FindOne(context.Background(), bson.M{"_id": callbackId}).Decode(&callback); err != nil {
+		return nil, err
+	}
+
+	return &callback, nil
+}
+
+// GetDueCallbacks retrieves the scheduled callbacks of an organization whose time has come, soonest first.
+//
+// Parameters:
+//   - orgId: The organization ID whose callbacks are read
+//   - now: Callbacks scheduled at or before this time are due
+//   - limit: The maximum number of callbacks to return
+//
+// Returns:
+//   - []mongodb.Callback: The due callbacks
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CALLBACKS environment variable
+//   - Query: Filters by status scheduled and scheduled_at $lte now, sorts by scheduled_at ascending and limits
+func GetDueCallbacks(orgId string, now time.Time, limit int) ([]mongodb.Callback, error) {
+	coll := callbacksCollection(orgId)
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "scheduled_at", Value: 1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := coll.Find(context.Background(), bson.M{
+		"status":       mongodb.CALLBACK_STATUS_SCHEDULED,
+		"scheduled_at": bson.M{"$lte": now},
+	}, opts)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	callbacks := []mongodb.Callback{}
+	if err := cursor.All(context.Background(), &callbacks); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return callbacks, nil
+}
+
+// UpdateCallbackStatus moves a callback from one status to another, recording the queued call or the error.
+// The update only applies if the callback is still in the expected status, so a callback cancelled
+// meanwhile is never queued, and a queued one is never cancelled.
+//
+// Parameters:
+//   - orgId: The organization ID the callback belongs to
+//   - callbackId: The ObjectID of the callback
+//   - from: The status the callback must be in
+//   - to: The new status
+//   - queuedCallId: The call queue entry of the callback, left unchanged if nil
+//   - reason: The error to record, empty to clear it
+//
+// Returns:
+//   - bool: Whether the callback was in the expected status and was updated
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CALLBACKS environment variable
+//   - Operation: Updates the callback only if its status matches from
+func UpdateCallbackStatus(orgId string, callbackId bson.ObjectID, from mongodb.CallbackStatus, to mongodb.CallbackStatus, queuedCallId *bson.ObjectID, reason string) (bool, error) {
+	coll := callbacksCollection(orgId)
+
+	update := bson.M{
+		"status":     to,
+		"error":      reason,
+		"updated_at": time.Now(),
+	}
+	if queuedCallId != nil {
+		update["queued_call_id"] = *queuedCallId
+	}
+
+	result, err := coll.UpdateOne(context.Background(),
+		bson.M{"_id": callbackId, "status": from},
+		bson.M{"$set": update},
+	)
+	if err != nil {
+		log.Println(err)
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
@@ -0,0 +1,743 @@
+package mongodb
+
+import (
+	"context"
+	"log"
+	"os"
+	"sarah/types/mongodb"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// callIndexesEnsured records the organizations whose calls collection indexes were already created
+var callIndexesEnsured sync.Map
+
+// callsCollection returns the calls collection of an organization, creating its indexes
+// the first time the collection is used by this process.
+func callsCollection(orgId string) *mongo.Collection {
+	coll := Client.Database(orgId).Collection(os.Getenv("MONGO_COLLECTION_CALLS"))
+
+	if _, loaded := callIndexesEnsured.LoadOrStore(orgId, true); !loaded {
+		if err := EnsureCallIndexes(orgId); err != nil {
+			callIndexesEnsured.Delete(orgId)
+		}
+	}
+
+	return coll
+}
+
+// EnsureCallIndexes creates the indexes used to look up, list and filter an organization's calls.
+// A unique index on vapi_call_id guarantees a VapiAI call is only ever stored once.
+//
+// Parameters:
+//   - orgId: The organization ID whose calls collection is indexed
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CALLS environment variable
+//   - Operation: Creates the indexes if they don't exist yet
+func EnsureCallIndexes(orgId string) error {
+	coll := Client.Database(orgId).Collection(os.Getenv("MONGO_COLLECTION_CALLS"))
+
+	_, err := coll.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "vapi_call_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "assistant_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "campaign_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "tags", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "disposition", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "updated_at", Value: 1}}},
+	})
+	if err != nil {
+		log.Printf("Error creating call indexes for organization %s: %v", orgId, err)
+		return err
+	}
+
+	return nil
+}
+
+// GetOrganizationCalls retrieves a page of an organization's calls, newest first.
+//
+// Parameters:
+//   - orgId: The organization ID to retrieve calls for
+//   - filter: The filter the calls must match
+//   - page: The 1-based page number
+//   - limit: The maximum number of calls per page
+//
+// Returns:
+//   - *mongodb.CallPage: The calls on the page and the total number of matching calls
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CALLS environment variable
+//   - Query: Filters, sorts by created_at descending, then skips and limits
+func GetOrganizationCalls(orgId string, filter mongodb.CallFilter, page int, limit int) (*mongodb.CallPage, error) {
+	coll := callsCollection(orgId)
+	query := callFilterQuery(filter)
+
+	total, err := coll.CountDocuments(context.Background(), query)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+
+	cursor, err := coll.Find(context.Background(), query, opts)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	calls := []mongodb.Call{}
+	if err := cursor.All(context.Background(), &calls); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return &mongodb.CallPage{
+		Calls: calls,
+		Total: total,
+		Page:  page,
+		Limit: limit,
+	}, nil
+}
+
+// GetCallByVapiId retrieves the local record of a VapiAI call.
+// Returns mongo.ErrNoDocuments if the organization has no record of the call.
+func GetCallByVapiId(orgId string, vapiCallId string) (*mongodb.Call, error) {
+	coll := callsCollection(orgId)
+
+	var call mongodb.Call
+	if err := coll.FindOne(context.Background(), bson.M{"vapi_call_id": vapiCallId}).Decode(&call); err != nil {
+		return nil, err
+	}
+
+	return &call, nil
+}
+
+// callFilterQuery converts a CallFilter into a MongoDB query document.
+func callFilterQuery(filter mongodb.CallFilter) bson.M {
+	query := bson.M{}
+
+	if filter.AssistantId != "" {
+		query["assistant_id"] = filter.AssistantId
+	}
+	if filter.PhoneNumberId != "" {
+		query["phone_number_id"] = filter.PhoneNumberId
+	}
+	if filter.CampaignId != "" {
+		query["campaign_id"] = filter.CampaignId
+	}
+	if filter.CustomerNumber != "" {
+		query["customer_number"] = filter.CustomerNumber
+	}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	if filter.EndedReason != "" {
+		query["ended_reason"] = filter.EndedReason
+	}
+	if filter.Flagged {
+		query["flags.0"] = bson.M{"$exists": true}
+	}
+	if len(filter.Tags) > 0 {
+		query["tags"] = bson.M{"$all": filter.Tags}
+	}
+	if filter.Disposition != "" {
+		query["disposition"] = filter.Disposition
+	}
+	if filter.HasNotes {
+		query["note_count"] = bson.M{"$gt": 0}
+	}
+
+	createdAt := bson.M{}
+	if filter.From != nil {
+		createdAt["$gte"] = *filter.From
+	}
+	if filter.To != nil {
+		createdAt["$lt"] = *filter.To
+	}
+	if len(createdAt) > 0 {
+		query["created_at"] = createdAt
+	}
+
+	return query
+}
+
+// UpsertCall creates or updates the local record of a VapiAI call.
+// Calls are matched by their VapiAI call ID so the same call can be saved
+// repeatedly as new information about it arrives.
+//
+// Parameters:
+//   - orgId: The organization ID the call belongs to
+//   - call: The call data to store
+//
+// Returns:
+//   - *mongo.UpdateResult: The result of the upsert operation
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CALLS environment variable
+//   - Operation: Upserts a single call document keyed by vapi_call_id
+//
+// The campaign ID, caller ID strategy, creation date, customer name, summary and transcript are only
+// written when known, so later updates that lack them never erase what was recorded before.
+func UpsertCall(orgId string, call mongodb.Call) (*mongo.UpdateResult, error) {
+	coll := callsCollection(orgId)
+
+	set := bson.M{
+		"assistant_id":       call.AssistantId,
+		"phone_number_id":    call.PhoneNumberId,
+		"customer_number":    call.CustomerNumber,
+		"status":             call.Status,
+		"ended_reason":       call.EndedReason,
+		"cost":               call.Cost,
+		"duration_seconds":   call.DurationSeconds,
+		"success_evaluation": call.SuccessEvaluation,
+		"successful":         call.Successful,
+		"started_at":         call.StartedAt,
+		"ended_at":           call.EndedAt,
+		"updated_at":         time.Now(),
+	}
+	setOnInsert := bson.M{}
+
+	for field, value := range map[string]string{
+		"customer_name":      call.CustomerName,
+		"summary":            call.Summary,
+		"transcript":         call.Transcript,
+		"caller_id_strategy": string(call.CallerIdStrategy),
+	} {
+		if value != "" {
+			set[field] = value
+		} else {
+			setOnInsert[field] = ""
+		}
+	}
+
+	if call.CampaignId != "" {
+		set["campaign_id"] = call.CampaignId
+	} else {
+		setOnInsert["campaign_id"] = ""
+	}
+
+	if !call.CreatedAt.IsZero() {
+		set["created_at"] = call.CreatedAt
+	} else {
+		setOnInsert["created_at"] = time.Now()
+	}
+
+	update := bson.M{"$set": set}
+	if len(setOnInsert) > 0 {
+		update["$setOnInsert"] = setOnInsert
+	}
+
+	result, err := coll.UpdateOne(context.Background(), bson.M{"vapi_call_id": call.VapiCallId}, update, options.UpdateOne().SetUpsert(true))
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// UpdateCallStatus records a status change of a VapiAI call, creating the call record if needed.
+// Fields already stored are kept, so status updates never erase the outcome written by
+// an end-of-call report, and an ended call is never moved back to an earlier status
+// by a status update delivered out of order.
+//
+// Parameters:
+//   - orgId: The organization ID the call belongs to
+//   - call: The call data carried by the status update
+//
+// Returns:
+//   - *mongo.UpdateResult: The result of the update operation
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CALLS environment variable
+//   - Operation: Upserts a single call document with an aggregation pipeline update
+func UpdateCallStatus(orgId string, call mongodb.Call) (*mongo.UpdateResult, error) {
+	coll := callsCollection(orgId)
+
+	keep := func(field string, value interface{}) bson.M {
+		return bson.M{"$ifNull": bson.A{"$" + field, bson.M{"$literal": value}}}
+	}
+
+	set := bson.M{
+		"status": bson.M{"$cond": bson.A{
+			bson.M{"$eq": bson.A{"$status", mongodb.CALL_STATUS_ENDED}},
+			"$status",
+			bson.M{"$literal": call.Status},
+		}},
+		"assistant_id":       keep("assistant_id", call.AssistantId),
+		"phone_number_id":    keep("phone_number_id", call.PhoneNumberId),
+		"customer_number":    keep("customer_number", call.CustomerNumber),
+		"customer_name":      keep("customer_name", call.CustomerName),
+		"campaign_id":        keep("campaign_id", call.CampaignId),
+		"caller_id_strategy": keep("caller_id_strategy", call.CallerIdStrategy),
+		"cost":               keep("cost", call.Cost),
+		"duration_seconds":   keep("duration_seconds", call.DurationSeconds),
+		"success_evaluation": keep("success_evaluation", call.SuccessEvaluation),
+		"successful":         keep("successful", call.Successful),
+		"summary":            keep("summary", call.Summary),
+		"transcript":         keep("transcript", call.Transcript),
+		"started_at":         keep("started_at", call.StartedAt),
+		"ended_at":           keep("ended_at", call.EndedAt),
+		"created_at":         keep("created_at", call.CreatedAt),
+		"updated_at":         time.Now(),
+	}
+
+	if call.EndedReason != "" {
+		set["ended_reason"] = bson.M{"$literal": call.EndedReason}
+	} else {
+		set["ended_reason"] = keep("ended_reason", "")
+	}
+
+	result, err := coll.UpdateOne(
+		context.Background(),
+		bson.M{"vapi_call_id": call.VapiCallId},
+		mongo.Pipeline{{{Key: "$set", Value: set}}},
+		options.UpdateOne().SetUpsert(true),
+	)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// AddCallFlag adds an alert rule's flag to a call, creating the call record if it isn't stored yet.
+// The other fields of a created record are filled in by the next status update or end-of-call report.
+//
+// Parameters:
+//   - orgId: The organization ID the call belongs to
+//   - vapiCallId: The VapiAI call to flag
+//   - flag: The flag to add
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CALLS environment variable
+//   - Operation: Upserts a single call document keyed by vapi_call_id, pushing the flag to flags
+func AddCallFlag(orgId string, vapiCallId string, flag mongodb.CallFlag) error {
+	coll := callsCollection(orgId)
+
+	update := bson.M{
+		"$push": bson.M{"flags": flag},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+
+	if _, err := coll.UpdateOne(context.Background(), bson.M{"vapi_call_id": vapiCallId}, update, options.UpdateOne().SetUpsert(true)); err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return nil
+}
+
+// UpdateCallTags removes and adds tags of a call in a single atomic update, so agents tagging the same call
+// at once don't overwrite each other's tags. Tags both removed and added are kept.
+//
+// Parameters:
+//   - orgId: The organization ID the call belongs to
+//   - vapiCallId: The VapiAI call to tag
+//   - add: The tags to add
+//   - remove: The tags to remove
+//
+// Returns:
+//   - *mongodb.Call: The call record with its new tags, or mongo.ErrNoDocuments if the organization has no record of the call
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CALLS environment variable
+//   - Operation: Updates the call matching vapi_call_id with an aggregation pipeline and returns the updated document
+func UpdateCallTags(orgId string, vapiCallId string, add []string, remove []string) (*mongodb.Call, error) {
+	coll := callsCollection(orgId)
+
+	tags := bson.M{"$setUnion": bson.A{
+		bson.M{"$setDifference": bson.A{bson.M{"$ifNull": bson.A{"$tags", bson.A{}}}, bson.M{"$literal": remove}}},
+		bson.M{"$literal": add},
+	}}
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{"tags": tags, "updated_at": time.Now()}}}}
+
+	var call mongodb.Call
+	err := coll.FindOneAndUpdate(context.Background(), bson.M{"vapi_call_id": vapiCallId}, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&call)
+	if err != nil {
+		return nil, err
+	}
+
+	return &call, nil
+}
+
+// SetCallDisposition sets the disposition of a call, or clears it when code is empty.
+//
+// Parameters:
+//   - orgId: The organization ID the call belongs to
+//   - vapiCallId: The VapiAI call to update
+//   - code: The disposition code, empty to clear it
+//   - userId: The Clerk user ID of the agent setting the disposition
+//
+// Returns:
+//   - *mongodb.Call: The updated call record, or mongo.ErrNoDocuments if the organization has no record of the call
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CALLS environment variable
+//   - Operation: Updates the call matching vapi_call_id and returns the updated document
+func SetCallDisposition(orgId string, vapiCallId string, code string, userId string) (*mongodb.Call, error) {
+	coll := callsCollection(orgId)
+
+	now := time.Now()
+	update := bson.M{"$set": bson.M{
+		"disposition":    code,
+		"disposition_by": userId,
+		"disposition_at": now,
+		"updated_at":     now,
+	}}
+	if code == "" {
+		update = bson.M{
+			"$unset": bson.M{"disposition": "", "disposition_by": "", "disposition_at": ""},
+			"$set":   bson.M{"updated_at": now},
+		}
+	}
+
+	var call mongodb.Call
+	err := coll.FindOneAndUpdate(context.Background(), bson.M{"vapi_call_id": vapiCallId}, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&call)
+	if err != nil {
+		return nil, err
+	}
+
+	return &call, nil
+}
+
+// IncrementCallNoteCount adds delta to the number of notes of a call.
+//
+// Parameters:
+//   - orgId: The organization ID the call belongs to
+//   - vapiCallId: The VapiAI call whose notes changed
+//   - delta: 1 when a note was written, -1 when one was deleted
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CALLS environment variable
+//   - Operation: Increments note_count of the call matching vapi_call_id
+func IncrementCallNoteCount(orgId string, vapiCallId string, delta int) error {
+	coll := callsCollection(orgId)
+
+	if _, err := coll.UpdateOne(context.Background(), bson.M{"vapi_call_id": vapiCallId}, bson.M{"$inc": bson.M{"note_count": delta}}); err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return nil
+}
+
+// GetCampaignAnalytics aggregates the stored calls of a campaign into totals,
+// ended reason counts and per-interval buckets using a single aggregation pipeline.
+//
+// Parameters:
+//   - orgId: The organization ID the campaign belongs to
+//   - campaignId: The hex ObjectID of the campaign
+//   - from: Inclusive lower bound on the call creation date, nil for no bound
+//   - to: Exclusive upper bound on the call creation date, nil for no bound
+//   - interval: The bucket size (day or week)
+//   - timezone: The IANA timezone used to compute bucket boundaries
+//
+// Returns:
+//   - *mongodb.CampaignAnalytics: The aggregated metrics for the campaign
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CALLS environment variable
+//   - Operation: Runs a $match + $facet aggregation over the campaign's calls
+func GetCampaignAnalytics(orgId string, campaignId string, from *time.Time, to *time.Time, interval mongodb.AnalyticsInterval, timezone string) (*mongodb.CampaignAnalytics, error) {
+	coll := callsCollection(orgId)
+
+	match := callFilterQuery(mongodb.CallFilter{CampaignId: campaignId, From: from, To: to})
+
+	if timezone == "" {
+		timezone = "UTC"
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$facet", Value: bson.M{
+			"totals": bson.A{
+				bson.M{"$group": callMetricsGroup(nil)},
+				bson.M{"$project": callMetricsProjection()},
+			},
+			"ended_reasons": bson.A{
+				bson.M{"$match": bson.M{"ended_reason": bson.M{"$ne": ""}}},
+				bson.M{"$group": bson.M{"_id": "$ended_reason", "count": bson.M{"$sum": 1}}},
+				bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+			},
+			"buckets": bson.A{
+				bson.M{"$group": callMetricsGroup(bson.M{"$dateTrunc": bson.M{
+					"date":     "$created_at",
+					"unit":     string(interval),
+					"timezone": timezone,
+				}})},
+				bson.M{"$project": callMetricsProjection()},
+				bson.M{"$sort": bson.M{"_id": 1}},
+			},
+		}}},
+	}
+
+	cursor, err := coll.Aggregate(context.Background(), pipeline)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	var results []struct {
+		Totals       []mongodb.CallMetrics      `bson:"totals"`
+		EndedReasons []mongodb.EndedReasonCount `bson:"ended_reasons"`
+		Buckets      []mongodb.AnalyticsBucket  `bson:"buckets"`
+	}
+	if err := cursor.All(context.Background(), &results); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	analytics := &mongodb.CampaignAnalytics{
+		CampaignId:   campaignId,
+		From:         from,
+		To:           to,
+		Interval:     interval,
+		EndedReasons: []mongodb.EndedReasonCount{},
+		Buckets:      []mongodb.AnalyticsBucket{},
+	}
+
+	if len(results) == 0 {
+		return analytics, nil
+	}
+
+	if len(results[0].Totals) > 0 {
+		analytics.Totals = results[0].Totals[0]
+	}
+	if results[0].EndedReasons != nil {
+		analytics.EndedReasons = results[0].EndedReasons
+	}
+	if results[0].Buckets != nil {
+		analytics.Buckets = results[0].Buckets
+	}
+
+	return analytics, nil
+}
+
+// callMetricsGroup builds the $group stage shared by the totals and bucket facets.
+func callMetricsGroup(id interface{}) bson.M {
+	answered := bson.M{"$and": bson.A{
+		bson.M{"$gt": bson.A{"$duration_seconds", 0}},
+		bson.M{"$not": bson.A{bson.M{"$in": bson.A{"$ended_reason", mongodb.UnansweredEndedReasons}}}},
+	}}
+
+	// Calls recorded before evaluations were normalized only count when they were evaluated "true"
+	successful := bson.M{"$eq": bson.A{
+		bson.M{"$ifNull": bson.A{"$successful", bson.M{"$eq": bson.A{"$success_evaluation", "true"}}}},
+		true,
+	}}
+
+	return bson.M{
+		"_id":                    id,
+		"customers":              bson.M{"$addToSet": "$customer_number"},
+		"calls_placed":           bson.M{"$sum": 1},
+		"calls_answered":         bson.M{"$sum": bson.M{"$cond": bson.A{answered, 1, 0}}},
+		"successful_calls":       bson.M{"$sum": bson.M{"$cond": bson.A{successful, 1, 0}}},
+		"total_duration_seconds": bson.M{"$sum": "$duration_seconds"},
+		"answered_duration":      bson.M{"$sum": bson.M{"$cond": bson.A{answered, "$duration_seconds", 0}}},
+		"total_cost":             bson.M{"$sum": "$cost"},
+	}
+}
+
+// callMetricsProjection turns the raw $group output into the CallMetrics shape.
+func callMetricsProjection() bson.M {
+	return bson.M{
+		"customers_called":       bson.M{"$size": "$customers"},
+		"calls_placed":           1,
+		"calls_answered":         1,
+		"successful_calls":       1,
+		"total_duration_seconds": 1,
+		"total_cost":             1,
+		"average_duration_seconds": bson.M{"$cond": bson.A{
+			bson.M{"$gt": bson.A{"$calls_answered", 0}},
+			bson.M{"$divide": bson.A{"$answered_duration", "$calls_answered"}},
+			0,
+		}},
+		"success_rate": bson.M{"$cond": bson.A{
+			bson.M{"$gt": bson.A{"$calls_answered", 0}},
+			bson.M{"$divide": bson.A{"$successful_calls", "$calls_answered"}},
+			0,
+		}},
+	}
+}
+
+// StreamOrganizationCalls iterates over an organization's calls matching a filter, oldest first,
+// without loading them all in memory. Iteration stops at the first error returned by fn.
+//
+// Parameters:
+//   - orgId: The organization ID to stream calls for
+//   - filter: The filter the calls must match
+//   - fn: Called once per call, in order
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CALLS environment variable
+//   - Query: Filters and sorts by created_at ascending, read through a cursor
+func StreamOrganizationCalls(orgId string, filter mongodb.CallFilter, fn func(call mongodb.Call) error) error {
+	coll := callsCollection(orgId)
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}).SetBatchSize(500)
+
+	cursor, err := coll.Find(context.Background(), callFilterQuery(filter), opts)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	defer cursor.Close(context.Background())
+
+	for cursor.Next(context.Background()) {
+		var call mongodb.Call
+		if err := cursor.Decode(&call); err != nil {
+			return err
+		}
+		if err := fn(call); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+// GetCampaignCallsByStatus retrieves the calls of a campaign that are in one of the given statuses.
+//
+// Parameters:
+//   - orgId: The organization ID the campaign belongs to
+//   - campaignId: The hex ObjectID of the campaign
+//   - statuses: The statuses the calls must be in
+//
+// Returns:
+//   - []mongodb.Call: The matching calls, oldest first
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CALLS environment variable
+//   - Query: Filters by campaign_id and status, sorts by created_at ascending
+func GetCampaignCallsByStatus(orgId string, campaignId string, statuses []mongodb.CallStatus) ([]mongodb.Call, error) {
+	coll := callsCollection(orgId)
+
+	query := bson.M{"campaign_id": campaignId, "status": bson.M{"$in": statuses}}
+
+	cursor, err := coll.Find(context.Background(), query, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	calls := []mongodb.Call{}
+	if err := cursor.All(context.Background(), &calls); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return calls, nil
+}
+
+// CountActiveCalls counts an organization's calls VapiAI has not ended yet, by the phone number they were placed from.
+//
+// Parameters:
+//   - orgId: The organization ID whose calls are counted
+//   - since: Only calls created at or after this time are counted, so calls whose end was never recorded don't count forever
+//
+// Returns:
+//   - map[string]int: The number of active calls by VapiAI phone number ID
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CALLS environment variable
+//   - Aggregation: Matches calls not ended and created since the given time, then groups by phone_number_id
+func CountActiveCalls(orgId string, since time.Time) (map[string]int, error) {
+	coll := callsCollection(orgId)
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"status":     bson.M{"$ne": mongodb.CALL_STATUS_ENDED},
+			"created_at": bson.M{"$gte": since},
+		}}},
+		{{Key: "$group", Value: bson.M{"_id": "$phone_number_id", "count": bson.M{"$sum": 1}}}},
+	}
+
+	cursor, err := coll.Aggregate(context.Background(), pipeline)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	var groups []struct {
+		PhoneNumberId string `bson:"_id"`
+		Count         int    `bson:"count"`
+	}
+	if err := cursor.All(context.Background(), &groups); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	active := map[string]int{}
+	for _, group := range groups {
+		active[group.PhoneNumberId] = group.Count
+	}
+
+	return active, nil
+}
+
+// GetStaleCalls retrieves an organization's calls that have not ended and were not updated since a given time,
+// least recently updated first. These are the calls whose webhooks may have been lost.
+//
+// Parameters:
+//   - orgId: The organization ID whose calls are read
+//   - before: Only calls last updated before this time are returned
+//   - limit: The maximum number of calls to return
+//
+// Returns:
+//   - []mongodb.Call: The stale calls
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CALLS environment variable
+//   - Query: Filters by status $ne ended and updated_at before the given time, sorts by updated_at ascending and limits
+func GetStaleCalls(orgId string, before time.Time, limit int) ([]mongodb.Call, error) {
+	coll := callsCollection(orgId)
+
+	query := bson.M{
+		"status":     bson.M{"$ne": mongodb.CALL_STATUS_ENDED},
+		"updated_at": bson.M{"$lt": before},
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "updated_at", Value: 1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := coll.Find(context.Background(), query, opts)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	calls := []mongodb.Call{}
+	if err := cursor.All(context.Background(), &calls); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return calls, nil
+}
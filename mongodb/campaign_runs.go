@@ -0,0 +1,190 @@
+package mongodb
+
+import (
+	"context"
+	"log"
+	"os"
+	"sarah/types/mongodb"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// CreateCampaignRun stores a run of a campaign.
+//
+// Parameters:
+//   - orgId: The organization ID the campaign belongs to
+//   - run: The campaign run to store
+//
+// Returns:
+//   - *mongo.InsertOneResult: The result of the insertion operation
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CAMPAIGN_RUNS environment variable
+//   - Operation: Inserts a single campaign run document
+func CreateCampaignRun(orgId string, run mongodb.CampaignRun) (*mongo.InsertOneResult, error) {
+	coll := Client.Database(orgId).Collection(os.Getenv("MONGO_COLLECTION_CAMPAIGN_RUNS"))
+
+	if run.CallIds == nil {
+		run.CallIds = []string{}
+	}
+	if run.CappedNumbers == nil {
+		run.CappedNumbers = []string{}
+	}
+	if run.InvalidNumbers == nil {
+		run.InvalidNumbers = []string{}
+	}
+	if run.SuppressedNumbers == nil {
+		run.SuppressedNumbers = []string{}
+	}
+	if run.CancelledCallIds == nil {
+		run.CancelledCallIds = []string{}
+	}
+
+	result, err := coll.InsertOne(context.Background(), run)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// CompleteCampaignRun records the outcome of a campaign run once its calls were queued.
+// The calls the dispatcher added to the run in the meantime are kept.
+//
+// Parameters:
+//   - orgId: The organization ID the campaign belongs to
+//   - run: The campaign run, identified by its ID, with its outcome
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CAMPAIGN_RUNS environment variable
+//   - Operation: Sets the skipped numbers, queued calls, status and error of the run
+func CompleteCampaignRun(orgId string, run mongodb.CampaignRun) error {
+	coll := Client.Database(orgId).Collection(os.Getenv("MONGO_COLLECTION_CAMPAIGN_RUNS"))
+
+	_, err := coll.UpdateOne(context.Background(), bson.M{"_id": run.Id}, bson.M{"$set": bson.M{
+		"invalid_numbers":    nonNilStrings(run.InvalidNumbers),
+		"suppressed_numbers": nonNilStrings(run.SuppressedNumbers),
+		"capped_numbers":     nonNilStrings(run.CappedNumbers),
+		"queued_calls":       run.QueuedCalls,
+		"status":             run.Status,
+		"error":              run.Error,
+	}})
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return nil
+}
+
+// nonNilStrings returns an empty list instead of nil, so lists are stored as arrays rather than null.
+func nonNilStrings(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+// GetCampaignRuns retrieves the most recent runs of a campaign.
+//
+// Parameters:
+//   - orgId: The organization ID the campaign belongs to
+//   - campaignId: The hex ObjectID of the campaign
+//   - limit: The maximum number of runs to return
+//
+// Returns:
+//   - []mongodb.CampaignRun: The runs, newest first
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CAMPAIGN_RUNS environment variable
+//   - Query: Filters by campaign_id, sorts by started_at descending and limits
+func GetCampaignRuns(orgId string, campaignId string, limit int) ([]mongodb.CampaignRun, error) {
+	coll := Client.Database(orgId).Collection(os.Getenv("MONGO_COLLECTION_CAMPAIGN_RUNS"))
+
+	opts := options.Find().SetSort(bson.D{{Key: "started_at", Value: -1}}).SetLimit(int64(limit))
+
+	cursor, err := coll.Find(context.Background(), bson.M{"campaign_id": campaignId}, opts)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	runs := []mongodb.CampaignRun{}
+	if err := cursor.All(context.Background(), &runs); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return runs, nil
+}
+
+// MarkCampaignRunCallCancelled records that a call placed by a campaign run was cancelled.
+// The run becomes "cancelled" once all of its calls are cancelled, "partially_cancelled" before.
+//
+// Parameters:
+//   - orgId: The organization ID the campaign belongs to
+//   - vapiCallId: The VapiAI call that was cancelled
+//   - userId: The Clerk user ID that cancelled the call
+//
+// Returns:
+//   - *mongo.UpdateResult: The result of the update, matching nothing for calls not placed by a campaign
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CAMPAIGN_RUNS environment variable
+//   - Operation: Pipeline update of the run whose call_ids contain the call
+func MarkCampaignRunCallCancelled(orgId string, vapiCallId string, userId string) (*mongo.UpdateResult, error) {
+	coll := Client.Database(orgId).Collection(os.Getenv("MONGO_COLLECTION_CAMPAIGN_RUNS"))
+
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"cancelled_call_ids": bson.M{"$setUnion": bson.A{bson.M{"$ifNull": bson.A{"$cancelled_call_ids", bson.A{}}}, bson.A{vapiCallId}}},
+			"cancelled_at":       time.Now(),
+			"cancelled_by":       userId,
+		}}},
+		{{Key: "$set", Value: bson.M{
+			"status": bson.M{"$cond": bson.A{
+				bson.M{"$gte": bson.A{bson.M{"$size": "$cancelled_call_ids"}, bson.M{"$size": "$call_ids"}}},
+				mongodb.RUN_STATUS_CANCELLED,
+				mongodb.RUN_STATUS_PARTIALLY_CANCELLED,
+			}},
+		}}},
+	}
+
+	result, err := coll.UpdateOne(context.Background(), bson.M{"call_ids": vapiCallId}, update)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// AddCampaignRunCall records a call placed by a campaign run once the call dispatcher sent it to VapiAI.
+//
+// Parameters:
+//   - orgId: The organization ID the campaign belongs to
+//   - runId: The ObjectID of the campaign run
+//   - vapiCallId: The VapiAI call that was placed
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CAMPAIGN_RUNS environment variable
+//   - Operation: Adds the call to call_ids with $addToSet
+func AddCampaignRunCall(orgId string, runId bson.ObjectID, vapiCallId string) error {
+	coll := Client.Database(orgId).Collection(os.Getenv("MONGO_COLLECTION_CAMPAIGN_RUNS"))
+
+	if _, err := coll.UpdateOne(context.Background(), bson.M{"_id": runId}, bson.M{"$addToSet": bson.M{"call_ids": vapiCallId}}); err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
 // GetCampaignByOrgId retrieves all campaigns for a specific organization from the database.
@@ -45,6 +46,37 @@ func GetCampaignByOrgId(orgId string) ([]mongodb.Campaign, error) {
 	return campaigns, nil
 }
 
+// GetCampaignById retrieves a single campaign for a specific organization from the database.
+//
+// Parameters:
+//   - orgId: The organization ID the campaign belongs to
+//   - campaignId: The hex ObjectID of the campaign
+//
+// Returns:
+//   - *mongodb.Campaign: The campaign, or an error if it does not exist
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CAMPAIGNS environment variable
+//   - Query: Finds the document matching the campaign ObjectID
+func GetCampaignById(orgId string, campaignId string) (*mongodb.Campaign, error) {
+	coll := Client.Database(orgId).Collection(os.Getenv("MONGO_COLLECTION_CAMPAIGNS"))
+
+	objectId, err := bson.ObjectIDFromHex(campaignId)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	var campaign mongodb.Campaign
+	if err := coll.FindOne(context.Background(), bson.M{"_id": objectId}).Decode(&campaign); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return &campaign, nil
+}
+
 // CreateCampaign creates a new campaign in the database for the specified organization.
 // This function inserts a campaign document into the campaigns collection
 // and returns the result of the insertion operation.
@@ -120,3 +152,37 @@ func DeleteCampaign(orgId string, campaignId string) (*mongo.DeleteResult, error
 
 	return result, nil
 }
+
+// AdvanceCallerIdCursor moves the round robin position of a campaign's phone number pool forward.
+//
+// Parameters:
+//   - orgId: The organization ID the campaign belongs to
+//   - campaignId: The ObjectID of the campaign
+//   - calls: The number of calls about to be assigned a number
+//
+// Returns:
+//   - int64: The position before it was moved, where the assignment starts
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CAMPAIGNS environment variable
+//   - Operation: Atomically increments caller_id_cursor and returns the previous document
+func AdvanceCallerIdCursor(orgId string, campaignId bson.ObjectID, calls int) (int64, error) {
+	coll := Client.Database(orgId).Collection(os.Getenv("MONGO_COLLECTION_CAMPAIGNS"))
+
+	// The cursor isn't part of mongodb.Campaign, so saving a campaign loaded before the cursor moved doesn't rewind it
+	var campaign struct {
+		CallerIdCursor int64 `bson:"caller_id_cursor"`
+	}
+	err := coll.FindOneAndUpdate(context.Background(),
+		bson.M{"_id": campaignId},
+		bson.M{"$inc": bson.M{"caller_id_cursor": calls}},
+		options.FindOneAndUpdate().SetReturnDocument(options.Before).SetProjection(bson.M{"caller_id_cursor": 1}),
+	).Decode(&campaign)
+	if err != nil {
+		log.Println(err)
+		return 0, err
+	}
+
+	return campaign.CallerIdCursor, nil
+}
@@ -0,0 +1,136 @@
+package mongodb
+
+import (
+	"context"
+	"log"
+	"os"
+	"sarah/types/mongodb"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// contactUpdateIndexesEnsured records the organizations whose contact update indexes were created by this process
+var contactUpdateIndexesEnsured sync.Map
+
+// contactUpdatesCollection returns the contact update history collection of an organization, creating its indexes
+// the first time the collection is used by this process.
+func contactUpdatesCollection(orgId string) *mongo.Collection {
+	coll := Client.Database(orgId).Collection(os.Getenv("MONGO_COLLECTION_CONTACT_UPDATES"))
+
+	if _, loaded := contactUpdateIndexesEnsured.LoadOrStore(orgId, true); !loaded {
+		_, err := coll.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+			{Keys: bson.D{{Key: "contact_id", Value: 1}, {Key: "vapi_call_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "contact_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		})
+		if err != nil {
+			log.Printf("Error creating contact update indexes for organization %s: %v", orgId, err)
+			contactUpdateIndexesEnsured.Delete(orgId)
+		}
+	}
+
+	return coll
+}
+
+// CreateContactUpdate records an update of a contact's metadata from the analysis of a call.
+// A contact is only updated once per call, so a report VapiAI sends again is not applied twice.
+//
+// Parameters:
+//   - orgId: The organization ID the contact belongs to
+//   - update: The update to record
+//
+// Returns:
+//   - bool: Whether the update was recorded, false if the call already updated the contact
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CONTACT_UPDATES environment variable
+//   - Operation: Inserts the update, unique on contact_id and vapi_call_id
+func CreateContactUpdate(orgId string, update mongodb.ContactUpdate) (bool, error) {
+	coll := contactUpdatesCollection(orgId)
+
+	_, err := coll.InsertOne(context.Background(), update)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	} else if err != nil {
+		log.Println(err)
+		return false, err
+	}
+
+	return true, nil
+}
+
+// GetContactUpdates retrieves a page of a contact's update history, newest first.
+//
+// Parameters:
+//   - orgId: The organization ID the contact belongs to
+//   - contactId: The ObjectID of the contact
+//   - page: The 1-based page number
+//   - limit: The maximum number of updates per page
+//
+// Returns:
+//   - *mongodb.ContactUpdatePage: The updates on the page and the total number of updates of the contact
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CONTACT_UPDATES environment variable
+//   - Query: Filters by contact_id, sorts by created_at descending, then skips and limits
+func GetContactUpdates(orgId string, contactId bson.ObjectID, page int, limit int) (*mongodb.ContactUpdatePage, error) {
+	coll := contactUpdatesCollection(orgId)
+
+	query := bson.M{"contact_id": contactId}
+
+	total, err := coll.CountDocuments(context.Background(), query)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+
+	cursor, err := coll.Find(context.Background(), query, opts)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	updates := []mongodb.ContactUpdate{}
+	if err := cursor.All(context.Background(), &updates); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return &mongodb.ContactUpdatePage{
+		Updates: updates,
+		Total:   total,
+		Page:    page,
+		Limit:   limit,
+	}, nil
+}
+
+// DeleteContactUpdate removes the update a call recorded for a contact, so the call's analysis can be applied again.
+//
+// Parameters:
+//   - orgId: The organization ID the contact belongs to
+//   - contactId: The ObjectID of the contact
+//   - vapiCallId: The VapiAI call that updated the contact
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CONTACT_UPDATES environment variable
+//   - Operation: Deletes the update matching contact_id and vapi_call_id
+func DeleteContactUpdate(orgId string, contactId bson.ObjectID, vapiCallId string) error {
+	coll := contactUpdatesCollection(orgId)
+
+	if _, err := coll.DeleteOne(context.Background(), bson.M{"contact_id": contactId, "vapi_call_id": vapiCallId}); err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package mongodb
 
 import (
 	"context"
+	"errors"
 	"log"
 	"os"
 	"sarah/types/mongodb"
@@ -113,3 +114,79 @@ func DeleteContact(orgId string, contactId string) (*mongo.DeleteResult, error)
 
 	return result, nil
 }
+
+// GetContactByPhoneNumber retrieves the contact of an organization with the given phone number.
+// Returns mongo.ErrNoDocuments if the organization has no such contact.
+//
+// Parameters:
+//   - orgId: The organization ID whose contacts are searched
+//   - phoneNumber: The phone number in E.164 format
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CONTACTS environment variable
+//   - Query: Finds the first contact whose phone_number matches
+func GetContactByPhoneNumber(orgId string, phoneNumber string) (*mongodb.Contact, error) {
+	coll := Client.Database(orgId).Collection(os.Getenv("MONGO_COLLECTION_CONTACTS"))
+
+	var contact mongodb.Contact
+	err := coll.FindOne(context.Background(), bson.M{"phone_number": phoneNumber}).Decode(&contact)
+	if err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			log.Println(err)
+		}
+		return nil, err
+	}
+
+	return &contact, nil
+}
+
+// GetContactById retrieves a single contact of an organization.
+// Returns mongo.ErrNoDocuments if the organization has no such contact.
+func GetContactById(orgId string, contactId bson.ObjectID) (*mongodb.Contact, error) {
+	coll := Client.Database(orgId).Collection(os.Getenv("MONGO_COLLECTION_CONTACTS"))
+
+	var contact mongodb.Contact
+	if err := coll.FindOne(context.Background(), bson.M{"_id": contactId}).Decode(&contact); err != nil {
+		return nil, err
+	}
+
+	return &contact, nil
+}
+
+// SetContactMetadata writes keys of a contact's metadata, leaving the other keys unchanged.
+//
+// Parameters:
+//   - orgId: The organization ID the contact belongs to
+//   - contactId: The ObjectID of the contact
+//   - values: The values to write by metadata key, nested keys separated by dots
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_CONTACTS environment variable
+//   - Operation: Replaces a null metadata with an empty document, then $sets each metadata.<key>
+func SetContactMetadata(orgId string, contactId bson.ObjectID, values map[string]interface{}) error {
+	coll := Client.Database(orgId).Collection(os.Getenv("MONGO_COLLECTION_CONTACTS"))
+
+	// Contacts created without metadata store it as null, which dotted keys can't be set in
+	_, err := coll.UpdateOne(context.Background(),
+		bson.M{"_id": contactId, "metadata": bson.M{"$type": "null"}},
+		bson.M{"$set": bson.M{"metadata": bson.M{}}},
+	)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	set := bson.M{}
+	for key, value := range values {
+		set["metadata."+key] = value
+	}
+
+	if _, err := coll.UpdateOne(context.Background(), bson.M{"_id": contactId}, bson.M{"$set": set}); err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return nil
+}
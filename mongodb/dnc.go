@@ -0,0 +1,187 @@
+package mongodb
+
+import (
+	"context"
+	"log"
+	"os"
+	"sarah/types/mongodb"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// dncIndexesEnsured records the organizations whose Do-Not-Call indexes were created by this process
+var dncIndexesEnsured sync.Map
+
+// dncCollection returns the Do-Not-Call collection of an organization, creating its indexes
+// the first time the collection is used by this process.
+func dncCollection(orgId string) *mongo.Collection {
+	coll := Client.Database(orgId).Collection(os.Getenv("MONGO_COLLECTION_DNC"))
+
+	if _, loaded := dncIndexesEnsured.LoadOrStore(orgId, true); !loaded {
+		_, err := coll.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+			{Keys: bson.D{{Key: "phone_number", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "created_at", Value: -1}}},
+		})
+		if err != nil {
+			log.Printf("Error creating Do-Not-Call indexes for organization %s: %v", orgId, err)
+			dncIndexesEnsured.Delete(orgId)
+		}
+	}
+
+	return coll
+}
+
+// AddDncEntries adds numbers to an organization's Do-Not-Call list.
+// Numbers that are already on the list keep their original entry.
+//
+// Parameters:
+//   - orgId: The organization ID whose list is updated
+//   - entries: The entries to add, with normalized phone numbers
+//
+// Returns:
+//   - int64: The number of entries that were not on the list yet
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_DNC environment variable
+//   - Operation: Unordered bulk upsert by phone_number with $setOnInsert
+func AddDncEntries(orgId string, entries []mongodb.DncEntry) (int64, error) {
+	if len(entries) == 0 {
+		return 0, nil
+	}
+
+	coll := dncCollection(orgId)
+
+	models := []mongo.WriteModel{}
+	for _, entry := range entries {
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"phone_number": entry.PhoneNumber}).
+			SetUpdate(bson.M{"$setOnInsert": entry}).
+			SetUpsert(true))
+	}
+
+	result, err := coll.BulkWrite(context.Background(), models, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		log.Println(err)
+		return 0, err
+	}
+
+	return result.UpsertedCount, nil
+}
+
+// GetDncEntries retrieves a page of an organization's Do-Not-Call list, newest first.
+//
+// Parameters:
+//   - orgId: The organization ID whose list is retrieved
+//   - page: The 1-based page number
+//   - limit: The maximum number of entries per page
+//
+// Returns:
+//   - *mongodb.DncPage: The entries on the page and the total number of entries
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_DNC environment variable
+//   - Query: Sorts by created_at descending, then skips and limits
+func GetDncEntries(orgId string, page int, limit int) (*mongodb.DncPage, error) {
+	coll := dncCollection(orgId)
+
+	total, err := coll.CountDocuments(context.Background(), bson.M{})
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+
+	cursor, err := coll.Find(context.Background(), bson.M{}, opts)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	entries := []mongodb.DncEntry{}
+	if err := cursor.All(context.Background(), &entries); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return &mongodb.DncPage{
+		Entries: entries,
+		Total:   total,
+		Page:    page,
+		Limit:   limit,
+	}, nil
+}
+
+// GetSuppressedNumbers returns which of the given numbers are on an organization's Do-Not-Call list.
+//
+// Parameters:
+//   - orgId: The organization ID whose list is checked
+//   - phoneNumbers: The normalized numbers to check
+//
+// Returns:
+//   - []string: The numbers that are on the list
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_DNC environment variable
+//   - Query: Filters by phone_number $in the given numbers, projecting only the number
+func GetSuppressedNumbers(orgId string, phoneNumbers []string) ([]string, error) {
+	if len(phoneNumbers) == 0 {
+		return []string{}, nil
+	}
+
+	coll := dncCollection(orgId)
+
+	opts := options.Find().SetProjection(bson.M{"phone_number": 1})
+	cursor, err := coll.Find(context.Background(), bson.M{"phone_number": bson.M{"$in": phoneNumbers}}, opts)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	entries := []mongodb.DncEntry{}
+	if err := cursor.All(context.Background(), &entries); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	suppressed := []string{}
+	for _, entry := range entries {
+		suppressed = append(suppressed, entry.PhoneNumber)
+	}
+
+	return suppressed, nil
+}
+
+// DeleteDncEntry removes a number from an organization's Do-Not-Call list.
+//
+// Parameters:
+//   - orgId: The organization ID whose list is updated
+//   - phoneNumber: The normalized number to remove
+//
+// Returns:
+//   - *mongo.DeleteResult: The result of the deletion, with a zero count if the number wasn't on the list
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_DNC environment variable
+//   - Operation: Deletes the entry matching phone_number
+func DeleteDncEntry(orgId string, phoneNumber string) (*mongo.DeleteResult, error) {
+	coll := dncCollection(orgId)
+
+	result, err := coll.DeleteOne(context.Background(), bson.M{"phone_number": phoneNumber})
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,100 @@
+package mongodb
+
+import (
+	"context"
+	"log"
+	"os"
+	"sarah/types/mongodb"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// usageIndexesEnsured records the organizations whose phone number usage indexes were created by this process
+var usageIndexesEnsured sync.Map
+
+// usageCollection returns the phone number usage collection of an organization, creating its indexes
+// the first time the collection is used by this process.
+func usageCollection(orgId string) *mongo.Collection {
+	coll := Client.Database(orgId).Collection(os.Getenv("MONGO_COLLECTION_PHONE_NUMBER_USAGE"))
+
+	if _, loaded := usageIndexesEnsured.LoadOrStore(orgId, true); !loaded {
+		_, err := coll.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+			Keys:    bson.D{{Key: "phone_number_id", Value: 1}, {Key: "date", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		})
+		if err != nil {
+			log.Printf("Error creating phone number usage indexes for organization %s: %v", orgId, err)
+			usageIndexesEnsured.Delete(orgId)
+		}
+	}
+
+	return coll
+}
+
+// GetPhoneNumberUsage retrieves how many calls each of the given phone numbers placed on a day.
+//
+// Parameters:
+//   - orgId: The organization ID the phone numbers belong to
+//   - date: The UTC day, formatted with USAGE_DATE_FORMAT
+//   - phoneNumberIds: The VapiAI phone number IDs to count
+//
+// Returns:
+//   - map[string]int: The number of calls by phone number ID, missing for numbers that placed none
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_PHONE_NUMBER_USAGE environment variable
+//   - Query: Filters by date and phone_number_id $in the given IDs
+func GetPhoneNumberUsage(orgId string, date string, phoneNumberIds []string) (map[string]int, error) {
+	coll := usageCollection(orgId)
+
+	cursor, err := coll.Find(context.Background(), bson.M{"date": date, "phone_number_id": bson.M{"$in": phoneNumberIds}})
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	counters := []mongodb.PhoneNumberUsage{}
+	if err := cursor.All(context.Background(), &counters); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	usage := map[string]int{}
+	for _, counter := range counters {
+		usage[counter.PhoneNumberId] = counter.Calls
+	}
+
+	return usage, nil
+}
+
+// IncrementPhoneNumberUsage adds calls to the number of calls a phone number placed on a day.
+//
+// Parameters:
+//   - orgId: The organization ID the phone number belongs to
+//   - phoneNumberId: The VapiAI phone number ID the calls were placed from
+//   - date: The UTC day, formatted with USAGE_DATE_FORMAT
+//   - calls: The number of calls placed
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_PHONE_NUMBER_USAGE environment variable
+//   - Operation: Upserts the day's counter with $inc
+func IncrementPhoneNumberUsage(orgId string, phoneNumberId string, date string, calls int) error {
+	coll := usageCollection(orgId)
+
+	_, err := coll.UpdateOne(context.Background(),
+		bson.M{"phone_number_id": phoneNumberId, "date": date},
+		bson.M{"$inc": bson.M{"calls": calls}},
+		options.UpdateOne().SetUpsert(true),
+	)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return nil
+}
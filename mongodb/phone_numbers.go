@@ -50,6 +50,19 @@ func GetPhoneNumberByOrgId(orgId string) ([]mongodb.PhoneNumber, error) {
 	return phoneNumbers, nil
 }
 
+// GetPhoneNumberByVapiId retrieves the phone number record of an organization matching a VapiAI phone number ID.
+// Returns mongo.ErrNoDocuments if the organization has no such phone number.
+func GetPhoneNumberByVapiId(orgId string, phoneNumberId string) (*mongodb.PhoneNumber, error) {
+	coll := Client.Database(orgId).Collection(os.Getenv("MONGO_COLLECTION_PHONE_NUMBERS"))
+
+	var phoneNumber mongodb.PhoneNumber
+	if err := coll.FindOne(context.Background(), bson.M{"phone_number_id": phoneNumberId}).Decode(&phoneNumber); err != nil {
+		return nil, err
+	}
+
+	return &phoneNumber, nil
+}
+
 func CreatePhoneNumber(orgId string, phoneNumber mongodb.PhoneNumber) (*mongo.InsertOneResult, error) {
 	coll := Client.Database(orgId).Collection(os.Getenv("MONGO_COLLECTION_PHONE_NUMBERS"))
 
@@ -73,3 +86,35 @@ func DeletePhoneNumber(orgId string, phoneNumberId string) (*mongo.DeleteResult,
 
 	return result, nil
 }
+
+// UpdatePhoneNumber updates the name and limits of a phone number of an organization.
+//
+// Parameters:
+//   - orgId: The organization ID the phone number belongs to
+//   - phoneNumber: The phone number, identified by its VapiAI phone number ID, with the new name and limits
+//
+// Returns:
+//   - *mongo.UpdateResult: The result of the update operation
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_PHONE_NUMBERS environment variable
+//   - Operation: Sets name, daily_cap and max_concurrent_calls of the document matching phone_number_id
+func UpdatePhoneNumber(orgId string, phoneNumber mongodb.PhoneNumber) (*mongo.UpdateResult, error) {
+	coll := Client.Database(orgId).Collection(os.Getenv("MONGO_COLLECTION_PHONE_NUMBERS"))
+
+	result, err := coll.UpdateOne(context.Background(),
+		bson.M{"phone_number_id": phoneNumber.PhoneNumberId},
+		bson.M{"$set": bson.M{
+			"name":                 phoneNumber.Name,
+			"daily_cap":            phoneNumber.DailyCap,
+			"max_concurrent_calls": phoneNumber.MaxConcurrentCalls,
+		}},
+	)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,94 @@
+package mongodb
+
+import (
+	"context"
+	"log"
+	"os"
+	"sarah/types/mongodb"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// reconciliationIndexesEnsured records the organizations whose reconciliation report indexes were created by this process
+var reconciliationIndexesEnsured sync.Map
+
+// reconciliationsCollection returns the reconciliation reports collection of an organization, creating its indexes
+// the first time the collection is used by this process.
+func reconciliationsCollection(orgId string) *mongo.Collection {
+	coll := Client.Database(orgId).Collection(os.Getenv("MONGO_COLLECTION_RECONCILIATIONS"))
+
+	if _, loaded := reconciliationIndexesEnsured.LoadOrStore(orgId, true); !loaded {
+		_, err := coll.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+			{Keys: bson.D{{Key: "started_at", Value: -1}}},
+		})
+		if err != nil {
+			log.Printf("Error creating reconciliation indexes for organization %s: %v", orgId, err)
+			reconciliationIndexesEnsured.Delete(orgId)
+		}
+	}
+
+	return coll
+}
+
+// CreateReconciliation stores the report of a pass of the call reconciler.
+//
+// Parameters:
+//   - orgId: The organization ID whose calls were reconciled
+//   - reconciliation: The report to store
+//
+// Returns:
+//   - *mongo.InsertOneResult: The result of the insert operation
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_RECONCILIATIONS environment variable
+//   - Operation: Inserts a single report document
+func CreateReconciliation(orgId string, reconciliation mongodb.Reconciliation) (*mongo.InsertOneResult, error) {
+	coll := reconciliationsCollection(orgId)
+
+	result, err := coll.InsertOne(context.Background(), reconciliation)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetReconciliations retrieves the latest reports of the call reconciler, newest first.
+//
+// Parameters:
+//   - orgId: The organization ID whose reports are retrieved
+//   - limit: The maximum number of reports to return
+//
+// Returns:
+//   - []mongodb.Reconciliation: The reports
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_RECONCILIATIONS environment variable
+//   - Query: Sorts by started_at descending and limits
+func GetReconciliations(orgId string, limit int) ([]mongodb.Reconciliation, error) {
+	coll := reconciliationsCollection(orgId)
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "started_at", Value: -1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := coll.Find(context.Background(), bson.M{}, opts)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	reconciliations := []mongodb.Reconciliation{}
+	if err := cursor.All(context.Background(), &reconciliations); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return reconciliations, nil
+}
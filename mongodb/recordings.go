@@ -0,0 +1,217 @@
+package mongodb
+
+import (
+	"context"
+	"log"
+	"os"
+	"sarah/types/mongodb"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// recordingIndexesEnsured records the organizations whose recordings collection indexes were already created
+var recordingIndexesEnsured sync.Map
+
+// recordingsCollection returns the recordings collection of an organization, creating its
+// indexes the first time the collection is used by this process.
+func recordingsCollection(orgId string) *mongo.Collection {
+	coll := Client.Database(orgId).Collection(os.Getenv("MONGO_COLLECTION_RECORDINGS"))
+
+	if _, loaded := recordingIndexesEnsured.LoadOrStore(orgId, true); !loaded {
+		if err := EnsureRecordingIndexes(orgId); err != nil {
+			recordingIndexesEnsured.Delete(orgId)
+		}
+	}
+
+	return coll
+}
+
+// EnsureRecordingIndexes creates the indexes used to look up recordings and to find
+// the recordings to archive or expire. A unique index on vapi_call_id guarantees a call
+// recording is only archived once.
+//
+// Parameters:
+//   - orgId: The organization ID whose recordings collection is indexed
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_RECORDINGS environment variable
+//   - Operation: Creates the indexes if they don't exist yet
+func EnsureRecordingIndexes(orgId string) error {
+	coll := Client.Database(orgId).Collection(os.Getenv("MONGO_COLLECTION_RECORDINGS"))
+
+	_, err := coll.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "vapi_call_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "updated_at", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "archived_at", Value: 1}}},
+	})
+	if err != nil {
+		log.Printf("Error creating recording indexes for organization %s: %v", orgId, err)
+		return err
+	}
+
+	return nil
+}
+
+// CreatePendingRecording schedules the recording of a call for archival, unless it was already scheduled.
+//
+// Parameters:
+//   - orgId: The organization ID the call belongs to
+//   - vapiCallId: The VapiAI call the recording belongs to
+//   - sourceUrl: The VapiAI URL of the recording
+//
+// Returns:
+//   - bool: True if the recording was newly scheduled
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_RECORDINGS environment variable
+//   - Operation: Inserts a pending recording keyed by vapi_call_id if none exists
+func CreatePendingRecording(orgId string, vapiCallId string, sourceUrl string) (bool, error) {
+	coll := recordingsCollection(orgId)
+
+	now := time.Now()
+	update := bson.M{"$setOnInsert": mongodb.Recording{
+		VapiCallId: vapiCallId,
+		SourceUrl:  sourceUrl,
+		Status:     mongodb.RECORDING_STATUS_PENDING,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}}
+
+	result, err := coll.UpdateOne(context.Background(), bson.M{"vapi_call_id": vapiCallId}, update, options.UpdateOne().SetUpsert(true))
+	if err != nil {
+		log.Println(err)
+		return false, err
+	}
+
+	return result.UpsertedCount == 1, nil
+}
+
+// UpdateRecording saves the archival state of a recording.
+//
+// Parameters:
+//   - orgId: The organization ID the call belongs to
+//   - recording: The recording, matched by its VapiAI call ID
+//
+// Returns:
+//   - *mongo.UpdateResult: The result of the update operation
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_RECORDINGS environment variable
+//   - Operation: Updates a single recording document keyed by vapi_call_id
+func UpdateRecording(orgId string, recording mongodb.Recording) (*mongo.UpdateResult, error) {
+	coll := recordingsCollection(orgId)
+
+	update := bson.M{"$set": bson.M{
+		"storage_key":  recording.StorageKey,
+		"content_type": recording.ContentType,
+		"size_bytes":   recording.SizeBytes,
+		"status":       recording.Status,
+		"attempts":     recording.Attempts,
+		"error":        recording.Error,
+		"archived_at":  recording.ArchivedAt,
+		"updated_at":   time.Now(),
+	}}
+
+	result, err := coll.UpdateOne(context.Background(), bson.M{"vapi_call_id": recording.VapiCallId}, update)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetRecordingByVapiId retrieves the recording of a call.
+// Returns mongo.ErrNoDocuments if the call has no recording.
+func GetRecordingByVapiId(orgId string, vapiCallId string) (*mongodb.Recording, error) {
+	coll := recordingsCollection(orgId)
+
+	var recording mongodb.Recording
+	if err := coll.FindOne(context.Background(), bson.M{"vapi_call_id": vapiCallId}).Decode(&recording); err != nil {
+		return nil, err
+	}
+
+	return &recording, nil
+}
+
+// GetRecordingsToArchive retrieves the pending and failed recordings that can be attempted again.
+//
+// Parameters:
+//   - orgId: The organization ID to retrieve recordings for
+//   - maxAttempts: Recordings attempted this many times are given up on
+//   - before: Only recordings last updated before this time are returned, so recordings
+//     being archived right now are left alone
+//
+// Returns:
+//   - []mongodb.Recording: The recordings to archive, oldest first
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_RECORDINGS environment variable
+//   - Query: Filters by status, attempts and updated_at, sorts by updated_at ascending
+func GetRecordingsToArchive(orgId string, maxAttempts int, before time.Time) ([]mongodb.Recording, error) {
+	coll := recordingsCollection(orgId)
+
+	query := bson.M{
+		"status":     bson.M{"$in": []mongodb.RecordingStatus{mongodb.RECORDING_STATUS_PENDING, mongodb.RECORDING_STATUS_FAILED}},
+		"attempts":   bson.M{"$lt": maxAttempts},
+		"updated_at": bson.M{"$lt": before},
+	}
+
+	cursor, err := coll.Find(context.Background(), query, options.Find().SetSort(bson.D{{Key: "updated_at", Value: 1}}))
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	recordings := []mongodb.Recording{}
+	if err := cursor.All(context.Background(), &recordings); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return recordings, nil
+}
+
+// GetRecordingsArchivedBefore retrieves the archived recordings stored before a cutoff date.
+//
+// Parameters:
+//   - orgId: The organization ID to retrieve recordings for
+//   - cutoff: Only recordings archived before this date are returned
+//
+// Returns:
+//   - []mongodb.Recording: The expired recordings
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_RECORDINGS environment variable
+//   - Query: Filters by archived status and archived_at
+func GetRecordingsArchivedBefore(orgId string, cutoff time.Time) ([]mongodb.Recording, error) {
+	coll := recordingsCollection(orgId)
+
+	query := bson.M{
+		"status":      mongodb.RECORDING_STATUS_ARCHIVED,
+		"archived_at": bson.M{"$lt": cutoff},
+	}
+
+	cursor, err := coll.Find(context.Background(), query)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	recordings := []mongodb.Recording{}
+	if err := cursor.All(context.Background(), &recordings); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return recordings, nil
+}
@@ -0,0 +1,90 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"sarah/types/mongodb"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// GetOrganizationSettings retrieves the settings of an organization.
+// Organizations that never saved their settings get the defaults.
+//
+// Parameters:
+//   - orgId: The organization ID to retrieve the settings for
+//
+// Returns:
+//   - *mongodb.OrganizationSettings: The stored settings, or the defaults
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_SETTINGS environment variable
+//   - Query: Finds the single settings document
+func GetOrganizationSettings(orgId string) (*mongodb.OrganizationSettings, error) {
+	coll := Client.Database(orgId).Collection(os.Getenv("MONGO_COLLECTION_SETTINGS"))
+
+	var settings mongodb.OrganizationSettings
+	err := coll.FindOne(context.Background(), bson.M{"_id": mongodb.ORGANIZATION_SETTINGS_ID}).Decode(&settings)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return &mongodb.OrganizationSettings{Id: mongodb.ORGANIZATION_SETTINGS_ID}, nil
+	} else if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return &settings, nil
+}
+
+// UpdateOrganizationSettings updates the settings of an organization given in the update.
+// Settings missing from the update keep their stored value.
+//
+// Parameters:
+//   - orgId: The organization ID to update the settings for
+//   - update: The settings to change
+//
+// Returns:
+//   - *mongo.UpdateResult: The result of the upsert operation
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_SETTINGS environment variable
+//   - Operation: Sets the given fields of the single settings document, creating it if needed
+func UpdateOrganizationSettings(orgId string, update mongodb.OrganizationSettingsUpdate) (*mongo.UpdateResult, error) {
+	coll := Client.Database(orgId).Collection(os.Getenv("MONGO_COLLECTION_SETTINGS"))
+
+	set := bson.M{"updated_at": time.Now()}
+	if update.RecordingRetentionDays != nil {
+		set["recording_retention_days"] = *update.RecordingRetentionDays
+	}
+	if update.DefaultCountry != nil {
+		set["default_country"] = *update.DefaultCountry
+	}
+	if update.MaxConcurrentCalls != nil {
+		set["max_concurrent_calls"] = *update.MaxConcurrentCalls
+	}
+	if update.AnalysisMappings != nil {
+		set["analysis_mappings"] = *update.AnalysisMappings
+	}
+	if update.Dispositions != nil {
+		set["dispositions"] = *update.Dispositions
+	}
+
+	result, err := coll.UpdateOne(
+		context.Background(),
+		bson.M{"_id": mongodb.ORGANIZATION_SETTINGS_ID},
+		bson.M{"$set": set},
+		options.UpdateOne().SetUpsert(true),
+	)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return result, nil
+}
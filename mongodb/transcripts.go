@@ -0,0 +1,177 @@
+package mongodb
+
+import (
+	"context"
+	"log"
+	"os"
+	"sarah/types/mongodb"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// transcriptIndexesEnsured records the organizations whose transcripts collection indexes were already created
+var transcriptIndexesEnsured sync.Map
+
+// transcriptsCollection returns the transcripts collection of an organization, creating its
+// indexes the first time the collection is used by this process.
+func transcriptsCollection(orgId string) *mongo.Collection {
+	coll := Client.Database(orgId).Collection(os.Getenv("MONGO_COLLECTION_TRANSCRIPTS"))
+
+	if _, loaded := transcriptIndexesEnsured.LoadOrStore(orgId, true); !loaded {
+		if err := EnsureTranscriptIndexes(orgId); err != nil {
+			transcriptIndexesEnsured.Delete(orgId)
+		}
+	}
+
+	return coll
+}
+
+// EnsureTranscriptIndexes creates the full-text index used to search an organization's transcripts,
+// along with a unique index on vapi_call_id so a call only ever has one transcript.
+// The text index covers both the plain transcript and the individual messages, with English
+// stemming so a search for "cancel" also matches "cancelled" and "cancelling".
+//
+// Parameters:
+//   - orgId: The organization ID whose transcripts collection is indexed
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_TRANSCRIPTS environment variable
+//   - Operation: Creates the indexes if they don't exist yet
+func EnsureTranscriptIndexes(orgId string) error {
+	coll := Client.Database(orgId).Collection(os.Getenv("MONGO_COLLECTION_TRANSCRIPTS"))
+
+	_, err := coll.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "vapi_call_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{
+			Keys: bson.D{{Key: "transcript", Value: "text"}, {Key: "messages.message", Value: "text"}},
+			Options: options.Index().
+				SetName("transcript_text").
+				SetDefaultLanguage("english").
+				SetWeights(bson.D{{Key: "messages.message", Value: 2}, {Key: "transcript", Value: 1}}),
+		},
+		{Keys: bson.D{{Key: "assistant_id", Value: 1}, {Key: "call_created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "campaign_id", Value: 1}, {Key: "call_created_at", Value: -1}}},
+	})
+	if err != nil {
+		log.Printf("Error creating transcript indexes for organization %s: %v", orgId, err)
+		return err
+	}
+
+	return nil
+}
+
+// UpsertTranscript creates or replaces the transcript of a VapiAI call.
+//
+// Parameters:
+//   - orgId: The organization ID the call belongs to
+//   - transcript: The transcript to store
+//
+// Returns:
+//   - *mongo.UpdateResult: The result of the upsert operation
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_TRANSCRIPTS environment variable
+//   - Operation: Upserts a single transcript document keyed by vapi_call_id
+func UpsertTranscript(orgId string, transcript mongodb.Transcript) (*mongo.UpdateResult, error) {
+	coll := transcriptsCollection(orgId)
+
+	transcript.UpdatedAt = time.Now()
+	if transcript.Messages == nil {
+		transcript.Messages = []mongodb.TranscriptMessage{}
+	}
+
+	update := bson.M{"$set": bson.M{
+		"vapi_call_id":    transcript.VapiCallId,
+		"assistant_id":    transcript.AssistantId,
+		"campaign_id":     transcript.CampaignId,
+		"customer_number": transcript.CustomerNumber,
+		"transcript":      transcript.Transcript,
+		"messages":        transcript.Messages,
+		"call_created_at": transcript.CallCreatedAt,
+		"updated_at":      transcript.UpdatedAt,
+	}}
+
+	result, err := coll.UpdateOne(context.Background(), bson.M{"vapi_call_id": transcript.VapiCallId}, update, options.UpdateOne().SetUpsert(true))
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// SearchTranscripts runs a full-text search over an organization's transcripts.
+//
+// Parameters:
+//   - orgId: The organization ID whose transcripts are searched
+//   - search: The MongoDB $text search string (words, "quoted phrases" and -negations)
+//   - filter: The filter the matching calls must also match
+//   - page: The 1-based page number
+//   - limit: The maximum number of calls per page
+//
+// Returns:
+//   - *mongodb.TranscriptSearchPage: The matching calls, most relevant first, without snippets
+//
+// Database Operations:
+//   - Database: Uses the organization ID as the database name
+//   - Collection: Uses the MONGO_COLLECTION_TRANSCRIPTS environment variable
+//   - Query: $text search plus filters, sorted by text score then call_created_at descending
+func SearchTranscripts(orgId string, search string, filter mongodb.TranscriptFilter, page int, limit int) (*mongodb.TranscriptSearchPage, error) {
+	coll := transcriptsCollection(orgId)
+
+	query := bson.M{"$text": bson.M{"$search": search}}
+	if filter.AssistantId != "" {
+		query["assistant_id"] = filter.AssistantId
+	}
+	if filter.CampaignId != "" {
+		query["campaign_id"] = filter.CampaignId
+	}
+
+	createdAt := bson.M{}
+	if filter.From != nil {
+		createdAt["$gte"] = *filter.From
+	}
+	if filter.To != nil {
+		createdAt["$lt"] = *filter.To
+	}
+	if len(createdAt) > 0 {
+		query["call_created_at"] = createdAt
+	}
+
+	total, err := coll.CountDocuments(context.Background(), query)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	opts := options.Find().
+		SetProjection(bson.M{"score": bson.M{"$meta": "textScore"}, "vapi_call_id": 1, "assistant_id": 1, "campaign_id": 1, "customer_number": 1, "call_created_at": 1, "transcript": 1, "messages": 1}).
+		SetSort(bson.D{{Key: "score", Value: bson.M{"$meta": "textScore"}}, {Key: "call_created_at", Value: -1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+
+	cursor, err := coll.Find(context.Background(), query, opts)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	matches := []mongodb.TranscriptMatch{}
+	if err := cursor.All(context.Background(), &matches); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return &mongodb.TranscriptSearchPage{
+		Matches: matches,
+		Total:   total,
+		Page:    page,
+		Limit:   limit,
+	}, nil
+}
@@ -0,0 +1,153 @@
+package phone
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nyaruka/phonenumbers"
+)
+
+// ErrInvalidNumber is returned when a value cannot be parsed as a dialable phone number.
+var ErrInvalidNumber = errors.New("invalid phone number")
+
+// NumberType classifies what kind of line a phone number belongs to.
+type NumberType string
+
+const (
+	// TYPE_FIXED_LINE is a landline number
+	TYPE_FIXED_LINE NumberType = "fixed_line"
+
+	// TYPE_MOBILE is a mobile number
+	TYPE_MOBILE NumberType = "mobile"
+
+	// TYPE_FIXED_LINE_OR_MOBILE is a number of a region where landlines and mobiles can't be told apart (e.g., the US)
+	TYPE_FIXED_LINE_OR_MOBILE NumberType = "fixed_line_or_mobile"
+
+	// TYPE_TOLL_FREE is a toll-free number
+	TYPE_TOLL_FREE NumberType = "toll_free"
+
+	// TYPE_PREMIUM_RATE is a premium-rate number
+	TYPE_PREMIUM_RATE NumberType = "premium_rate"
+
+	// TYPE_SHARED_COST is a number whose cost is shared between the caller and the recipient
+	TYPE_SHARED_COST NumberType = "shared_cost"
+
+	// TYPE_VOIP is a Voice over IP number
+	TYPE_VOIP NumberType = "voip"
+
+	// TYPE_PERSONAL is a personal number routed to a mobile or landline
+	TYPE_PERSONAL NumberType = "personal"
+
+	// TYPE_PAGER is a pager number
+	TYPE_PAGER NumberType = "pager"
+
+	// TYPE_UAN is a company number routed to specific offices
+	TYPE_UAN NumberType = "uan"
+
+	// TYPE_VOICEMAIL is a voicemail access number
+	TYPE_VOICEMAIL NumberType = "voicemail"
+
+	// TYPE_UNKNOWN is a valid number that doesn't match any known pattern of its region
+	TYPE_UNKNOWN NumberType = "unknown"
+)
+
+// numberTypes maps the libphonenumber number types to NumberType
+var numberTypes = map[phonenumbers.PhoneNumberType]NumberType{
+	phonenumbers.FIXED_LINE:           TYPE_FIXED_LINE,
+	phonenumbers.MOBILE:               TYPE_MOBILE,
+	phonenumbers.FIXED_LINE_OR_MOBILE: TYPE_FIXED_LINE_OR_MOBILE,
+	phonenumbers.TOLL_FREE:            TYPE_TOLL_FREE,
+	phonenumbers.PREMIUM_RATE:         TYPE_PREMIUM_RATE,
+	phonenumbers.SHARED_COST:          TYPE_SHARED_COST,
+	phonenumbers.VOIP:                 TYPE_VOIP,
+	phonenumbers.PERSONAL_NUMBER:      TYPE_PERSONAL,
+	phonenumbers.PAGER:                TYPE_PAGER,
+	phonenumbers.UAN:                  TYPE_UAN,
+	phonenumbers.VOICEMAIL:            TYPE_VOICEMAIL,
+}
+
+// Number is a parsed and validated phone number.
+type Number struct {
+	// E164 is the number in E.164 format (e.g., "+14155552671")
+	E164 string `json:"e164"`
+
+	// Country is the ISO 3166-1 alpha-2 code of the region the number belongs to (e.g., "US")
+	Country string `json:"country"`
+
+	// Type is the kind of line the number belongs to
+	Type NumberType `json:"type"`
+}
+
+// Normalize parses a phone number written in international or national format and returns it in E.164.
+// National numbers (e.g., "(415) 555-2671") are read as numbers of defaultCountry, an ISO 3166-1
+// alpha-2 code; with an empty defaultCountry only numbers starting with "+" or an international
+// prefix are accepted. Returns an error wrapping ErrInvalidNumber that explains why the value was rejected.
+func Normalize(value string, defaultCountry string) (*Number, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, fmt.Errorf("%w: phone number is required", ErrInvalidNumber)
+	}
+
+	region := strings.ToUpper(strings.TrimSpace(defaultCountry))
+	if region == "" {
+		region = "ZZ"
+	}
+
+	parsed, err := phonenumbers.Parse(value, region)
+	if errors.Is(err, phonenumbers.ErrInvalidCountryCode) && region == "ZZ" && !strings.HasPrefix(value, "+") {
+		return nil, fmt.Errorf("%w: %q is not in international format and no default country is set", ErrInvalidNumber, value)
+	} else if errors.Is(err, phonenumbers.ErrInvalidCountryCode) {
+		return nil, fmt.Errorf("%w: %q has an invalid country code", ErrInvalidNumber, value)
+	} else if err != nil {
+		return nil, fmt.Errorf("%w: %q is not a phone number", ErrInvalidNumber, value)
+	}
+
+	switch phonenumbers.IsPossibleNumberWithReason(parsed) {
+	case phonenumbers.INVALID_COUNTRY_CODE:
+		return nil, fmt.Errorf("%w: %q has an invalid country code", ErrInvalidNumber, value)
+	case phonenumbers.TOO_SHORT:
+		return nil, fmt.Errorf("%w: %q is too short", ErrInvalidNumber, value)
+	case phonenumbers.TOO_LONG:
+		return nil, fmt.Errorf("%w: %q is too long", ErrInvalidNumber, value)
+	}
+
+	if !phonenumbers.IsValidNumber(parsed) {
+		return nil, fmt.Errorf("%w: %q is not a valid number for its country", ErrInvalidNumber, value)
+	}
+
+	numberType, ok := numberTypes[phonenumbers.GetNumberType(parsed)]
+	if !ok {
+		numberType = TYPE_UNKNOWN
+	}
+
+	return &Number{
+		E164:    phonenumbers.Format(parsed, phonenumbers.E164),
+		Country: phonenumbers.GetRegionCodeForNumber(parsed),
+		Type:    numberType,
+	}, nil
+}
+
+// ValidCountry reports whether code is an ISO 3166-1 alpha-2 code of a region with known phone numbering rules.
+func ValidCountry(code string) bool {
+	return phonenumbers.GetSupportedRegions()[strings.ToUpper(code)]
+}
+
+// LocalArea returns the geographic area of an E.164 number as its country calling code followed by
+// its area code (e.g., "1-415" for "+14155552671"), so numbers of the same area can be matched.
+// Returns an empty string for numbers without a geographic area code, such as most mobile numbers outside
+// North America.
+func LocalArea(e164 string) string {
+	parsed, err := phonenumbers.Parse(e164, "ZZ")
+	if err != nil {
+		return ""
+	}
+
+	length := phonenumbers.GetLengthOfGeographicalAreaCode(parsed)
+	if length == 0 {
+		return ""
+	}
+
+	national := phonenumbers.GetNationalSignificantNumber(parsed)
+	return fmt.Sprintf("%d-%s", parsed.GetCountryCode(), national[:length])
+}
@@ -0,0 +1,904 @@
+package sarah
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/netip"
+	"net/smtp"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"sarah/mongodb"
+	mongodbTypes "sarah/types/mongodb"
+	vapiTypes "sarah/types/vapi"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// MAX_ALERT_RULES is the maximum number of alert rules of an organization
+const MAX_ALERT_RULES = 50
+
+// MAX_ALERT_ACTIONS is the maximum number of actions of an alert rule
+const MAX_ALERT_ACTIONS = 10
+
+// MAX_ALERT_KEYWORDS is the maximum number of keywords of an alert rule
+const MAX_ALERT_KEYWORDS = 100
+
+// MAX_ALERT_PATTERN_LENGTH is the maximum length of the regular expression of an alert rule
+const MAX_ALERT_PATTERN_LENGTH = 500
+
+// ALERT_RULE_NAME_MAX_LENGTH is the maximum length of the name of an alert rule
+const ALERT_RULE_NAME_MAX_LENGTH = 100
+
+// ALERT_EXCERPT_LENGTH is the maximum number of characters of the transcript sent with an alert
+const ALERT_EXCERPT_LENGTH = 500
+
+// ALERT_DELIVERY_ATTEMPTS is how many times a webhook or email alert is sent before giving up
+const ALERT_DELIVERY_ATTEMPTS = 3
+
+// ALERT_DELIVERY_TIMEOUT is how long sending a webhook or email alert may take
+const ALERT_DELIVERY_TIMEOUT = 5 * time.Second
+
+// ALERT_RETRY_DELAY is the delay before sending an alert again, multiplied by the attempt number
+const ALERT_RETRY_DELAY = 2 * time.Second
+
+// alertRoles maps the roles of VapiAI transcripts and transcript turns to the roles of alert conditions
+var alertRoles = map[string]string{
+	"user":      "user",
+	"assistant": "assistant",
+	"bot":       "assistant",
+}
+
+// alertPatterns caches the compiled regular expressions of alert conditions, keyed by their source
+var alertPatterns sync.Map
+
+// ALERT_DELIVERY_FAILED is the error reported by /alerts/test for an alert that couldn't be sent.
+// The cause is only logged, so test deliveries can't be used to probe the network the server is in.
+const ALERT_DELIVERY_FAILED = "the alert couldn't be delivered"
+
+// ErrAlertAddressBlocked is returned when a webhook alert would connect to a loopback, private,
+// link-local or unspecified address.
+var ErrAlertAddressBlocked = errors.New("webhook alerts can't be sent to loopback, private or link-local addresses")
+
+// alertClient posts webhook alerts. It only connects to public addresses, checked on the resolved IP
+// when connecting so DNS rebinding can't get around it, ignores proxies, and doesn't follow redirects:
+// a redirect is reported as a failed delivery.
+var alertClient = &http.Client{
+	Timeout: ALERT_DELIVERY_TIMEOUT,
+	Transport: &http.Transport{
+		Proxy: nil,
+		DialContext: (&net.Dialer{
+			Timeout: ALERT_DELIVERY_TIMEOUT,
+			Control: alertDialControl,
+		}).DialContext,
+		TLSHandshakeTimeout: ALERT_DELIVERY_TIMEOUT,
+		MaxIdleConns:        10,
+		IdleConnTimeout:     90 * time.Second,
+	},
+	CheckRedirect: func(request *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// alertDialControl refuses connections of the alert client to blocked addresses. It runs after DNS
+// resolution, on the IP actually connected to.
+func alertDialControl(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip, err := netip.ParseAddr(host)
+	if err != nil || isBlockedAlertAddress(ip) {
+		return ErrAlertAddressBlocked
+	}
+
+	return nil
+}
+
+// isBlockedAlertAddress reports whether webhook alerts can't be sent to an IP: loopback, private (RFC 1918
+// and IPv6 unique local), shared (RFC 6598), link-local, including cloud metadata at 169.254.169.254,
+// multicast and unspecified addresses.
+func isBlockedAlertAddress(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified() ||
+		sharedAddressSpace.Contains(ip) ||
+		thisNetwork.Contains(ip)
+}
+
+// sharedAddressSpace is the carrier-grade NAT range of RFC 6598, private to the network the server is in
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// thisNetwork is the "this network" range, which reaches the server itself on some systems
+var thisNetwork = netip.MustParsePrefix("0.0.0.0/8")
+
+// AlertSample is a transcript and analysis an alert rule is tested against with /alerts/test.
+type AlertSample struct {
+	// Transcript is checked by keyword and regex conditions
+	Transcript string `json:"transcript"`
+
+	// Role is who said the transcript, "user" for the customer or "assistant". Defaults to "user".
+	Role string `json:"role"`
+
+	// Analysis is checked by negative_score conditions, in the format of VapiAI end-of-call reports
+	Analysis *vapiTypes.Analysis `json:"analysis"`
+}
+
+// AlertNotification is the alert sent by webhook and email actions when a rule triggers.
+type AlertNotification struct {
+	// RuleId is the hex ObjectID of the rule that triggered, empty when testing a rule that isn't stored
+	RuleId string `json:"rule_id"`
+
+	// RuleName is the name of the rule that triggered
+	RuleName string `json:"rule_name"`
+
+	// OrgId is the organization of the call
+	OrgId string `json:"org_id"`
+
+	// VapiCallId is the VapiAI call the rule triggered on, empty for tests
+	VapiCallId string `json:"vapi_call_id"`
+
+	// AssistantId is the VapiAI assistant that handled the call
+	AssistantId string `json:"assistant_id"`
+
+	// CampaignId is the hex ObjectID of the campaign that placed the call, empty for calls created directly
+	CampaignId string `json:"campaign_id"`
+
+	// CustomerNumber is the phone number of the customer
+	CustomerNumber string `json:"customer_number"`
+
+	// Source is the part of the call that matched
+	Source mongodbTypes.AlertSource `json:"source"`
+
+	// Role is who said the matching text, empty for negative_score conditions
+	Role string `json:"role,omitempty"`
+
+	// Match is the text or score that matched
+	Match string `json:"match"`
+
+	// Excerpt is the transcript turn that matched, shortened to ALERT_EXCERPT_LENGTH characters
+	Excerpt string `json:"excerpt,omitempty"`
+
+	// Test is set when the alert was sent from /alerts/test
+	Test bool `json:"test"`
+
+	// TriggeredAt is when the rule triggered
+	TriggeredAt time.Time `json:"triggered_at"`
+}
+
+// AlertDelivery is the outcome of an action run by /alerts/test.
+type AlertDelivery struct {
+	// Type is the kind of action
+	Type mongodbTypes.AlertActionType `json:"type"`
+
+	// Error is ALERT_DELIVERY_FAILED if the alert couldn't be sent, empty if it was
+	Error string `json:"error,omitempty"`
+}
+
+// AlertTestResult is the outcome of testing an alert rule against a sample.
+type AlertTestResult struct {
+	// Matched is whether the rule would trigger on the sample
+	Matched bool `json:"matched"`
+
+	// Match is the text or score that matched
+	Match string `json:"match,omitempty"`
+
+	// Deliveries are the outcomes of the webhook and email actions, if they were run
+	Deliveries []AlertDelivery `json:"deliveries"`
+}
+
+// alertCandidate is a piece of a call checked against alert rules.
+type alertCandidate struct {
+	source   mongodbTypes.AlertSource
+	role     string
+	text     string
+	analysis *vapiTypes.Analysis
+}
+
+// alertMatch is what an alert rule matched in a call.
+type alertMatch struct {
+	candidate alertCandidate
+	match     string
+}
+
+// GetAlertRules returns the alert rules of an organization.
+func GetAlertRules(orgId string) ([]mongodbTypes.AlertRule, error) {
+	rules, err := mongodb.GetAlertRules(orgId, false)
+	if err != nil {
+		log.Printf("Error getting alert rules: %v", err)
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+// CreateAlertRule validates and stores an alert rule of the caller's organization.
+// Returns a *ValidationError if the rule is invalid or the organization already has MAX_ALERT_RULES rules.
+func CreateAlertRule(caller Caller, rule mongodbTypes.AlertRule) (*mongo.InsertOneResult, error) {
+	if err := validateAlertRule(&rule); err != nil {
+		return nil, err
+	}
+
+	count, err := mongodb.CountAlertRules(caller.OrgId)
+	if err != nil {
+		log.Printf("Error counting alert rules: %v", err)
+		return nil, err
+	}
+	if count >= MAX_ALERT_RULES {
+		return nil, &ValidationError{Errors: []FieldError{{Field: "rule", Message: fmt.Sprintf("an organization can have at most %d alert rules", MAX_ALERT_RULES)}}}
+	}
+
+	now := time.Now()
+	rule.Id = bson.NewObjectID()
+	rule.TriggerCount = 0
+	rule.LastTriggeredAt = nil
+	rule.CreatedAt = now
+	rule.UpdatedAt = now
+
+	result, err := mongodb.CreateAlertRule(caller.OrgId, rule)
+	if err != nil {
+		log.Printf("Error creating alert rule: %v", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// UpdateAlertRule validates and replaces the name, enabled flag, condition and actions of an alert rule
+// of the caller's organization, keeping its trigger count.
+// Returns ErrNotFound if the organization has no such rule, or a *ValidationError if the rule is invalid.
+func UpdateAlertRule(caller Caller, ruleId string, rule mongodbTypes.AlertRule) (*mongo.UpdateResult, error) {
+	existing, err := AuthorizeAlertRule(caller, "alerts.update", ruleId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateAlertRule(&rule); err != nil {
+		return nil, err
+	}
+	rule.Id = existing.Id
+
+	result, err := mongodb.UpdateAlertRule(caller.OrgId, rule)
+	if err != nil {
+		log.Printf("Error updating alert rule %s: %v", ruleId, err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// DeleteAlertRule deletes an alert rule of the caller's organization.
+// Returns ErrNotFound if the organization has no such rule.
+func DeleteAlertRule(caller Caller, ruleId string) (*mongo.DeleteResult, error) {
+	rule, err := AuthorizeAlertRule(caller, "alerts.delete", ruleId)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := mongodb.DeleteAlertRule(caller.OrgId, rule.Id)
+	if err != nil {
+		log.Printf("Error deleting alert rule %s: %v", ruleId, err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// TestAlertRule checks whether an alert rule would trigger on a sample, without counting a trigger.
+// The rule is the caller's stored rule with the given ID, or the given rule if ruleId is empty,
+// so a rule can be tried before it is created. If deliver is set and the rule matches, its webhook
+// and email actions are run with a test alert; flag actions are skipped since the sample has no call.
+// Returns ErrNotFound if the organization has no such rule, or a *ValidationError if the rule or sample is invalid.
+func TestAlertRule(caller Caller, ruleId string, rule *mongodbTypes.AlertRule, sample AlertSample, deliver bool) (*AlertTestResult, error) {
+	if ruleId != "" {
+		stored, err := AuthorizeAlertRule(caller, "alerts.test", ruleId)
+		if err != nil {
+			return nil, err
+		}
+		rule = stored
+	} else if rule == nil {
+		return nil, &ValidationError{Errors: []FieldError{{Field: "rule", Message: "is required without a ruleId"}}}
+	} else if err := validateAlertRule(rule); err != nil {
+		return nil, err
+	}
+
+	invalid := &ValidationError{}
+	role := strings.TrimSpace(sample.Role)
+	if role == "" {
+		role = "user"
+	}
+	if role != "user" && role != "assistant" {
+		invalid.Errors = append(invalid.Errors, FieldError{Field: "sample.role", Value: sample.Role, Message: "must be user or assistant"})
+	}
+	if strings.TrimSpace(sample.Transcript) == "" && sample.Analysis == nil {
+		invalid.Errors = append(invalid.Errors, FieldError{Field: "sample", Message: "must have a transcript or an analysis"})
+	}
+	if err := invalid.orNil(); err != nil {
+		return nil, err
+	}
+
+	candidates := []alertCandidate{}
+	if sample.Transcript != "" {
+		candidates = append(candidates, alertCandidate{source: mongodbTypes.ALERT_SOURCE_TEST, role: role, text: sample.Transcript})
+	}
+	if sample.Analysis != nil {
+		candidates = append(candidates, alertCandidate{source: mongodbTypes.ALERT_SOURCE_TEST, analysis: sample.Analysis})
+	}
+
+	result := &AlertTestResult{Deliveries: []AlertDelivery{}}
+	match, ok := matchAlertRule(*rule, candidates)
+	if !ok {
+		return result, nil
+	}
+	result.Matched = true
+	result.Match = match.match
+
+	if !deliver {
+		return result, nil
+	}
+
+	notification := alertNotification(caller.OrgId, *rule, match)
+	notification.Test = true
+
+	actions := []mongodbTypes.AlertAction{}
+	for _, action := range rule.Actions {
+		if action.Type != mongodbTypes.ALERT_ACTION_FLAG {
+			actions = append(actions, action)
+			result.Deliveries = append(result.Deliveries, AlertDelivery{Type: action.Type})
+		}
+	}
+
+	// Actions are sent at once, so the test answers within ALERT_DELIVERY_TIMEOUT
+	var wg sync.WaitGroup
+	for i, action := range actions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := sendAlert(action, notification); err != nil {
+				log.Printf("[Alerts] Error sending test %s alert of rule %q for organization %s: %v", action.Type, rule.Name, caller.OrgId, err)
+				result.Deliveries[i].Error = ALERT_DELIVERY_FAILED
+			}
+		}()
+	}
+	wg.Wait()
+
+	return result, nil
+}
+
+// validateAlertRule checks an alert rule and normalizes its name, keywords, role and email recipients.
+// Fields that don't apply to the rule's condition are cleared.
+// Returns a *ValidationError listing the invalid fields.
+func validateAlertRule(rule *mongodbTypes.AlertRule) error {
+	invalid := &ValidationError{}
+	condition := &rule.Condition
+
+	rule.Name = strings.TrimSpace(rule.Name)
+	if rule.Name == "" {
+		invalid.Errors = append(invalid.Errors, FieldError{Field: "name", Message: "is required"})
+	} else if len([]rune(rule.Name)) > ALERT_RULE_NAME_MAX_LENGTH {
+		invalid.Errors = append(invalid.Errors, FieldError{Field: "name", Value: rule.Name, Message: fmt.Sprintf("must be at most %d characters", ALERT_RULE_NAME_MAX_LENGTH)})
+	}
+
+	switch condition.Type {
+	case mongodbTypes.ALERT_CONDITION_KEYWORD, mongodbTypes.ALERT_CONDITION_REGEX:
+		condition.Field = ""
+		condition.Threshold = 0
+
+		condition.Role = strings.TrimSpace(condition.Role)
+		if condition.Role != "" && condition.Role != "user" && condition.Role != "assistant" {
+			invalid.Errors = append(invalid.Errors, FieldError{Field: "condition.role", Value: condition.Role, Message: "must be user, assistant or empty for both"})
+		}
+	case mongodbTypes.ALERT_CONDITION_NEGATIVE_SCORE:
+		condition.Keywords = nil
+		condition.Pattern = ""
+		condition.Role = ""
+
+		field := condition.Field
+		if field != ANALYSIS_FIELD_SUCCESS_EVALUATION &&
+			!(strings.HasPrefix(field, ANALYSIS_FIELD_STRUCTURED_DATA) && metadataKeyPattern.MatchString(strings.TrimPrefix(field, ANALYSIS_FIELD_STRUCTURED_DATA))) {
+			invalid.Errors = append(invalid.Errors, FieldError{Field: "condition.field", Value: field, Message: "must be success_evaluation or structured_data.<key>"})
+		}
+	default:
+		invalid.Errors = append(invalid.Errors, FieldError{Field: "condition.type", Value: string(condition.Type), Message: "must be keyword, regex or negative_score"})
+	}
+
+	if condition.Type == mongodbTypes.ALERT_CONDITION_KEYWORD {
+		condition.Pattern = ""
+
+		keywords := []string{}
+		empty := len(condition.Keywords) == 0
+		for i, keyword := range condition.Keywords {
+			keyword = strings.Join(strings.Fields(keyword), " ")
+			if keyword == "" {
+				invalid.Errors = append(invalid.Errors, FieldError{Field: fmt.Sprintf("condition.keywords[%d]", i), Message: "must not be empty"})
+				continue
+			}
+			keywords = append(keywords, keyword)
+		}
+		condition.Keywords = keywords
+
+		if empty {
+			invalid.Errors = append(invalid.Errors, FieldError{Field: "condition.keywords", Message: "must have at least one keyword"})
+		} else if len(condition.Keywords) > MAX_ALERT_KEYWORDS {
+			invalid.Errors = append(invalid.Errors, FieldError{Field: "condition.keywords", Message: fmt.Sprintf("must have at most %d keywords", MAX_ALERT_KEYWORDS)})
+		}
+	}
+
+	if condition.Type == mongodbTypes.ALERT_CONDITION_REGEX {
+		condition.Keywords = nil
+
+		if condition.Pattern == "" {
+			invalid.Errors = append(invalid.Errors, FieldError{Field: "condition.pattern", Message: "is required"})
+		} else if len(condition.Pattern) > MAX_ALERT_PATTERN_LENGTH {
+			invalid.Errors = append(invalid.Errors, FieldError{Field: "condition.pattern", Value: condition.Pattern, Message: fmt.Sprintf("must be at most %d characters", MAX_ALERT_PATTERN_LENGTH)})
+		} else if _, err := regexp.Compile(condition.Pattern); err != nil {
+			invalid.Errors = append(invalid.Errors, FieldError{Field: "condition.pattern", Value: condition.Pattern, Message: err.Error()})
+		}
+	}
+
+	if len(rule.Actions) == 0 {
+		invalid.Errors = append(invalid.Errors, FieldError{Field: "actions", Message: "must have at least one action"})
+	} else if len(rule.Actions) > MAX_ALERT_ACTIONS {
+		invalid.Errors = append(invalid.Errors, FieldError{Field: "actions", Message: fmt.Sprintf("must have at most %d actions", MAX_ALERT_ACTIONS)})
+	}
+
+	for i := range rule.Actions {
+		action := &rule.Actions[i]
+		field := fmt.Sprintf("actions[%d]", i)
+
+		switch action.Type {
+		case mongodbTypes.ALERT_ACTION_WEBHOOK:
+			action.Recipients = nil
+
+			target, err := url.Parse(action.Url)
+			if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Hostname() == "" {
+				invalid.Errors = append(invalid.Errors, FieldError{Field: field + ".url", Value: action.Url, Message: "must be an absolute http or https URL"})
+			} else if ip, err := netip.ParseAddr(target.Hostname()); (err == nil && isBlockedAlertAddress(ip)) || strings.EqualFold(target.Hostname(), "localhost") {
+				invalid.Errors = append(invalid.Errors, FieldError{Field: field + ".url", Value: action.Url, Message: "must not be a loopback, private or link-local address"})
+			}
+		case mongodbTypes.ALERT_ACTION_EMAIL:
+			action.Url = ""
+			action.Secret = ""
+
+			if os.Getenv("SMTP_HOST") == "" {
+				invalid.Errors = append(invalid.Errors, FieldError{Field: field + ".type", Value: string(action.Type), Message: "email alerts are not configured on this server (SMTP_HOST is not set)"})
+			}
+			if len(action.Recipients) == 0 {
+				invalid.Errors = append(invalid.Errors, FieldError{Field: field + ".recipients", Message: "must have at least one email address"})
+			}
+			for j, recipient := range action.Recipients {
+				address, err := mail.ParseAddress(recipient)
+				if err != nil {
+					invalid.Errors = append(invalid.Errors, FieldError{Field: fmt.Sprintf("%s.recipients[%d]", field, j), Value: recipient, Message: "must be an email address"})
+					continue
+				}
+				action.Recipients[j] = address.Address
+			}
+		case mongodbTypes.ALERT_ACTION_FLAG:
+			action.Url = ""
+			action.Secret = ""
+			action.Recipients = nil
+		default:
+			invalid.Errors = append(invalid.Errors, FieldError{Field: field + ".type", Value: string(action.Type), Message: "must be webhook, email or flag"})
+		}
+	}
+
+	return invalid.orNil()
+}
+
+// evaluateAlerts checks a server message against the organization's enabled alert rules and raises
+// the alerts of the rules that match. Final transcripts are checked while the call is in progress, and
+// the full transcript and analysis of the end-of-call report once it ends, catching what live transcripts
+// missed. Failures are logged rather than returned, so an alert never makes VapiAI send the message again.
+func evaluateAlerts(orgId string, message vapiTypes.ServerMessage, campaignId string) {
+	candidates := []alertCandidate{}
+
+	switch {
+	case streamEventName(message) == STREAM_EVENT_TRANSCRIPT:
+		candidates = append(candidates, alertCandidate{
+			source: mongodbTypes.ALERT_SOURCE_LIVE_TRANSCRIPT,
+			role:   alertRoles[message.Role],
+			text:   message.Transcript,
+		})
+	case message.Type == vapiTypes.MESSAGE_END_OF_CALL_REPORT:
+		for _, turn := range transcriptMessagesFromServerMessage(message.Artifact) {
+			candidates = append(candidates, alertCandidate{
+				source: mongodbTypes.ALERT_SOURCE_FINAL_TRANSCRIPT,
+				role:   alertRoles[turn.Role],
+				text:   turn.Message,
+			})
+		}
+		if message.Analysis != nil {
+			candidates = append(candidates, alertCandidate{source: mongodbTypes.ALERT_SOURCE_ANALYSIS, analysis: message.Analysis})
+		}
+	}
+	if len(candidates) == 0 {
+		return
+	}
+
+	rules, err := mongodb.GetAlertRules(orgId, true)
+	if err != nil {
+		log.Printf("[Alerts] Error getting alert rules of organization %s: %v", orgId, err)
+		return
+	}
+
+	for _, rule := range rules {
+		match, ok := matchAlertRule(rule, candidates)
+		if !ok {
+			continue
+		}
+
+		notification := alertNotification(orgId, rule, match)
+		notification.VapiCallId = message.Call.Id
+		notification.AssistantId = message.Call.AssistantId
+		notification.CampaignId = campaignId
+		if message.Call.Customer != nil {
+			notification.CustomerNumber = message.Call.Customer.Number
+		}
+
+		raiseAlert(orgId, rule, notification)
+	}
+}
+
+// raiseAlert records an alert rule triggering on a call and runs its actions, unless the rule already
+// triggered on the call. Flags are added before returning; webhooks and emails are sent in the background.
+func raiseAlert(orgId string, rule mongodbTypes.AlertRule, notification AlertNotification) {
+	recorded, err := mongodb.RecordAlertTrigger(orgId, mongodbTypes.AlertTrigger{
+		Id:         bson.NewObjectID(),
+		RuleId:     rule.Id,
+		VapiCallId: notification.VapiCallId,
+		Source:     notification.Source,
+		Match:      notification.Match,
+		CreatedAt:  notification.TriggeredAt,
+	})
+	if err != nil {
+		log.Printf("[Alerts] Error recording trigger of rule %s on call %s: %v", rule.Id.Hex(), notification.VapiCallId, err)
+		return
+	}
+	if !recorded {
+		return
+	}
+
+	log.Printf("[Alerts] Rule %q of organization %s triggered on call %s (%s: %q)", rule.Name, orgId, notification.VapiCallId, notification.Source, notification.Match)
+
+	for _, action := range rule.Actions {
+		if action.Type == mongodbTypes.ALERT_ACTION_FLAG {
+			err := mongodb.AddCallFlag(orgId, notification.VapiCallId, mongodbTypes.CallFlag{
+				RuleId:    rule.Id,
+				RuleName:  rule.Name,
+				Source:    notification.Source,
+				Match:     notification.Match,
+				FlaggedAt: notification.TriggeredAt,
+			})
+			if err != nil {
+				log.Printf("[Alerts] Error flagging call %s: %v", notification.VapiCallId, err)
+			}
+			continue
+		}
+
+		go deliverAlert(action, notification)
+	}
+}
+
+// matchAlertRule returns the first piece of a call matching an alert rule's condition, and whether one did.
+func matchAlertRule(rule mongodbTypes.AlertRule, candidates []alertCandidate) (alertMatch, bool) {
+	condition := rule.Condition
+
+	for _, candidate := range candidates {
+		switch condition.Type {
+		case mongodbTypes.ALERT_CONDITION_KEYWORD, mongodbTypes.ALERT_CONDITION_REGEX:
+			if candidate.text == "" || (condition.Role != "" && condition.Role != candidate.role) {
+				continue
+			}
+
+			pattern, err := alertPattern(condition)
+			if err != nil {
+				log.Printf("[Alerts] Invalid pattern of rule %s: %v", rule.Id.Hex(), err)
+				return alertMatch{}, false
+			}
+
+			found := pattern.FindStringSubmatch(candidate.text)
+			if found == nil {
+				continue
+			}
+
+			// Keyword patterns capture the keyword without the characters around it
+			match := found[0]
+			if condition.Type == mongodbTypes.ALERT_CONDITION_KEYWORD {
+				match = found[1]
+			}
+			return alertMatch{candidate: candidate, match: match}, true
+		case mongodbTypes.ALERT_CONDITION_NEGATIVE_SCORE:
+			if candidate.analysis == nil {
+				continue
+			}
+
+			score, ok := alertScore(candidate.analysis, condition.Field)
+			if !ok || score > condition.Threshold {
+				continue
+			}
+			return alertMatch{candidate: candidate, match: strconv.FormatFloat(score, 'f', -1, 64)}, true
+		}
+	}
+
+	return alertMatch{}, false
+}
+
+// alertPattern returns the compiled regular expression of a keyword or regex condition.
+// Keywords match as whole words or phrases, ignoring case; the first submatch of the expression is the matched keyword.
+func alertPattern(condition mongodbTypes.AlertCondition) (*regexp.Regexp, error) {
+	source := condition.Pattern
+	if condition.Type == mongodbTypes.ALERT_CONDITION_KEYWORD {
+		keywords := []string{}
+		for _, keyword := range condition.Keywords {
+			// Any run of spaces in the transcript matches a space of the keyword
+			keywords = append(keywords, strings.ReplaceAll(regexp.QuoteMeta(keyword), " ", `\s+`))
+		}
+		source = `(?i)(?:^|[^\pL\pN_])(` + strings.Join(keywords, "|") + `)(?:[^\pL\pN_]|$)`
+	}
+
+	if pattern, ok := alertPatterns.Load(source); ok {
+		return pattern.(*regexp.Regexp), nil
+	}
+
+	pattern, err := regexp.Compile(source)
+	if err != nil {
+		return nil, err
+	}
+	alertPatterns.Store(source, pattern)
+	return pattern, nil
+}
+
+// alertScore returns the numeric value of an analysis field, and whether it has one.
+// Numbers and numeric strings are read as is; booleans, such as a pass/fail success evaluation, score 1 or 0.
+func alertScore(analysis *vapiTypes.Analysis, field string) (float64, bool) {
+	value, ok := analysisValue(analysis, field)
+	if !ok {
+		return 0, false
+	}
+
+	switch value := value.(type) {
+	case float64:
+		return value, true
+	case int:
+		return float64(value), true
+	case int32:
+		return float64(value), true
+	case int64:
+		return float64(value), true
+	case bool:
+		if value {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		value = strings.TrimSpace(value)
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			if parsed {
+				return 1, true
+			}
+			return 0, true
+		}
+		score, err := strconv.ParseFloat(value, 64)
+		return score, err == nil
+	}
+
+	return 0, false
+}
+
+// alertNotification builds the alert sent for a match of a rule. The call fields are left for the caller to fill in.
+func alertNotification(orgId string, rule mongodbTypes.AlertRule, match alertMatch) AlertNotification {
+	notification := AlertNotification{
+		RuleName:    rule.Name,
+		OrgId:       orgId,
+		Source:      match.candidate.source,
+		Role:        match.candidate.role,
+		Match:       match.match,
+		TriggeredAt: time.Now(),
+	}
+	if !rule.Id.IsZero() {
+		notification.RuleId = rule.Id.Hex()
+	}
+
+	excerpt := []rune(match.candidate.text)
+	if len(excerpt) > ALERT_EXCERPT_LENGTH {
+		excerpt = append(excerpt[:ALERT_EXCERPT_LENGTH], '…')
+	}
+	notification.Excerpt = string(excerpt)
+
+	return notification
+}
+
+// deliverAlert sends an alert with a webhook or email action, trying again up to ALERT_DELIVERY_ATTEMPTS times.
+func deliverAlert(action mongodbTypes.AlertAction, notification AlertNotification) {
+	for attempt := 1; attempt <= ALERT_DELIVERY_ATTEMPTS; attempt++ {
+		err := sendAlert(action, notification)
+		if err == nil {
+			return
+		}
+
+		log.Printf("[Alerts] Error sending %s alert of rule %q for call %s (attempt %d of %d): %v", action.Type, notification.RuleName, notification.VapiCallId, attempt, ALERT_DELIVERY_ATTEMPTS, err)
+		if attempt < ALERT_DELIVERY_ATTEMPTS {
+			time.Sleep(time.Duration(attempt) * ALERT_RETRY_DELAY)
+		}
+	}
+}
+
+// sendAlert sends an alert once with a webhook or email action.
+func sendAlert(action mongodbTypes.AlertAction, notification AlertNotification) error {
+	switch action.Type {
+	case mongodbTypes.ALERT_ACTION_WEBHOOK:
+		return postAlertWebhook(action, notification)
+	case mongodbTypes.ALERT_ACTION_EMAIL:
+		return sendAlertEmail(action.Recipients, notification)
+	}
+	return fmt.Errorf("%s actions are not sent", action.Type)
+}
+
+// postAlertWebhook posts an alert as JSON to the URL of a webhook action, signed with its secret if it has one.
+func postAlertWebhook(action mongodbTypes.AlertAction, notification AlertNotification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequest(http.MethodPost, action.Url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if action.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(action.Secret))
+		mac.Write(body)
+		request.Header.Set("X-Sarah-Signature", hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	response, err := alertClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", response.Status)
+	}
+
+	return nil
+}
+
+// sendAlertEmail emails an alert through the SMTP server configured with SMTP_HOST and SMTP_PORT (default 587).
+// Port 465 uses implicit TLS; other ports upgrade with STARTTLS when the server offers it. The server is
+// authenticated with SMTP_USERNAME and SMTP_PASSWORD when set, and the email is sent from SMTP_FROM.
+func sendAlertEmail(recipients []string, notification AlertNotification) error {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		return errors.New("SMTP_HOST is not set")
+	}
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+	from := os.Getenv("SMTP_FROM")
+	if from == "" {
+		from = os.Getenv("SMTP_USERNAME")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ALERT_DELIVERY_TIMEOUT)
+	defer cancel()
+
+	address := net.JoinHostPort(host, port)
+	var conn net.Conn
+	var err error
+	if port == "465" {
+		conn, err = (&tls.Dialer{Config: &tls.Config{ServerName: host}}).DialContext(ctx, "tcp", address)
+	} else {
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok && port != "465" {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if username := os.Getenv("SMTP_USERNAME"); username != "" {
+		if err := client.Auth(smtp.PlainAuth("", username, os.Getenv("SMTP_PASSWORD"), host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	for _, recipient := range recipients {
+		if err := client.Rcpt(recipient); err != nil {
+			return err
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(alertEmail(from, recipients, notification)); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// alertEmail returns the message sent by email actions, headers included.
+func alertEmail(from string, recipients []string, notification AlertNotification) []byte {
+	subject := fmt.Sprintf("Sarah alert: %s", notification.RuleName)
+	if notification.Test {
+		subject = "[Test] " + subject
+	}
+
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", from)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(recipients, ", "))
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&message, "Date: %s\r\n", notification.TriggeredAt.Format(time.RFC1123Z))
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+
+	fmt.Fprintf(&message, "The alert rule %q triggered.\r\n\r\n", notification.RuleName)
+	for _, line := range [][2]string{
+		{"Call", notification.VapiCallId},
+		{"Customer", notification.CustomerNumber},
+		{"Assistant", notification.AssistantId},
+		{"Campaign", notification.CampaignId},
+		{"Source", string(notification.Source)},
+		{"Said by", notification.Role},
+		{"Match", notification.Match},
+		{"Time", notification.TriggeredAt.UTC().Format(time.RFC3339)},
+	} {
+		if line[1] != "" {
+			fmt.Fprintf(&message, "%s: %s\r\n", line[0], line[1])
+		}
+	}
+	if notification.Excerpt != "" {
+		fmt.Fprintf(&message, "\r\n> %s\r\n", strings.ReplaceAll(notification.Excerpt, "\n", "\r\n> "))
+	}
+
+	return message.Bytes()
+}
@@ -0,0 +1,111 @@
+package sarah
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"sarah/mongodb"
+	mongodbTypes "sarah/types/mongodb"
+)
+
+// GetCampaignAnalytics computes the progress metrics of a campaign from the calls
+// stored in the organization's database. Buckets are computed in the campaign's
+// timezone so a "day" matches the day the campaign was scheduled for.
+// Returns ErrNotFound if the caller's organization has no such campaign.
+func GetCampaignAnalytics(caller Caller, campaignId string, from *time.Time, to *time.Time, interval mongodbTypes.AnalyticsInterval) (*mongodbTypes.CampaignAnalytics, error) {
+	if interval == "" {
+		interval = mongodbTypes.INTERVAL_DAY
+	}
+
+	if interval != mongodbTypes.INTERVAL_DAY && interval != mongodbTypes.INTERVAL_WEEK {
+		return nil, fmt.Errorf("interval %s not supported", interval)
+	}
+
+	if from != nil && to != nil && !from.Before(*to) {
+		return nil, fmt.Errorf("from must be before to")
+	}
+
+	campaign, err := AuthorizeCampaign(caller, "campaigns.analytics", campaignId)
+	if err != nil {
+		return nil, err
+	}
+
+	timezone := getTimezoneLocation(campaign.TimeZone).String()
+
+	analytics, err := mongodb.GetCampaignAnalytics(caller.OrgId, campaignId, from, to, interval, timezone)
+	if err != nil {
+		log.Printf("Error aggregating campaign analytics: %v", err)
+		return nil, err
+	}
+
+	return analytics, nil
+}
+
+// SUCCESS_SCORE_THRESHOLD is the share of the maximum score a numeric success evaluation must reach
+// for the call to count as successful, e.g. 7 on a 1-10 scale or 70 on a percentage scale.
+const SUCCESS_SCORE_THRESHOLD = 0.7
+
+// successfulEvaluations and unsuccessfulEvaluations map the verdicts of the VapiAI rubrics that
+// don't evaluate to a number: pass/fail, descriptive and Likert scales, and letter grades.
+var (
+	successfulEvaluations = map[string]bool{
+		"true": true, "pass": true, "passed": true, "yes": true,
+		"excellent": true, "good": true,
+		"strongly agree": true, "agree": true,
+		"a+": true, "a": true, "a-": true, "b+": true, "b": true, "b-": true, "c+": true, "c": true, "c-": true,
+	}
+	unsuccessfulEvaluations = map[string]bool{
+		"false": true, "fail": true, "failed": true, "no": true,
+		"fair": true, "poor": true,
+		"neutral": true, "disagree": true, "strongly disagree": true,
+		"d+": true, "d": true, "d-": true, "e": true, "f": true,
+	}
+)
+
+// evaluationSuccessful normalizes a VapiAI success evaluation into whether the call succeeded,
+// so calls evaluated with different rubrics can be counted together. Numbers are read on a
+// 1-10 scale, or a 0-100 scale above 10, and succeed at SUCCESS_SCORE_THRESHOLD; percentages
+// and "7/10" scores are read as is. Returns nil for an empty or unrecognized evaluation.
+func evaluationSuccessful(evaluation string) *bool {
+	value := strings.ToLower(strings.TrimSpace(evaluation))
+	if value == "" {
+		return nil
+	}
+
+	if successfulEvaluations[value] || unsuccessfulEvaluations[value] {
+		successful := successfulEvaluations[value]
+		return &successful
+	}
+
+	scale := 0.0
+	if score, max, found := strings.Cut(value, "/"); found {
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(max), 64)
+		if err != nil || parsed <= 0 {
+			return nil
+		}
+		value, scale = strings.TrimSpace(score), parsed
+	} else if score, found := strings.CutSuffix(value, "%"); found {
+		value, scale = strings.TrimSpace(score), 100
+	}
+
+	score, err := strconv.ParseFloat(value, 64)
+	if err != nil || score < 0 {
+		return nil
+	}
+	if scale == 0 {
+		switch {
+		case score <= 10:
+			scale = 10
+		case score <= 100:
+			scale = 100
+		default:
+			return nil
+		}
+	}
+
+	successful := score >= SUCCESS_SCORE_THRESHOLD*scale
+	return &successful
+}
@@ -0,0 +1,56 @@
+package sarah
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestEvaluationSuccessful(t *testing.T) {
+	tests := []struct {
+		evaluation string
+		want       string
+	}{
+		{"true", "true"},
+		{"false", "false"},
+		{"Pass", "true"},
+		{"FAIL", "false"},
+		{"8", "true"},
+		{"7", "true"},
+		{"6.5", "false"},
+		{"1", "false"},
+		{"85", "true"},
+		{"40", "false"},
+		{"72%", "true"},
+		{"9%", "false"},
+		{"3/5", "false"},
+		{"4/5", "true"},
+		{"Excellent", "true"},
+		{"Poor", "false"},
+		{"Strongly Agree", "true"},
+		{"Neutral", "false"},
+		{"B+", "true"},
+		{"F", "false"},
+		{"", "nil"},
+		{"  ", "nil"},
+		{"150", "nil"},
+		{"-2", "nil"},
+		{"3/0", "nil"},
+		{"the customer was happy", "nil"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.evaluation, func(t *testing.T) {
+			if got := formatSuccessful(evaluationSuccessful(test.evaluation)); got != test.want {
+				t.Errorf("evaluationSuccessful(%q) = %s, want %s", test.evaluation, got, test.want)
+			}
+		})
+	}
+}
+
+// formatSuccessful formats a normalized evaluation as "true", "false" or "nil".
+func formatSuccessful(successful *bool) string {
+	if successful == nil {
+		return "nil"
+	}
+	return strconv.FormatBool(*successful)
+}
@@ -0,0 +1,356 @@
+package sarah
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"time"
+
+	"sarah/clerk"
+	"sarah/mongodb"
+	mongodbTypes "sarah/types/mongodb"
+
+	vapiApi "github.com/VapiAI/server-sdk-go"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// ErrRepairNotApplicable is returned when a drift repair doesn't match the assistant's current state in VapiAI,
+// e.g. unregistering an assistant that still exists.
+var ErrRepairNotApplicable = errors.New("the repair doesn't apply to the assistant's current state in VapiAI")
+
+const (
+	// DRIFT_TRIGGER_SCHEDULED identifies a report of the AssistantDriftChecker
+	DRIFT_TRIGGER_SCHEDULED = "scheduled"
+
+	// DRIFT_TRIGGER_MANUAL identifies a report requested through the API
+	DRIFT_TRIGGER_MANUAL = "manual"
+)
+
+// AssistantDriftChecker periodically compares every organization's assistant records with VapiAI,
+// so assistants renamed or deleted in the VapiAI dashboard are reported instead of silently going stale.
+type AssistantDriftChecker struct {
+	// Interval is the time between two checks, defaults to 1 hour
+	Interval time.Duration
+}
+
+func (c *AssistantDriftChecker) Start() {
+	if c.Interval <= 0 {
+		c.Interval = time.Hour
+	}
+
+	go func() {
+		c.run()
+	}()
+}
+
+func (c *AssistantDriftChecker) run() {
+	for {
+		allOrgIDs, err := clerk.GetAllOrganizations()
+		if err != nil {
+			log.Printf("[AssistantDriftChecker] Error getting organizations: %v", err)
+		}
+
+		for _, id := range allOrgIDs {
+			_, err := checkAssistantDrift(id, DRIFT_TRIGGER_SCHEDULED)
+			if errors.Is(err, ErrCircuitOpen) {
+				// Every organization would fail the same way, the next check tries again
+				log.Printf("[AssistantDriftChecker] VapiAI is unavailable, check paused until the next run")
+				break
+			} else if err != nil {
+				log.Printf("[AssistantDriftChecker] Error checking assistants of organization %s: %v", id, err)
+			}
+		}
+
+		time.Sleep(c.Interval)
+	}
+}
+
+// GetAssistantDrift returns the latest assistant drift report of the caller's organization. The assistants
+// are checked now when refresh is set or they were never checked.
+func GetAssistantDrift(caller Caller, refresh bool) (*mongodbTypes.AssistantDriftReport, error) {
+	if !refresh {
+		report, err := mongodb.GetAssistantDriftReport(caller.OrgId)
+		if err == nil {
+			return report, nil
+		} else if !errors.Is(err, mongo.ErrNoDocuments) {
+			log.Printf("Error getting assistant drift report: %v", err)
+			return nil, err
+		}
+	}
+
+	return checkAssistantDrift(caller.OrgId, DRIFT_TRIGGER_MANUAL)
+}
+
+// checkAssistantDrift looks up every assistant of the organization in VapiAI, as well as the assistants its
+// campaigns use without them being registered, and replaces the organization's drift report.
+// The check stops, keeping the previous report, if VapiAI fails for another reason than a missing assistant.
+func checkAssistantDrift(orgId string, trigger string) (*mongodbTypes.AssistantDriftReport, error) {
+	assistants, err := mongodb.GetOrganizationAssistants(orgId)
+	if err != nil {
+		log.Printf("Error getting organization assistants: %v", err)
+		return nil, err
+	}
+
+	campaigns, err := mongodb.GetCampaignByOrgId(orgId)
+	if err != nil {
+		log.Printf("Error getting organization campaigns: %v", err)
+		return nil, err
+	}
+
+	campaignIds := map[string][]string{}
+	for _, campaign := range campaigns {
+		if campaign.AssistantId != "" {
+			campaignIds[campaign.AssistantId] = append(campaignIds[campaign.AssistantId], campaign.Id.Hex())
+		}
+	}
+
+	report := &mongodbTypes.AssistantDriftReport{
+		Trigger:   trigger,
+		Issues:    []mongodbTypes.AssistantDrift{},
+		CheckedAt: time.Now(),
+	}
+
+	registered := map[string]bool{}
+	for _, assistant := range assistants {
+		if registered[assistant.VapiAssistantId] {
+			continue
+		}
+		registered[assistant.VapiAssistantId] = true
+
+		vapiAssistant, err := lookupAssistant(assistant.VapiAssistantId)
+		if err != nil {
+			return nil, err
+		}
+		report.Checked++
+
+		drift := mongodbTypes.AssistantDrift{
+			VapiAssistantId: assistant.VapiAssistantId,
+			Name:            assistant.Name,
+			CampaignIds:     nonNilIds(campaignIds[assistant.VapiAssistantId]),
+			Repairs:         []mongodbTypes.AssistantRepair{},
+		}
+		switch {
+		case vapiAssistant == nil:
+			drift.Kind = mongodbTypes.DRIFT_DELETED
+			drift.Repairs = append(drift.Repairs, mongodbTypes.REPAIR_UNREGISTER)
+		case derefString(vapiAssistant.Name) != assistant.Name:
+			drift.Kind = mongodbTypes.DRIFT_NAME
+			drift.VapiName = derefString(vapiAssistant.Name)
+			if drift.VapiName != "" {
+				drift.Repairs = append(drift.Repairs, mongodbTypes.REPAIR_PULL_NAME)
+			}
+			if drift.Name != "" {
+				drift.Repairs = append(drift.Repairs, mongodbTypes.REPAIR_PUSH_NAME)
+			}
+		default:
+			continue
+		}
+		report.Issues = append(report.Issues, drift)
+	}
+
+	unregistered := []string{}
+	for assistantId := range campaignIds {
+		if !registered[assistantId] {
+			unregistered = append(unregistered, assistantId)
+		}
+	}
+	sort.Strings(unregistered)
+
+	for _, assistantId := range unregistered {
+		// An assistant registered by another organization is not reported, so its name isn't disclosed
+		if owner, err := ResolveCallOrganization(assistantId, ""); err == nil && owner != orgId {
+			continue
+		} else if err != nil && !errors.Is(err, ErrOrganizationNotFound) {
+			return nil, err
+		}
+
+		vapiAssistant, err := lookupAssistant(assistantId)
+		if err != nil {
+			return nil, err
+		}
+		report.Checked++
+
+		drift := mongodbTypes.AssistantDrift{
+			Kind:            mongodbTypes.DRIFT_ORPHAN,
+			VapiAssistantId: assistantId,
+			CampaignIds:     campaignIds[assistantId],
+			Repairs:         []mongodbTypes.AssistantRepair{mongodbTypes.REPAIR_REGISTER},
+		}
+		if vapiAssistant == nil {
+			// Nothing left to register, the campaigns need another assistant
+			drift.Kind = mongodbTypes.DRIFT_DELETED
+			drift.Repairs = []mongodbTypes.AssistantRepair{}
+		} else {
+			drift.VapiName = derefString(vapiAssistant.Name)
+		}
+		report.Issues = append(report.Issues, drift)
+	}
+
+	if err := mongodb.SaveAssistantDriftReport(orgId, *report); err != nil {
+		log.Printf("Error saving assistant drift report of organization %s: %v", orgId, err)
+		return nil, err
+	}
+	if len(report.Issues) > 0 {
+		log.Printf("[AssistantDriftChecker] Organization %s: %d of %d assistants drifted from VapiAI", orgId, len(report.Issues), report.Checked)
+	}
+
+	return report, nil
+}
+
+// lookupAssistant returns an assistant from VapiAI, or nil if VapiAI has no such assistant.
+func lookupAssistant(assistantId string) (*vapiApi.Assistant, error) {
+	assistant, err := Telephony.GetAssistant(context.Background(), assistantId)
+	if isVapiNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		log.Printf("Error getting assistant %s: %v", assistantId, err)
+		return nil, err
+	}
+
+	return assistant, nil
+}
+
+// nonNilIds returns ids, or an empty slice if it is nil, so reports list no campaigns as [] rather than null.
+func nonNilIds(ids []string) []string {
+	if ids == nil {
+		return []string{}
+	}
+	return ids
+}
+
+// RepairAssistantDrift applies a repair to an assistant of the caller's drift report and removes the assistant
+// from the report. The assistant's state is looked up in VapiAI again rather than trusting the report.
+// Returns ErrNotFound if the organization hasn't registered the assistant (except for register),
+// ErrRepairNotApplicable if the repair doesn't match the assistant's state, or a *ValidationError
+// if the repair is unknown.
+func RepairAssistantDrift(caller Caller, assistantId string, repair mongodbTypes.AssistantRepair) (*mongodbTypes.AssistantDriftReport, error) {
+	var err error
+	switch repair {
+	case mongodbTypes.REPAIR_PULL_NAME:
+		err = pullAssistantName(caller, assistantId)
+	case mongodbTypes.REPAIR_PUSH_NAME:
+		err = pushAssistantName(caller, assistantId)
+	case mongodbTypes.REPAIR_UNREGISTER:
+		err = unregisterDeletedAssistant(caller, assistantId)
+	case mongodbTypes.REPAIR_REGISTER:
+		err = registerOrphanAssistant(caller, assistantId)
+	default:
+		return nil, &ValidationError{Errors: []FieldError{{
+			Field:   "action",
+			Value:   string(repair),
+			Message: "must be one of pull_name, push_name, unregister, register",
+		}}}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	recordAudit(caller, "assistants.repair", "assistant", assistantId, mongodbTypes.AUDIT_ALLOWED, string(repair))
+
+	report, err := mongodb.RemoveAssistantDrift(caller.OrgId, assistantId)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return &mongodbTypes.AssistantDriftReport{Issues: []mongodbTypes.AssistantDrift{}}, nil
+	} else if err != nil {
+		log.Printf("Error updating assistant drift report: %v", err)
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// pullAssistantName copies the VapiAI name of an assistant of the caller into its record.
+func pullAssistantName(caller Caller, assistantId string) error {
+	if _, err := AuthorizeAssistant(caller, "assistants.repair", assistantId); err != nil {
+		return err
+	}
+
+	vapiAssistant, err := lookupAssistant(assistantId)
+	if err != nil {
+		return err
+	} else if vapiAssistant == nil || derefString(vapiAssistant.Name) == "" {
+		return ErrRepairNotApplicable
+	}
+
+	if _, err := mongodb.UpdateAssistantName(caller.OrgId, assistantId, *vapiAssistant.Name); err != nil {
+		log.Printf("Error renaming assistant %s: %v", assistantId, err)
+		return err
+	}
+
+	return nil
+}
+
+// pushAssistantName renames an assistant of the caller in VapiAI after its record.
+func pushAssistantName(caller Caller, assistantId string) error {
+	assistant, err := AuthorizeAssistant(caller, "assistants.repair", assistantId)
+	if err != nil {
+		return err
+	} else if assistant.Name == "" {
+		return ErrRepairNotApplicable
+	}
+
+	ensureAssistantBaseline(caller, assistantId)
+
+	vapiAssistant, err := Telephony.UpdateAssistant(context.Background(), assistantId, &vapiApi.UpdateAssistantDto{Name: vapiApi.String(assistant.Name)})
+	if isVapiNotFound(err) {
+		return ErrRepairNotApplicable
+	} else if err != nil {
+		log.Printf("Error renaming assistant %s in VapiAI: %v", assistantId, err)
+		return err
+	}
+	snapshotAssistant(caller.OrgId, caller.UserId, vapiAssistant, mongodbTypes.VERSION_UPDATE, 0)
+
+	return nil
+}
+
+// unregisterDeletedAssistant deletes the record of an assistant of the caller that no longer exists in VapiAI.
+func unregisterDeletedAssistant(caller Caller, assistantId string) error {
+	if _, err := AuthorizeAssistant(caller, "assistants.repair", assistantId); err != nil {
+		return err
+	}
+
+	vapiAssistant, err := lookupAssistant(assistantId)
+	if err != nil {
+		return err
+	} else if vapiAssistant != nil {
+		return ErrRepairNotApplicable
+	}
+
+	if _, err := mongodb.DeleteAssistant(caller.OrgId, assistantId); err != nil {
+		log.Printf("Error deleting assistant %s: %v", assistantId, err)
+		return err
+	}
+	callOrganizations.Delete("assistant:" + assistantId)
+
+	return nil
+}
+
+// registerOrphanAssistant registers, with its VapiAI name, an assistant used by the caller's campaigns that
+// exists in VapiAI but isn't registered. Returns ErrNotFound if another organization registered it.
+func registerOrphanAssistant(caller Caller, assistantId string) error {
+	if _, err := ResolveAssistant(caller.OrgId, assistantId); err == nil {
+		return ErrRepairNotApplicable
+	} else if !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	if err := authorizeClaim(caller, "assistants.repair", assistantId, ""); err != nil {
+		return err
+	}
+
+	vapiAssistant, err := lookupAssistant(assistantId)
+	if err != nil {
+		return err
+	} else if vapiAssistant == nil {
+		return ErrRepairNotApplicable
+	}
+
+	assistant := mongodbTypes.Assistant{Name: derefString(vapiAssistant.Name), VapiAssistantId: assistantId}
+	if _, err := mongodb.CreateAssistant(caller.OrgId, assistant); err != nil {
+		log.Printf("Error registering assistant %s: %v", assistantId, err)
+		return err
+	}
+	snapshotAssistant(caller.OrgId, caller.UserId, vapiAssistant, mongodbTypes.VERSION_REGISTER, 0)
+
+	return nil
+}
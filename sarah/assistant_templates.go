@@ -0,0 +1,385 @@
+package sarah
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sarah/mongodb"
+	mongodbTypes "sarah/types/mongodb"
+	"sort"
+	"strings"
+	"time"
+
+	vapiApi "github.com/VapiAI/server-sdk-go"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// ErrBuiltinTemplate is returned when changing or deleting a built-in assistant template.
+var ErrBuiltinTemplate = errors.New("built-in assistant templates can't be changed")
+
+// MAX_ASSISTANT_TEMPLATES is the maximum number of assistant templates of an organization
+const MAX_ASSISTANT_TEMPLATES = 100
+
+// MAX_TEMPLATE_PARAMETERS is the maximum number of parameters of an assistant template
+const MAX_TEMPLATE_PARAMETERS = 20
+
+// ASSISTANT_TEMPLATE_NAME_MAX_LENGTH is the maximum length of the name of an assistant template
+const ASSISTANT_TEMPLATE_NAME_MAX_LENGTH = 100
+
+// ASSISTANT_TEMPLATE_DESCRIPTION_MAX_LENGTH is the maximum length of the description of an assistant template
+const ASSISTANT_TEMPLATE_DESCRIPTION_MAX_LENGTH = 500
+
+// templatePlaceholderPattern matches the {{placeholders}} of an assistant template, the first submatch is the parameter
+var templatePlaceholderPattern = regexp.MustCompile(`\{\{\s*([a-z][a-z0-9_]*)\s*\}\}`)
+
+// templateParameterPattern matches the names of assistant template parameters
+var templateParameterPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// builtinAssistantTemplates are the assistant templates shared by every organization, in the order they are listed
+var builtinAssistantTemplates = []mongodbTypes.AssistantTemplate{
+	{
+		Id:          "insurance-renewal-reminder",
+		Name:        "Insurance renewal reminder",
+		Description: "Reminds customers that their policy is up for renewal and offers to have an agent call them back.",
+		Parameters: []mongodbTypes.TemplateParameter{
+			{Name: "company_name", Description: "Name of the insurance company"},
+			{Name: "agent_name", Description: "Name the assistant introduces itself with", Default: "Sarah"},
+			{Name: "voice_id", Description: "VapiAI voice, e.g. Elliot, Kylie, Rohan, Lily, Savannah, Hana, Neha, Cole, Harry, Paige or Spencer", Default: "Savannah"},
+			{Name: "language", Description: "ISO 639-1 code of the language the customer speaks", Default: "en"},
+		},
+		Assistant: mustParseTemplateAssistant(`{
+			"name": "{{company_name}} renewals",
+			"firstMessage": "Hi, this is {{agent_name}} from {{company_name}}. I'm calling about your insurance policy, which is up for renewal soon. Do you have a minute?",
+			"transcriber": { "provider": "deepgram", "model": "nova-2", "language": "{{language}}" },
+			"voice": { "provider": "vapi", "voiceId": "{{voice_id}}" },
+			"model": {
+				"provider": "openai",
+				"model": "gpt-4o",
+				"messages": [{
+					"role": "system",
+					"content": "You are {{agent_name}}, a friendly renewal assistant calling on behalf of {{company_name}}. Speak in the language with ISO 639-1 code {{language}}. Remind the customer that their policy is due for renewal, answer general questions about the renewal process, and offer to have an agent call them back for anything about prices or coverage. Never quote prices, never collect payment details, and end the call politely if the customer isn't interested."
+				}]
+			},
+			"endCallMessage": "Thank you for your time, and have a great day!",
+			"voicemailMessage": "Hi, this is {{agent_name}} from {{company_name}}. Your insurance policy is up for renewal soon. Please call us back at your convenience."
+		}`),
+	},
+	{
+		Id:          "appointment-confirmation",
+		Name:        "Appointment confirmation",
+		Description: "Confirms an upcoming appointment with the customer and offers to have someone call them back when they need to reschedule. Calls must set the appointment_date and appointment_time variables.",
+		Parameters: []mongodbTypes.TemplateParameter{
+			{Name: "company_name", Description: "Name of the business the appointment is with"},
+			{Name: "agent_name", Description: "Name the assistant introduces itself with", Default: "Sarah"},
+			{Name: "voice_id", Description: "VapiAI voice, e.g. Elliot, Kylie, Rohan, Lily, Savannah, Hana, Neha, Cole, Harry, Paige or Spencer", Default: "Elliot"},
+			{Name: "language", Description: "ISO 639-1 code of the language the customer speaks", Default: "en"},
+		},
+		Assistant: mustParseTemplateAssistant(`{
+			"name": "{{company_name}} appointments",
+			"firstMessage": "Hi, this is {{agent_name}} from {{company_name}}. I'm calling to confirm your appointment on {{appointment_date}}. Is now a good time?",
+			"transcriber": { "provider": "deepgram", "model": "nova-2", "language": "{{language}}" },
+			"voice": { "provider": "vapi", "voiceId": "{{voice_id}}" },
+			"model": {
+				"provider": "openai",
+				"model": "gpt-4o",
+				"messages": [{
+					"role": "system",
+					"content": "You are {{agent_name}}, a friendly scheduling assistant calling on behalf of {{company_name}}. Speak in the language with ISO 639-1 code {{language}}. Confirm that the customer's appointment is on {{appointment_date}} at {{appointment_time}}. If the customer can't make it, ask when suits them better and tell them someone will call them back to reschedule. Keep the call short and never discuss anything but the appointment."
+				}]
+			},
+			"endCallMessage": "Thank you, see you soon!",
+			"voicemailMessage": "Hi, this is {{agent_name}} from {{company_name}} calling to confirm your upcoming appointment. Please call us back if you need to reschedule."
+		}`),
+	},
+}
+
+// mustParseTemplateAssistant parses the assistant of a built-in template, panicking if it isn't a JSON object.
+func mustParseTemplateAssistant(assistant string) map[string]interface{} {
+	var parsed map[string]interface{}
+	if err := json.Unmarshal([]byte(assistant), &parsed); err != nil {
+		panic(fmt.Sprintf("invalid built-in assistant template: %v", err))
+	}
+	return parsed
+}
+
+// builtinAssistantTemplate returns the built-in assistant template with the given ID, or nil if there is none.
+func builtinAssistantTemplate(templateId string) *mongodbTypes.AssistantTemplate {
+	for _, template := range builtinAssistantTemplates {
+		if template.Id == templateId {
+			template.Builtin = true
+			return &template
+		}
+	}
+	return nil
+}
+
+// GetAssistantTemplates returns the built-in assistant templates followed by the templates of an organization.
+func GetAssistantTemplates(orgId string) ([]mongodbTypes.AssistantTemplate, error) {
+	templates, err := mongodb.GetAssistantTemplates(orgId)
+	if err != nil {
+		log.Printf("Error getting assistant templates: %v", err)
+		return nil, err
+	}
+
+	result := make([]mongodbTypes.AssistantTemplate, 0, len(builtinAssistantTemplates)+len(templates))
+	for _, template := range builtinAssistantTemplates {
+		template.Builtin = true
+		result = append(result, template)
+	}
+
+	return append(result, templates...), nil
+}
+
+// CreateAssistantTemplate validates and stores an assistant template of the caller's organization.
+// Returns a *ValidationError if the template is invalid or the organization already has MAX_ASSISTANT_TEMPLATES templates.
+func CreateAssistantTemplate(caller Caller, template mongodbTypes.AssistantTemplate) (*mongo.InsertOneResult, error) {
+	if err := validateAssistantTemplate(&template); err != nil {
+		return nil, err
+	}
+
+	count, err := mongodb.CountAssistantTemplates(caller.OrgId)
+	if err != nil {
+		log.Printf("Error counting assistant templates: %v", err)
+		return nil, err
+	}
+	if count >= MAX_ASSISTANT_TEMPLATES {
+		return nil, &ValidationError{Errors: []FieldError{{Field: "template", Message: fmt.Sprintf("an organization can have at most %d assistant templates", MAX_ASSISTANT_TEMPLATES)}}}
+	}
+
+	now := time.Now()
+	template.Id = bson.NewObjectID().Hex()
+	template.CreatedBy = caller.UserId
+	template.CreatedAt = now
+	template.UpdatedAt = now
+
+	result, err := mongodb.CreateAssistantTemplate(caller.OrgId, template)
+	if err != nil {
+		log.Printf("Error creating assistant template: %v", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// UpdateAssistantTemplate validates and replaces the name, description, parameters and assistant of an
+// assistant template of the caller's organization. Assistants already created from the template are unchanged.
+// Returns ErrBuiltinTemplate for built-in templates, ErrNotFound if the organization has no such template,
+// or a *ValidationError if the template is invalid.
+func UpdateAssistantTemplate(caller Caller, templateId string, template mongodbTypes.AssistantTemplate) (*mongo.UpdateResult, error) {
+	if builtinAssistantTemplate(templateId) != nil {
+		return nil, ErrBuiltinTemplate
+	}
+
+	existing, err := AuthorizeAssistantTemplate(caller, "assistant_templates.update", templateId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateAssistantTemplate(&template); err != nil {
+		return nil, err
+	}
+	template.Id = existing.Id
+
+	result, err := mongodb.UpdateAssistantTemplate(caller.OrgId, template)
+	if err != nil {
+		log.Printf("Error updating assistant template %s: %v", templateId, err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// DeleteAssistantTemplate deletes an assistant template of the caller's organization.
+// Assistants already created from the template are kept.
+// Returns ErrBuiltinTemplate for built-in templates, or ErrNotFound if the organization has no such template.
+func DeleteAssistantTemplate(caller Caller, templateId string) (*mongo.DeleteResult, error) {
+	if builtinAssistantTemplate(templateId) != nil {
+		return nil, ErrBuiltinTemplate
+	}
+
+	template, err := AuthorizeAssistantTemplate(caller, "assistant_templates.delete", templateId)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := mongodb.DeleteAssistantTemplate(caller.OrgId, template.Id)
+	if err != nil {
+		log.Printf("Error deleting assistant template %s: %v", templateId, err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// RenderAssistantTemplate replaces the placeholders of a built-in template, or of a template of the caller's
+// organization, with the given values, falling back to the parameters' defaults.
+// Returns ErrNotFound if there is no such template, or a *ValidationError if a value is missing or unknown,
+// or the rendered assistant isn't a valid VapiAI create assistant request.
+func RenderAssistantTemplate(caller Caller, templateId string, values map[string]string) (*vapiApi.CreateAssistantDto, error) {
+	template := builtinAssistantTemplate(templateId)
+	if template == nil {
+		var err error
+		if template, err = AuthorizeAssistantTemplate(caller, "assistant_templates.render", templateId); err != nil {
+			return nil, err
+		}
+	}
+
+	invalid := &ValidationError{}
+	resolved := map[string]string{}
+	declared := map[string]bool{}
+	for _, parameter := range template.Parameters {
+		declared[parameter.Name] = true
+
+		value := strings.TrimSpace(values[parameter.Name])
+		if value == "" {
+			value = parameter.Default
+		}
+		if value == "" {
+			invalid.Errors = append(invalid.Errors, FieldError{Field: "values." + parameter.Name, Message: "is required"})
+		}
+		resolved[parameter.Name] = value
+	}
+
+	unknown := []string{}
+	for name := range values {
+		if !declared[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		invalid.Errors = append(invalid.Errors, FieldError{Field: "values." + name, Value: values[name], Message: "is not a parameter of the template"})
+	}
+
+	if len(invalid.Errors) > 0 {
+		return nil, invalid
+	}
+
+	assistantCreateDto, err := renderTemplateAssistant(template.Assistant, resolved)
+	if err != nil {
+		return nil, &ValidationError{Errors: []FieldError{{Field: "assistant", Message: err.Error()}}}
+	}
+
+	return assistantCreateDto, nil
+}
+
+// CreateAssistantFromTemplate renders an assistant template with the given values and creates the assistant
+// for the caller's organization, like /assistants/create.
+// Returns the errors of RenderAssistantTemplate and CreateAsisstant.
+func CreateAssistantFromTemplate(caller Caller, templateId string, values map[string]string) (*mongo.InsertOneResult, error) {
+	assistantCreateDto, err := RenderAssistantTemplate(caller, templateId, values)
+	if err != nil {
+		return nil, err
+	}
+
+	return CreateAsisstant(caller, *assistantCreateDto)
+}
+
+// renderTemplateAssistant replaces the placeholders of a template's assistant with JSON-escaped values and
+// reads the result as a VapiAI create assistant request. Placeholders without a value are left as is, so
+// placeholders that don't name a parameter reach VapiAI as dynamic variables filled in when calls are placed.
+func renderTemplateAssistant(assistant map[string]interface{}, values map[string]string) (*vapiApi.CreateAssistantDto, error) {
+	body, err := json.Marshal(assistant)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered := templatePlaceholderPattern.ReplaceAllFunc(body, func(placeholder []byte) []byte {
+		name := string(templatePlaceholderPattern.FindSubmatch(placeholder)[1])
+		value, ok := values[name]
+		if !ok || value == "" {
+			return placeholder
+		}
+
+		escaped, _ := json.Marshal(value)
+		return escaped[1 : len(escaped)-1]
+	})
+
+	var assistantCreateDto vapiApi.CreateAssistantDto
+	if err := json.Unmarshal(rendered, &assistantCreateDto); err != nil {
+		return nil, fmt.Errorf("is not a valid VapiAI assistant: %v", err)
+	} else if derefString(assistantCreateDto.Name) == "" {
+		return nil, errors.New("must have a name")
+	}
+
+	return &assistantCreateDto, nil
+}
+
+// validateAssistantTemplate checks and normalizes an assistant template. Every parameter must be used by the
+// assistant, and the assistant must be a valid VapiAI create assistant request once the parameters' defaults are applied.
+// Returns a *ValidationError listing the invalid fields.
+func validateAssistantTemplate(template *mongodbTypes.AssistantTemplate) error {
+	invalid := &ValidationError{}
+
+	template.Name = strings.TrimSpace(template.Name)
+	if template.Name == "" {
+		invalid.Errors = append(invalid.Errors, FieldError{Field: "name", Message: "is required"})
+	} else if len([]rune(template.Name)) > ASSISTANT_TEMPLATE_NAME_MAX_LENGTH {
+		invalid.Errors = append(invalid.Errors, FieldError{Field: "name", Value: template.Name, Message: fmt.Sprintf("must be at most %d characters", ASSISTANT_TEMPLATE_NAME_MAX_LENGTH)})
+	}
+
+	template.Description = strings.TrimSpace(template.Description)
+	if len([]rune(template.Description)) > ASSISTANT_TEMPLATE_DESCRIPTION_MAX_LENGTH {
+		invalid.Errors = append(invalid.Errors, FieldError{Field: "description", Message: fmt.Sprintf("must be at most %d characters", ASSISTANT_TEMPLATE_DESCRIPTION_MAX_LENGTH)})
+	}
+
+	if len(template.Parameters) > MAX_TEMPLATE_PARAMETERS {
+		invalid.Errors = append(invalid.Errors, FieldError{Field: "parameters", Message: fmt.Sprintf("must have at most %d parameters", MAX_TEMPLATE_PARAMETERS)})
+	}
+	if template.Parameters == nil {
+		template.Parameters = []mongodbTypes.TemplateParameter{}
+	}
+
+	defaults := map[string]string{}
+	declared := map[string]bool{}
+	for i := range template.Parameters {
+		parameter := &template.Parameters[i]
+		field := fmt.Sprintf("parameters[%d]", i)
+
+		parameter.Name = strings.TrimSpace(parameter.Name)
+		parameter.Description = strings.TrimSpace(parameter.Description)
+		parameter.Default = strings.TrimSpace(parameter.Default)
+
+		if !templateParameterPattern.MatchString(parameter.Name) {
+			invalid.Errors = append(invalid.Errors, FieldError{Field: field + ".name", Value: parameter.Name, Message: "must be lowercase letters, digits and underscores, starting with a letter"})
+		} else if declared[parameter.Name] {
+			invalid.Errors = append(invalid.Errors, FieldError{Field: field + ".name", Value: parameter.Name, Message: "is already a parameter of the template"})
+		}
+		declared[parameter.Name] = true
+		defaults[parameter.Name] = parameter.Default
+	}
+
+	if len(template.Assistant) == 0 {
+		invalid.Errors = append(invalid.Errors, FieldError{Field: "assistant", Message: "is required"})
+		return invalid
+	}
+
+	body, err := json.Marshal(template.Assistant)
+	if err != nil {
+		invalid.Errors = append(invalid.Errors, FieldError{Field: "assistant", Message: err.Error()})
+		return invalid
+	}
+
+	used := map[string]bool{}
+	for _, match := range templatePlaceholderPattern.FindAllSubmatch(body, -1) {
+		used[string(match[1])] = true
+	}
+	for i, parameter := range template.Parameters {
+		if parameter.Name != "" && !used[parameter.Name] {
+			invalid.Errors = append(invalid.Errors, FieldError{Field: fmt.Sprintf("parameters[%d].name", i), Value: parameter.Name, Message: "is not used by the assistant"})
+		}
+	}
+
+	if _, err := renderTemplateAssistant(template.Assistant, defaults); err != nil {
+		invalid.Errors = append(invalid.Errors, FieldError{Field: "assistant", Message: err.Error()})
+	}
+
+	if len(invalid.Errors) > 0 {
+		return invalid
+	}
+
+	return nil
+}
@@ -0,0 +1,345 @@
+package sarah
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"sarah/mongodb"
+	mongodbTypes "sarah/types/mongodb"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	vapiApi "github.com/VapiAI/server-sdk-go"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// ASSISTANT_READ_ONLY_KEYS are the fields of a VapiAI assistant set by VapiAI rather than its configuration.
+// They are left out of diffs and of the configuration re-applied by a rollback.
+var ASSISTANT_READ_ONLY_KEYS = []string{"id", "orgId", "createdAt", "updatedAt", "isServerUrlSecretSet"}
+
+// ErrRollbackIncomplete is returned when a rollback would leave fields of the assistant at their current value,
+// because they were added after the version was taken or can't be sent in an update.
+var ErrRollbackIncomplete = errors.New("the version can't be fully restored")
+
+// snapshotAssistant stores the configuration of a VapiAI assistant as its next version.
+// A failed snapshot is logged and doesn't fail the change it records, which VapiAI already applied.
+func snapshotAssistant(orgId string, authorId string, assistant *vapiApi.Assistant, action mongodbTypes.AssistantVersionAction, restoredVersion int) *mongodbTypes.AssistantVersion {
+	if assistant == nil || assistant.Id == "" {
+		return nil
+	}
+
+	config, err := assistantConfig(assistant)
+	if err != nil {
+		log.Printf("Error reading configuration of assistant %s: %v", assistant.Id, err)
+		return nil
+	}
+
+	version, err := mongodb.CreateAssistantVersion(orgId, mongodbTypes.AssistantVersion{
+		VapiAssistantId: assistant.Id,
+		Action:          action,
+		Config:          config,
+		AuthorId:        authorId,
+		RestoredVersion: restoredVersion,
+		CreatedAt:       time.Now().UTC(),
+	})
+	if err != nil {
+		log.Printf("Error storing version of assistant %s: %v", assistant.Id, err)
+		return nil
+	}
+
+	return version
+}
+
+// assistantConfig returns the configuration of a VapiAI assistant as a JSON object. The JSON VapiAI returned
+// is used when available, so fields the SDK doesn't know about are kept in the snapshot.
+func assistantConfig(assistant *vapiApi.Assistant) (map[string]interface{}, error) {
+	var config map[string]interface{}
+	if err := json.Unmarshal([]byte(assistant.String()), &config); err == nil {
+		return config, nil
+	}
+
+	body, err := json.Marshal(assistant)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(body, &config); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// ensureAssistantBaseline snapshots the current VapiAI configuration of an assistant without versions,
+// so the first change of an assistant created before versioning can be rolled back.
+func ensureAssistantBaseline(caller Caller, assistantId string) {
+	if _, err := mongodb.GetLatestAssistantVersion(caller.OrgId, assistantId); err == nil {
+		return
+	} else if !errors.Is(err, mongo.ErrNoDocuments) {
+		log.Printf("Error getting latest version of assistant %s: %v", assistantId, err)
+		return
+	}
+
+	assistant, err := Telephony.GetAssistant(context.Background(), assistantId)
+	if err != nil {
+		log.Printf("Error getting assistant %s for its baseline version: %v", assistantId, err)
+		return
+	}
+
+	snapshotAssistant(caller.OrgId, caller.UserId, assistant, mongodbTypes.VERSION_BASELINE, 0)
+}
+
+// GetAssistantVersions returns the latest versions of an assistant of the caller's organization, newest first.
+// Returns ErrNotFound if the organization hasn't registered the assistant.
+func GetAssistantVersions(caller Caller, assistantId string, limit int) ([]mongodbTypes.AssistantVersion, error) {
+	if _, err := AuthorizeAssistant(caller, "assistants.versions", assistantId); err != nil {
+		return nil, err
+	}
+
+	versions, err := mongodb.GetAssistantVersions(caller.OrgId, assistantId, limit)
+	if err != nil {
+		log.Printf("Error getting versions of assistant %s: %v", assistantId, err)
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+// DiffAssistantVersions compares two versions of an assistant of the caller's organization.
+// When to is 0 the latest version is compared. Returns ErrNotFound if the organization hasn't
+// registered the assistant or either version doesn't exist.
+func DiffAssistantVersions(caller Caller, assistantId string, from int, to int) (*mongodbTypes.AssistantVersionDiff, error) {
+	if _, err := AuthorizeAssistant(caller, "assistants.versions", assistantId); err != nil {
+		return nil, err
+	}
+
+	fromVersion, err := getAssistantVersion(caller.OrgId, assistantId, from)
+	if err != nil {
+		return nil, err
+	}
+
+	var toVersion *mongodbTypes.AssistantVersion
+	if to == 0 {
+		toVersion, err = mongodb.GetLatestAssistantVersion(caller.OrgId, assistantId)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		} else if err != nil {
+			log.Printf("Error getting latest version of assistant %s: %v", assistantId, err)
+			return nil, err
+		}
+	} else if toVersion, err = getAssistantVersion(caller.OrgId, assistantId, to); err != nil {
+		return nil, err
+	}
+
+	return &mongodbTypes.AssistantVersionDiff{
+		VapiAssistantId: assistantId,
+		From:            fromVersion.Version,
+		To:              toVersion.Version,
+		Changes:         diffAssistantConfigs(fromVersion.Config, toVersion.Config),
+	}, nil
+}
+
+// RollbackAssistant re-applies the configuration of an earlier version to a VapiAI assistant of the
+// caller's organization and stores the result as a new version. Returns ErrNotFound if the organization
+// hasn't registered the assistant or the version doesn't exist. VapiAI only updates the fields sent, so
+// the rollback is refused with an error wrapping ErrRollbackIncomplete, listing the fields, if fields were
+// added since the version or changed fields can't be sent in an update; the assistant is left unchanged.
+func RollbackAssistant(caller Caller, assistantId string, version int) (*mongodbTypes.AssistantVersion, error) {
+	if _, err := AuthorizeAssistant(caller, "assistants.rollback", assistantId); err != nil {
+		return nil, err
+	}
+
+	restored, err := getAssistantVersion(caller.OrgId, assistantId, version)
+	if err != nil {
+		return nil, err
+	}
+
+	config := withoutReadOnlyKeys(restored.Config)
+	body, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var assistantUpdateDto vapiApi.UpdateAssistantDto
+	if err := json.Unmarshal(body, &assistantUpdateDto); err != nil {
+		log.Printf("Error reading version %d of assistant %s: %v", version, assistantId, err)
+		return nil, err
+	}
+
+	current, err := Telephony.GetAssistant(context.Background(), assistantId)
+	if err != nil {
+		log.Printf("Error getting assistant %s: %v", assistantId, err)
+		return nil, err
+	}
+	currentConfig, err := assistantConfig(current)
+	if err != nil {
+		log.Printf("Error reading configuration of assistant %s: %v", assistantId, err)
+		return nil, err
+	}
+
+	fields, err := unrevertableFields(config, currentConfig, &assistantUpdateDto)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) > 0 {
+		reason := fmt.Sprintf("version %d: fields %s would keep their current value", version, strings.Join(fields, ", "))
+		recordAudit(caller, "assistants.rollback", "assistant", assistantId, mongodbTypes.AUDIT_DENIED, reason)
+		return nil, fmt.Errorf("%w, %s", ErrRollbackIncomplete, reason)
+	}
+
+	assistant, err := Telephony.UpdateAssistant(context.Background(), assistantId, &assistantUpdateDto)
+	if err != nil {
+		log.Printf("Error rolling back assistant %s to version %d: %v", assistantId, version, err)
+		return nil, err
+	}
+
+	recordAudit(caller, "assistants.rollback", "assistant", assistantId, mongodbTypes.AUDIT_ALLOWED, fmt.Sprintf("version %d", version))
+
+	snapshot := snapshotAssistant(caller.OrgId, caller.UserId, assistant, mongodbTypes.VERSION_ROLLBACK, version)
+	if snapshot == nil {
+		return nil, fmt.Errorf("assistant %s was rolled back to version %d but its new version couldn't be stored", assistantId, version)
+	}
+
+	return snapshot, nil
+}
+
+// getAssistantVersion returns a version of an assistant, or ErrNotFound if it doesn't exist.
+func getAssistantVersion(orgId string, assistantId string, version int) (*mongodbTypes.AssistantVersion, error) {
+	assistantVersion, err := mongodb.GetAssistantVersion(orgId, assistantId, version)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		log.Printf("Error getting version %d of assistant %s: %v", version, assistantId, err)
+		return nil, err
+	}
+
+	return assistantVersion, nil
+}
+
+// unrevertableFields lists, sorted, the fields of an assistant's current configuration a rollback to config
+// can't restore: fields the version doesn't have, which VapiAI would keep since an update only changes the
+// fields sent, and changed fields the update request doesn't carry. Fields set to null count as unset.
+func unrevertableFields(config map[string]interface{}, current map[string]interface{}, update *vapiApi.UpdateAssistantDto) ([]string, error) {
+	body, err := json.Marshal(update)
+	if err != nil {
+		return nil, err
+	}
+	var sent map[string]interface{}
+	if err := json.Unmarshal(body, &sent); err != nil {
+		return nil, err
+	}
+
+	fields := []string{}
+	for key, value := range withoutReadOnlyKeys(current) {
+		if _, ok := config[key]; !ok && value != nil {
+			fields = append(fields, key)
+		}
+	}
+	for key, value := range config {
+		if _, ok := sent[key]; ok || value == nil {
+			continue
+		}
+		if same, err := sameJSON(value, current[key]); err != nil {
+			return nil, err
+		} else if !same {
+			fields = append(fields, key)
+		}
+	}
+	sort.Strings(fields)
+
+	return fields, nil
+}
+
+// sameJSON reports whether two configuration values encode to the same JSON, which compares values
+// decoded from the database with values decoded from VapiAI.
+func sameJSON(a interface{}, b interface{}) (bool, error) {
+	encodedA, err := json.Marshal(a)
+	if err != nil {
+		return false, err
+	}
+	encodedB, err := json.Marshal(b)
+	if err != nil {
+		return false, err
+	}
+
+	return string(encodedA) == string(encodedB), nil
+}
+
+// withoutReadOnlyKeys returns a copy of an assistant configuration without ASSISTANT_READ_ONLY_KEYS.
+func withoutReadOnlyKeys(config map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(config))
+	for key, value := range config {
+		result[key] = value
+	}
+	for _, key := range ASSISTANT_READ_ONLY_KEYS {
+		delete(result, key)
+	}
+
+	return result
+}
+
+// diffAssistantConfigs lists the fields that differ between two assistant configurations, sorted by path.
+func diffAssistantConfigs(from map[string]interface{}, to map[string]interface{}) []mongodbTypes.AssistantConfigChange {
+	oldFields := map[string]interface{}{}
+	newFields := map[string]interface{}{}
+	flattenConfig("", withoutReadOnlyKeys(from), oldFields)
+	flattenConfig("", withoutReadOnlyKeys(to), newFields)
+
+	paths := []string{}
+	for path := range oldFields {
+		paths = append(paths, path)
+	}
+	for path := range newFields {
+		if _, ok := oldFields[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	changes := []mongodbTypes.AssistantConfigChange{}
+	for _, path := range paths {
+		oldValue, newValue := oldFields[path], newFields[path]
+		if !reflect.DeepEqual(oldValue, newValue) {
+			changes = append(changes, mongodbTypes.AssistantConfigChange{Path: path, Old: oldValue, New: newValue})
+		}
+	}
+
+	return changes
+}
+
+// flattenConfig adds the leaf values of a configuration value to fields, keyed by their dotted and indexed path.
+// Empty objects and lists are leaves, so adding or removing one is reported. Nested documents read back
+// from MongoDB are bson.M and bson.A, and are flattened like the JSON objects and lists they were stored from.
+func flattenConfig(path string, value interface{}, fields map[string]interface{}) {
+	switch typed := value.(type) {
+	case bson.M:
+		flattenConfig(path, map[string]interface{}(typed), fields)
+	case bson.A:
+		flattenConfig(path, []interface{}(typed), fields)
+	case map[string]interface{}:
+		if len(typed) == 0 && path != "" {
+			fields[path] = typed
+		}
+		for key, nested := range typed {
+			if path == "" {
+				flattenConfig(key, nested, fields)
+			} else {
+				flattenConfig(path+"."+key, nested, fields)
+			}
+		}
+	case []interface{}:
+		if len(typed) == 0 {
+			fields[path] = typed
+		}
+		for index, nested := range typed {
+			flattenConfig(path+"["+strconv.Itoa(index)+"]", nested, fields)
+		}
+	default:
+		fields[path] = value
+	}
+}
@@ -17,14 +17,15 @@ func init() {
 	}
 }
 
-func CreateAsisstant(orgId string, assistantCreateDto vapiApi.CreateAssistantDto) (*mongo.InsertOneResult, error) {
-	assistant, err := VapiClient.Assistants.Create(context.Background(), &assistantCreateDto)
+// CreateAsisstant creates a VapiAI assistant for the caller's organization, registers it and stores its first version.
+func CreateAsisstant(caller Caller, assistantCreateDto vapiApi.CreateAssistantDto) (*mongo.InsertOneResult, error) {
+	assistant, err := Telephony.CreateAssistant(context.Background(), &assistantCreateDto)
 	if err != nil {
 		log.Println(err)
 		return nil, err
 	}
 
-	result, err := mongodb.CreateAssistant(orgId, mongodbTypes.Assistant{
+	result, err := mongodb.CreateAssistant(caller.OrgId, mongodbTypes.Assistant{
 		Name:            *assistant.Name,
 		VapiAssistantId: assistant.Id,
 		Type:            "placeholder type",
@@ -35,37 +36,82 @@ func CreateAsisstant(orgId string, assistantCreateDto vapiApi.CreateAssistantDto
 		return nil, err
 	}
 
+	snapshotAssistant(caller.OrgId, caller.UserId, assistant, mongodbTypes.VERSION_CREATE, 0)
+
+	return result, nil
+}
+
+// RegisterAssistant registers an assistant that already exists in VapiAI for the caller's organization
+// and stores its current configuration as a version.
+// Returns ErrNotFound if the assistant doesn't exist in VapiAI or is registered by another organization.
+func RegisterAssistant(caller Caller, assistant mongodbTypes.Assistant) (*mongo.InsertOneResult, error) {
+	vapiAssistant, err := Telephony.GetAssistant(context.Background(), assistant.VapiAssistantId)
+	if err != nil || vapiAssistant == nil || vapiAssistant.Id == "" {
+		return nil, ErrNotFound
+	}
+
+	if err := authorizeClaim(caller, "assistants.register", assistant.VapiAssistantId, ""); err != nil {
+		return nil, err
+	}
+
+	result, err := mongodb.CreateAssistant(caller.OrgId, assistant)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	snapshotAssistant(caller.OrgId, caller.UserId, vapiAssistant, mongodbTypes.VERSION_REGISTER, 0)
+
 	return result, nil
 }
 
-func UpdateAssistant(assistantId string, assistantUpdateDto vapiApi.UpdateAssistantDto) (*vapiApi.Assistant, error) {
-	result, err := VapiClient.Assistants.Update(context.Background(), assistantId, &assistantUpdateDto)
+// UpdateAssistant updates a VapiAI assistant of the caller's organization and stores the updated configuration as a version.
+// An assistant without versions has its configuration before the update stored first, so the update can be rolled back.
+// Returns ErrNotFound if the organization hasn't registered the assistant.
+func UpdateAssistant(caller Caller, assistantId string, assistantUpdateDto vapiApi.UpdateAssistantDto) (*vapiApi.Assistant, error) {
+	if _, err := AuthorizeAssistant(caller, "assistants.update", assistantId); err != nil {
+		return nil, err
+	}
+
+	ensureAssistantBaseline(caller, assistantId)
+
+	result, err := Telephony.UpdateAssistant(context.Background(), assistantId, &assistantUpdateDto)
 	if err != nil {
 		log.Println(err)
 		return nil, err
 	}
 
+	snapshotAssistant(caller.OrgId, caller.UserId, result, mongodbTypes.VERSION_UPDATE, 0)
+
 	return result, nil
 }
 
-func DeleteAssistant(orgId string, assistantId string) (*mongo.DeleteResult, error) {
-	_, err := VapiClient.Assistants.Delete(context.Background(), assistantId)
+// DeleteAssistant deletes a VapiAI assistant of the caller's organization and its record.
+// Returns ErrNotFound if the organization hasn't registered the assistant.
+func DeleteAssistant(caller Caller, assistantId string) (*mongo.DeleteResult, error) {
+	if _, err := AuthorizeAssistant(caller, "assistants.delete", assistantId); err != nil {
+		return nil, err
+	}
+
+	err := Telephony.DeleteAssistant(context.Background(), assistantId)
 	if err != nil {
 		log.Println(err)
 		return nil, err
 	}
 
-	result, err := mongodb.DeleteAssistant(orgId, assistantId)
+	result, err := mongodb.DeleteAssistant(caller.OrgId, assistantId)
 	if err != nil {
 		log.Println(err)
 		return nil, err
 	}
 
+	callOrganizations.Delete("assistant:" + assistantId)
+
 	return result, nil
 }
 
 func ExistsAssistant(assistantId string) bool {
-	assistant, err := VapiClient.Assistants.Get(context.Background(), assistantId)
+	assistant, err := Telephony.GetAssistant(context.Background(), assistantId)
 	if err != nil {
 		return false
 	}
@@ -0,0 +1,286 @@
+package sarah
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"sarah/mongodb"
+	mongodbTypes "sarah/types/mongodb"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// ErrNotNoteAuthor is returned when a user tries to delete a note written by someone else.
+var ErrNotNoteAuthor = errors.New("only the author of a note can delete it")
+
+// MAX_CALL_TAGS is the maximum number of tags of a call
+const MAX_CALL_TAGS = 20
+
+// CALL_TAG_MAX_LENGTH is the maximum length of a call tag
+const CALL_TAG_MAX_LENGTH = 50
+
+// CALL_NOTE_MAX_LENGTH is the maximum length of a call note
+const CALL_NOTE_MAX_LENGTH = 5000
+
+// MAX_DISPOSITIONS is the maximum number of dispositions of an organization's taxonomy
+const MAX_DISPOSITIONS = 50
+
+// DISPOSITION_LABEL_MAX_LENGTH is the maximum length of the label of a disposition
+const DISPOSITION_LABEL_MAX_LENGTH = 100
+
+// callTagPattern matches call tags: lowercase letters and digits, with spaces, underscores, hyphens and colons inside
+var callTagPattern = regexp.MustCompile(`^[\p{Ll}\p{Lo}\pN]([\p{Ll}\p{Lo}\pN _:-]*[\p{Ll}\p{Lo}\pN])?$`)
+
+// dispositionCodePattern matches disposition codes: lowercase letters, digits, underscores and hyphens
+var dispositionCodePattern = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// validateDispositions checks the disposition taxonomy of an organization, trimming the labels.
+// Returns an error wrapping ErrInvalidSettings if a code is invalid or repeated, or a label is missing.
+func validateDispositions(dispositions []mongodbTypes.Disposition) error {
+	if len(dispositions) > MAX_DISPOSITIONS {
+		return fmt.Errorf("%w: dispositions can have at most %d dispositions", ErrInvalidSettings, MAX_DISPOSITIONS)
+	}
+
+	codes := map[string]bool{}
+	for i := range dispositions {
+		disposition := &dispositions[i]
+
+		if !dispositionCodePattern.MatchString(disposition.Code) {
+			return fmt.Errorf("%w: dispositions[%d].code must be lowercase letters, digits, underscores and hyphens", ErrInvalidSettings, i)
+		}
+		if codes[disposition.Code] {
+			return fmt.Errorf("%w: dispositions[%d].code %s is repeated", ErrInvalidSettings, i, disposition.Code)
+		}
+		codes[disposition.Code] = true
+
+		disposition.Label = strings.TrimSpace(disposition.Label)
+		if disposition.Label == "" || len([]rune(disposition.Label)) > DISPOSITION_LABEL_MAX_LENGTH {
+			return fmt.Errorf("%w: dispositions[%d].label must be 1 to %d characters", ErrInvalidSettings, i, DISPOSITION_LABEL_MAX_LENGTH)
+		}
+	}
+
+	return nil
+}
+
+// resolveCallRecord returns the caller's record of a call. Calls not recorded yet, e.g. placed before the
+// last sync, are fetched from the telephony provider and recorded if they belong to the organization.
+// Returns ErrNotFound, and records the denied attempt, if the call doesn't belong to the organization.
+func resolveCallRecord(caller Caller, callId string) (*mongodbTypes.Call, error) {
+	call, err := mongodb.GetCallByVapiId(caller.OrgId, callId)
+	if err == nil {
+		return call, nil
+	} else if !errors.Is(err, mongo.ErrNoDocuments) {
+		log.Printf("Error getting call %s: %v", callId, err)
+		return nil, err
+	}
+
+	vapiCall, err := GetCall(caller, callId)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := mongodb.UpsertCall(caller.OrgId, callRecordFromVapi(vapiCall)); err != nil {
+		log.Printf("Error recording call %s: %v", callId, err)
+		return nil, err
+	}
+
+	return mongodb.GetCallByVapiId(caller.OrgId, callId)
+}
+
+// UpdateCallTags adds and removes tags of a call of the caller's organization. Tags are lowercased and
+// their spaces collapsed, so "Follow Up" and "follow up" are the same tag.
+// Returns ErrNotFound if the call doesn't belong to the organization, or a *ValidationError if a tag is
+// invalid or the call would have more than MAX_CALL_TAGS tags.
+func UpdateCallTags(caller Caller, callId string, add []string, remove []string) (*mongodbTypes.Call, error) {
+	invalid := &ValidationError{}
+	add = normalizeCallTags(invalid, "tags.add", add)
+	remove = normalizeCallTags(invalid, "tags.remove", remove)
+	if err := invalid.orNil(); err != nil {
+		return nil, err
+	}
+
+	call, err := resolveCallRecord(caller, callId)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := map[string]bool{}
+	for _, tag := range call.Tags {
+		tags[tag] = true
+	}
+	for _, tag := range remove {
+		delete(tags, tag)
+	}
+	for _, tag := range add {
+		tags[tag] = true
+	}
+	if len(tags) > MAX_CALL_TAGS {
+		return nil, &ValidationError{Errors: []FieldError{{Field: "tags.add", Message: fmt.Sprintf("a call can have at most %d tags", MAX_CALL_TAGS)}}}
+	}
+
+	call, err = mongodb.UpdateCallTags(caller.OrgId, callId, add, remove)
+	if err != nil {
+		log.Printf("Error updating tags of call %s: %v", callId, err)
+		return nil, err
+	}
+
+	return call, nil
+}
+
+// normalizeCallTags lowercases tags and collapses their spaces, dropping repeated tags.
+// Invalid tags are added to invalid, under field.
+func normalizeCallTags(invalid *ValidationError, field string, tags []string) []string {
+	normalized := []string{}
+	seen := map[string]bool{}
+
+	for i, tag := range tags {
+		value := strings.ToLower(strings.Join(strings.Fields(tag), " "))
+		if !callTagPattern.MatchString(value) || len([]rune(value)) > CALL_TAG_MAX_LENGTH {
+			invalid.Errors = append(invalid.Errors, FieldError{
+				Field:   fmt.Sprintf("%s[%d]", field, i),
+				Value:   tag,
+				Message: fmt.Sprintf("must be 1 to %d letters and digits, with spaces, underscores, hyphens or colons inside", CALL_TAG_MAX_LENGTH),
+			})
+			continue
+		}
+
+		if !seen[value] {
+			seen[value] = true
+			normalized = append(normalized, value)
+		}
+	}
+
+	return normalized
+}
+
+// SetCallDisposition sets the disposition of a call of the caller's organization, or clears it when code is empty.
+// The code must be in the organization's disposition taxonomy; the caller is recorded as who set it.
+// Returns ErrNotFound if the call doesn't belong to the organization, or a *ValidationError if the code is unknown.
+func SetCallDisposition(caller Caller, callId string, code string) (*mongodbTypes.Call, error) {
+	code = strings.TrimSpace(code)
+
+	if code != "" {
+		settings, err := mongodb.GetOrganizationSettings(caller.OrgId)
+		if err != nil {
+			log.Printf("Error getting dispositions of organization %s: %v", caller.OrgId, err)
+			return nil, err
+		}
+
+		codes := []string{}
+		known := false
+		for _, disposition := range settings.Dispositions {
+			codes = append(codes, disposition.Code)
+			known = known || disposition.Code == code
+		}
+		if !known {
+			message := "no dispositions are configured in the organization settings"
+			if len(codes) > 0 {
+				message = "must be one of " + strings.Join(codes, ", ")
+			}
+			return nil, &ValidationError{Errors: []FieldError{{Field: "disposition", Value: code, Message: message}}}
+		}
+	}
+
+	if _, err := resolveCallRecord(caller, callId); err != nil {
+		return nil, err
+	}
+
+	call, err := mongodb.SetCallDisposition(caller.OrgId, callId, code, caller.UserId)
+	if err != nil {
+		log.Printf("Error setting disposition of call %s: %v", callId, err)
+		return nil, err
+	}
+
+	return call, nil
+}
+
+// GetCallNotes returns the notes written on a call of the caller's organization, oldest first.
+// Returns ErrNotFound if the call doesn't belong to the organization.
+func GetCallNotes(caller Caller, callId string) ([]mongodbTypes.CallNote, error) {
+	if _, err := resolveCallRecord(caller, callId); err != nil {
+		return nil, err
+	}
+
+	notes, err := mongodb.GetCallNotes(caller.OrgId, callId)
+	if err != nil {
+		log.Printf("Error getting notes of call %s: %v", callId, err)
+		return nil, err
+	}
+
+	return notes, nil
+}
+
+// CreateCallNote writes a note on a call of the caller's organization, authored by the caller.
+// Returns ErrNotFound if the call doesn't belong to the organization, or a *ValidationError if the text
+// is empty or longer than CALL_NOTE_MAX_LENGTH.
+func CreateCallNote(caller Caller, callId string, text string) (*mongodbTypes.CallNote, error) {
+	text = strings.TrimSpace(text)
+	if text == "" || len([]rune(text)) > CALL_NOTE_MAX_LENGTH {
+		return nil, &ValidationError{Errors: []FieldError{{Field: "note.text", Message: fmt.Sprintf("must be 1 to %d characters", CALL_NOTE_MAX_LENGTH)}}}
+	}
+
+	if _, err := resolveCallRecord(caller, callId); err != nil {
+		return nil, err
+	}
+
+	note := mongodbTypes.CallNote{
+		Id:         bson.NewObjectID(),
+		VapiCallId: callId,
+		AuthorId:   caller.UserId,
+		Text:       text,
+		CreatedAt:  time.Now(),
+	}
+	if _, err := mongodb.CreateCallNote(caller.OrgId, note); err != nil {
+		log.Printf("Error creating note on call %s: %v", callId, err)
+		return nil, err
+	}
+
+	if err := mongodb.IncrementCallNoteCount(caller.OrgId, callId, 1); err != nil {
+		log.Printf("Error counting note on call %s: %v", callId, err)
+	}
+
+	return &note, nil
+}
+
+// DeleteCallNote deletes a note of the caller's organization. Only the author of a note can delete it.
+// Returns ErrNotFound if the organization has no such note, or ErrNotNoteAuthor if the caller didn't write it.
+func DeleteCallNote(caller Caller, noteId string) (*mongo.DeleteResult, error) {
+	id, err := bson.ObjectIDFromHex(noteId)
+	if err != nil {
+		recordDenied(caller, "calls.notes.delete", "call_note", noteId)
+		return nil, ErrNotFound
+	}
+
+	note, err := mongodb.GetCallNoteById(caller.OrgId, id)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		recordDenied(caller, "calls.notes.delete", "call_note", noteId)
+		return nil, ErrNotFound
+	} else if err != nil {
+		log.Printf("Error getting note %s: %v", noteId, err)
+		return nil, err
+	}
+
+	if note.AuthorId != caller.UserId {
+		recordAudit(caller, "calls.notes.delete", "call_note", noteId, mongodbTypes.AUDIT_DENIED, ErrNotNoteAuthor.Error())
+		return nil, ErrNotNoteAuthor
+	}
+
+	result, err := mongodb.DeleteCallNote(caller.OrgId, id)
+	if err != nil {
+		log.Printf("Error deleting note %s: %v", noteId, err)
+		return nil, err
+	}
+
+	if result.DeletedCount > 0 {
+		if err := mongodb.IncrementCallNoteCount(caller.OrgId, note.VapiCallId, -1); err != nil {
+			log.Printf("Error counting deleted note on call %s: %v", note.VapiCallId, err)
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,213 @@
+package sarah
+
+import (
+	"fmt"
+	"log"
+
+	mongodbTypes "sarah/types/mongodb"
+)
+
+// MAX_CALL_BATCH_SIZE is the maximum number of customers of a call batch
+const MAX_CALL_BATCH_SIZE = 1000
+
+// CALL_BATCH_CHUNK_SIZE is the number of customers of a batch checked against the Do-Not-Call lists
+// and queued together. A chunk that can't be queued only fails its own customers.
+const CALL_BATCH_CHUNK_SIZE = 100
+
+// CallBatchStatus is the outcome of a customer of a call batch.
+type CallBatchStatus string
+
+const (
+	// CALL_BATCH_ACCEPTED indicates the call was added to the call queue
+	CALL_BATCH_ACCEPTED CallBatchStatus = "accepted"
+
+	// CALL_BATCH_SUPPRESSED indicates the customer's number is on a Do-Not-Call list
+	CALL_BATCH_SUPPRESSED CallBatchStatus = "suppressed"
+
+	// CALL_BATCH_INVALID indicates the customer was rejected, e.g. for an invalid phone number
+	CALL_BATCH_INVALID CallBatchStatus = "invalid"
+
+	// CALL_BATCH_FAILED indicates the call couldn't be queued, e.g. because of the phone number's daily cap
+	CALL_BATCH_FAILED CallBatchStatus = "failed"
+)
+
+// CallBatchResult is the outcome of a single customer of a call batch.
+type CallBatchResult struct {
+	// Index is the position of the customer in the request
+	Index int `json:"index"`
+
+	// PhoneNumber is the customer's number, in E.164 unless it is invalid
+	PhoneNumber string `json:"phone_number"`
+
+	// Status is what happened to the customer's call
+	Status CallBatchStatus `json:"status"`
+
+	// QueuedCallId is the hex ObjectID of the queued call of an accepted customer
+	QueuedCallId string `json:"queued_call_id,omitempty"`
+
+	// Errors are the invalid fields of an invalid customer
+	Errors []FieldError `json:"errors,omitempty"`
+
+	// Error explains why the call of a failed customer wasn't queued
+	Error string `json:"error,omitempty"`
+}
+
+// CallBatch is the outcome of a call batch, with a result for every customer in the order of the request.
+type CallBatch struct {
+	// Calls are the queued calls of the accepted customers
+	Calls []mongodbTypes.QueuedCall `json:"calls"`
+
+	// Suppressed are the numbers on a Do-Not-Call list
+	Suppressed []string `json:"suppressed"`
+
+	// Results are the outcomes of the customers, in the order of the request
+	Results []CallBatchResult `json:"results"`
+
+	// Counts are the number of customers with each status
+	Counts map[CallBatchStatus]int `json:"counts"`
+}
+
+// CreateCallBatch queues calls to a batch of customers, each with its own name, time zone and template variables,
+// and returns the outcome of every customer rather than failing the whole batch. Customers are validated one by
+// one, then checked against the Do-Not-Call lists and queued in chunks of CALL_BATCH_CHUNK_SIZE; customers beyond
+// the daily cap of the phone number fail. The assistant and phone number must belong to the caller's organization,
+// otherwise ErrNotFound is returned and no call is queued. Returns a *ValidationError if the batch is empty or
+// has more than MAX_CALL_BATCH_SIZE customers.
+func CreateCallBatch(caller Caller, assistantId string, assistantNumberId string, customers []mongodbTypes.Customer) (*CallBatch, error) {
+	if len(customers) == 0 || len(customers) > MAX_CALL_BATCH_SIZE {
+		return nil, &ValidationError{Errors: []FieldError{{
+			Field:   "customers",
+			Value:   fmt.Sprint(len(customers)),
+			Message: fmt.Sprintf("must have 1 to %d customers", MAX_CALL_BATCH_SIZE),
+		}}}
+	}
+
+	if _, err := AuthorizeAssistant(caller, "calls.create", assistantId); err != nil {
+		return nil, err
+	}
+	phoneNumber, err := AuthorizePhoneNumber(caller, "calls.create", assistantNumberId)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := &CallBatch{
+		Calls:      []mongodbTypes.QueuedCall{},
+		Suppressed: []string{},
+		Results:    make([]CallBatchResult, len(customers)),
+		Counts:     map[CallBatchStatus]int{},
+	}
+
+	defaultCountry := organizationCountry(caller.OrgId)
+	first := map[string]int{}
+	valid := []int{}
+	for i := range customers {
+		field := fmt.Sprintf("customers[%d]", i)
+		customer, invalid := normalizeCustomer(customers[i], field, defaultCountry)
+		customers[i] = customer
+
+		if len(invalid) == 0 {
+			if j, repeated := first[customer.PhoneNumber]; repeated {
+				invalid = append(invalid, FieldError{Field: field + ".phone_number", Value: customer.PhoneNumber, Message: fmt.Sprintf("is repeated, first given in customers[%d]", j)})
+			} else {
+				first[customer.PhoneNumber] = i
+			}
+		}
+
+		batch.Results[i] = CallBatchResult{Index: i, PhoneNumber: customer.PhoneNumber}
+		if len(invalid) > 0 {
+			batch.Results[i].Status = CALL_BATCH_INVALID
+			batch.Results[i].Errors = invalid
+			continue
+		}
+		valid = append(valid, i)
+	}
+
+	remaining, err := dailyCallsLeft(caller.OrgId, phoneNumber)
+	if err != nil {
+		log.Printf("Error getting the daily usage of phone number %s: %v", assistantNumberId, err)
+		return nil, err
+	}
+
+	for start := 0; start < len(valid); start += CALL_BATCH_CHUNK_SIZE {
+		chunk := valid[start:min(start+CALL_BATCH_CHUNK_SIZE, len(valid))]
+		remaining = batch.queueChunk(caller.OrgId, assistantId, phoneNumber, customers, chunk, remaining)
+	}
+
+	for _, result := range batch.Results {
+		batch.Counts[result.Status]++
+	}
+	log.Printf("[CallBatch] Organization %s: %d accepted, %d suppressed, %d invalid, %d failed", caller.OrgId,
+		batch.Counts[CALL_BATCH_ACCEPTED], batch.Counts[CALL_BATCH_SUPPRESSED], batch.Counts[CALL_BATCH_INVALID], batch.Counts[CALL_BATCH_FAILED])
+
+	return batch, nil
+}
+
+// queueChunk checks the customers of a chunk, given by their index in customers, against the Do-Not-Call lists
+// and queues the calls of those allowed within the remaining daily calls of the phone number (-1 for no cap).
+// Returns the daily calls left once the chunk is queued.
+func (b *CallBatch) queueChunk(orgId string, assistantId string, phoneNumber *mongodbTypes.PhoneNumber, customers []mongodbTypes.Customer, chunk []int, remaining int) int {
+	chunkCustomers := []mongodbTypes.Customer{}
+	for _, i := range chunk {
+		chunkCustomers = append(chunkCustomers, customers[i])
+	}
+
+	_, suppressed, err := suppressCustomers(orgId, chunkCustomers)
+	if err != nil {
+		log.Printf("[CallBatch] Error checking the do-not-call lists: %v", err)
+		b.fail(chunk, "the Do-Not-Call lists couldn't be checked, try again")
+		return remaining
+	}
+
+	isSuppressed := map[string]bool{}
+	for _, number := range suppressed {
+		isSuppressed[number] = true
+	}
+
+	pending := []int{}
+	for _, i := range chunk {
+		if isSuppressed[customers[i].PhoneNumber] {
+			b.Results[i].Status = CALL_BATCH_SUPPRESSED
+			b.Suppressed = append(b.Suppressed, customers[i].PhoneNumber)
+			continue
+		}
+		pending = append(pending, i)
+	}
+
+	if remaining >= 0 && len(pending) > remaining {
+		b.fail(pending[remaining:], dailyCapError(phoneNumber, remaining).Error())
+		pending = pending[:remaining]
+	}
+	if len(pending) == 0 {
+		return remaining
+	}
+
+	pendingCustomers := []mongodbTypes.Customer{}
+	for _, i := range pending {
+		pendingCustomers = append(pendingCustomers, customers[i])
+	}
+
+	queued, err := enqueueCalls(orgId, callOrigin{}, assistantId, phoneNumber.PhoneNumberId, pendingCustomers)
+	if err != nil {
+		b.fail(pending, "the call couldn't be queued, try again")
+		return remaining
+	}
+
+	for j, i := range pending {
+		b.Results[i].Status = CALL_BATCH_ACCEPTED
+		b.Results[i].QueuedCallId = queued[j].Id.Hex()
+	}
+	b.Calls = append(b.Calls, queued...)
+
+	if remaining >= 0 {
+		remaining -= len(queued)
+	}
+	return remaining
+}
+
+// fail marks the customers at the given indexes as failed.
+func (b *CallBatch) fail(indexes []int, reason string) {
+	for _, i := range indexes {
+		b.Results[i].Status = CALL_BATCH_FAILED
+		b.Results[i].Error = reason
+	}
+}
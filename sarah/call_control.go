@@ -0,0 +1,204 @@
+package sarah
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"sarah/mongodb"
+	mongodbTypes "sarah/types/mongodb"
+
+	vapiApi "github.com/VapiAI/server-sdk-go"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// ErrCallState is returned when a call is not in a status that allows the requested action,
+// e.g. cancelling a call that already started.
+var ErrCallState = errors.New("call is not in a state that allows this action")
+
+// ErrCallNotControllable is returned when an active call has no control URL, which happens
+// when the assistant's monitorPlan.controlEnabled is not set.
+var ErrCallNotControllable = errors.New("call has no control URL")
+
+// CampaignCancellation is the outcome of cancelling every pending call of a campaign.
+type CampaignCancellation struct {
+	// Dequeued is the number of calls removed from the call queue before they were dispatched
+	Dequeued int64 `json:"dequeued"`
+
+	// Cancelled are the VapiAI call IDs that were cancelled
+	Cancelled []string `json:"cancelled"`
+
+	// Skipped are the calls that were no longer pending, by VapiAI call ID, with their status
+	Skipped map[string]string `json:"skipped"`
+
+	// Failed are the calls that could not be cancelled, by VapiAI call ID, with the error
+	Failed map[string]string `json:"failed"`
+}
+
+// CancelCall cancels a call of the caller's organization. A scheduled or queued call, which VapiAI hasn't
+// dialed yet, is deleted in VapiAI. A ringing or in-progress call is hung up through its control URL instead,
+// since deleting it would remove VapiAI's record of a call that took place.
+// Returns ErrNotFound if the call doesn't belong to the organization, ErrCallState if it already ended,
+// and ErrCallNotControllable if the call is active and its assistant doesn't allow call control.
+// The cancellation is audited and reflected in the run history of the campaign that placed the call.
+func CancelCall(caller Caller, callId string) (*vapiApi.Call, error) {
+	call, err := GetCall(caller, callId)
+	if err != nil {
+		return nil, err
+	}
+
+	switch status := callStatus(call); status {
+	case mongodbTypes.CALL_STATUS_SCHEDULED, mongodbTypes.CALL_STATUS_QUEUED:
+		return cancelUndialedCall(caller, call)
+	case mongodbTypes.CALL_STATUS_RINGING, mongodbTypes.CALL_STATUS_IN_PROGRESS, mongodbTypes.CALL_STATUS_FORWARDING:
+		return endActiveCall(caller, "calls.cancel", call)
+	default:
+		recordAudit(caller, "calls.cancel", "call", callId, mongodbTypes.AUDIT_DENIED, fmt.Sprintf("call is %s", status))
+		return nil, fmt.Errorf("%w: call is %s", ErrCallState, status)
+	}
+}
+
+// cancelPendingCall cancels a call of the caller's organization only if VapiAI hasn't dialed it yet.
+// Returns ErrCallState, leaving the call alone, if it already started.
+func cancelPendingCall(caller Caller, callId string) (*vapiApi.Call, error) {
+	call, err := GetCall(caller, callId)
+	if err != nil {
+		return nil, err
+	}
+
+	status := callStatus(call)
+	if status != mongodbTypes.CALL_STATUS_SCHEDULED && status != mongodbTypes.CALL_STATUS_QUEUED {
+		recordAudit(caller, "calls.cancel", "call", callId, mongodbTypes.AUDIT_DENIED, fmt.Sprintf("call is %s", status))
+		return nil, fmt.Errorf("%w: call is %s", ErrCallState, status)
+	}
+
+	return cancelUndialedCall(caller, call)
+}
+
+// cancelUndialedCall deletes a scheduled or queued call in VapiAI, which cancels it before it is dialed,
+// and records it locally as ended with reason manually-canceled. Deleting is only used for calls that
+// weren't dialed, so VapiAI never loses the record of a call that took place.
+func cancelUndialedCall(caller Caller, call *vapiApi.Call) (*vapiApi.Call, error) {
+	callId := call.Id
+	if err := Telephony.DeleteCall(context.Background(), callId); err != nil {
+		log.Printf("Error cancelling call %s: %v", callId, err)
+		return nil, err
+	}
+
+	endedAt := time.Now()
+	record := callRecordFromVapi(call)
+	record.Status = mongodbTypes.CALL_STATUS_ENDED
+	record.EndedReason = mongodbTypes.ENDED_REASON_MANUALLY_CANCELED
+	record.EndedAt = &endedAt
+	if _, err := mongodb.UpdateCallStatus(caller.OrgId, record); err != nil {
+		log.Printf("Error recording cancellation of call %s: %v", callId, err)
+	}
+
+	markCallCancelled(caller, "calls.cancel", callId)
+
+	return call, nil
+}
+
+// EndCall hangs up a ringing or in-progress call of the caller's organization through the telephony provider.
+// Returns ErrNotFound if the call doesn't belong to the organization, ErrCallState if it is not active,
+// and ErrCallNotControllable if the assistant doesn't allow call control.
+// The call record is updated by the end-of-call report VapiAI sends once the call has ended.
+func EndCall(caller Caller, callId string) (*vapiApi.Call, error) {
+	call, err := GetCall(caller, callId)
+	if err != nil {
+		return nil, err
+	}
+
+	status := callStatus(call)
+	if status != mongodbTypes.CALL_STATUS_RINGING && status != mongodbTypes.CALL_STATUS_IN_PROGRESS && status != mongodbTypes.CALL_STATUS_FORWARDING {
+		recordAudit(caller, "calls.end", "call", callId, mongodbTypes.AUDIT_DENIED, fmt.Sprintf("call is %s", status))
+		return nil, fmt.Errorf("%w: call is %s", ErrCallState, status)
+	}
+
+	return endActiveCall(caller, "calls.end", call)
+}
+
+// endActiveCall hangs up an active call through its control URL and audits it under the given action.
+// The call is kept in VapiAI, which sends its end-of-call report once the call has ended.
+func endActiveCall(caller Caller, action string, call *vapiApi.Call) (*vapiApi.Call, error) {
+	if err := Telephony.EndCall(context.Background(), call); errors.Is(err, ErrCallNotControllable) {
+		recordAudit(caller, action, "call", call.Id, mongodbTypes.AUDIT_DENIED, ErrCallNotControllable.Error())
+		return nil, ErrCallNotControllable
+	} else if err != nil {
+		log.Printf("Error ending call %s: %v", call.Id, err)
+		return nil, err
+	}
+
+	markCallCancelled(caller, action, call.Id)
+
+	return call, nil
+}
+
+// CancelCampaignCalls removes the calls of one of the caller's campaigns from the call queue, then cancels
+// every scheduled or queued call it placed in VapiAI. Each call is checked against VapiAI first, so calls
+// that started since they were last recorded are skipped.
+// Returns ErrNotFound if the organization has no such campaign.
+func CancelCampaignCalls(caller Caller, campaignId string) (*CampaignCancellation, error) {
+	if _, err := AuthorizeCampaign(caller, "campaigns.cancel", campaignId); err != nil {
+		return nil, err
+	}
+
+	// Dequeue first, so the dispatcher doesn't place calls while the placed ones are cancelled
+	dequeued, err := mongodb.CancelQueuedCalls(caller.OrgId, bson.M{"campaign_id": campaignId})
+	if err != nil {
+		log.Printf("Error dequeuing calls of campaign %s: %v", campaignId, err)
+		return nil, err
+	}
+
+	calls, err := mongodb.GetCampaignCallsByStatus(caller.OrgId, campaignId, []mongodbTypes.CallStatus{
+		mongodbTypes.CALL_STATUS_SCHEDULED,
+		mongodbTypes.CALL_STATUS_QUEUED,
+	})
+	if err != nil {
+		log.Printf("Error getting pending calls of campaign %s: %v", campaignId, err)
+		return nil, err
+	}
+
+	result := &CampaignCancellation{
+		Dequeued:  dequeued,
+		Cancelled: []string{},
+		Skipped:   map[string]string{},
+		Failed:    map[string]string{},
+	}
+
+	for _, call := range calls {
+		_, err := cancelPendingCall(caller, call.VapiCallId)
+		switch {
+		case err == nil:
+			result.Cancelled = append(result.Cancelled, call.VapiCallId)
+		case errors.Is(err, ErrCallState), errors.Is(err, ErrNotFound):
+			result.Skipped[call.VapiCallId] = err.Error()
+		default:
+			result.Failed[call.VapiCallId] = err.Error()
+		}
+	}
+
+	recordAudit(caller, "campaigns.cancel", "campaign", campaignId, mongodbTypes.AUDIT_ALLOWED,
+		fmt.Sprintf("%d dequeued, %d cancelled, %d skipped, %d failed", result.Dequeued, len(result.Cancelled), len(result.Skipped), len(result.Failed)))
+
+	return result, nil
+}
+
+// markCallCancelled audits a cancelled or ended call and records it in the run history of its campaign.
+func markCallCancelled(caller Caller, action string, callId string) {
+	recordAudit(caller, action, "call", callId, mongodbTypes.AUDIT_ALLOWED, "")
+
+	if _, err := mongodb.MarkCampaignRunCallCancelled(caller.OrgId, callId, caller.UserId); err != nil {
+		log.Printf("Error recording cancellation of call %s in its campaign run: %v", callId, err)
+	}
+}
+
+// callStatus returns the status of a VapiAI call.
+func callStatus(call *vapiApi.Call) mongodbTypes.CallStatus {
+	if call.Status == nil {
+		return ""
+	}
+	return mongodbTypes.CallStatus(*call.Status)
+}
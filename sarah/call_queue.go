@@ -0,0 +1,496 @@
+package sarah
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"sarah/clerk"
+	"sarah/mongodb"
+	mongodbTypes "sarah/types/mongodb"
+
+	vapiApi "github.com/VapiAI/server-sdk-go"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// QUEUE_DISPATCH_BATCH_SIZE is the maximum number of waiting calls of an organization read per dispatch pass
+const QUEUE_DISPATCH_BATCH_SIZE = 200
+
+// QUEUE_MAX_ATTEMPTS is the number of times a call is sent to VapiAI before it is failed
+const QUEUE_MAX_ATTEMPTS = 3
+
+// ACTIVE_CALL_MAX_AGE is how long a call VapiAI hasn't reported as ended counts against the concurrency limits.
+// It keeps calls whose end was never recorded from holding a slot forever.
+const ACTIVE_CALL_MAX_AGE = 2 * time.Hour
+
+// DISPATCH_TIMEOUT is how long a call can stay in the dispatching status before it is considered interrupted
+const DISPATCH_TIMEOUT = 5 * time.Minute
+
+// dispatchSignals wakes the call dispatcher up when calls are queued or a call ends.
+// It holds a single pending signal, since one dispatch pass serves every organization.
+var dispatchSignals = make(chan struct{}, 1)
+
+// wakeCallDispatcher asks the call dispatcher for a dispatch pass, and adds the organization
+// to the ones it dispatches for if it isn't known yet.
+func wakeCallDispatcher(orgId string) {
+	dispatchOrganizations.Store(orgId, true)
+
+	select {
+	case dispatchSignals <- struct{}{}:
+	default:
+	}
+}
+
+// dispatchOrganizations are the organizations that queued calls since the organization list was last refreshed
+var dispatchOrganizations sync.Map
+
+// globalConcurrencyLimit returns the maximum number of calls in flight across every organization,
+// read from MAX_CONCURRENT_CALLS. 0 places calls without a global limit.
+func globalConcurrencyLimit() int {
+	limit, err := strconv.Atoi(os.Getenv("MAX_CONCURRENT_CALLS"))
+	if err != nil || limit < 0 {
+		return 0
+	}
+	return limit
+}
+
+// enqueueCalls adds one call per customer to the organization's call queue and wakes the dispatcher up.
+func enqueueCalls(orgId string, origin callOrigin, assistantId string, phoneNumberId string, customers []mongodbTypes.Customer) ([]mongodbTypes.QueuedCall, error) {
+	now := time.Now()
+
+	calls := []mongodbTypes.QueuedCall{}
+	for _, customer := range customers {
+		calls = append(calls, mongodbTypes.QueuedCall{
+			AssistantId:      assistantId,
+			PhoneNumberId:    phoneNumberId,
+			Customer:         customer,
+			CampaignId:       origin.campaignId,
+			RunId:            origin.runId,
+			CallerIdStrategy: origin.callerIdStrategy,
+			Status:           mongodbTypes.QUEUE_STATUS_QUEUED,
+			EnqueuedAt:       now,
+			UpdatedAt:        now,
+		})
+	}
+
+	queued, err := mongodb.EnqueueCalls(orgId, calls)
+	if err != nil {
+		log.Printf("Error queueing calls: %v", err)
+		return nil, err
+	}
+
+	log.Printf("[CallQueue] Queued %d calls for organization %s", len(queued), orgId)
+	wakeCallDispatcher(orgId)
+
+	return queued, nil
+}
+
+// GetCallQueue returns a page of the organization's call queue, oldest first.
+func GetCallQueue(orgId string, status mongodbTypes.QueueStatus, page int, limit int) (*mongodbTypes.QueuedCallPage, error) {
+	calls, err := mongodb.GetQueuedCalls(orgId, status, page, limit)
+	if err != nil {
+		log.Printf("Error getting call queue: %v", err)
+		return nil, err
+	}
+
+	return calls, nil
+}
+
+// GetCallQueueStats returns the depth of the organization's call queue, its calls in flight
+// and the concurrency limits that apply to it.
+func GetCallQueueStats(orgId string) (*mongodbTypes.QueueStats, error) {
+	stats, err := mongodb.GetQueueStats(orgId)
+	if err != nil {
+		log.Printf("Error getting call queue stats: %v", err)
+		return nil, err
+	}
+
+	limits, err := loadConcurrencyLimits(orgId)
+	if err != nil {
+		return nil, err
+	}
+	stats.Limits = *limits
+
+	active, err := mongodb.CountActiveCalls(orgId, time.Now().Add(-ACTIVE_CALL_MAX_AGE))
+	if err != nil {
+		log.Printf("Error counting active calls: %v", err)
+		return nil, err
+	}
+	stats.ActiveByPhoneNumber = active
+	for _, count := range active {
+		stats.Active += count
+	}
+
+	return stats, nil
+}
+
+// CancelQueuedCall removes a call of the caller's organization from the queue before it is dispatched.
+// Returns ErrNotFound if the organization has no such queued call and ErrCallState if it already left the queue.
+func CancelQueuedCall(caller Caller, queuedCallId string) (*mongodbTypes.QueuedCall, error) {
+	id, err := bson.ObjectIDFromHex(queuedCallId)
+	if err != nil {
+		recordDenied(caller, "queue.cancel", "queued_call", queuedCallId)
+		return nil, ErrNotFound
+	}
+
+	call, err := mongodb.GetQueuedCallById(caller.OrgId, id)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		recordDenied(caller, "queue.cancel", "queued_call", queuedCallId)
+		return nil, ErrNotFound
+	} else if err != nil {
+		log.Printf("Error getting queued call %s: %v", queuedCallId, err)
+		return nil, err
+	}
+
+	cancelled, err := mongodb.CancelQueuedCalls(caller.OrgId, bson.M{"_id": id})
+	if err != nil {
+		log.Printf("Error cancelling queued call %s: %v", queuedCallId, err)
+		return nil, err
+	}
+	if cancelled == 0 {
+		recordAudit(caller, "queue.cancel", "queued_call", queuedCallId, mongodbTypes.AUDIT_DENIED, fmt.Sprintf("call is %s", call.Status))
+		return nil, fmt.Errorf("%w: call is %s", ErrCallState, call.Status)
+	}
+
+	recordAudit(caller, "queue.cancel", "queued_call", queuedCallId, mongodbTypes.AUDIT_ALLOWED, "")
+
+	call.Status = mongodbTypes.QUEUE_STATUS_CANCELLED
+	return call, nil
+}
+
+// loadConcurrencyLimits reads the global limit and the limits of the organization and its phone numbers.
+func loadConcurrencyLimits(orgId string) (*mongodbTypes.QueueLimits, error) {
+	limits := &mongodbTypes.QueueLimits{
+		Global:       globalConcurrencyLimit(),
+		PhoneNumbers: map[string]int{},
+	}
+
+	settings, err := mongodb.GetOrganizationSettings(orgId)
+	if err != nil {
+		log.Printf("Error getting concurrency limit of organization %s: %v", orgId, err)
+		return nil, err
+	}
+	limits.Organization = settings.MaxConcurrentCalls
+
+	phoneNumbers, err := mongodb.GetPhoneNumberByOrgId(orgId)
+	if err != nil {
+		log.Printf("Error getting phone number concurrency limits of organization %s: %v", orgId, err)
+		return nil, err
+	}
+	for _, phoneNumber := range phoneNumbers {
+		if phoneNumber.MaxConcurrentCalls > 0 {
+			limits.PhoneNumbers[phoneNumber.PhoneNumberId] = phoneNumber.MaxConcurrentCalls
+		}
+	}
+
+	return limits, nil
+}
+
+// CallDispatcher sends queued calls to VapiAI as the concurrency limits allow.
+// A dispatch pass runs whenever calls are queued or a call ends, and at least every Interval
+// to pick up calls queued by other processes or freed by calls whose end was missed.
+type CallDispatcher struct {
+	// Interval is the maximum time between two dispatch passes, defaults to 30 seconds
+	Interval time.Duration
+
+	// OrganizationsInterval is the time between two refreshes of the organization list, defaults to 5 minutes
+	OrganizationsInterval time.Duration
+
+	organizations  []string
+	refreshedAt    time.Time
+	firstOrgOffset int
+}
+
+func (d *CallDispatcher) Start() {
+	if d.Interval <= 0 {
+		d.Interval = 30 * time.Second
+	}
+	if d.OrganizationsInterval <= 0 {
+		d.OrganizationsInterval = 5 * time.Minute
+	}
+
+	go func() {
+		d.run()
+	}()
+}
+
+func (d *CallDispatcher) run() {
+	for {
+		if d.dispatch() > 0 {
+			// The queue may hold more calls than a pass reads, keep going while calls are placed
+			continue
+		}
+
+		select {
+		case <-dispatchSignals:
+		case <-time.After(d.Interval):
+		}
+	}
+}
+
+// dispatch runs a single dispatch pass over every organization and returns the number of calls placed.
+// The global limit is shared, so the organization served first rotates from one pass to the next.
+// Dispatching pauses while the VapiAI circuit is open, leaving calls in the queue.
+func (d *CallDispatcher) dispatch() int {
+	if status := VapiCircuit.Status(); status.State == CIRCUIT_OPEN {
+		log.Printf("[CallDispatcher] VapiAI is unavailable, dispatching paused until %s", status.RetryAt.Format(time.RFC3339))
+		return 0
+	}
+
+	orgIds := d.organizationIds()
+	if len(orgIds) == 0 {
+		return 0
+	}
+
+	since := time.Now().Add(-ACTIVE_CALL_MAX_AGE)
+	active := map[string]map[string]int{}
+	globalActive := 0
+	for _, orgId := range orgIds {
+		counts, err := mongodb.CountActiveCalls(orgId, since)
+		if err != nil {
+			log.Printf("[CallDispatcher] Error counting active calls of organization %s: %v", orgId, err)
+			continue
+		}
+		active[orgId] = counts
+		for _, count := range counts {
+			globalActive += count
+		}
+	}
+
+	dispatched := 0
+	d.firstOrgOffset = (d.firstOrgOffset + 1) % len(orgIds)
+	for i := range orgIds {
+		orgId := orgIds[(d.firstOrgOffset+i)%len(orgIds)]
+		counts, ok := active[orgId]
+		if !ok {
+			continue
+		}
+
+		placed, err := dispatchOrganizationCalls(orgId, counts, &globalActive)
+		if err != nil {
+			log.Printf("[CallDispatcher] Error dispatching calls of organization %s: %v", orgId, err)
+		}
+		dispatched += placed
+	}
+
+	return dispatched
+}
+
+// organizationIds returns the organizations to dispatch calls for: every Clerk organization, refreshed
+// every OrganizationsInterval, and the ones that queued calls since. Refreshing also fails the calls
+// left dispatching by an interrupted dispatcher.
+func (d *CallDispatcher) organizationIds() []string {
+	if time.Since(d.refreshedAt) >= d.OrganizationsInterval {
+		allOrgIDs, err := clerk.GetAllOrganizations()
+		if err != nil {
+			log.Printf("[CallDispatcher] Error getting organizations: %v", err)
+		} else {
+			d.organizations = allOrgIDs
+			d.refreshedAt = time.Now()
+			dispatchOrganizations.Clear()
+
+			for _, orgId := range allOrgIDs {
+				failed, err := mongodb.FailInterruptedDispatches(orgId, time.Now().Add(-DISPATCH_TIMEOUT))
+				if err != nil {
+					log.Printf("[CallDispatcher] Error failing interrupted dispatches of organization %s: %v", orgId, err)
+				} else if failed > 0 {
+					log.Printf("[CallDispatcher] Failed %d interrupted dispatches of organization %s", failed, orgId)
+				}
+			}
+		}
+	}
+
+	known := map[string]bool{}
+	for _, orgId := range d.organizations {
+		known[orgId] = true
+	}
+
+	orgIds := append([]string{}, d.organizations...)
+	dispatchOrganizations.Range(func(key, value any) bool {
+		if orgId := key.(string); !known[orgId] {
+			orgIds = append(orgIds, orgId)
+		}
+		return true
+	})
+
+	return orgIds
+}
+
+// dispatchOrganizationCalls sends the organization's waiting calls to VapiAI, oldest first, until the global
+// or organization limit is reached. Calls from a phone number at its limit wait for the next pass without
+// holding back the calls of other numbers. active and globalActive are updated with the dispatched calls.
+// Returns the number of calls placed.
+func dispatchOrganizationCalls(orgId string, active map[string]int, globalActive *int) (int, error) {
+	limits, err := loadConcurrencyLimits(orgId)
+	if err != nil {
+		return 0, err
+	}
+
+	orgActive := 0
+	for _, count := range active {
+		orgActive += count
+	}
+	if (limits.Global > 0 && *globalActive >= limits.Global) || (limits.Organization > 0 && orgActive >= limits.Organization) {
+		return 0, nil
+	}
+
+	// Calls from numbers already at their limit aren't read, so they don't fill the batch
+	saturated := []string{}
+	for phoneNumberId, limit := range limits.PhoneNumbers {
+		if active[phoneNumberId] >= limit {
+			saturated = append(saturated, phoneNumberId)
+		}
+	}
+
+	calls, err := mongodb.GetDispatchableCalls(orgId, saturated, QUEUE_DISPATCH_BATCH_SIZE)
+	if err != nil {
+		return 0, err
+	}
+
+	dispatched := 0
+	for _, call := range calls {
+		if VapiCircuit.State() == CIRCUIT_OPEN {
+			break
+		}
+		if limits.Global > 0 && *globalActive >= limits.Global {
+			break
+		}
+		if limits.Organization > 0 && orgActive >= limits.Organization {
+			break
+		}
+		if limit := limits.PhoneNumbers[call.PhoneNumberId]; limit > 0 && active[call.PhoneNumberId] >= limit {
+			continue
+		}
+
+		if dispatchQueuedCall(orgId, call) {
+			active[call.PhoneNumberId]++
+			orgActive++
+			*globalActive++
+			dispatched++
+		}
+	}
+
+	if dispatched > 0 {
+		log.Printf("[CallDispatcher] Dispatched %d calls of organization %s", dispatched, orgId)
+	}
+
+	return dispatched, nil
+}
+
+// dispatchQueuedCall claims a waiting call and sends it to VapiAI. Customers added to a Do-Not-Call list
+// while their call was waiting are not called. A call VapiAI rejects goes back to the queue until it was
+// tried QUEUE_MAX_ATTEMPTS times, while a call not sent because the VapiAI circuit is open goes back
+// without counting the attempt. A call whose request failed with a server or network error may have been
+// placed, so it is failed for a human to review rather than sent again, and counts towards the daily cap
+// of its phone number. Returns whether VapiAI accepted the call.
+func dispatchQueuedCall(orgId string, call mongodbTypes.QueuedCall) bool {
+	claimed, err := mongodb.ClaimQueuedCall(orgId, call.Id)
+	if err != nil || !claimed {
+		return false
+	}
+	call.Attempts++
+
+	_, suppressed, err := suppressCustomers(orgId, []mongodbTypes.Customer{call.Customer})
+	if err != nil {
+		log.Printf("[CallDispatcher] Error checking the do-not-call lists for queued call %s: %v", call.Id.Hex(), err)
+		releaseQueuedCall(orgId, call, err)
+		return false
+	}
+	if len(suppressed) > 0 {
+		if err := mongodb.ReleaseQueuedCall(orgId, call.Id, mongodbTypes.QUEUE_STATUS_CANCELLED, ErrAllSuppressed.Error()); err != nil {
+			log.Printf("[CallDispatcher] Error cancelling suppressed queued call %s: %v", call.Id.Hex(), err)
+		}
+		return false
+	}
+
+	resp, err := Telephony.CreateCall(context.Background(), createCallRequest(call))
+	if err == nil && len(createdCalls(resp)) == 0 {
+		err = errors.New("VapiAI accepted the request without creating a call")
+	}
+	if errors.Is(err, ErrCircuitOpen) {
+		if err := mongodb.RequeueQueuedCall(orgId, call.Id, err.Error()); err != nil {
+			log.Printf("[CallDispatcher] Error requeuing queued call %s: %v", call.Id.Hex(), err)
+		}
+		return false
+	} else if err != nil && isRequestRejected(err) {
+		log.Printf("[CallDispatcher] Error creating queued call %s: %v", call.Id.Hex(), err)
+		releaseQueuedCall(orgId, call, err)
+		return false
+	} else if err != nil {
+		// VapiAI may have placed the call before the request failed, so it isn't sent again
+		log.Printf("[CallDispatcher] Queued call %s may have been placed, failing it for review: %v", call.Id.Hex(), err)
+		reason := fmt.Sprintf("VapiAI may have placed the call before the request failed (%v); review it before calling the customer again", err)
+		if err := mongodb.ReleaseQueuedCall(orgId, call.Id, mongodbTypes.QUEUE_STATUS_FAILED, reason); err != nil {
+			log.Printf("[CallDispatcher] Error failing queued call %s: %v", call.Id.Hex(), err)
+		}
+		recordPhoneNumberUsage(orgId, call.PhoneNumberId, 1)
+		return false
+	}
+
+	// Usage is recorded before the call leaves the dispatching status, so the call always counts towards the daily cap
+	recordPhoneNumberUsage(orgId, call.PhoneNumberId, 1)
+
+	vapiCallId := createdCalls(resp)[0].Id
+	if err := mongodb.MarkQueuedCallDispatched(orgId, call.Id, vapiCallId); err != nil {
+		log.Printf("[CallDispatcher] Error marking queued call %s as dispatched: %v", call.Id.Hex(), err)
+	}
+
+	recordCalls(orgId, callOrigin{campaignId: call.CampaignId, runId: call.RunId, callerIdStrategy: call.CallerIdStrategy}, resp)
+
+	if call.RunId != nil {
+		if err := mongodb.AddCampaignRunCall(orgId, *call.RunId, vapiCallId); err != nil {
+			log.Printf("[CallDispatcher] Error recording call %s in its campaign run: %v", vapiCallId, err)
+		}
+	}
+
+	return true
+}
+
+// createCallRequest builds the VapiAI request placing a queued call. The customer's name is sent along,
+// and its variables and time zone are given to the assistant as template variable values.
+func createCallRequest(call mongodbTypes.QueuedCall) *vapiApi.CreateCallDto {
+	customer := &vapiApi.CreateCustomerDto{Number: vapiApi.String(call.Customer.PhoneNumber)}
+	if call.Customer.Name != "" {
+		customer.Name = vapiApi.String(call.Customer.Name)
+	}
+
+	request := &vapiApi.CreateCallDto{
+		AssistantId:   vapiApi.String(call.AssistantId),
+		PhoneNumberId: vapiApi.String(call.PhoneNumberId),
+		Customer:      customer,
+	}
+
+	variables := map[string]interface{}{}
+	for name, value := range call.Customer.Variables {
+		variables[name] = value
+	}
+	if _, ok := variables["timezone"]; !ok && call.Customer.TimeZone != "" {
+		variables["timezone"] = call.Customer.TimeZone
+	}
+	if len(variables) > 0 {
+		request.AssistantOverrides = &vapiApi.AssistantOverrides{VariableValues: variables}
+	}
+
+	return request
+}
+
+// releaseQueuedCall puts a call whose dispatch failed back in the queue, or fails it after QUEUE_MAX_ATTEMPTS.
+// Returns the status the call was moved to.
+func releaseQueuedCall(orgId string, call mongodbTypes.QueuedCall, cause error) mongodbTypes.QueueStatus {
+	status := mongodbTypes.QUEUE_STATUS_QUEUED
+	if call.Attempts >= QUEUE_MAX_ATTEMPTS {
+		status = mongodbTypes.QUEUE_STATUS_FAILED
+	}
+
+	if err := mongodb.ReleaseQueuedCall(orgId, call.Id, status, cause.Error()); err != nil {
+		log.Printf("[CallDispatcher] Error releasing queued call %s: %v", call.Id.Hex(), err)
+	}
+
+	return status
+}
@@ -0,0 +1,279 @@
+package sarah
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"sarah/mongodb"
+	mongodbTypes "sarah/types/mongodb"
+)
+
+// enqueueTestCall queues a call to customerNumber and returns it.
+func enqueueTestCall(t *testing.T, orgId string, assistantId string, phoneNumberId string, customerNumber string) mongodbTypes.QueuedCall {
+	t.Helper()
+
+	queued, err := enqueueCalls(orgId, callOrigin{}, assistantId, phoneNumberId, []mongodbTypes.Customer{{PhoneNumber: customerNumber}})
+	if err != nil {
+		t.Fatalf("queueing call: %v", err)
+	}
+	if len(queued) != 1 {
+		t.Fatalf("queued %d calls, want 1", len(queued))
+	}
+
+	return queued[0]
+}
+
+// storedQueuedCall reads a queued call back from the database.
+func storedQueuedCall(t *testing.T, orgId string, call mongodbTypes.QueuedCall) mongodbTypes.QueuedCall {
+	t.Helper()
+
+	stored, err := mongodb.GetQueuedCallById(orgId, call.Id)
+	if err != nil {
+		t.Fatalf("getting queued call %s: %v", call.Id.Hex(), err)
+	}
+
+	return *stored
+}
+
+// todaysUsage returns the number of calls recorded today for a phone number.
+func todaysUsage(t *testing.T, orgId string, phoneNumberId string) int {
+	t.Helper()
+
+	usage, err := mongodb.GetPhoneNumberUsage(orgId, usageDate(), []string{phoneNumberId})
+	if err != nil {
+		t.Fatalf("getting phone number usage: %v", err)
+	}
+
+	return usage[phoneNumberId]
+}
+
+// placedCalls returns the number of calls placed with a FakeProvider.
+func placedCalls(t *testing.T, fake *FakeProvider) int {
+	t.Helper()
+
+	calls, err := fake.ListCalls(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("listing calls: %v", err)
+	}
+
+	return len(calls)
+}
+
+func TestDispatchQueuedCallPlacesCall(t *testing.T) {
+	orgId := newTestOrganization(t)
+	fake, assistantId, phoneNumberId := newFakeTelephony(t)
+	call := enqueueTestCall(t, orgId, assistantId, phoneNumberId, "+15557654321")
+
+	if !dispatchQueuedCall(orgId, call) {
+		t.Fatal("dispatchQueuedCall didn't place the call")
+	}
+
+	stored := storedQueuedCall(t, orgId, call)
+	if stored.Status != mongodbTypes.QUEUE_STATUS_DISPATCHED || stored.Attempts != 1 || stored.DispatchedAt == nil {
+		t.Errorf("queued call is %s after %d attempts, dispatched at %v; want dispatched after 1 attempt", stored.Status, stored.Attempts, stored.DispatchedAt)
+	}
+
+	placed, err := fake.GetCall(context.Background(), stored.VapiCallId)
+	if err != nil {
+		t.Fatalf("queued call points to call %q, which wasn't placed: %v", stored.VapiCallId, err)
+	}
+	if number := derefString(placed.Customer.Number); number != "+15557654321" {
+		t.Errorf("placed call is to %s, want +15557654321", number)
+	}
+
+	record, err := mongodb.GetCallByVapiId(orgId, stored.VapiCallId)
+	if err != nil {
+		t.Fatalf("placed call wasn't recorded: %v", err)
+	}
+	if record.Status != mongodbTypes.CALL_STATUS_QUEUED {
+		t.Errorf("recorded call is %s, want queued", record.Status)
+	}
+
+	if usage := todaysUsage(t, orgId, phoneNumberId); usage != 1 {
+		t.Errorf("phone number was used for %d calls today, want 1", usage)
+	}
+}
+
+func TestDispatchQueuedCallReleasesRejectedCall(t *testing.T) {
+	orgId := newTestOrganization(t)
+	fake, assistantId, phoneNumberId := newFakeTelephony(t)
+	fake.CreateCallError = apiError(http.StatusBadRequest)
+	call := enqueueTestCall(t, orgId, assistantId, phoneNumberId, "+15557654321")
+
+	for attempt := 1; attempt <= QUEUE_MAX_ATTEMPTS; attempt++ {
+		if dispatchQueuedCall(orgId, storedQueuedCall(t, orgId, call)) {
+			t.Fatalf("attempt %d placed a call VapiAI rejected", attempt)
+		}
+
+		want := mongodbTypes.QUEUE_STATUS_QUEUED
+		if attempt == QUEUE_MAX_ATTEMPTS {
+			want = mongodbTypes.QUEUE_STATUS_FAILED
+		}
+		stored := storedQueuedCall(t, orgId, call)
+		if stored.Status != want || stored.Attempts != attempt {
+			t.Fatalf("queued call is %s after %d attempts, want %s after %d", stored.Status, stored.Attempts, want, attempt)
+		}
+		if stored.Error == "" {
+			t.Errorf("attempt %d didn't record the error", attempt)
+		}
+	}
+
+	// A failed call stays out of the queue
+	if dispatchQueuedCall(orgId, storedQueuedCall(t, orgId, call)) {
+		t.Error("a failed call was dispatched")
+	}
+	if usage := todaysUsage(t, orgId, phoneNumberId); usage != 0 {
+		t.Errorf("phone number was used for %d calls today, want 0", usage)
+	}
+}
+
+func TestDispatchQueuedCallFailsCallThatMayHaveBeenPlaced(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"server error", apiError(http.StatusInternalServerError)},
+		{"network error", errors.New("read: connection reset by peer")},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			orgId := newTestOrganization(t)
+			fake := &flakyProvider{FakeProvider: NewFakeProvider()}
+			assistantId, phoneNumberId := addFakeLine(t, fake.FakeProvider)
+			fake.createCallErrors = []error{test.err}
+
+			breaker := &CircuitBreaker{Name: "test", FailureThreshold: CIRCUIT_FAILURE_THRESHOLD, OpenDuration: time.Minute}
+			useTelephony(t, NewResilientProvider(fake, breaker))
+
+			call := enqueueTestCall(t, orgId, assistantId, phoneNumberId, "+15557654321")
+			for pass := 1; pass <= QUEUE_MAX_ATTEMPTS; pass++ {
+				if dispatchQueuedCall(orgId, storedQueuedCall(t, orgId, call)) {
+					t.Fatalf("pass %d placed the call again", pass)
+				}
+			}
+
+			if fake.createCallAttempts != 1 {
+				t.Errorf("VapiAI was asked to create the call %d times, want 1", fake.createCallAttempts)
+			}
+			stored := storedQueuedCall(t, orgId, call)
+			if stored.Status != mongodbTypes.QUEUE_STATUS_FAILED || stored.Error == "" {
+				t.Errorf("queued call is %s with error %q, want failed with the review reason", stored.Status, stored.Error)
+			}
+			if usage := todaysUsage(t, orgId, phoneNumberId); usage != 1 {
+				t.Errorf("phone number was used for %d calls today, want 1", usage)
+			}
+		})
+	}
+}
+
+func TestDispatchQueuedCallRequeuesWhileCircuitOpen(t *testing.T) {
+	orgId := newTestOrganization(t)
+	fake := NewFakeProvider()
+	assistantId, phoneNumberId := addFakeLine(t, fake)
+
+	breaker := &CircuitBreaker{Name: "test", FailureThreshold: 1, OpenDuration: time.Minute}
+	breaker.Record(apiError(http.StatusServiceUnavailable))
+	useTelephony(t, NewResilientProvider(fake, breaker))
+
+	call := enqueueTestCall(t, orgId, assistantId, phoneNumberId, "+15557654321")
+	if dispatchQueuedCall(orgId, call) {
+		t.Fatal("a call was placed while the circuit is open")
+	}
+
+	stored := storedQueuedCall(t, orgId, call)
+	if stored.Status != mongodbTypes.QUEUE_STATUS_QUEUED || stored.Attempts != 0 {
+		t.Errorf("queued call is %s after %d attempts, want queued after 0", stored.Status, stored.Attempts)
+	}
+	if placedCalls(t, fake) != 0 {
+		t.Errorf("%d calls reached the provider while the circuit is open", placedCalls(t, fake))
+	}
+}
+
+func TestDispatchQueuedCallCancelsSuppressedCall(t *testing.T) {
+	orgId := newTestOrganization(t)
+	fake, assistantId, phoneNumberId := newFakeTelephony(t)
+	call := enqueueTestCall(t, orgId, assistantId, phoneNumberId, "+15557654321")
+
+	// The customer opts out while their call waits in the queue
+	_, err := mongodb.AddDncEntries(orgId, []mongodbTypes.DncEntry{{
+		PhoneNumber: "+15557654321",
+		Reason:      "customer opted out",
+		Source:      mongodbTypes.DNC_SOURCE_MANUAL,
+		AddedBy:     "user_test",
+		CreatedAt:   time.Now(),
+	}})
+	if err != nil {
+		t.Fatalf("adding do-not-call entry: %v", err)
+	}
+
+	if dispatchQueuedCall(orgId, call) {
+		t.Fatal("a suppressed customer was called")
+	}
+
+	stored := storedQueuedCall(t, orgId, call)
+	if stored.Status != mongodbTypes.QUEUE_STATUS_CANCELLED {
+		t.Errorf("queued call is %s, want cancelled", stored.Status)
+	}
+	if placedCalls(t, fake) != 0 {
+		t.Errorf("%d calls reached the provider for a suppressed customer", placedCalls(t, fake))
+	}
+}
+
+func TestCancelQueuedCall(t *testing.T) {
+	orgId := newTestOrganization(t)
+	_, assistantId, phoneNumberId := newFakeTelephony(t)
+	caller := Caller{OrgId: orgId, UserId: "user_test"}
+	call := enqueueTestCall(t, orgId, assistantId, phoneNumberId, "+15557654321")
+
+	cancelled, err := CancelQueuedCall(caller, call.Id.Hex())
+	if err != nil {
+		t.Fatalf("cancelling queued call: %v", err)
+	}
+	if cancelled.Status != mongodbTypes.QUEUE_STATUS_CANCELLED {
+		t.Errorf("cancelled call is %s, want cancelled", cancelled.Status)
+	}
+	if dispatchQueuedCall(orgId, storedQueuedCall(t, orgId, call)) {
+		t.Error("a cancelled call was dispatched")
+	}
+
+	if _, err := CancelQueuedCall(caller, call.Id.Hex()); !errors.Is(err, ErrCallState) {
+		t.Errorf("cancelling a cancelled call returned %v, want ErrCallState", err)
+	}
+	if _, err := CancelQueuedCall(caller, "not-an-id"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("cancelling an invalid ID returned %v, want ErrNotFound", err)
+	}
+	if _, err := CancelQueuedCall(Caller{OrgId: newTestOrganization(t), UserId: "user_other"}, call.Id.Hex()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("cancelling another organization's call returned %v, want ErrNotFound", err)
+	}
+}
+
+func TestPendingCallsCountTowardsPhoneNumberUsage(t *testing.T) {
+	orgId := newTestOrganization(t)
+	_, assistantId, phoneNumberId := newFakeTelephony(t)
+	first := enqueueTestCall(t, orgId, assistantId, phoneNumberId, "+15557654321")
+	enqueueTestCall(t, orgId, assistantId, phoneNumberId, "+15557654322")
+
+	assertUsage := func(want int) {
+		t.Helper()
+		usage, err := phoneNumberUsage(orgId, []string{phoneNumberId})
+		if err != nil {
+			t.Fatalf("getting phone number usage: %v", err)
+		}
+		if usage[phoneNumberId] != want {
+			t.Errorf("phone number usage is %d, want %d", usage[phoneNumberId], want)
+		}
+	}
+
+	// Queued calls count before they are placed, and placing one doesn't count it twice
+	assertUsage(2)
+	if todaysUsage(t, orgId, phoneNumberId) != 0 {
+		t.Error("usage was recorded before any call was placed")
+	}
+	if !dispatchQueuedCall(orgId, first) {
+		t.Fatal("dispatchQueuedCall didn't place the call")
+	}
+	assertUsage(2)
+}
@@ -0,0 +1,273 @@
+package sarah
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"sarah/mongodb"
+	mongodbTypes "sarah/types/mongodb"
+	vapiTypes "sarah/types/vapi"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// STREAM_EVENT_ENDED is the stream event of end-of-call reports, also sent as the status of ended calls
+const STREAM_EVENT_ENDED = "ended"
+
+// STREAM_EVENT_TRANSCRIPT is the stream event of final transcripts
+const STREAM_EVENT_TRANSCRIPT = "transcript"
+
+// CALL_STREAM_BATCH_SIZE is the maximum number of events read per query of a call stream
+const CALL_STREAM_BATCH_SIZE = 200
+
+// CALL_STREAM_POLL_INTERVAL is how often a call stream checks for events received by other instances
+const CALL_STREAM_POLL_INTERVAL = 5 * time.Second
+
+// CALL_STREAM_HEARTBEAT_INTERVAL is how often an idle call stream sends a heartbeat, keeping proxies from closing it
+const CALL_STREAM_HEARTBEAT_INTERVAL = 15 * time.Second
+
+// CALL_STREAM_SETTLE_WINDOW is how far back a call stream reads events again. Concurrent webhooks may store
+// events out of _id order, so events stored late within the window are still sent, once.
+const CALL_STREAM_SETTLE_WINDOW = 5 * time.Second
+
+// streamedStatuses are the call statuses whose status-update messages are streamed
+var streamedStatuses = map[mongodbTypes.CallStatus]bool{
+	mongodbTypes.CALL_STATUS_QUEUED:      true,
+	mongodbTypes.CALL_STATUS_RINGING:     true,
+	mongodbTypes.CALL_STATUS_IN_PROGRESS: true,
+	mongodbTypes.CALL_STATUS_FORWARDING:  true,
+	mongodbTypes.CALL_STATUS_ENDED:       true,
+}
+
+// CallStreamEvent is a call lifecycle event sent on /calls/stream.
+type CallStreamEvent struct {
+	// Id is the hex ObjectID of the stored call event, sent as the SSE event ID
+	Id string `json:"id"`
+
+	// Event is the SSE event name: the new call status, or "transcript"
+	Event string `json:"event"`
+
+	// VapiCallId is the VapiAI call the event is about
+	VapiCallId string `json:"vapi_call_id"`
+
+	// AssistantId is the VapiAI assistant that handled the call
+	AssistantId string `json:"assistant_id"`
+
+	// CampaignId is the hex ObjectID of the campaign that placed the call, empty for calls created directly
+	CampaignId string `json:"campaign_id"`
+
+	// EndedReason is why the call ended, set on ended events
+	EndedReason string `json:"ended_reason,omitempty"`
+
+	// Role is who spoke ("user" or "assistant"), set on transcript events
+	Role string `json:"role,omitempty"`
+
+	// Transcript is the transcribed speech, set on transcript events
+	Transcript string `json:"transcript,omitempty"`
+
+	// OccurredAt is when VapiAI emitted the event
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// CallStreamFilter narrows down the events of a call stream. Empty fields are ignored.
+type CallStreamFilter struct {
+	// CampaignId only streams events of calls placed by this campaign
+	CampaignId string
+
+	// AssistantId only streams events of calls handled by this VapiAI assistant
+	AssistantId string
+}
+
+// CallStreamWriter writes the events of a call stream to a client.
+type CallStreamWriter interface {
+	// Open starts the stream, once it is authorized and before any event is sent
+	Open() error
+
+	// Send sends an event
+	Send(event CallStreamEvent) error
+
+	// Heartbeat tells the client the stream is alive while no event is sent
+	Heartbeat() error
+}
+
+// callStreamSignals holds, per organization, a channel closed when the organization receives a streamed event
+var callStreamSignals = struct {
+	sync.Mutex
+	channels map[string]chan struct{}
+}{channels: map[string]chan struct{}{}}
+
+// callStreamSignal returns the channel closed when the organization next receives a streamed event.
+func callStreamSignal(orgId string) <-chan struct{} {
+	callStreamSignals.Lock()
+	defer callStreamSignals.Unlock()
+
+	signal, ok := callStreamSignals.channels[orgId]
+	if !ok {
+		signal = make(chan struct{})
+		callStreamSignals.channels[orgId] = signal
+	}
+	return signal
+}
+
+// notifyCallStreams wakes up the call streams of an organization after a streamed event was stored.
+func notifyCallStreams(orgId string) {
+	callStreamSignals.Lock()
+	defer callStreamSignals.Unlock()
+
+	if signal, ok := callStreamSignals.channels[orgId]; ok {
+		close(signal)
+		delete(callStreamSignals.channels, orgId)
+	}
+}
+
+// streamEventName returns the name a server message is streamed under, or an empty string
+// if it isn't streamed: the status of status updates, "ended" for end-of-call reports
+// and "transcript" for final transcripts.
+func streamEventName(message vapiTypes.ServerMessage) string {
+	switch {
+	case message.Type == vapiTypes.MESSAGE_STATUS_UPDATE:
+		if streamedStatuses[mongodbTypes.CallStatus(message.Status)] {
+			return message.Status
+		}
+	case message.Type == vapiTypes.MESSAGE_END_OF_CALL_REPORT:
+		return STREAM_EVENT_ENDED
+	case isTranscriptMessage(message.Type):
+		final := message.TranscriptType == "final" || strings.Contains(string(message.Type), `"final"`)
+		if final && message.Transcript != "" {
+			return STREAM_EVENT_TRANSCRIPT
+		}
+	}
+
+	return ""
+}
+
+// streamedCallCampaign returns the campaign that placed a call, or an empty string if the call
+// was created directly or isn't recorded yet.
+func streamedCallCampaign(orgId string, vapiCallId string) string {
+	call, err := mongodb.GetCallByVapiId(orgId, vapiCallId)
+	if err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			log.Printf("[Webhook] Error getting campaign of call %s: %v", vapiCallId, err)
+		}
+		return ""
+	}
+	return call.CampaignId
+}
+
+// StreamCallEvents sends the lifecycle events of the caller's calls to writer as the webhook receiver stores them,
+// until ctx is done or writing fails. If lastEventId is the ID of an event sent before, the events stored
+// since are sent first, so a client that reconnects misses nothing; otherwise only new events are sent.
+// A heartbeat is sent while no event is sent for CALL_STREAM_HEARTBEAT_INTERVAL.
+// Returns ErrNotFound, before the stream is opened, if the filter names a campaign or assistant of another organization.
+func StreamCallEvents(ctx context.Context, caller Caller, filter CallStreamFilter, lastEventId string, writer CallStreamWriter) error {
+	if filter.CampaignId != "" {
+		if _, err := AuthorizeCampaign(caller, "calls.stream", filter.CampaignId); err != nil {
+			return err
+		}
+	}
+	if filter.AssistantId != "" {
+		if _, err := AuthorizeAssistant(caller, "calls.stream", filter.AssistantId); err != nil {
+			return err
+		}
+	}
+
+	if err := writer.Open(); err != nil {
+		return err
+	}
+
+	// Events after the last one the client received, or only new events
+	start, err := bson.ObjectIDFromHex(lastEventId)
+	if err != nil {
+		start = bson.NewObjectIDFromTimestamp(time.Now())
+	}
+
+	after := start
+	sent := map[bson.ObjectID]time.Time{}
+	catchingUp := false
+	heartbeatAt := time.Now().Add(CALL_STREAM_HEARTBEAT_INTERVAL)
+
+	for {
+		// Subscribe before reading, so an event stored during the read wakes the stream up
+		signal := callStreamSignal(caller.OrgId)
+
+		// Read the settle window again unless catching up with a backlog, never going back before start
+		from := after
+		if !catchingUp {
+			settled := bson.NewObjectIDFromTimestamp(time.Now().Add(-CALL_STREAM_SETTLE_WINDOW))
+			if bytes.Compare(settled[:], from[:]) < 0 {
+				from = settled
+			}
+			if bytes.Compare(from[:], start[:]) < 0 {
+				from = start
+			}
+		}
+
+		events, err := mongodb.GetStreamedCallEvents(caller.OrgId, filter.CampaignId, filter.AssistantId, from, CALL_STREAM_BATCH_SIZE)
+		if err != nil {
+			log.Printf("Error reading call events of organization %s: %v", caller.OrgId, err)
+			return err
+		}
+
+		for _, event := range events {
+			if bytes.Compare(event.Id[:], after[:]) > 0 {
+				after = event.Id
+			}
+			if _, ok := sent[event.Id]; ok {
+				continue
+			}
+
+			if err := writer.Send(callStreamEvent(event)); err != nil {
+				return err
+			}
+			sent[event.Id] = time.Now()
+			heartbeatAt = time.Now().Add(CALL_STREAM_HEARTBEAT_INTERVAL)
+		}
+
+		// Events older than the settle window are never read again
+		for id, sentAt := range sent {
+			if time.Since(sentAt) > 2*CALL_STREAM_SETTLE_WINDOW {
+				delete(sent, id)
+			}
+		}
+
+		catchingUp = len(events) == CALL_STREAM_BATCH_SIZE
+		if catchingUp {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-signal:
+		case <-time.After(CALL_STREAM_POLL_INTERVAL):
+		}
+
+		if time.Now().After(heartbeatAt) {
+			if err := writer.Heartbeat(); err != nil {
+				return err
+			}
+			heartbeatAt = time.Now().Add(CALL_STREAM_HEARTBEAT_INTERVAL)
+		}
+	}
+}
+
+// callStreamEvent converts a stored call event into the event sent on the stream.
+func callStreamEvent(event mongodbTypes.CallEvent) CallStreamEvent {
+	return CallStreamEvent{
+		Id:          event.Id.Hex(),
+		Event:       event.StreamEvent,
+		VapiCallId:  event.VapiCallId,
+		AssistantId: event.AssistantId,
+		CampaignId:  event.CampaignId,
+		EndedReason: event.EndedReason,
+		Role:        event.Role,
+		Transcript:  event.Transcript,
+		OccurredAt:  event.OccurredAt,
+	}
+}
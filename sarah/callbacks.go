@@ -0,0 +1,358 @@
+package sarah
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"sarah/clerk"
+	"sarah/mongodb"
+	mongodbTypes "sarah/types/mongodb"
+	vapiTypes "sarah/types/vapi"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// CALLBACK_MAX_DELAY is how far in the future a callback can be scheduled
+const CALLBACK_MAX_DELAY = 90 * 24 * time.Hour
+
+// CALLBACK_REASON_MAX_LENGTH is the maximum number of characters of a callback reason
+const CALLBACK_REASON_MAX_LENGTH = 500
+
+// CALLBACK_BATCH_SIZE is the maximum number of due callbacks of an organization queued per scheduler pass
+const CALLBACK_BATCH_SIZE = 200
+
+// callbackTimeLayouts are the local time formats accepted for a callback without a UTC offset,
+// read in the time zone given with the callback
+var callbackTimeLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+// CallbackRequest are the arguments the assistant passes to the callback tool.
+type CallbackRequest struct {
+	// Time is when to call the customer back, in RFC 3339 or as a local time read in TimeZone
+	Time string `json:"time"`
+
+	// TimeZone is the IANA time zone of a Time without UTC offset (e.g., "America/New_York"), UTC if empty
+	TimeZone string `json:"timeZone"`
+
+	// Reason is why the customer wants to be called back
+	Reason string `json:"reason"`
+}
+
+// HandleCallbackToolCalls registers a callback for every tool call of a "tool-calls" message sent by VapiAI
+// to the callback tool's server URL. The callback is placed to the customer of the call, with the same
+// assistant and phone number, and linked to the call and to the organization's contact with the customer's number.
+//
+// Invalid arguments are reported to the assistant in the tool call's result, so it can ask the customer again.
+//
+// Returns:
+//   - string: The organization ID the call belongs to
+//   - []vapiTypes.ToolCallResult: One result per tool call
+//   - error: ErrOrganizationNotFound if the call can't be mapped to an organization
+func HandleCallbackToolCalls(message vapiTypes.ServerMessage) (string, []vapiTypes.ToolCallResult, error) {
+	if message.Call == nil || message.Call.Id == "" {
+		return "", nil, fmt.Errorf("server message %s has no call", message.Type)
+	}
+
+	orgId, err := ResolveCallOrganization(message.Call.AssistantId, message.Call.PhoneNumberId)
+	if err != nil {
+		log.Printf("[Callbacks] Could not resolve organization for call %s: %v", message.Call.Id, err)
+		return "", nil, err
+	}
+
+	results := []vapiTypes.ToolCallResult{}
+	for _, toolCall := range message.ToolCallList {
+		result := vapiTypes.ToolCallResult{ToolCallId: toolCall.Id}
+
+		callback, err := requestCallback(orgId, message.Call, toolCall)
+		var invalid *ValidationError
+		if errors.As(err, &invalid) {
+			result.Error = invalid.Error()
+		} else if err != nil {
+			result.Error = "The callback could not be registered, please try again later"
+		} else {
+			result.Result = fmt.Sprintf("Callback scheduled for %s", callback.ScheduledAt.Format(time.RFC3339))
+		}
+
+		results = append(results, result)
+	}
+
+	return orgId, results, nil
+}
+
+// requestCallback validates the arguments of a callback tool call and stores the callback.
+// Returns a *ValidationError if the arguments are invalid.
+func requestCallback(orgId string, call *vapiTypes.Call, toolCall vapiTypes.ToolCall) (*mongodbTypes.Callback, error) {
+	request, err := decodeCallbackRequest(toolCall.Function.Arguments)
+	if err != nil {
+		return nil, &ValidationError{Errors: []FieldError{{Field: "arguments", Value: string(toolCall.Function.Arguments), Message: "must be a JSON object"}}}
+	}
+
+	invalid := &ValidationError{}
+
+	scheduledAt, err := parseCallbackTime(request.Time, request.TimeZone, time.Now())
+	if err != nil {
+		invalid.Errors = append(invalid.Errors, FieldError{Field: "time", Value: request.Time, Message: err.Error()})
+	}
+
+	reason := strings.TrimSpace(request.Reason)
+	if len([]rune(reason)) > CALLBACK_REASON_MAX_LENGTH {
+		invalid.Errors = append(invalid.Errors, FieldError{Field: "reason", Value: reason, Message: fmt.Sprintf("must be at most %d characters", CALLBACK_REASON_MAX_LENGTH)})
+	}
+
+	customerNumber := ""
+	if call.Customer != nil {
+		customerNumber = call.Customer.Number
+	}
+	customers, invalidNumber := normalizeCustomers([]mongodbTypes.Customer{{PhoneNumber: customerNumber}}, "customer", organizationCountry(orgId))
+	if invalidNumber != nil {
+		invalid.Errors = append(invalid.Errors, invalidNumber.Errors...)
+	}
+
+	if err := invalid.orNil(); err != nil {
+		return nil, err
+	}
+
+	callback := mongodbTypes.Callback{
+		ToolCallId:    toolCall.Id,
+		VapiCallId:    call.Id,
+		AssistantId:   call.AssistantId,
+		PhoneNumberId: call.PhoneNumberId,
+		Customer:      customers[0],
+		ScheduledAt:   scheduledAt,
+		Reason:        reason,
+		Status:        mongodbTypes.CALLBACK_STATUS_SCHEDULED,
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+	if call.Customer != nil {
+		callback.CustomerName = call.Customer.Name
+	}
+
+	contact, err := mongodb.GetContactByPhoneNumber(orgId, callback.Customer.PhoneNumber)
+	if err == nil {
+		callback.ContactId = &contact.Id
+	} else if !errors.Is(err, mongo.ErrNoDocuments) {
+		log.Printf("Error finding contact of callback for call %s: %v", call.Id, err)
+		return nil, err
+	}
+
+	stored, err := mongodb.CreateCallback(orgId, callback)
+	if err != nil {
+		log.Printf("Error storing callback for call %s: %v", call.Id, err)
+		return nil, err
+	}
+
+	log.Printf("[Callbacks] Scheduled callback %s for call %s at %s", stored.Id.Hex(), call.Id, stored.ScheduledAt.Format(time.RFC3339))
+	return stored, nil
+}
+
+// decodeCallbackRequest decodes the arguments of a callback tool call,
+// whether VapiAI sent them as a JSON object or as a string holding one.
+func decodeCallbackRequest(arguments json.RawMessage) (CallbackRequest, error) {
+	var request CallbackRequest
+
+	var encoded string
+	if err := json.Unmarshal(arguments, &encoded); err == nil {
+		arguments = json.RawMessage(encoded)
+	}
+
+	err := json.Unmarshal(arguments, &request)
+	return request, err
+}
+
+// parseCallbackTime reads the time of a callback, in RFC 3339 or as a local time in the given IANA time zone.
+// The time must be after now and at most CALLBACK_MAX_DELAY later.
+func parseCallbackTime(value string, timeZone string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, errors.New("is required")
+	}
+
+	scheduledAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		location := time.UTC
+		if timeZone != "" {
+			location, err = time.LoadLocation(timeZone)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("time zone %s is not a valid IANA time zone", timeZone)
+			}
+		}
+
+		parsed := false
+		for _, layout := range callbackTimeLayouts {
+			if scheduledAt, err = time.ParseInLocation(layout, value, location); err == nil {
+				parsed = true
+				break
+			}
+		}
+		if !parsed {
+			return time.Time{}, errors.New("must be an ISO 8601 date and time, e.g. 2024-01-02T15:00:00-05:00")
+		}
+	}
+
+	if !scheduledAt.After(now) {
+		return time.Time{}, errors.New("must be in the future")
+	}
+	if scheduledAt.After(now.Add(CALLBACK_MAX_DELAY)) {
+		return time.Time{}, fmt.Errorf("must be within %d days", int(CALLBACK_MAX_DELAY.Hours()/24))
+	}
+
+	return scheduledAt.UTC(), nil
+}
+
+// GetCallbacks returns a page of the organization's callbacks, soonest first.
+// contactId, if not empty, restricts the callbacks to those of a contact; a *ValidationError is returned if it is not a valid ID.
+func GetCallbacks(orgId string, status mongodbTypes.CallbackStatus, contactId string, page int, limit int) (*mongodbTypes.CallbackPage, error) {
+	var contactObjectId *bson.ObjectID
+	if contactId != "" {
+		id, err := bson.ObjectIDFromHex(contactId)
+		if err != nil {
+			return nil, &ValidationError{Errors: []FieldError{{Field: "contactId", Value: contactId, Message: "must be a contact ID"}}}
+		}
+		contactObjectId = &id
+	}
+
+	callbacks, err := mongodb.GetCallbacks(orgId, status, contactObjectId, page, limit)
+	if err != nil {
+		log.Printf("Error getting callbacks: %v", err)
+		return nil, err
+	}
+
+	return callbacks, nil
+}
+
+// CancelCallback cancels a scheduled callback of the caller's organization before it is queued.
+// Returns ErrNotFound if the organization has no such callback and ErrCallState if it is no longer scheduled.
+func CancelCallback(caller Caller, callbackId string) (*mongodbTypes.Callback, error) {
+	id, err := bson.ObjectIDFromHex(callbackId)
+	if err != nil {
+		recordDenied(caller, "callbacks.cancel", "callback", callbackId)
+		return nil, ErrNotFound
+	}
+
+	callback, err := mongodb.GetCallbackById(caller.OrgId, id)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		recordDenied(caller, "callbacks.cancel", "callback", callbackId)
+		return nil, ErrNotFound
+	} else if err != nil {
+		log.Printf("Error getting callback %s: %v", callbackId, err)
+		return nil, err
+	}
+
+	cancelled, err := mongodb.UpdateCallbackStatus(caller.OrgId, id, mongodbTypes.CALLBACK_STATUS_SCHEDULED, mongodbTypes.CALLBACK_STATUS_CANCELLED, nil, "")
+	if err != nil {
+		log.Printf("Error cancelling callback %s: %v", callbackId, err)
+		return nil, err
+	}
+	if !cancelled {
+		recordAudit(caller, "callbacks.cancel", "callback", callbackId, mongodbTypes.AUDIT_DENIED, fmt.Sprintf("callback is %s", callback.Status))
+		return nil, fmt.Errorf("%w: callback is %s", ErrCallState, callback.Status)
+	}
+
+	recordAudit(caller, "callbacks.cancel", "callback", callbackId, mongodbTypes.AUDIT_ALLOWED, "")
+
+	callback.Status = mongodbTypes.CALLBACK_STATUS_CANCELLED
+	return callback, nil
+}
+
+// CallbackScheduler queues the callbacks whose time has come, with the assistant and phone number of the call
+// they were requested on. The queued calls go through the same Do-Not-Call, daily cap and concurrency checks as any other call.
+type CallbackScheduler struct {
+	// Interval is the time between two runs, defaults to 1 minute
+	Interval time.Duration
+}
+
+func (s *CallbackScheduler) Start() {
+	if s.Interval <= 0 {
+		s.Interval = time.Minute
+	}
+
+	go func() {
+		s.run()
+	}()
+}
+
+func (s *CallbackScheduler) run() {
+	for {
+		allOrgIDs, err := clerk.GetAllOrganizations()
+		if err != nil {
+			log.Printf("[CallbackScheduler] Error getting organizations: %v", err)
+		}
+
+		for _, id := range allOrgIDs {
+			queueDueCallbacks(id)
+		}
+
+		time.Sleep(s.Interval)
+	}
+}
+
+// queueDueCallbacks queues the organization's callbacks whose time has come.
+func queueDueCallbacks(orgId string) {
+	callbacks, err := mongodb.GetDueCallbacks(orgId, time.Now(), CALLBACK_BATCH_SIZE)
+	if err != nil {
+		log.Printf("[CallbackScheduler] Error getting due callbacks for organization %s: %v", orgId, err)
+		return
+	}
+
+	for _, callback := range callbacks {
+		queueCallback(orgId, callback)
+	}
+}
+
+// queueCallback adds a due callback to the call queue. Callbacks that can never be placed, because the number
+// is on a Do-Not-Call list or the assistant or phone number is no longer registered, are failed. Callbacks
+// held back by the daily cap or a transient error stay scheduled and are retried on the next run.
+func queueCallback(orgId string, callback mongodbTypes.Callback) {
+	caller := Caller{OrgId: orgId, UserId: SYSTEM_CALLBACK_SCHEDULER}
+
+	queued, _, err := createCall(caller, callOrigin{}, callback.AssistantId, callback.PhoneNumberId, []mongodbTypes.Customer{callback.Customer})
+
+	var invalid *ValidationError
+	status := mongodbTypes.CALLBACK_STATUS_QUEUED
+	reason := ""
+	switch {
+	case errors.Is(err, ErrAllSuppressed):
+		status, reason = mongodbTypes.CALLBACK_STATUS_FAILED, "customer number is on a Do-Not-Call list"
+	case errors.Is(err, ErrNotFound):
+		status, reason = mongodbTypes.CALLBACK_STATUS_FAILED, "assistant or phone number is no longer registered"
+	case errors.As(err, &invalid):
+		status, reason = mongodbTypes.CALLBACK_STATUS_FAILED, invalid.Error()
+	case err != nil:
+		status, reason = mongodbTypes.CALLBACK_STATUS_SCHEDULED, err.Error()
+	}
+
+	var queuedCallId *bson.ObjectID
+	if len(queued) > 0 {
+		queuedCallId = &queued[0].Id
+	}
+
+	updated, updateErr := mongodb.UpdateCallbackStatus(orgId, callback.Id, mongodbTypes.CALLBACK_STATUS_SCHEDULED, status, queuedCallId, reason)
+	if updateErr != nil {
+		log.Printf("[CallbackScheduler] Error updating callback %s: %v", callback.Id.Hex(), updateErr)
+		return
+	}
+
+	// The callback was cancelled while it was being queued, so its call is taken out of the queue again
+	if !updated && queuedCallId != nil {
+		if _, err := mongodb.CancelQueuedCalls(orgId, bson.M{"_id": *queuedCallId}); err != nil {
+			log.Printf("[CallbackScheduler] Error dequeueing call of cancelled callback %s: %v", callback.Id.Hex(), err)
+		}
+		return
+	}
+
+	if err != nil {
+		log.Printf("[CallbackScheduler] Callback %s of organization %s not queued: %v", callback.Id.Hex(), orgId, err)
+	} else {
+		log.Printf("[CallbackScheduler] Queued callback %s of organization %s", callback.Id.Hex(), orgId)
+	}
+}
@@ -0,0 +1,249 @@
+package sarah
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"sarah/mongodb"
+	"sarah/phone"
+	mongodbTypes "sarah/types/mongodb"
+)
+
+// ErrDailyCapReached is returned when placing the calls would exceed the daily cap of the phone number.
+var ErrDailyCapReached = errors.New("phone number reached its daily call cap")
+
+// callerIdAssignment are the customers assigned to one number of a campaign's phone number pool.
+type callerIdAssignment struct {
+	phoneNumberId string
+	customers     []mongodbTypes.Customer
+}
+
+// callerIdPool is the state of a campaign's phone number pool while customers are assigned to it.
+type callerIdPool struct {
+	numbers  []mongodbTypes.PhoneNumber
+	areas    []string
+	used     []int
+	assigned []int
+}
+
+// remaining returns how many more calls the i-th number of the pool can place today.
+func (p *callerIdPool) remaining(i int) int {
+	if p.numbers[i].DailyCap <= 0 {
+		return math.MaxInt
+	}
+	return p.numbers[i].DailyCap - p.used[i] - p.assigned[i]
+}
+
+// leastUsed returns the number of the pool with the fewest calls today, among the ones accepted
+// by match, or -1 if every accepted number reached its daily cap. Ties go to the first number of the pool.
+func (p *callerIdPool) leastUsed(match func(i int) bool) int {
+	best := -1
+	for i := range p.numbers {
+		if !match(i) || p.remaining(i) <= 0 {
+			continue
+		}
+		if best < 0 || p.used[i]+p.assigned[i] < p.used[best]+p.assigned[best] {
+			best = i
+		}
+	}
+	return best
+}
+
+// usageDate returns the UTC day phone number usage is counted on.
+func usageDate() string {
+	return time.Now().UTC().Format(mongodbTypes.USAGE_DATE_FORMAT)
+}
+
+// assignCallerIds chooses a number of the campaign's phone number pool for each customer with the campaign's
+// strategy, skipping numbers that reached their daily cap. Customer numbers must be in E.164.
+// Returns the customers grouped by number, in pool order, and the numbers of the customers that could not
+// be assigned because every number of the pool reached its daily cap.
+func assignCallerIds(orgId string, campaign mongodbTypes.Campaign, customers []mongodbTypes.Customer) ([]callerIdAssignment, []string, error) {
+	pool, err := loadCallerIdPool(orgId, campaign.PhoneNumberPool)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(pool.numbers) == 0 {
+		return nil, nil, fmt.Errorf("campaign %s has no registered number in its phone number pool", campaign.Name)
+	}
+
+	var cursor int64
+	if campaign.CallerIdStrategy == mongodbTypes.CALLER_ID_ROUND_ROBIN {
+		cursor, err = mongodb.AdvanceCallerIdCursor(orgId, campaign.Id, len(customers))
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	assignments := make([][]mongodbTypes.Customer, len(pool.numbers))
+	capped := []string{}
+	anyNumber := func(int) bool { return true }
+
+	for i, customer := range customers {
+		chosen := -1
+
+		switch campaign.CallerIdStrategy {
+		case mongodbTypes.CALLER_ID_LEAST_USED:
+			chosen = pool.leastUsed(anyNumber)
+
+		case mongodbTypes.CALLER_ID_LOCAL_PRESENCE:
+			area := phone.LocalArea(customer.PhoneNumber)
+			if area != "" {
+				chosen = pool.leastUsed(func(n int) bool { return pool.areas[n] == area })
+			}
+			if chosen < 0 {
+				chosen = pool.leastUsed(anyNumber)
+			}
+
+		default:
+			for offset := 0; offset < len(pool.numbers); offset++ {
+				n := int((cursor + int64(i) + int64(offset)) % int64(len(pool.numbers)))
+				if pool.remaining(n) > 0 {
+					chosen = n
+					break
+				}
+			}
+		}
+
+		if chosen < 0 {
+			capped = append(capped, customer.PhoneNumber)
+			continue
+		}
+
+		pool.assigned[chosen]++
+		assignments[chosen] = append(assignments[chosen], customer)
+	}
+
+	groups := []callerIdAssignment{}
+	for i, assigned := range assignments {
+		if len(assigned) > 0 {
+			groups = append(groups, callerIdAssignment{phoneNumberId: pool.numbers[i].PhoneNumberId, customers: assigned})
+		}
+	}
+
+	return groups, capped, nil
+}
+
+// loadCallerIdPool loads the registered numbers of a phone number pool and their usage today.
+// Numbers the organization no longer has are left out of the pool.
+func loadCallerIdPool(orgId string, phoneNumberIds []string) (*callerIdPool, error) {
+	pool := &callerIdPool{}
+
+	for _, phoneNumberId := range phoneNumberIds {
+		phoneNumber, err := ResolvePhoneNumber(orgId, phoneNumberId)
+		if errors.Is(err, ErrNotFound) {
+			log.Printf("Phone number %s of the pool is not registered, skipping it", phoneNumberId)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		pool.numbers = append(pool.numbers, *phoneNumber)
+		pool.areas = append(pool.areas, phone.LocalArea(phoneNumber.PhoneNumber))
+	}
+
+	usage, err := phoneNumberUsage(orgId, phoneNumberIds)
+	if err != nil {
+		return nil, err
+	}
+
+	pool.used = make([]int, len(pool.numbers))
+	pool.assigned = make([]int, len(pool.numbers))
+	for i, phoneNumber := range pool.numbers {
+		pool.used[i] = usage[phoneNumber.PhoneNumberId]
+	}
+
+	return pool, nil
+}
+
+// checkDailyCap returns an error wrapping ErrDailyCapReached if placing calls from the phone number
+// would exceed its daily cap.
+func checkDailyCap(orgId string, phoneNumber *mongodbTypes.PhoneNumber, calls int) error {
+	remaining, err := dailyCallsLeft(orgId, phoneNumber)
+	if err != nil {
+		return err
+	}
+
+	if remaining >= 0 && calls > remaining {
+		return dailyCapError(phoneNumber, remaining)
+	}
+
+	return nil
+}
+
+// dailyCallsLeft returns how many more calls the phone number can place today, or -1 if it has no daily cap.
+func dailyCallsLeft(orgId string, phoneNumber *mongodbTypes.PhoneNumber) (int, error) {
+	if phoneNumber.DailyCap <= 0 {
+		return -1, nil
+	}
+
+	usage, err := phoneNumberUsage(orgId, []string{phoneNumber.PhoneNumberId})
+	if err != nil {
+		return 0, err
+	}
+
+	return max(phoneNumber.DailyCap-usage[phoneNumber.PhoneNumberId], 0), nil
+}
+
+// dailyCapError returns the error wrapping ErrDailyCapReached for a phone number with remaining calls left today.
+func dailyCapError(phoneNumber *mongodbTypes.PhoneNumber, remaining int) error {
+	return fmt.Errorf("%w: %d of %d calls left today", ErrDailyCapReached, remaining, phoneNumber.DailyCap)
+}
+
+// phoneNumberUsage returns how many calls of each phone number count towards its daily cap: the calls it
+// placed today and the calls waiting for it in the call queue, which it will place once dispatched.
+func phoneNumberUsage(orgId string, phoneNumberIds []string) (map[string]int, error) {
+	usage, err := mongodb.GetPhoneNumberUsage(orgId, usageDate(), phoneNumberIds)
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := mongodb.CountPendingCallsByPhoneNumber(orgId, phoneNumberIds)
+	if err != nil {
+		return nil, err
+	}
+
+	for phoneNumberId, calls := range pending {
+		usage[phoneNumberId] += calls
+	}
+
+	return usage, nil
+}
+
+// recordPhoneNumberUsage counts calls placed from a phone number towards its daily cap.
+// Calls are counted once VapiAI accepted them, so queued calls that are cancelled, suppressed or fail
+// don't use up the cap. Failures are logged, since the calls have already been placed.
+func recordPhoneNumberUsage(orgId string, phoneNumberId string, calls int) {
+	if calls == 0 {
+		return
+	}
+
+	if err := mongodb.IncrementPhoneNumberUsage(orgId, phoneNumberId, usageDate(), calls); err != nil {
+		log.Printf("Error recording usage of phone number %s: %v", phoneNumberId, err)
+	}
+}
+
+// validateCallerIdPool defaults the caller ID strategy of a campaign with a phone number pool
+// and returns a *ValidationError if the strategy is unknown.
+func validateCallerIdPool(campaign *mongodbTypes.Campaign) error {
+	if len(campaign.PhoneNumberPool) == 0 {
+		return nil
+	}
+
+	switch campaign.CallerIdStrategy {
+	case "":
+		campaign.CallerIdStrategy = mongodbTypes.CALLER_ID_ROUND_ROBIN
+	case mongodbTypes.CALLER_ID_ROUND_ROBIN, mongodbTypes.CALLER_ID_LEAST_USED, mongodbTypes.CALLER_ID_LOCAL_PRESENCE:
+	default:
+		return &ValidationError{Errors: []FieldError{{
+			Field:   "caller_id_strategy",
+			Value:   string(campaign.CallerIdStrategy),
+			Message: "must be round_robin, least_used or local_presence",
+		}}}
+	}
+
+	return nil
+}
@@ -4,94 +4,393 @@ import (
 	"context"
 	"errors"
 	"log"
+	"sarah/clerk"
 	"sarah/mongodb"
 	mongodbTypes "sarah/types/mongodb"
 
 	"os"
+	"sort"
+	"time"
 
 	vapiApi "github.com/VapiAI/server-sdk-go"
-
-	vapiclient "github.com/VapiAI/server-sdk-go/client"
 	"github.com/joho/godotenv"
+	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
-var VapiClient *vapiclient.Client
-
 func init() {
 	if err := godotenv.Load(); err != nil {
 		log.Printf("Warning: .env file not found, using system environment variables")
 	}
 
-	VapiClient = createClient(os.Getenv("VAPI_API_KEY"))
+	Telephony = NewResilientProvider(NewVapiProvider(os.Getenv("VAPI_API_KEY")), VapiCircuit)
 }
 
-func CreateCall(assistantId string, assistantNumberId string, customers []mongodbTypes.Customer) (*vapiApi.CallsCreateResponse, error) {
-	customerList := []*vapiApi.CreateCustomerDto{}
-	for _, customer := range customers {
-		customerList = append(customerList, &vapiApi.CreateCustomerDto{
-			Number: vapiApi.String(customer.PhoneNumber),
-		})
+// CreateCall queues calls to the given customers, which the CallDispatcher sends to VapiAI
+// as the concurrency limits of the organization, the phone number and the platform allow.
+// campaignId is the hex ObjectID of the campaign placing the calls, or empty for calls created
+// directly through the API. The assistant and phone number must belong to the caller's organization,
+// otherwise ErrNotFound is returned and no call is queued.
+//
+// Customer numbers are converted to E.164 before anything is queued; a *ValidationError
+// listing every invalid number is returned otherwise. Customers whose number is on the
+// organization's or the global Do-Not-Call list are not queued; their numbers are returned
+// as suppressed. ErrAllSuppressed is returned, along with the suppressed numbers, if no
+// customer is left to call. An error wrapping ErrDailyCapReached is returned if the calls
+// would exceed the daily cap of the phone number.
+func CreateCall(caller Caller, campaignId string, assistantId string, assistantNumberId string, customers []mongodbTypes.Customer) ([]mongodbTypes.QueuedCall, []string, error) {
+	return createCall(caller, callOrigin{campaignId: campaignId}, assistantId, assistantNumberId, customers)
+}
+
+// callOrigin describes what placed a batch of calls, recorded on each call.
+type callOrigin struct {
+	// campaignId is the hex ObjectID of the campaign, empty for calls created through the API
+	campaignId string
+
+	// runId is the campaign run that queued the calls, nil for calls created through the API
+	runId *bson.ObjectID
+
+	// callerIdStrategy is how the phone number was chosen from the campaign's pool, empty without a pool
+	callerIdStrategy mongodbTypes.CallerIdStrategy
+}
+
+// createCall implements CreateCall, recording the origin of the calls on each queued call.
+func createCall(caller Caller, origin callOrigin, assistantId string, assistantNumberId string, customers []mongodbTypes.Customer) ([]mongodbTypes.QueuedCall, []string, error) {
+	if _, err := AuthorizeAssistant(caller, "calls.create", assistantId); err != nil {
+		return nil, nil, err
+	}
+	phoneNumber, err := AuthorizePhoneNumber(caller, "calls.create", assistantNumberId)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	if len(customerList) == 0 {
+	if len(customers) == 0 {
 		log.Printf("No customers/phone numbers provided")
-		return nil, errors.New("no customers/phone numbers provided")
+		return nil, nil, errors.New("no customers/phone numbers provided")
 	}
 
-	resp, err := VapiClient.Calls.Create(context.Background(), &vapiApi.CreateCallDto{
-		AssistantId:   vapiApi.String(assistantId),
-		PhoneNumberId: vapiApi.String(assistantNumberId),
-		Customers:     customerList,
-	})
+	customers, invalid := normalizeCustomers(customers, "customers", organizationCountry(caller.OrgId))
+	if invalid != nil {
+		return nil, nil, invalid
+	}
 
+	customers, suppressed, err := suppressCustomers(caller.OrgId, customers)
 	if err != nil {
-		log.Printf("Error creating call: %v", err)
-		return nil, err
+		log.Printf("Error checking the do-not-call lists: %v", err)
+		return nil, nil, err
+	}
+	if len(suppressed) > 0 {
+		log.Printf("[DNC] Suppressed %d numbers for organization %s", len(suppressed), caller.OrgId)
+	}
+	if len(customers) == 0 {
+		return nil, suppressed, ErrAllSuppressed
 	}
 
-	log.Printf("Call created successfully: %+v\n", resp)
+	if err := checkDailyCap(caller.OrgId, phoneNumber, len(customers)); err != nil {
+		return nil, suppressed, err
+	}
 
-	return resp, nil
+	queued, err := enqueueCalls(caller.OrgId, origin, assistantId, assistantNumberId, customers)
+	if err != nil {
+		return nil, suppressed, err
+	}
+
+	return queued, suppressed, nil
 }
 
-func GetCall(callId string) (*vapiApi.Call, error) {
-	resp, err := VapiClient.Calls.Get(context.Background(), callId)
-	if err != nil {
+// recordCalls stores every call contained in a VapiAI create response.
+// Failures are logged and do not fail the call creation, since the calls
+// have already been placed by the time they are recorded.
+func recordCalls(orgId string, origin callOrigin, resp *vapiApi.CallsCreateResponse) {
+	for _, call := range createdCalls(resp) {
+		record := callRecordFromVapi(call)
+		record.CampaignId = origin.campaignId
+		record.CallerIdStrategy = origin.callerIdStrategy
+
+		if _, err := mongodb.UpsertCall(orgId, record); err != nil {
+			log.Printf("Error recording call %s: %v", call.Id, err)
+		}
+	}
+}
+
+// createdCalls returns the calls VapiAI accepted in a create response, whether it placed a single call or a batch.
+func createdCalls(resp *vapiApi.CallsCreateResponse) []*vapiApi.Call {
+	calls := []*vapiApi.Call{}
+	if resp == nil {
+		return calls
+	}
+	if resp.Call != nil {
+		calls = append(calls, resp.Call)
+	}
+	if resp.CallBatchResponse != nil {
+		calls = append(calls, resp.CallBatchResponse.Results...)
+	}
+	return calls
+}
+
+// callRecordFromVapi converts a VapiAI call into the locally stored call record.
+func callRecordFromVapi(call *vapiApi.Call) mongodbTypes.Call {
+	record := mongodbTypes.Call{
+		VapiCallId:    call.Id,
+		AssistantId:   derefString(call.AssistantId),
+		PhoneNumberId: derefString(call.PhoneNumberId),
+		CreatedAt:     call.CreatedAt,
+		StartedAt:     call.StartedAt,
+		EndedAt:       call.EndedAt,
+	}
+
+	if call.Customer != nil {
+		record.CustomerNumber = derefString(call.Customer.Number)
+		record.CustomerName = derefString(call.Customer.Name)
+	}
+	if call.Status != nil {
+		record.Status = mongodbTypes.CallStatus(*call.Status)
+	}
+	if call.EndedReason != nil {
+		record.EndedReason = string(*call.EndedReason)
+	}
+	if call.Cost != nil {
+		record.Cost = *call.Cost
+	}
+	if call.StartedAt != nil && call.EndedAt != nil {
+		record.DurationSeconds = call.EndedAt.Sub(*call.StartedAt).Seconds()
+	}
+	if call.Analysis != nil {
+		record.SuccessEvaluation = derefString(call.Analysis.SuccessEvaluation)
+		record.Successful = evaluationSuccessful(record.SuccessEvaluation)
+		record.Summary = derefString(call.Analysis.Summary)
+	}
+	if call.Artifact != nil {
+		record.Transcript = derefString(call.Artifact.Transcript)
+	}
+
+	return record
+}
+
+// GetCall returns a VapiAI call if it belongs to the organization, that is if it was
+// placed by one of the organization's assistants or phone numbers. Returns ErrNotFound otherwise,
+// and records the denied attempt in the audit log.
+func GetCall(caller Caller, callId string) (*vapiApi.Call, error) {
+	resp, err := Telephony.GetCall(context.Background(), callId)
+	if isVapiNotFound(err) {
+		return nil, ErrNotFound
+	} else if err != nil {
 		log.Printf("Error getting call: %v", err)
 		return nil, err
 	}
 
+	owned, err := ownsCall(caller.OrgId, derefString(resp.AssistantId), derefString(resp.PhoneNumberId))
+	if err != nil {
+		log.Printf("Error checking call ownership: %v", err)
+		return nil, err
+	}
+
+	if !owned {
+		recordDenied(caller, "calls.get", "call", callId)
+		return nil, ErrNotFound
+	}
+
 	return resp, nil
 }
 
-func ListCalls(callListRequest *vapiApi.CallsListRequest) ([]*vapiApi.Call, error) {
-	resp, err := VapiClient.Calls.List(context.Background(), callListRequest)
+// ListCalls lists VapiAI calls restricted to the organization's own assistants.
+// If the request names an assistant it must belong to the organization, otherwise ErrNotFound
+// is returned. Without an assistant, the request is run once per organization assistant and
+// the results are merged newest first, honoring the request limit.
+func ListCalls(caller Caller, callListRequest *vapiApi.CallsListRequest) ([]*vapiApi.Call, error) {
+	if callListRequest == nil {
+		callListRequest = &vapiApi.CallsListRequest{}
+	}
+
+	assistantIds := []string{}
+	if callListRequest.AssistantId != nil && *callListRequest.AssistantId != "" {
+		if _, err := AuthorizeAssistant(caller, "calls.list", *callListRequest.AssistantId); err != nil {
+			log.Printf("Error resolving assistant %s: %v", *callListRequest.AssistantId, err)
+			return nil, err
+		}
+		assistantIds = append(assistantIds, *callListRequest.AssistantId)
+	} else {
+		assistants, err := mongodb.GetOrganizationAssistants(caller.OrgId)
+		if err != nil {
+			log.Printf("Error getting organization assistants: %v", err)
+			return nil, err
+		}
+		for _, assistant := range assistants {
+			assistantIds = append(assistantIds, assistant.VapiAssistantId)
+		}
+	}
+
+	calls := []*vapiApi.Call{}
+	for _, assistantId := range assistantIds {
+		request := *callListRequest
+		request.AssistantId = vapiApi.String(assistantId)
+
+		resp, err := Telephony.ListCalls(context.Background(), &request)
+		if err != nil {
+			log.Printf("Error listing calls: %v", err)
+			return nil, err
+		}
+		calls = append(calls, resp...)
+	}
+
+	sort.Slice(calls, func(i, j int) bool {
+		return calls[i].CreatedAt.After(calls[j].CreatedAt)
+	})
+
+	if callListRequest.Limit != nil && len(calls) > int(*callListRequest.Limit) {
+		calls = calls[:int(*callListRequest.Limit)]
+	}
+
+	return calls, nil
+}
+
+// GetOrganizationCalls returns a page of the organization's calls from the local calls
+// collection, newest first. The collection is kept up to date by call creation, VapiAI
+// webhooks and the CallSyncer, so listing calls never reaches out to VapiAI.
+func GetOrganizationCalls(orgId string, filter mongodbTypes.CallFilter, page int, limit int) (*mongodbTypes.CallPage, error) {
+	calls, err := mongodb.GetOrganizationCalls(orgId, filter, page, limit)
 	if err != nil {
-		log.Printf("Error listing calls: %v", err)
+		log.Printf("Error getting organization calls: %v", err)
 		return nil, err
 	}
 
-	return resp, nil
+	return calls, nil
 }
 
-func GetOrganizationCalls(orgId string) ([]*vapiApi.Call, error) {
+// callSyncPageSize is the number of calls fetched from VapiAI per request during a sync
+const callSyncPageSize = 1000
+
+// SyncOrganizationCalls copies the calls of every assistant of the organization from VapiAI
+// into the local calls collection. When since is set, only calls updated at or after it are
+// fetched. Returns the number of calls synced.
+func SyncOrganizationCalls(orgId string, since *time.Time) (int, error) {
 	assistants, err := mongodb.GetOrganizationAssistants(orgId)
 	if err != nil {
 		log.Printf("Error getting organization assistants: %v", err)
-		return nil, err
+		return 0, err
 	}
 
-	calls := []*vapiApi.Call{}
+	synced := 0
 	for _, assistant := range assistants {
-		assistantCalls, err := VapiClient.Calls.List(context.Background(), &vapiApi.CallsListRequest{
-			AssistantId: vapiApi.String(assistant.VapiAssistantId),
+		count, err := syncAssistantCalls(orgId, assistant.VapiAssistantId, since)
+		synced += count
+		if err != nil {
+			return synced, err
+		}
+	}
+
+	return synced, nil
+}
+
+// syncAssistantCalls copies the calls of an assistant from VapiAI into the local calls collection,
+// a page at a time from the newest call back. Each page continues from the creation date of the
+// oldest call of the previous one, until a page comes back shorter than callSyncPageSize.
+// Calls created at the same instant as the cursor are fetched again, and skipped. Returns the number of calls synced.
+func syncAssistantCalls(orgId string, vapiAssistantId string, since *time.Time) (int, error) {
+	synced := 0
+	var createdBefore *time.Time
+	boundary := map[string]bool{}
+	for {
+		calls, err := Telephony.ListCalls(context.Background(), &vapiApi.CallsListRequest{
+			AssistantId: vapiApi.String(vapiAssistantId),
+			UpdatedAtGe: since,
+			CreatedAtLe: createdBefore,
+			Limit:       vapiApi.Float64(callSyncPageSize),
 		})
 		if err != nil {
-			log.Printf("Error listing calls: %v", err)
-			return nil, err
+			log.Printf("Error listing calls for assistant %s: %v", vapiAssistantId, err)
+			return synced, err
+		}
+
+		fresh := 0
+		for _, call := range calls {
+			if boundary[call.Id] {
+				continue
+			}
+			fresh++
+
+			if _, err := mongodb.UpsertCall(orgId, callRecordFromVapi(call)); err != nil {
+				log.Printf("Error syncing call %s: %v", call.Id, err)
+				return synced, err
+			}
+			if call.Artifact != nil {
+				if err := recordTranscript(orgId, call.Id, derefString(call.Artifact.Transcript), transcriptMessagesFromVapi(call.Artifact)); err != nil {
+					return synced, err
+				}
+				if err := ScheduleRecordingArchive(orgId, call.Id, derefString(call.Artifact.RecordingUrl)); err != nil {
+					return synced, err
+				}
+			}
+			synced++
+		}
+
+		// A full page of calls already synced means more calls share one creation date than fit in a page
+		if len(calls) < callSyncPageSize || fresh == 0 {
+			return synced, nil
+		}
+
+		oldest := calls[len(calls)-1].CreatedAt
+		if createdBefore == nil || !oldest.Equal(*createdBefore) {
+			boundary = map[string]bool{}
+		}
+		for _, call := range calls {
+			if call.CreatedAt.Equal(oldest) {
+				boundary[call.Id] = true
+			}
 		}
-		calls = append(calls, assistantCalls...)
+		createdBefore = &oldest
 	}
+}
 
-	return calls, nil
+// CallSyncer periodically syncs every organization's calls from VapiAI, catching
+// the updates of calls whose webhooks were missed.
+type CallSyncer struct {
+	// Interval is the time between two syncs, defaults to 15 minutes
+	Interval time.Duration
+
+	lastSync time.Time
+}
+
+func (c *CallSyncer) Start() {
+	if c.Interval <= 0 {
+		c.Interval = 15 * time.Minute
+	}
+
+	go func() {
+		c.run()
+	}()
+}
+
+func (c *CallSyncer) run() {
+	for {
+		startedAt := time.Now()
+
+		var since *time.Time
+		if !c.lastSync.IsZero() {
+			// Overlap the previous window so calls updated while the last sync ran are not missed
+			overlap := c.lastSync.Add(-c.Interval)
+			since = &overlap
+		}
+
+		allOrgIDs, err := clerk.GetAllOrganizations()
+		if err != nil {
+			log.Printf("[CallSyncer] Error getting organizations: %v", err)
+		} else {
+			failed := false
+			for _, id := range allOrgIDs {
+				synced, err := SyncOrganizationCalls(id, since)
+				if err != nil {
+					log.Printf("[CallSyncer] Error syncing calls for organization %s: %v", id, err)
+					failed = true
+					continue
+				}
+				log.Printf("[CallSyncer] Synced %d calls for organization %s", synced, id)
+			}
+
+			if !failed {
+				c.lastSync = startedAt
+			}
+		}
+
+		time.Sleep(c.Interval)
+	}
 }
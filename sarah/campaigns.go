@@ -1,9 +1,11 @@
 package sarah
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"slices"
 	"time"
 
 	clerk "sarah/clerk"
@@ -11,8 +13,8 @@ import (
 
 	mongodbTypes "sarah/types/mongodb"
 
-	api "github.com/VapiAI/server-sdk-go"
 	"github.com/joho/godotenv"
+	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 )
 
@@ -24,11 +26,30 @@ func init() {
 
 /* API Methods */
 
-func CreateCampaign(campaignCreateDto mongodbTypes.Campaign, orgId string) (*mongo.InsertOneResult, error) {
-	campaign, err := mongodb.CreateCampaign(orgId, mongodbTypes.Campaign{
+// CreateCampaign stores a new campaign for the caller's organization.
+// Returns ErrNotFound if the campaign's assistant or one of its phone numbers belongs to another organization,
+// and a *ValidationError if a customer phone number or the caller ID strategy is invalid.
+func CreateCampaign(caller Caller, campaignCreateDto mongodbTypes.Campaign) (*mongo.InsertOneResult, error) {
+	if err := authorizeCampaign(caller, "campaigns.create", campaignCreateDto); err != nil {
+		return nil, err
+	}
+
+	if err := validateCallerIdPool(&campaignCreateDto); err != nil {
+		return nil, err
+	}
+
+	customers, invalid := normalizeCustomers(campaignCreateDto.Customers, "customers", organizationCountry(caller.OrgId))
+	if invalid != nil {
+		return nil, invalid
+	}
+	campaignCreateDto.Customers = customers
+
+	campaign, err := mongodb.CreateCampaign(caller.OrgId, mongodbTypes.Campaign{
 		Name:             campaignCreateDto.Name,
 		AssistantId:      campaignCreateDto.AssistantId,
 		PhoneNumberId:    campaignCreateDto.PhoneNumberId,
+		PhoneNumberPool:  campaignCreateDto.PhoneNumberPool,
+		CallerIdStrategy: campaignCreateDto.CallerIdStrategy,
 		SchedulePlan:     campaignCreateDto.SchedulePlan,
 		Customers:        campaignCreateDto.Customers,
 		Type:             campaignCreateDto.Type,
@@ -47,6 +68,56 @@ func CreateCampaign(campaignCreateDto mongodbTypes.Campaign, orgId string) (*mon
 
 }
 
+// UpdateCampaign updates a campaign of the caller's organization.
+// Returns ErrNotFound if the campaign's assistant or one of its phone numbers belongs to another organization,
+// and a *ValidationError if a customer phone number or the caller ID strategy is invalid.
+func UpdateCampaign(caller Caller, campaignUpdateDto mongodbTypes.Campaign) (*mongo.UpdateResult, error) {
+	if err := authorizeCampaign(caller, "campaigns.update", campaignUpdateDto); err != nil {
+		return nil, err
+	}
+
+	if err := validateCallerIdPool(&campaignUpdateDto); err != nil {
+		return nil, err
+	}
+
+	if campaignUpdateDto.Customers != nil {
+		customers, invalid := normalizeCustomers(campaignUpdateDto.Customers, "customers", organizationCountry(caller.OrgId))
+		if invalid != nil {
+			return nil, invalid
+		}
+		campaignUpdateDto.Customers = customers
+	}
+
+	result, err := mongodb.UpdateCampaign(caller.OrgId, campaignUpdateDto)
+	if err != nil {
+		log.Printf("Error updating campaign: %v", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// authorizeCampaign checks that the assistant and phone numbers a campaign calls with belong
+// to the caller's organization. Empty IDs are left for the scheduler to report.
+func authorizeCampaign(caller Caller, action string, campaign mongodbTypes.Campaign) error {
+	if campaign.AssistantId != "" {
+		if _, err := AuthorizeAssistant(caller, action, campaign.AssistantId); err != nil {
+			return err
+		}
+	}
+	if campaign.PhoneNumberId != "" {
+		if _, err := AuthorizePhoneNumber(caller, action, campaign.PhoneNumberId); err != nil {
+			return err
+		}
+	}
+	for _, phoneNumberId := range campaign.PhoneNumberPool {
+		if _, err := AuthorizePhoneNumber(caller, action, phoneNumberId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // iterate voer all orgs in clerk
 // for each org, get the campaings from mongodb
 // for each campaign, check if it is time to send the call
@@ -244,7 +315,7 @@ func CheckRecurrentWeeklyCampaign(orgId string, campaign mongodbTypes.Campaign)
 		return nil
 	}
 
-	resp, err := executeCampaign(campaign.AssistantId, campaign.PhoneNumberId, customers)
+	resp, err := executeCampaign(orgId, campaign, customers)
 
 	if err != nil {
 		log.Printf("Error creating campaign: %v", err)
@@ -271,7 +342,7 @@ func CheckRecurrentMonthlyCampaign(orgId string, campaign mongodbTypes.Campaign)
 		return nil
 	}
 
-	resp, err := executeCampaign(campaign.AssistantId, campaign.PhoneNumberId, customers)
+	resp, err := executeCampaign(orgId, campaign, customers)
 
 	if err != nil {
 		log.Printf("Error creating campaign: %v", err)
@@ -298,7 +369,7 @@ func CheckRecurrentYearlyCampaign(orgId string, campaign mongodbTypes.Campaign)
 		return nil
 	}
 
-	resp, err := executeCampaign(campaign.AssistantId, campaign.PhoneNumberId, customers)
+	resp, err := executeCampaign(orgId, campaign, customers)
 
 	if err != nil {
 		log.Printf("Error creating campaign: %v", err)
@@ -325,7 +396,7 @@ func CheckOneTimeCampaign(orgId string, campaign mongodbTypes.Campaign) error {
 		return nil
 	}
 
-	resp, err := executeCampaign(campaign.AssistantId, campaign.PhoneNumberId, customers)
+	resp, err := executeCampaign(orgId, campaign, customers)
 
 	if err != nil {
 		log.Printf("[CampaignScheduler] Error creating campaign: %v", err)
@@ -355,17 +426,152 @@ func CheckOneTimeCampaign(orgId string, campaign mongodbTypes.Campaign) error {
 	return nil
 }
 
-// Creates an immediate campaign in Vapi
-func executeCampaign(assistantId string, phoneNumberId string, customers []mongodbTypes.Customer) (*api.CallsCreateResponse, error) {
-	resp, err := CreateCall(assistantId, phoneNumberId, customers)
+// Queues the calls of a campaign run
+func executeCampaign(orgId string, campaign mongodbTypes.Campaign, customers []mongodbTypes.Customer) ([]mongodbTypes.QueuedCall, error) {
+	run := mongodbTypes.CampaignRun{
+		Id:                bson.NewObjectID(),
+		CampaignId:        campaign.Id.Hex(),
+		CustomersTargeted: len(customers),
+		InvalidNumbers:    []string{},
+		SuppressedNumbers: []string{},
+		CappedNumbers:     []string{},
+		CallIds:           []string{},
+		Status:            mongodbTypes.RUN_STATUS_PLACED,
+		StartedAt:         time.Now(),
+	}
+
+	// Customers stored before numbers were validated, or contacts changed since, must not fail the whole batch
+	valid, invalid := normalizeCustomers(customers, "customers", organizationCountry(orgId))
+	if invalid != nil {
+		log.Printf("[CampaignScheduler] Skipping invalid numbers of campaign %s: %v", campaign.Name, invalid)
+		for _, fieldError := range invalid.Errors {
+			run.InvalidNumbers = append(run.InvalidNumbers, fieldError.Value)
+		}
+	}
 
-	if err != nil {
+	// The run is stored before its calls are queued, so the dispatcher can add the calls it places to it
+	if _, err := mongodb.CreateCampaignRun(orgId, run); err != nil {
+		log.Printf("[CampaignScheduler] Error recording run of campaign %s: %v", campaign.Name, err)
+	}
+
+	origin := callOrigin{campaignId: campaign.Id.Hex(), runId: &run.Id}
+
+	var queued []mongodbTypes.QueuedCall
+	var err error
+	if len(valid) > 0 && len(campaign.PhoneNumberPool) > 0 {
+		queued, err = executeCampaignPool(orgId, campaign, origin, valid, &run)
+	} else if len(valid) > 0 {
+		caller := Caller{OrgId: orgId, UserId: SYSTEM_CAMPAIGN_SCHEDULER}
+		queued, run.SuppressedNumbers, err = createCall(caller, origin, campaign.AssistantId, campaign.PhoneNumberId, valid)
+	}
+
+	if len(valid) == 0 || errors.Is(err, ErrAllSuppressed) || errors.Is(err, ErrDailyCapReached) {
+		// Nothing left to call is not a failure, the campaign ran like one without customers
+		log.Printf("[CampaignScheduler] No customer of campaign %s can be called: %v", campaign.Name, err)
+		if errors.Is(err, ErrDailyCapReached) {
+			for _, customer := range valid {
+				if !slices.Contains(run.SuppressedNumbers, customer.PhoneNumber) {
+					run.CappedNumbers = append(run.CappedNumbers, customer.PhoneNumber)
+				}
+			}
+		}
+		queued, err = []mongodbTypes.QueuedCall{}, nil
+	}
+
+	completeCampaignRun(orgId, campaign, run, queued, err)
+
+	if err != nil && len(queued) > 0 {
+		// Some numbers of the pool queued their calls, running the campaign again would call those customers twice
+		log.Printf("[CampaignScheduler] Campaign %s partially queued: %v", campaign.Name, err)
+	} else if err != nil {
 		log.Printf("[CampaignScheduler] Error creating call: %v", err)
 		return nil, err
 	}
 
-	log.Printf("[CampaignScheduler] Campaign created: %+v", resp)
-	return resp, nil
+	log.Printf("[CampaignScheduler] Campaign %s queued %d calls", campaign.Name, len(queued))
+	return queued, nil
+}
+
+// executeCampaignPool queues the calls of a campaign with a phone number pool, one batch per number
+// chosen by the campaign's caller ID strategy. Customers left over once every number reached its daily cap
+// are recorded on the run. A number whose customers are all suppressed or that reached its cap doesn't fail
+// the other batches; any other error is returned along with the calls that were queued.
+func executeCampaignPool(orgId string, campaign mongodbTypes.Campaign, origin callOrigin, customers []mongodbTypes.Customer, run *mongodbTypes.CampaignRun) ([]mongodbTypes.QueuedCall, error) {
+	assignments, capped, err := assignCallerIds(orgId, campaign, customers)
+	if err != nil {
+		return nil, err
+	}
+	run.CappedNumbers = append(run.CappedNumbers, capped...)
+
+	caller := Caller{OrgId: orgId, UserId: SYSTEM_CAMPAIGN_SCHEDULER}
+	origin.callerIdStrategy = campaign.CallerIdStrategy
+
+	queued := []mongodbTypes.QueuedCall{}
+	var firstErr error
+	for _, assignment := range assignments {
+		calls, suppressed, err := createCall(caller, origin, campaign.AssistantId, assignment.phoneNumberId, assignment.customers)
+		run.SuppressedNumbers = append(run.SuppressedNumbers, suppressed...)
+
+		switch {
+		case errors.Is(err, ErrAllSuppressed):
+		case errors.Is(err, ErrDailyCapReached):
+			// Another campaign used the number since the pool was loaded
+			log.Printf("[CampaignScheduler] Phone number %s of campaign %s: %v", assignment.phoneNumberId, campaign.Name, err)
+			for _, customer := range assignment.customers {
+				if !slices.Contains(suppressed, customer.PhoneNumber) {
+					run.CappedNumbers = append(run.CappedNumbers, customer.PhoneNumber)
+				}
+			}
+		case err != nil:
+			log.Printf("[CampaignScheduler] Error calling from phone number %s of campaign %s: %v", assignment.phoneNumberId, campaign.Name, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		default:
+			queued = append(queued, calls...)
+		}
+	}
+
+	if len(queued) == 0 && firstErr == nil {
+		// Every batch was suppressed or capped, or no number could take a customer
+		return nil, ErrAllSuppressed
+	}
+
+	return queued, firstErr
+}
+
+// completeCampaignRun records the outcome of a campaign execution in the campaign run history. The VapiAI calls
+// are added to the run as the call dispatcher sends its queued calls. The run only fails if no call was queued.
+// Failures are logged and don't fail the execution, since the calls have already been queued.
+func completeCampaignRun(orgId string, campaign mongodbTypes.Campaign, run mongodbTypes.CampaignRun, queued []mongodbTypes.QueuedCall, createErr error) {
+	run.QueuedCalls = len(queued)
+
+	if createErr != nil {
+		run.Error = createErr.Error()
+		if len(queued) == 0 {
+			run.Status = mongodbTypes.RUN_STATUS_FAILED
+		}
+	}
+
+	if err := mongodb.CompleteCampaignRun(orgId, run); err != nil {
+		log.Printf("[CampaignScheduler] Error recording outcome of run of campaign %s: %v", campaign.Name, err)
+	}
+}
+
+// GetCampaignRuns returns the most recent runs of one of the caller's campaigns.
+// Returns ErrNotFound if the organization has no such campaign.
+func GetCampaignRuns(caller Caller, campaignId string, limit int) ([]mongodbTypes.CampaignRun, error) {
+	if _, err := AuthorizeCampaign(caller, "campaigns.runs", campaignId); err != nil {
+		return nil, err
+	}
+
+	runs, err := mongodb.GetCampaignRuns(caller.OrgId, campaignId, limit)
+	if err != nil {
+		log.Printf("Error getting campaign runs: %v", err)
+		return nil, err
+	}
+
+	return runs, nil
 }
 
 func getDynamicCustomers(orgId string) ([]mongodbTypes.Customer, error) {
@@ -0,0 +1,209 @@
+package sarah
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"sarah/mongodb"
+	mongodbTypes "sarah/types/mongodb"
+	vapiTypes "sarah/types/vapi"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// MAX_ANALYSIS_MAPPINGS is the maximum number of analysis mappings of an organization
+const MAX_ANALYSIS_MAPPINGS = 50
+
+// ANALYSIS_FIELD_SUMMARY is the analysis field holding the call summary
+const ANALYSIS_FIELD_SUMMARY = "summary"
+
+// ANALYSIS_FIELD_SUCCESS_EVALUATION is the analysis field holding the success evaluation
+const ANALYSIS_FIELD_SUCCESS_EVALUATION = "success_evaluation"
+
+// ANALYSIS_FIELD_STRUCTURED_DATA is the prefix of the analysis fields read from the structured data
+const ANALYSIS_FIELD_STRUCTURED_DATA = "structured_data."
+
+// metadataKeyPattern matches metadata keys and structured data paths: names of letters, digits,
+// underscores and hyphens, separated by dots
+var metadataKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+(\.[A-Za-z0-9_-]+)*$`)
+
+// validateAnalysisMappings checks the fields and metadata keys of an organization's analysis mappings.
+// Returns an error wrapping ErrInvalidSettings if a mapping is invalid or two mappings write the same key.
+func validateAnalysisMappings(mappings []mongodbTypes.AnalysisMapping) error {
+	if len(mappings) > MAX_ANALYSIS_MAPPINGS {
+		return fmt.Errorf("%w: analysis_mappings can have at most %d mappings", ErrInvalidSettings, MAX_ANALYSIS_MAPPINGS)
+	}
+
+	keys := []string{}
+	for i, mapping := range mappings {
+		field := mapping.Field
+		if field != ANALYSIS_FIELD_SUMMARY && field != ANALYSIS_FIELD_SUCCESS_EVALUATION &&
+			!(strings.HasPrefix(field, ANALYSIS_FIELD_STRUCTURED_DATA) && metadataKeyPattern.MatchString(strings.TrimPrefix(field, ANALYSIS_FIELD_STRUCTURED_DATA))) {
+			return fmt.Errorf("%w: analysis_mappings[%d].field must be summary, success_evaluation or structured_data.<key>", ErrInvalidSettings, i)
+		}
+
+		if !metadataKeyPattern.MatchString(mapping.MetadataKey) {
+			return fmt.Errorf("%w: analysis_mappings[%d].metadata_key must be letters, digits, underscores and hyphens, nested keys separated by dots", ErrInvalidSettings, i)
+		}
+
+		// A key can't be written both as a value and as a document holding another mapped key
+		for _, key := range keys {
+			if key == mapping.MetadataKey || strings.HasPrefix(key, mapping.MetadataKey+".") || strings.HasPrefix(mapping.MetadataKey, key+".") {
+				return fmt.Errorf("%w: analysis_mappings[%d].metadata_key conflicts with %s", ErrInvalidSettings, i, key)
+			}
+		}
+		keys = append(keys, mapping.MetadataKey)
+	}
+
+	return nil
+}
+
+// applyCallAnalysis writes the analysis of an ended call to the metadata of the organization's contact
+// with the customer's number, following the organization's analysis mappings, and records the update
+// in the contact's history. Analysis fields missing from the report are left out.
+// A call updates a contact once, so a report VapiAI sends again is not applied twice.
+func applyCallAnalysis(orgId string, message vapiTypes.ServerMessage) error {
+	if message.Analysis == nil || message.Call.Customer == nil || message.Call.Customer.Number == "" {
+		return nil
+	}
+
+	settings, err := mongodb.GetOrganizationSettings(orgId)
+	if err != nil {
+		log.Printf("Error getting analysis mappings of organization %s: %v", orgId, err)
+		return err
+	}
+	if len(settings.AnalysisMappings) == 0 {
+		return nil
+	}
+
+	contact, err := mongodb.GetContactByPhoneNumber(orgId, message.Call.Customer.Number)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	} else if err != nil {
+		log.Printf("Error finding contact of call %s: %v", message.Call.Id, err)
+		return err
+	}
+
+	changes := []mongodbTypes.ContactChange{}
+	values := map[string]interface{}{}
+	for _, mapping := range settings.AnalysisMappings {
+		value, ok := analysisValue(message.Analysis, mapping.Field)
+		if !ok {
+			continue
+		}
+
+		oldValue, _ := lookupPath(contact.Metadata, mapping.MetadataKey)
+		changes = append(changes, mongodbTypes.ContactChange{
+			Field:       mapping.Field,
+			MetadataKey: mapping.MetadataKey,
+			OldValue:    oldValue,
+			NewValue:    value,
+		})
+		values[mapping.MetadataKey] = value
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	recorded, err := mongodb.CreateContactUpdate(orgId, mongodbTypes.ContactUpdate{
+		Id:         bson.NewObjectID(),
+		ContactId:  contact.Id,
+		VapiCallId: message.Call.Id,
+		Changes:    changes,
+		CreatedAt:  time.Now(),
+	})
+	if err != nil {
+		log.Printf("Error recording update of contact %s: %v", contact.Id.Hex(), err)
+		return err
+	}
+	if !recorded {
+		return nil
+	}
+
+	if err := mongodb.SetContactMetadata(orgId, contact.Id, values); err != nil {
+		log.Printf("Error updating metadata of contact %s: %v", contact.Id.Hex(), err)
+		// The update is removed from the history, so the report can be applied when VapiAI sends it again
+		if err := mongodb.DeleteContactUpdate(orgId, contact.Id, message.Call.Id); err != nil {
+			log.Printf("Error removing update of contact %s: %v", contact.Id.Hex(), err)
+		}
+		return err
+	}
+
+	log.Printf("[Webhook] Wrote %d analysis fields of call %s to contact %s", len(changes), message.Call.Id, contact.Id.Hex())
+	return nil
+}
+
+// analysisValue returns the value of an analysis field, and whether the analysis has it.
+func analysisValue(analysis *vapiTypes.Analysis, field string) (interface{}, bool) {
+	switch field {
+	case ANALYSIS_FIELD_SUMMARY:
+		return analysis.Summary, analysis.Summary != ""
+	case ANALYSIS_FIELD_SUCCESS_EVALUATION:
+		return analysis.SuccessEvaluation, analysis.SuccessEvaluation != nil
+	}
+
+	if !strings.HasPrefix(field, ANALYSIS_FIELD_STRUCTURED_DATA) {
+		return nil, false
+	}
+
+	value, ok := lookupPath(analysis.StructuredData, strings.TrimPrefix(field, ANALYSIS_FIELD_STRUCTURED_DATA))
+	return value, ok && value != nil
+}
+
+// lookupPath returns the value at a dot-separated path of nested documents, and whether it is set.
+// Documents decoded from JSON are maps, while documents decoded from MongoDB may also be bson.D.
+func lookupPath(document interface{}, path string) (interface{}, bool) {
+	value := document
+	for _, key := range strings.Split(path, ".") {
+		ok := false
+		switch current := value.(type) {
+		case map[string]interface{}:
+			value, ok = current[key]
+		case bson.M:
+			value, ok = current[key]
+		case bson.D:
+			for _, element := range current {
+				if element.Key == key {
+					value, ok = element.Value, true
+					break
+				}
+			}
+		}
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return value, true
+}
+
+// GetContactUpdates returns a page of the metadata updates written to a contact of the caller's organization
+// from call analyses, newest first. Returns ErrNotFound if the organization has no such contact.
+func GetContactUpdates(caller Caller, contactId string, page int, limit int) (*mongodbTypes.ContactUpdatePage, error) {
+	id, err := bson.ObjectIDFromHex(contactId)
+	if err != nil {
+		recordDenied(caller, "contacts.updates", "contact", contactId)
+		return nil, ErrNotFound
+	}
+
+	if _, err := mongodb.GetContactById(caller.OrgId, id); errors.Is(err, mongo.ErrNoDocuments) {
+		recordDenied(caller, "contacts.updates", "contact", contactId)
+		return nil, ErrNotFound
+	} else if err != nil {
+		log.Printf("Error getting contact %s: %v", contactId, err)
+		return nil, err
+	}
+
+	updates, err := mongodb.GetContactUpdates(caller.OrgId, id, page, limit)
+	if err != nil {
+		log.Printf("Error getting updates of contact %s: %v", contactId, err)
+		return nil, err
+	}
+
+	return updates, nil
+}
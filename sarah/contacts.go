@@ -0,0 +1,70 @@
+package sarah
+
+import (
+	"log"
+
+	"sarah/mongodb"
+	"sarah/phone"
+	mongodbTypes "sarah/types/mongodb"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// CreateContact validates and stores a contact of the organization.
+// Phone numbers are converted to E.164, reading national numbers in the organization's default country.
+// Returns a *ValidationError if a phone number is missing or invalid.
+func CreateContact(orgId string, contact mongodbTypes.Contact) (*mongo.InsertOneResult, error) {
+	if err := normalizeContact(&contact, organizationCountry(orgId)); err != nil {
+		return nil, err
+	}
+
+	result, err := mongodb.CreateContact(orgId, contact)
+	if err != nil {
+		log.Printf("Error creating contact: %v", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// UpdateContact validates and updates a contact of the organization.
+// Returns a *ValidationError if a phone number is missing or invalid.
+func UpdateContact(orgId string, contact mongodbTypes.Contact) (*mongo.UpdateResult, error) {
+	if err := normalizeContact(&contact, organizationCountry(orgId)); err != nil {
+		return nil, err
+	}
+
+	result, err := mongodb.UpdateContact(orgId, contact)
+	if err != nil {
+		log.Printf("Error updating contact: %v", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// normalizeContact converts the phone numbers of a contact to E.164. The contact's own number
+// is required; its customer's number, used by dynamic campaigns, is validated when set.
+func normalizeContact(contact *mongodbTypes.Contact, defaultCountry string) error {
+	invalid := &ValidationError{}
+
+	number, err := phone.Normalize(contact.PhoneNumber, defaultCountry)
+	if err != nil {
+		invalid.add("phone_number", contact.PhoneNumber, err)
+	} else {
+		contact.PhoneNumber = number.E164
+		contact.PhoneNumberType = string(number.Type)
+	}
+
+	if contact.Customer.PhoneNumber != "" {
+		number, err := phone.Normalize(contact.Customer.PhoneNumber, defaultCountry)
+		if err != nil {
+			invalid.add("customer.phone_number", contact.Customer.PhoneNumber, err)
+		} else {
+			contact.Customer.PhoneNumber = number.E164
+			contact.Customer.PhoneNumberType = string(number.Type)
+		}
+	}
+
+	return invalid.orNil()
+}
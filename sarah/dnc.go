@@ -0,0 +1,354 @@
+package sarah
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"sarah/mongodb"
+	"sarah/phone"
+	mongodbTypes "sarah/types/mongodb"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// ErrInvalidDnc is returned when a Do-Not-Call number or import file is invalid.
+var ErrInvalidDnc = errors.New("invalid do-not-call entry")
+
+// ErrAllSuppressed is returned by CreateCall when every customer number is on a Do-Not-Call list.
+var ErrAllSuppressed = errors.New("every customer number is on the do-not-call list")
+
+// DNC_IMPORT_BATCH_SIZE is the number of imported entries written to the database at once
+const DNC_IMPORT_BATCH_SIZE = 1000
+
+// MAX_DNC_IMPORT_ROWS is the maximum number of rows of an imported do-not-call CSV file, including the header
+const MAX_DNC_IMPORT_ROWS = 500000
+
+// dncPhoneColumns are the CSV header names recognized as the phone number column
+var dncPhoneColumns = []string{"phone_number", "phone number", "phonenumber", "phone", "number"}
+
+// DncImportResult is the outcome of a Do-Not-Call CSV import.
+type DncImportResult struct {
+	// Imported is the number of entries added to the list
+	Imported int64 `json:"imported"`
+
+	// Duplicates is the number of valid rows whose number was already on the list or earlier in the file
+	Duplicates int64 `json:"duplicates"`
+
+	// Invalid are the values of the rows that are not phone numbers, by line number
+	Invalid map[int]string `json:"invalid"`
+}
+
+// dncRow is a parsed row of a Do-Not-Call CSV file.
+type dncRow struct {
+	phoneNumber string
+	reason      string
+}
+
+// globalDnc is the optional Do-Not-Call list shared by every organization, loaded from DNC_GLOBAL_FILE.
+// The file is reloaded when it changes, so the list can be updated without a restart.
+var globalDnc = &globalDncList{}
+
+type globalDncList struct {
+	mu      sync.Mutex
+	modTime time.Time
+	numbers map[string]bool
+}
+
+// suppressed returns which of the given numbers are on the global list.
+// Returns an error if DNC_GLOBAL_FILE is set but was never loaded, so calls fail closed
+// rather than reaching numbers that should be suppressed.
+func (l *globalDncList) suppressed(numbers []string) (map[string]bool, error) {
+	path := os.Getenv("DNC_GLOBAL_FILE")
+	if path == "" {
+		return map[string]bool{}, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.reload(path); err != nil {
+		if l.numbers == nil {
+			return nil, fmt.Errorf("loading global do-not-call list: %w", err)
+		}
+		log.Printf("[DNC] Error reloading global list, using the previous version: %v", err)
+	}
+
+	suppressed := map[string]bool{}
+	for _, number := range numbers {
+		if l.numbers[number] {
+			suppressed[number] = true
+		}
+	}
+	return suppressed, nil
+}
+
+// reload reads the global list file again if it changed since it was last loaded.
+func (l *globalDncList) reload(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if l.numbers != nil && info.ModTime().Equal(l.modTime) {
+		return nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// The global list isn't tied to an organization, so its numbers must be in international format
+	rows, invalid, err := parseDncCsv(file, "", 0)
+	if err != nil {
+		return err
+	}
+
+	numbers := map[string]bool{}
+	for _, row := range rows {
+		numbers[row.phoneNumber] = true
+	}
+
+	l.numbers = numbers
+	l.modTime = info.ModTime()
+	log.Printf("[DNC] Loaded %d numbers from the global list (%d invalid lines skipped)", len(numbers), len(invalid))
+
+	return nil
+}
+
+// parseDncCsv reads the numbers of a Do-Not-Call CSV file. The file either has a header row naming
+// a phone number column (and optionally a "reason" column) or lists one number per line in the first column.
+// National numbers are read in defaultCountry. Returns the valid rows, in E.164, and the invalid values by line number.
+// Returns an error wrapping ErrInvalidDnc if the file has more than maxRows rows, unless maxRows is 0.
+func parseDncCsv(r io.Reader, defaultCountry string, maxRows int) ([]dncRow, map[int]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.ReuseRecord = true
+
+	rows := []dncRow{}
+	invalid := map[int]string{}
+	phoneColumn, reasonColumn := 0, -1
+
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrInvalidDnc, err)
+		} else if maxRows > 0 && line > maxRows {
+			return nil, nil, fmt.Errorf("%w: the file has more than %d rows", ErrInvalidDnc, maxRows)
+		}
+
+		if line == 1 {
+			if column, reason, ok := dncHeader(record); ok {
+				phoneColumn, reasonColumn = column, reason
+				continue
+			}
+		}
+
+		if phoneColumn >= len(record) || strings.TrimSpace(record[phoneColumn]) == "" {
+			continue
+		}
+
+		number, err := phone.Normalize(record[phoneColumn], defaultCountry)
+		if err != nil {
+			invalid[line] = record[phoneColumn]
+			continue
+		}
+
+		row := dncRow{phoneNumber: number.E164}
+		if reasonColumn >= 0 && reasonColumn < len(record) {
+			row.reason = strings.TrimSpace(record[reasonColumn])
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, invalid, nil
+}
+
+// dncHeader returns the phone number and reason columns of a CSV header row.
+// Returns false if the row is not a header.
+func dncHeader(record []string) (int, int, bool) {
+	phoneColumn, reasonColumn := -1, -1
+	for i, name := range record {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if name == "reason" {
+			reasonColumn = i
+		}
+		for _, column := range dncPhoneColumns {
+			if name == column && phoneColumn < 0 {
+				phoneColumn = i
+			}
+		}
+	}
+	return phoneColumn, reasonColumn, phoneColumn >= 0
+}
+
+// GetDncList returns a page of the organization's Do-Not-Call list, newest first.
+func GetDncList(orgId string, page int, limit int) (*mongodbTypes.DncPage, error) {
+	entries, err := mongodb.GetDncEntries(orgId, page, limit)
+	if err != nil {
+		log.Printf("Error getting do-not-call list: %v", err)
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// AddDncNumber adds a number to the caller's Do-Not-Call list. Adding a number that is already
+// on the list is not an error. Returns an error wrapping ErrInvalidDnc if the value is not a phone number.
+func AddDncNumber(caller Caller, phoneNumber string, reason string) (*mongodbTypes.DncEntry, error) {
+	number, err := normalizeDncNumber(caller.OrgId, phoneNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := mongodbTypes.DncEntry{
+		PhoneNumber: number,
+		Reason:      strings.TrimSpace(reason),
+		Source:      mongodbTypes.DNC_SOURCE_MANUAL,
+		AddedBy:     caller.UserId,
+		CreatedAt:   time.Now(),
+	}
+
+	if _, err := mongodb.AddDncEntries(caller.OrgId, []mongodbTypes.DncEntry{entry}); err != nil {
+		log.Printf("Error adding %s to the do-not-call list: %v", number, err)
+		return nil, err
+	}
+
+	recordAudit(caller, "dnc.create", "dnc", number, mongodbTypes.AUDIT_ALLOWED, entry.Reason)
+
+	return &entry, nil
+}
+
+// ImportDncCsv adds every number of a CSV file to the caller's Do-Not-Call list.
+// Invalid rows are reported and skipped; numbers already on the list are counted as duplicates.
+// Returns an error wrapping ErrInvalidDnc if the file is not valid CSV or has more than MAX_DNC_IMPORT_ROWS rows.
+func ImportDncCsv(caller Caller, r io.Reader) (*DncImportResult, error) {
+	rows, invalid, err := parseDncCsv(r, organizationCountry(caller.OrgId), MAX_DNC_IMPORT_ROWS)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DncImportResult{Invalid: invalid}
+	createdAt := time.Now()
+
+	seen := map[string]bool{}
+	batch := []mongodbTypes.DncEntry{}
+	flush := func() error {
+		imported, err := mongodb.AddDncEntries(caller.OrgId, batch)
+		if err != nil {
+			log.Printf("Error importing do-not-call list: %v", err)
+			return err
+		}
+		result.Imported += imported
+		result.Duplicates += int64(len(batch)) - imported
+		batch = batch[:0]
+		return nil
+	}
+
+	for _, row := range rows {
+		if seen[row.phoneNumber] {
+			result.Duplicates++
+			continue
+		}
+		seen[row.phoneNumber] = true
+
+		batch = append(batch, mongodbTypes.DncEntry{
+			PhoneNumber: row.phoneNumber,
+			Reason:      row.reason,
+			Source:      mongodbTypes.DNC_SOURCE_IMPORT,
+			AddedBy:     caller.UserId,
+			CreatedAt:   createdAt,
+		})
+
+		if len(batch) == DNC_IMPORT_BATCH_SIZE {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	recordAudit(caller, "dnc.import", "dnc", "", mongodbTypes.AUDIT_ALLOWED,
+		fmt.Sprintf("%d imported, %d duplicates, %d invalid", result.Imported, result.Duplicates, len(result.Invalid)))
+
+	return result, nil
+}
+
+// RemoveDncNumber removes a number from the caller's Do-Not-Call list.
+// Returns ErrNotFound if the number is not on the list. Numbers on the global list stay suppressed.
+func RemoveDncNumber(caller Caller, phoneNumber string) (*mongo.DeleteResult, error) {
+	number, err := normalizeDncNumber(caller.OrgId, phoneNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := mongodb.DeleteDncEntry(caller.OrgId, number)
+	if err != nil {
+		log.Printf("Error removing %s from the do-not-call list: %v", number, err)
+		return nil, err
+	}
+	if result.DeletedCount == 0 {
+		return nil, ErrNotFound
+	}
+
+	recordAudit(caller, "dnc.delete", "dnc", number, mongodbTypes.AUDIT_ALLOWED, "")
+
+	return result, nil
+}
+
+// normalizeDncNumber converts a number sent by the organization to E.164, so it matches the
+// customer numbers CreateCall checks. Returns an error wrapping ErrInvalidDnc if it is not a phone number.
+func normalizeDncNumber(orgId string, phoneNumber string) (string, error) {
+	number, err := phone.Normalize(phoneNumber, organizationCountry(orgId))
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidDnc, phoneErrorMessage(err))
+	}
+	return number.E164, nil
+}
+
+// suppressCustomers splits customers into the ones that may be called and the numbers
+// on the organization's or the global Do-Not-Call list. Customer numbers must be in E.164.
+func suppressCustomers(orgId string, customers []mongodbTypes.Customer) ([]mongodbTypes.Customer, []string, error) {
+	numbers := []string{}
+	for _, customer := range customers {
+		numbers = append(numbers, customer.PhoneNumber)
+	}
+
+	suppressed, err := globalDnc.suppressed(numbers)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	orgSuppressed, err := mongodb.GetSuppressedNumbers(orgId, numbers)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, number := range orgSuppressed {
+		suppressed[number] = true
+	}
+
+	allowed := []mongodbTypes.Customer{}
+	suppressedNumbers := []string{}
+	for _, customer := range customers {
+		if suppressed[customer.PhoneNumber] {
+			suppressedNumbers = append(suppressedNumbers, customer.PhoneNumber)
+			continue
+		}
+		allowed = append(allowed, customer)
+	}
+
+	return allowed, suppressedNumbers, nil
+}
@@ -0,0 +1,183 @@
+package sarah
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"sarah/mongodb"
+	mongodbTypes "sarah/types/mongodb"
+)
+
+// ErrInvalidExport is returned when an export format or column is not supported.
+var ErrInvalidExport = errors.New("invalid export")
+
+// ExportFormat defines the file formats calls can be exported to.
+type ExportFormat string
+
+const (
+	// EXPORT_CSV writes a header row followed by one comma-separated row per call
+	EXPORT_CSV ExportFormat = "csv"
+
+	// EXPORT_JSONL writes one JSON object per line per call
+	EXPORT_JSONL ExportFormat = "jsonl"
+)
+
+// exportBatchSize is the number of calls written between two flushes of the export
+const exportBatchSize = 500
+
+// callExportColumns maps the exportable columns to the value they take for a call
+var callExportColumns = map[string]func(call mongodbTypes.Call) interface{}{
+	"vapi_call_id":       func(call mongodbTypes.Call) interface{} { return call.VapiCallId },
+	"created_at":         func(call mongodbTypes.Call) interface{} { return call.CreatedAt },
+	"started_at":         func(call mongodbTypes.Call) interface{} { return call.StartedAt },
+	"ended_at":           func(call mongodbTypes.Call) interface{} { return call.EndedAt },
+	"assistant_id":       func(call mongodbTypes.Call) interface{} { return call.AssistantId },
+	"phone_number_id":    func(call mongodbTypes.Call) interface{} { return call.PhoneNumberId },
+	"campaign_id":        func(call mongodbTypes.Call) interface{} { return call.CampaignId },
+	"customer_number":    func(call mongodbTypes.Call) interface{} { return call.CustomerNumber },
+	"customer_name":      func(call mongodbTypes.Call) interface{} { return call.CustomerName },
+	"status":             func(call mongodbTypes.Call) interface{} { return call.Status },
+	"ended_reason":       func(call mongodbTypes.Call) interface{} { return call.EndedReason },
+	"duration_seconds":   func(call mongodbTypes.Call) interface{} { return call.DurationSeconds },
+	"cost":               func(call mongodbTypes.Call) interface{} { return call.Cost },
+	"success_evaluation": func(call mongodbTypes.Call) interface{} { return call.SuccessEvaluation },
+	"summary":            func(call mongodbTypes.Call) interface{} { return call.Summary },
+	"tags":               func(call mongodbTypes.Call) interface{} { return call.Tags },
+	"disposition":        func(call mongodbTypes.Call) interface{} { return call.Disposition },
+	"note_count":         func(call mongodbTypes.Call) interface{} { return call.NoteCount },
+}
+
+// DefaultExportColumns are the columns exported when none are requested
+var DefaultExportColumns = []string{"vapi_call_id", "created_at", "customer_number", "duration_seconds", "ended_reason", "cost", "summary"}
+
+// ParseExportColumns parses a comma-separated list of export columns, in the order given.
+// An empty list selects DefaultExportColumns. Returns an error wrapping ErrInvalidExport
+// for unknown or repeated columns.
+func ParseExportColumns(value string) ([]string, error) {
+	if strings.TrimSpace(value) == "" {
+		return DefaultExportColumns, nil
+	}
+
+	columns := []string{}
+	seen := map[string]bool{}
+	for _, column := range strings.Split(value, ",") {
+		column = strings.TrimSpace(column)
+		if _, ok := callExportColumns[column]; !ok {
+			return nil, fmt.Errorf("%w: unknown column %q", ErrInvalidExport, column)
+		}
+		if seen[column] {
+			return nil, fmt.Errorf("%w: column %q is repeated", ErrInvalidExport, column)
+		}
+		seen[column] = true
+		columns = append(columns, column)
+	}
+
+	return columns, nil
+}
+
+// ExportCalls writes the organization's calls matching the filter to w, oldest first, in the
+// given format and with the given columns. Calls are read from a database cursor and written
+// as they come, and flush is called every exportBatchSize calls, so exports of any size can be
+// streamed without being held in memory.
+func ExportCalls(orgId string, filter mongodbTypes.CallFilter, format ExportFormat, columns []string, w io.Writer, flush func() error) (int, error) {
+	var writeCall func(call mongodbTypes.Call) error
+	var flushWriter func() error
+
+	switch format {
+	case EXPORT_CSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(columns); err != nil {
+			return 0, err
+		}
+		writeCall = func(call mongodbTypes.Call) error {
+			row := make([]string, len(columns))
+			for i, column := range columns {
+				row[i] = csvValue(callExportColumns[column](call))
+			}
+			return writer.Write(row)
+		}
+		flushWriter = func() error {
+			writer.Flush()
+			return writer.Error()
+		}
+	case EXPORT_JSONL:
+		encoder := json.NewEncoder(w)
+		writeCall = func(call mongodbTypes.Call) error {
+			row := make(map[string]interface{}, len(columns))
+			for _, column := range columns {
+				row[column] = callExportColumns[column](call)
+			}
+			return encoder.Encode(row)
+		}
+		flushWriter = func() error { return nil }
+	default:
+		return 0, fmt.Errorf("%w: unknown format %q", ErrInvalidExport, format)
+	}
+
+	exported := 0
+	err := mongodb.StreamOrganizationCalls(orgId, filter, func(call mongodbTypes.Call) error {
+		if err := writeCall(call); err != nil {
+			return err
+		}
+
+		exported++
+		if exported%exportBatchSize == 0 {
+			if err := flushWriter(); err != nil {
+				return err
+			}
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error exporting calls after %d calls: %v", exported, err)
+		return exported, err
+	}
+
+	if err := flushWriter(); err != nil {
+		return exported, err
+	}
+	return exported, flush()
+}
+
+// csvValue formats an export value as a CSV cell. Cells that spreadsheet applications would
+// evaluate as formulas are prefixed with a quote, except for phone numbers and negative numbers.
+func csvValue(value interface{}) string {
+	var cell string
+	switch v := value.(type) {
+	case time.Time:
+		cell = v.UTC().Format(time.RFC3339)
+	case *time.Time:
+		if v != nil {
+			cell = v.UTC().Format(time.RFC3339)
+		}
+	case float64:
+		cell = strconv.FormatFloat(v, 'f', -1, 64)
+	case []string:
+		cell = strings.Join(v, ";")
+	default:
+		cell = fmt.Sprint(v)
+	}
+
+	if cell == "" {
+		return cell
+	}
+
+	switch cell[0] {
+	case '=', '@', '\t', '\r':
+		return "'" + cell
+	case '+', '-':
+		if _, err := strconv.ParseFloat(strings.TrimPrefix(cell, "+"), 64); err != nil {
+			return "'" + cell
+		}
+	}
+
+	return cell
+}
@@ -0,0 +1,569 @@
+package sarah
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	vapiTypes "sarah/types/vapi"
+
+	vapiApi "github.com/VapiAI/server-sdk-go"
+	"github.com/VapiAI/server-sdk-go/core"
+)
+
+// FakeCallOutcome is how a call to a customer of a FakeProvider ends.
+type FakeCallOutcome struct {
+	// EndedReason is why the call ends, customer-ended-call if empty. Calls ending with
+	// customer-did-not-answer, customer-busy or voicemail are never answered
+	EndedReason vapiApi.CallEndedReason
+
+	// Duration is how long the call lasts once answered
+	Duration time.Duration
+
+	// Cost is the total cost of the call in USD
+	Cost float64
+
+	// Summary, StructuredData and SuccessEvaluation are the analysis of an answered call
+	Summary           string
+	StructuredData    map[string]interface{}
+	SuccessEvaluation string
+}
+
+// FakeProvider is an in-memory Provider for running Sarah without the network, e.g. in tests.
+// Calls are created queued and move through their lifecycle each time Advance is called:
+// queued, ringing, in-progress, then ended with the outcome set for their customer.
+// Replace Telephony with a FakeProvider to exercise the scheduler and the handlers against it.
+type FakeProvider struct {
+	// CreateCallError, if set, is returned by CreateCall instead of creating calls
+	CreateCallError error
+
+	// OnTransition, if set, is called with a copy of a call every time its status changes
+	OnTransition func(call vapiApi.Call)
+
+	// Now returns the current time, time.Now if nil
+	Now func() time.Time
+
+	mu           sync.Mutex
+	nextId       int
+	calls        map[string]*vapiApi.Call
+	assistants   map[string]*vapiApi.Assistant
+	phoneNumbers map[string]*vapiApi.PhoneNumbersGetResponse
+	outcomes     map[string]FakeCallOutcome
+}
+
+// NewFakeProvider returns an empty FakeProvider.
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{
+		calls:        map[string]*vapiApi.Call{},
+		assistants:   map[string]*vapiApi.Assistant{},
+		phoneNumbers: map[string]*vapiApi.PhoneNumbersGetResponse{},
+		outcomes:     map[string]FakeCallOutcome{},
+	}
+}
+
+// AddPhoneNumber adds a phone number calls can be placed from, returning its ID.
+func (p *FakeProvider) AddPhoneNumber(number string, name string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	id := p.newId("phone")
+	p.phoneNumbers[id] = &vapiApi.PhoneNumbersGetResponse{
+		VapiPhoneNumber: &vapiApi.VapiPhoneNumber{
+			Id:        id,
+			Number:    vapiApi.String(number),
+			Name:      vapiApi.String(name),
+			CreatedAt: p.now(),
+			UpdatedAt: p.now(),
+		},
+	}
+
+	return id
+}
+
+// SetOutcome sets how calls to a customer number end. Calls already placed to the number end with it too.
+func (p *FakeProvider) SetOutcome(customerNumber string, outcome FakeCallOutcome) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.outcomes[customerNumber] = outcome
+}
+
+// Advance moves every call that hasn't ended one step through its lifecycle.
+// Returns the number of calls whose status changed.
+func (p *FakeProvider) Advance() int {
+	p.mu.Lock()
+	transitions := []vapiApi.Call{}
+	for _, id := range p.callIds() {
+		call := p.calls[id]
+		if p.advance(call) {
+			transitions = append(transitions, *call)
+		}
+	}
+	p.mu.Unlock()
+
+	p.notify(transitions)
+	return len(transitions)
+}
+
+// CompleteCalls advances every call until it has ended.
+func (p *FakeProvider) CompleteCalls() {
+	for p.Advance() > 0 {
+	}
+}
+
+// ServerMessage returns the message VapiAI sends to the server URL about the current state of a call:
+// an end-of-call-report once the call has ended, a status-update otherwise.
+func (p *FakeProvider) ServerMessage(callId string) (vapiTypes.ServerMessage, error) {
+	call, err := p.GetCall(context.Background(), callId)
+	if err != nil {
+		return vapiTypes.ServerMessage{}, err
+	}
+
+	// The server message call is the subset of the VapiAI call with the same JSON names
+	body, err := json.Marshal(call)
+	if err != nil {
+		return vapiTypes.ServerMessage{}, err
+	}
+	var messageCall vapiTypes.Call
+	if err := json.Unmarshal(body, &messageCall); err != nil {
+		return vapiTypes.ServerMessage{}, err
+	}
+
+	message := vapiTypes.ServerMessage{
+		Type:        vapiTypes.MESSAGE_STATUS_UPDATE,
+		Timestamp:   float64(p.now().UnixMilli()),
+		Call:        &messageCall,
+		Status:      messageCall.Status,
+		EndedReason: messageCall.EndedReason,
+	}
+
+	if fakeStatus(call) == vapiApi.CallStatusEnded {
+		message.Type = vapiTypes.MESSAGE_END_OF_CALL_REPORT
+		message.Cost = call.Cost
+		message.StartedAt = call.StartedAt
+		message.EndedAt = call.EndedAt
+		if call.Analysis != nil {
+			message.Analysis = &vapiTypes.Analysis{
+				Summary:        derefString(call.Analysis.Summary),
+				StructuredData: call.Analysis.StructuredData,
+			}
+			if call.Analysis.SuccessEvaluation != nil {
+				message.Analysis.SuccessEvaluation = *call.Analysis.SuccessEvaluation
+			}
+		}
+	}
+
+	return message, nil
+}
+
+func (p *FakeProvider) CreateCall(ctx context.Context, request *vapiApi.CreateCallDto) (*vapiApi.CallsCreateResponse, error) {
+	if p.CreateCallError != nil {
+		return nil, p.CreateCallError
+	}
+
+	p.mu.Lock()
+
+	if _, ok := p.assistants[derefString(request.AssistantId)]; !ok {
+		p.mu.Unlock()
+		return nil, core.NewAPIError(http.StatusBadRequest, nil, fmt.Errorf("assistant %q not found", derefString(request.AssistantId)))
+	}
+	if _, ok := p.phoneNumbers[derefString(request.PhoneNumberId)]; !ok {
+		p.mu.Unlock()
+		return nil, core.NewAPIError(http.StatusBadRequest, nil, fmt.Errorf("phone number %q not found", derefString(request.PhoneNumberId)))
+	}
+
+	customers := request.Customers
+	if request.Customer != nil {
+		customers = append([]*vapiApi.CreateCustomerDto{request.Customer}, customers...)
+	}
+	if len(customers) == 0 {
+		p.mu.Unlock()
+		return nil, core.NewAPIError(http.StatusBadRequest, nil, fmt.Errorf("customer or customers is required"))
+	}
+
+	created := []*vapiApi.Call{}
+	transitions := []vapiApi.Call{}
+	for _, customer := range customers {
+		if derefString(customer.Number) == "" {
+			continue
+		}
+
+		status := vapiApi.CallStatusQueued
+		id := p.newId("call")
+		call := &vapiApi.Call{
+			Id:            id,
+			Status:        &status,
+			AssistantId:   request.AssistantId,
+			PhoneNumberId: request.PhoneNumberId,
+			Customer:      &vapiApi.CreateCustomerDto{Number: customer.Number, Name: customer.Name},
+			Name:          request.Name,
+			CreatedAt:     p.now(),
+			UpdatedAt:     p.now(),
+			Monitor: &vapiApi.Monitor{
+				ListenUrl:  vapiApi.String("wss://fake.vapi.invalid/" + id + "/listen"),
+				ControlUrl: vapiApi.String("https://fake.vapi.invalid/" + id + "/control"),
+			},
+		}
+		p.calls[id] = call
+
+		created = append(created, copyCall(call))
+		transitions = append(transitions, *call)
+	}
+	p.mu.Unlock()
+
+	if len(created) == 0 {
+		return nil, core.NewAPIError(http.StatusBadRequest, nil, fmt.Errorf("customer number is required"))
+	}
+
+	p.notify(transitions)
+
+	if request.Customer != nil && len(request.Customers) == 0 {
+		return &vapiApi.CallsCreateResponse{Call: created[0]}, nil
+	}
+	return &vapiApi.CallsCreateResponse{CallBatchResponse: &vapiApi.CallBatchResponse{Results: created}}, nil
+}
+
+func (p *FakeProvider) GetCall(ctx context.Context, callId string) (*vapiApi.Call, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	call, ok := p.calls[callId]
+	if !ok {
+		return nil, fakeNotFound("call", callId)
+	}
+
+	return copyCall(call), nil
+}
+
+// ListCalls returns the calls matching the request, newest first. Without a limit, 100 calls are returned, as VapiAI does.
+func (p *FakeProvider) ListCalls(ctx context.Context, request *vapiApi.CallsListRequest) ([]*vapiApi.Call, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if request == nil {
+		request = &vapiApi.CallsListRequest{}
+	}
+
+	calls := []*vapiApi.Call{}
+	for _, call := range p.calls {
+		if request.Id != nil && call.Id != *request.Id ||
+			request.AssistantId != nil && derefString(call.AssistantId) != *request.AssistantId ||
+			request.PhoneNumberId != nil && derefString(call.PhoneNumberId) != *request.PhoneNumberId ||
+			!inTimeRange(call.CreatedAt, request.CreatedAtGt, request.CreatedAtGe, request.CreatedAtLt, request.CreatedAtLe) ||
+			!inTimeRange(call.UpdatedAt, request.UpdatedAtGt, request.UpdatedAtGe, request.UpdatedAtLt, request.UpdatedAtLe) {
+			continue
+		}
+		calls = append(calls, copyCall(call))
+	}
+
+	sort.Slice(calls, func(i, j int) bool {
+		if calls[i].CreatedAt.Equal(calls[j].CreatedAt) {
+			return calls[i].Id > calls[j].Id
+		}
+		return calls[i].CreatedAt.After(calls[j].CreatedAt)
+	})
+
+	limit := 100
+	if request.Limit != nil {
+		limit = int(*request.Limit)
+	}
+	if len(calls) > limit {
+		calls = calls[:limit]
+	}
+
+	return calls, nil
+}
+
+// DeleteCall ends a call that wasn't dialed yet as manually-canceled, and removes any other call.
+func (p *FakeProvider) DeleteCall(ctx context.Context, callId string) error {
+	p.mu.Lock()
+
+	call, ok := p.calls[callId]
+	if !ok {
+		p.mu.Unlock()
+		return fakeNotFound("call", callId)
+	}
+
+	transitions := []vapiApi.Call{}
+	switch fakeStatus(call) {
+	case vapiApi.CallStatusScheduled, vapiApi.CallStatusQueued:
+		p.end(call, vapiApi.CallEndedReasonManuallyCanceled)
+		transitions = append(transitions, *call)
+	default:
+		delete(p.calls, callId)
+	}
+	p.mu.Unlock()
+
+	p.notify(transitions)
+	return nil
+}
+
+// EndCall ends an active call as assistant-ended-call, like the end-call control message does.
+func (p *FakeProvider) EndCall(ctx context.Context, call *vapiApi.Call) error {
+	if call.Monitor == nil || derefString(call.Monitor.ControlUrl) == "" {
+		return ErrCallNotControllable
+	}
+
+	p.mu.Lock()
+
+	stored, ok := p.calls[call.Id]
+	if !ok {
+		p.mu.Unlock()
+		return fakeNotFound("call", call.Id)
+	}
+
+	transitions := []vapiApi.Call{}
+	switch fakeStatus(stored) {
+	case vapiApi.CallStatusRinging, vapiApi.CallStatusInProgress, vapiApi.CallStatusForwarding:
+		p.end(stored, vapiApi.CallEndedReasonAssistantEndedCall)
+		transitions = append(transitions, *stored)
+	}
+	p.mu.Unlock()
+
+	p.notify(transitions)
+	return nil
+}
+
+// CreateAssistant creates an assistant with the fields set in the request.
+func (p *FakeProvider) CreateAssistant(ctx context.Context, request *vapiApi.CreateAssistantDto) (*vapiApi.Assistant, error) {
+	var assistant vapiApi.Assistant
+	if err := mergeJSON(&assistant, request); err != nil {
+		return nil, core.NewAPIError(http.StatusBadRequest, nil, err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	assistant.Id = p.newId("asst")
+	assistant.CreatedAt = p.now()
+	assistant.UpdatedAt = p.now()
+	p.assistants[assistant.Id] = &assistant
+
+	copied := assistant
+	return &copied, nil
+}
+
+func (p *FakeProvider) GetAssistant(ctx context.Context, assistantId string) (*vapiApi.Assistant, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	assistant, ok := p.assistants[assistantId]
+	if !ok {
+		return nil, fakeNotFound("assistant", assistantId)
+	}
+
+	copied := *assistant
+	return &copied, nil
+}
+
+// UpdateAssistant overwrites the fields of an assistant set in the request, keeping the others.
+func (p *FakeProvider) UpdateAssistant(ctx context.Context, assistantId string, request *vapiApi.UpdateAssistantDto) (*vapiApi.Assistant, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stored, ok := p.assistants[assistantId]
+	if !ok {
+		return nil, fakeNotFound("assistant", assistantId)
+	}
+
+	assistant := *stored
+	if err := mergeJSON(&assistant, request); err != nil {
+		return nil, core.NewAPIError(http.StatusBadRequest, nil, err)
+	}
+	assistant.Id = assistantId
+	assistant.CreatedAt = stored.CreatedAt
+	assistant.UpdatedAt = p.now()
+	p.assistants[assistantId] = &assistant
+
+	copied := assistant
+	return &copied, nil
+}
+
+func (p *FakeProvider) DeleteAssistant(ctx context.Context, assistantId string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.assistants[assistantId]; !ok {
+		return fakeNotFound("assistant", assistantId)
+	}
+	delete(p.assistants, assistantId)
+
+	return nil
+}
+
+func (p *FakeProvider) GetPhoneNumber(ctx context.Context, phoneNumberId string) (*vapiApi.PhoneNumbersGetResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	phoneNumber, ok := p.phoneNumbers[phoneNumberId]
+	if !ok {
+		return nil, fakeNotFound("phone number", phoneNumberId)
+	}
+
+	return phoneNumber, nil
+}
+
+// advance moves a call one step through its lifecycle, reporting whether its status changed.
+// The caller must hold the lock.
+func (p *FakeProvider) advance(call *vapiApi.Call) bool {
+	outcome := p.outcomes[derefString(call.Customer.Number)]
+
+	switch fakeStatus(call) {
+	case vapiApi.CallStatusScheduled, vapiApi.CallStatusQueued:
+		p.setStatus(call, vapiApi.CallStatusRinging)
+
+	case vapiApi.CallStatusRinging:
+		switch outcome.EndedReason {
+		case vapiApi.CallEndedReasonCustomerDidNotAnswer, vapiApi.CallEndedReasonCustomerBusy, vapiApi.CallEndedReasonVoicemail:
+			p.end(call, outcome.EndedReason)
+			return true
+		}
+		startedAt := p.now()
+		call.StartedAt = &startedAt
+		p.setStatus(call, vapiApi.CallStatusInProgress)
+
+	case vapiApi.CallStatusInProgress, vapiApi.CallStatusForwarding:
+		reason := outcome.EndedReason
+		if reason == "" {
+			reason = vapiApi.CallEndedReasonCustomerEndedCall
+		}
+		p.end(call, reason)
+
+	default:
+		return false
+	}
+
+	return true
+}
+
+// end ends a call with the given reason, recording the outcome of its customer.
+// The caller must hold the lock.
+func (p *FakeProvider) end(call *vapiApi.Call, reason vapiApi.CallEndedReason) {
+	outcome := p.outcomes[derefString(call.Customer.Number)]
+
+	endedAt := p.now()
+	if call.StartedAt != nil && outcome.Duration > 0 {
+		endedAt = call.StartedAt.Add(outcome.Duration)
+	}
+	call.EndedAt = &endedAt
+	call.EndedReason = &reason
+	call.Cost = vapiApi.Float64(outcome.Cost)
+
+	// Only answered calls are analyzed
+	if call.StartedAt != nil {
+		call.Analysis = &vapiApi.Analysis{
+			Summary:        vapiApi.String(outcome.Summary),
+			StructuredData: outcome.StructuredData,
+		}
+		if outcome.SuccessEvaluation != "" {
+			call.Analysis.SuccessEvaluation = vapiApi.String(outcome.SuccessEvaluation)
+		}
+	}
+
+	p.setStatus(call, vapiApi.CallStatusEnded)
+}
+
+// setStatus changes the status of a call. The caller must hold the lock.
+func (p *FakeProvider) setStatus(call *vapiApi.Call, status vapiApi.CallStatus) {
+	call.Status = &status
+	call.UpdatedAt = p.now()
+}
+
+// notify passes the calls whose status changed to OnTransition. The lock must not be held,
+// so OnTransition can use the provider.
+func (p *FakeProvider) notify(transitions []vapiApi.Call) {
+	if p.OnTransition == nil {
+		return
+	}
+	for _, call := range transitions {
+		p.OnTransition(call)
+	}
+}
+
+// callIds returns the IDs of the calls in creation order. The caller must hold the lock.
+func (p *FakeProvider) callIds() []string {
+	ids := make([]string, 0, len(p.calls))
+	for id := range p.calls {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if p.calls[ids[i]].CreatedAt.Equal(p.calls[ids[j]].CreatedAt) {
+			return ids[i] < ids[j]
+		}
+		return p.calls[ids[i]].CreatedAt.Before(p.calls[ids[j]].CreatedAt)
+	})
+	return ids
+}
+
+// newId returns a new ID with the given prefix. The caller must hold the lock.
+func (p *FakeProvider) newId(prefix string) string {
+	p.nextId++
+	return fmt.Sprintf("%s_fake%012d", prefix, p.nextId)
+}
+
+func (p *FakeProvider) now() time.Time {
+	if p.Now != nil {
+		return p.Now()
+	}
+	return time.Now()
+}
+
+// fakeStatus returns the status of a call.
+func fakeStatus(call *vapiApi.Call) vapiApi.CallStatus {
+	if call.Status == nil {
+		return ""
+	}
+	return *call.Status
+}
+
+// copyCall returns a copy of a call, so callers can't change the provider's state.
+// Fields are only ever replaced, never modified in place, so a shallow copy is enough.
+func copyCall(call *vapiApi.Call) *vapiApi.Call {
+	copied := *call
+	return &copied
+}
+
+// fakeNotFound returns the error VapiAI returns for a missing resource.
+func fakeNotFound(resource string, id string) error {
+	return core.NewAPIError(http.StatusNotFound, nil, fmt.Errorf("%s %q not found", resource, id))
+}
+
+// inTimeRange reports whether a time is within the bounds that are set.
+func inTimeRange(value time.Time, gt *time.Time, ge *time.Time, lt *time.Time, le *time.Time) bool {
+	return (gt == nil || value.After(*gt)) &&
+		(ge == nil || !value.Before(*ge)) &&
+		(lt == nil || value.Before(*lt)) &&
+		(le == nil || !value.After(*le))
+}
+
+// mergeJSON overwrites the fields of target with the fields set in source, matching them by JSON name.
+func mergeJSON(target interface{}, source interface{}) error {
+	fields := map[string]json.RawMessage{}
+
+	current, err := json.Marshal(target)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(current, &fields); err != nil {
+		return err
+	}
+
+	update, err := json.Marshal(source)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(update, &fields); err != nil {
+		return err
+	}
+
+	merged, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(merged, target)
+}
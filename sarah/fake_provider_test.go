@@ -0,0 +1,175 @@
+package sarah
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	vapiTypes "sarah/types/vapi"
+
+	vapiApi "github.com/VapiAI/server-sdk-go"
+	"github.com/VapiAI/server-sdk-go/core"
+)
+
+// placeFakeCall places a call to customerNumber with the fake provider.
+func placeFakeCall(t *testing.T, fake *FakeProvider, assistantId string, phoneNumberId string, customerNumber string) *vapiApi.Call {
+	t.Helper()
+
+	resp, err := fake.CreateCall(context.Background(), &vapiApi.CreateCallDto{
+		AssistantId:   vapiApi.String(assistantId),
+		PhoneNumberId: vapiApi.String(phoneNumberId),
+		Customer:      &vapiApi.CreateCustomerDto{Number: vapiApi.String(customerNumber)},
+	})
+	if err != nil {
+		t.Fatalf("creating call: %v", err)
+	}
+
+	return resp.Call
+}
+
+// fakeCallStatus returns the current status of a call of the fake provider.
+func fakeCallStatus(t *testing.T, fake *FakeProvider, callId string) (*vapiApi.Call, vapiApi.CallStatus) {
+	t.Helper()
+
+	call, err := fake.GetCall(context.Background(), callId)
+	if err != nil {
+		t.Fatalf("getting call %s: %v", callId, err)
+	}
+
+	return call, fakeStatus(call)
+}
+
+func TestFakeProviderCallLifecycle(t *testing.T) {
+	now := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	fake := NewFakeProvider()
+	fake.Now = func() time.Time { return now }
+	transitions := []vapiApi.CallStatus{}
+	fake.OnTransition = func(call vapiApi.Call) {
+		transitions = append(transitions, *call.Status)
+	}
+
+	assistantId, phoneNumberId := addFakeLine(t, fake)
+	fake.SetOutcome("+15557654321", FakeCallOutcome{
+		Duration:          90 * time.Second,
+		Cost:              0.42,
+		Summary:           "Customer renewed the policy",
+		SuccessEvaluation: "true",
+	})
+	call := placeFakeCall(t, fake, assistantId, phoneNumberId, "+15557654321")
+
+	for _, want := range []vapiApi.CallStatus{vapiApi.CallStatusRinging, vapiApi.CallStatusInProgress, vapiApi.CallStatusEnded} {
+		if changed := fake.Advance(); changed != 1 {
+			t.Fatalf("Advance changed %d calls, want 1", changed)
+		}
+		if _, status := fakeCallStatus(t, fake, call.Id); status != want {
+			t.Fatalf("call is %s, want %s", status, want)
+		}
+	}
+	if changed := fake.Advance(); changed != 0 {
+		t.Errorf("Advance changed %d ended calls, want 0", changed)
+	}
+
+	ended, _ := fakeCallStatus(t, fake, call.Id)
+	if ended.StartedAt == nil || ended.EndedAt == nil || ended.EndedAt.Sub(*ended.StartedAt) != 90*time.Second {
+		t.Errorf("call ran from %v to %v, want 90s", ended.StartedAt, ended.EndedAt)
+	}
+	if ended.EndedReason == nil || *ended.EndedReason != vapiApi.CallEndedReasonCustomerEndedCall {
+		t.Errorf("call ended with %v, want %s", ended.EndedReason, vapiApi.CallEndedReasonCustomerEndedCall)
+	}
+	if ended.Analysis == nil || derefString(ended.Analysis.Summary) != "Customer renewed the policy" {
+		t.Errorf("answered call has analysis %+v, want the outcome's summary", ended.Analysis)
+	}
+	if want := []vapiApi.CallStatus{vapiApi.CallStatusQueued, vapiApi.CallStatusRinging, vapiApi.CallStatusInProgress, vapiApi.CallStatusEnded}; !slices.Equal(transitions, want) {
+		t.Errorf("OnTransition saw %v, want %v", transitions, want)
+	}
+
+	message, err := fake.ServerMessage(call.Id)
+	if err != nil {
+		t.Fatalf("building server message: %v", err)
+	}
+	if message.Type != vapiTypes.MESSAGE_END_OF_CALL_REPORT || message.Analysis == nil || message.Analysis.Summary != "Customer renewed the policy" {
+		t.Errorf("ended call has a %s message with analysis %+v, want an end-of-call-report with the summary", message.Type, message.Analysis)
+	}
+}
+
+func TestFakeProviderUnansweredCall(t *testing.T) {
+	fake := NewFakeProvider()
+	assistantId, phoneNumberId := addFakeLine(t, fake)
+	fake.SetOutcome("+15557654321", FakeCallOutcome{EndedReason: vapiApi.CallEndedReasonCustomerBusy})
+	call := placeFakeCall(t, fake, assistantId, phoneNumberId, "+15557654321")
+
+	fake.CompleteCalls()
+
+	ended, status := fakeCallStatus(t, fake, call.Id)
+	if status != vapiApi.CallStatusEnded || ended.EndedReason == nil || *ended.EndedReason != vapiApi.CallEndedReasonCustomerBusy {
+		t.Fatalf("call is %s with ended reason %v, want ended with %s", status, ended.EndedReason, vapiApi.CallEndedReasonCustomerBusy)
+	}
+	if ended.StartedAt != nil || ended.Analysis != nil {
+		t.Errorf("unanswered call was started at %v with analysis %+v, want neither", ended.StartedAt, ended.Analysis)
+	}
+}
+
+func TestFakeProviderCancelAndEndCalls(t *testing.T) {
+	fake := NewFakeProvider()
+	assistantId, phoneNumberId := addFakeLine(t, fake)
+
+	// A call that wasn't dialed is cancelled
+	undialed := placeFakeCall(t, fake, assistantId, phoneNumberId, "+15557654321")
+	if err := fake.DeleteCall(context.Background(), undialed.Id); err != nil {
+		t.Fatalf("deleting undialed call: %v", err)
+	}
+	if cancelled, status := fakeCallStatus(t, fake, undialed.Id); status != vapiApi.CallStatusEnded ||
+		*cancelled.EndedReason != vapiApi.CallEndedReasonManuallyCanceled {
+		t.Errorf("deleted undialed call is %s, want ended as %s", status, vapiApi.CallEndedReasonManuallyCanceled)
+	}
+
+	// An active call is hung up
+	active := placeFakeCall(t, fake, assistantId, phoneNumberId, "+15557654322")
+	fake.Advance()
+	if err := fake.EndCall(context.Background(), active); err != nil {
+		t.Fatalf("ending active call: %v", err)
+	}
+	if hungUp, status := fakeCallStatus(t, fake, active.Id); status != vapiApi.CallStatusEnded ||
+		*hungUp.EndedReason != vapiApi.CallEndedReasonAssistantEndedCall {
+		t.Errorf("ended active call is %s, want ended as %s", status, vapiApi.CallEndedReasonAssistantEndedCall)
+	}
+
+	if err := fake.EndCall(context.Background(), &vapiApi.Call{Id: active.Id}); err != ErrCallNotControllable {
+		t.Errorf("ending a call without a control URL returned %v, want ErrCallNotControllable", err)
+	}
+	if _, err := fake.GetCall(context.Background(), "call-unknown"); !isVapiNotFound(err) {
+		t.Errorf("getting an unknown call returned %v, want a 404", err)
+	}
+}
+
+func TestFakeProviderRejectsUnknownResources(t *testing.T) {
+	fake := NewFakeProvider()
+	assistantId, phoneNumberId := addFakeLine(t, fake)
+
+	tests := []struct {
+		name          string
+		assistantId   string
+		phoneNumberId string
+	}{
+		{"unknown assistant", "assistant-unknown", phoneNumberId},
+		{"unknown phone number", assistantId, "phone-unknown"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := fake.CreateCall(context.Background(), &vapiApi.CreateCallDto{
+				AssistantId:   vapiApi.String(test.assistantId),
+				PhoneNumberId: vapiApi.String(test.phoneNumberId),
+				Customer:      &vapiApi.CreateCustomerDto{Number: vapiApi.String("+15557654321")},
+			})
+			apiErr, ok := err.(*core.APIError)
+			if !ok || apiErr.StatusCode != http.StatusBadRequest {
+				t.Errorf("CreateCall returned %v, want a 400", err)
+			}
+		})
+	}
+	if calls, _ := fake.ListCalls(context.Background(), nil); len(calls) != 0 {
+		t.Errorf("rejected requests created %d calls", len(calls))
+	}
+}
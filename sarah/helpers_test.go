@@ -0,0 +1,118 @@
+package sarah
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"sync"
+	"testing"
+
+	"sarah/mongodb"
+
+	vapiApi "github.com/VapiAI/server-sdk-go"
+	"github.com/VapiAI/server-sdk-go/core"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// testCollections are the collection names used when the environment doesn't set them, as in the README's .env
+var testCollections = map[string]string{
+	"MONGO_COLLECTION_CAMPAIGNS":          "campaigns",
+	"MONGO_COLLECTION_ASSISTANTS":         "assistants",
+	"MONGO_COLLECTION_CONTACTS":           "contacts",
+	"MONGO_COLLECTION_PHONE_NUMBERS":      "phone_numbers",
+	"MONGO_COLLECTION_CALLS":              "calls",
+	"MONGO_COLLECTION_CALL_EVENTS":        "call_events",
+	"MONGO_COLLECTION_AUDIT_LOGS":         "audit_logs",
+	"MONGO_COLLECTION_TRANSCRIPTS":        "transcripts",
+	"MONGO_COLLECTION_RECORDINGS":         "recordings",
+	"MONGO_COLLECTION_SETTINGS":           "settings",
+	"MONGO_COLLECTION_CAMPAIGN_RUNS":      "campaign_runs",
+	"MONGO_COLLECTION_DNC":                "dnc",
+	"MONGO_COLLECTION_PHONE_NUMBER_USAGE": "phone_number_usage",
+	"MONGO_COLLECTION_CALL_QUEUE":         "call_queue",
+	"MONGO_COLLECTION_CALLBACKS":          "callbacks",
+	"MONGO_COLLECTION_CONTACT_UPDATES":    "contact_updates",
+	"MONGO_COLLECTION_ALERT_RULES":        "alert_rules",
+	"MONGO_COLLECTION_ALERT_TRIGGERS":     "alert_triggers",
+	"MONGO_COLLECTION_CALL_NOTES":         "call_notes",
+	"MONGO_COLLECTION_RECONCILIATIONS":    "reconciliations",
+}
+
+var (
+	connectOnce sync.Once
+	connectErr  error
+)
+
+// newTestOrganization returns the ID of an organization with an empty database, which is dropped when the
+// test ends. Tests needing a database are skipped unless MONGO_TEST_URI points to a MongoDB deployment.
+func newTestOrganization(t *testing.T) string {
+	t.Helper()
+
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI is not set")
+	}
+	connectOnce.Do(func() {
+		connectErr = mongodb.Connect(uri)
+	})
+	if connectErr != nil {
+		t.Fatalf("connecting to MongoDB: %v", connectErr)
+	}
+
+	for name, collection := range testCollections {
+		if os.Getenv(name) == "" {
+			t.Setenv(name, collection)
+		}
+	}
+
+	orgId := "sarah_test_" + bson.NewObjectID().Hex()
+	t.Cleanup(func() {
+		if err := mongodb.Client.Database(orgId).Drop(context.Background()); err != nil {
+			t.Logf("dropping database %s: %v", orgId, err)
+		}
+	})
+
+	return orgId
+}
+
+// useTelephony replaces Telephony with provider until the test ends.
+func useTelephony(t *testing.T, provider Provider) {
+	t.Helper()
+
+	previous := Telephony
+	Telephony = provider
+	t.Cleanup(func() {
+		Telephony = previous
+	})
+}
+
+// newFakeTelephony replaces Telephony with a new FakeProvider until the test ends, and returns it
+// with the IDs of an assistant and a phone number calls can be placed with.
+func newFakeTelephony(t *testing.T) (*FakeProvider, string, string) {
+	t.Helper()
+
+	fake := NewFakeProvider()
+	useTelephony(t, fake)
+	assistantId, phoneNumberId := addFakeLine(t, fake)
+
+	return fake, assistantId, phoneNumberId
+}
+
+// addFakeLine adds an assistant and a phone number to a FakeProvider and returns their IDs.
+func addFakeLine(t *testing.T, fake *FakeProvider) (string, string) {
+	t.Helper()
+
+	assistant, err := fake.CreateAssistant(context.Background(), &vapiApi.CreateAssistantDto{Name: vapiApi.String("Test assistant")})
+	if err != nil {
+		t.Fatalf("creating assistant: %v", err)
+	}
+	phoneNumberId := fake.AddPhoneNumber("+15551230000", "Test line")
+
+	return assistant.Id, phoneNumberId
+}
+
+// apiError returns the error VapiAI returns with the given status code.
+func apiError(statusCode int) error {
+	return core.NewAPIError(statusCode, nil, errors.New(http.StatusText(statusCode)))
+}
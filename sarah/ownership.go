@@ -0,0 +1,225 @@
+package sarah
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"sarah/mongodb"
+	mongodbTypes "sarah/types/mongodb"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// ErrNotFound is returned when a resource doesn't exist or doesn't belong to the caller's organization.
+// Both cases are reported the same way so other tenants' resource IDs can't be probed.
+var ErrNotFound = errors.New("resource not found")
+
+// SYSTEM_CAMPAIGN_SCHEDULER identifies the campaign scheduler as the caller in the audit log.
+const SYSTEM_CAMPAIGN_SCHEDULER = "system:campaign-scheduler"
+
+// SYSTEM_CALLBACK_SCHEDULER identifies the callback scheduler as the caller in the audit log.
+const SYSTEM_CALLBACK_SCHEDULER = "system:callback-scheduler"
+
+// SYSTEM_CALL_RECONCILER identifies the call reconciler as the caller in the audit log and campaign runs.
+const SYSTEM_CALL_RECONCILER = "system:call-reconciler"
+
+// Caller identifies who is acting on an organization's resources.
+type Caller struct {
+	// OrgId is the organization the caller acts for
+	OrgId string
+
+	// UserId is the Clerk user ID of the caller, or the name of the background job
+	UserId string
+}
+
+// ResolveAssistant returns the organization's record of a VapiAI assistant.
+// Returns ErrNotFound if the organization hasn't registered the assistant.
+func ResolveAssistant(orgId string, vapiAssistantId string) (*mongodbTypes.Assistant, error) {
+	if vapiAssistantId == "" {
+		return nil, ErrNotFound
+	}
+
+	assistant, err := mongodb.GetAssistantByVapiId(orgId, vapiAssistantId)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return assistant, nil
+}
+
+// ResolvePhoneNumber returns the organization's record of a VapiAI phone number.
+// Returns ErrNotFound if the organization hasn't registered the phone number.
+func ResolvePhoneNumber(orgId string, phoneNumberId string) (*mongodbTypes.PhoneNumber, error) {
+	if phoneNumberId == "" {
+		return nil, ErrNotFound
+	}
+
+	phoneNumber, err := mongodb.GetPhoneNumberByVapiId(orgId, phoneNumberId)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return phoneNumber, nil
+}
+
+// ResolveCampaign returns one of the organization's campaigns.
+// Returns ErrNotFound if the campaign ID is invalid or the organization has no such campaign.
+func ResolveCampaign(orgId string, campaignId string) (*mongodbTypes.Campaign, error) {
+	if _, err := bson.ObjectIDFromHex(campaignId); err != nil {
+		return nil, ErrNotFound
+	}
+
+	campaign, err := mongodb.GetCampaignById(orgId, campaignId)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return campaign, nil
+}
+
+// AuthorizeAssistant resolves a VapiAI assistant for an action of the caller.
+// Returns ErrNotFound, and records the denied attempt in the audit log, if the
+// caller's organization hasn't registered the assistant.
+func AuthorizeAssistant(caller Caller, action string, vapiAssistantId string) (*mongodbTypes.Assistant, error) {
+	assistant, err := ResolveAssistant(caller.OrgId, vapiAssistantId)
+	if errors.Is(err, ErrNotFound) {
+		recordDenied(caller, action, "assistant", vapiAssistantId)
+	}
+	return assistant, err
+}
+
+// AuthorizePhoneNumber resolves a VapiAI phone number for an action of the caller.
+// Returns ErrNotFound, and records the denied attempt in the audit log, if the
+// caller's organization hasn't registered the phone number.
+func AuthorizePhoneNumber(caller Caller, action string, phoneNumberId string) (*mongodbTypes.PhoneNumber, error) {
+	phoneNumber, err := ResolvePhoneNumber(caller.OrgId, phoneNumberId)
+	if errors.Is(err, ErrNotFound) {
+		recordDenied(caller, action, "phone_number", phoneNumberId)
+	}
+	return phoneNumber, err
+}
+
+// AuthorizeCampaign resolves a campaign for an action of the caller.
+// Returns ErrNotFound, and records the denied attempt in the audit log, if the
+// caller's organization has no such campaign.
+func AuthorizeCampaign(caller Caller, action string, campaignId string) (*mongodbTypes.Campaign, error) {
+	campaign, err := ResolveCampaign(caller.OrgId, campaignId)
+	if errors.Is(err, ErrNotFound) {
+		recordDenied(caller, action, "campaign", campaignId)
+	}
+	return campaign, err
+}
+
+// AuthorizeAlertRule resolves an alert rule for an action of the caller.
+// Returns ErrNotFound, and records the denied attempt in the audit log, if the
+// caller's organization has no such rule.
+func AuthorizeAlertRule(caller Caller, action string, ruleId string) (*mongodbTypes.AlertRule, error) {
+	id, err := bson.ObjectIDFromHex(ruleId)
+	if err != nil {
+		recordDenied(caller, action, "alert_rule", ruleId)
+		return nil, ErrNotFound
+	}
+
+	rule, err := mongodb.GetAlertRuleById(caller.OrgId, id)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		recordDenied(caller, action, "alert_rule", ruleId)
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return rule, nil
+}
+
+// AuthorizeAssistantTemplate resolves an assistant template of the caller's organization for an action of the caller.
+// Returns ErrNotFound, and records the denied attempt in the audit log, if the caller's organization has no such template.
+func AuthorizeAssistantTemplate(caller Caller, action string, templateId string) (*mongodbTypes.AssistantTemplate, error) {
+	template, err := mongodb.GetAssistantTemplateById(caller.OrgId, templateId)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		recordDenied(caller, action, "assistant_template", templateId)
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return template, nil
+}
+
+// authorizeClaim checks that a VapiAI assistant or phone number about to be registered by the
+// caller isn't already registered by another organization. Returns ErrNotFound, and records the
+// denied attempt, if it is.
+func authorizeClaim(caller Caller, action string, assistantId string, phoneNumberId string) error {
+	orgId, err := ResolveCallOrganization(assistantId, phoneNumberId)
+	if errors.Is(err, ErrOrganizationNotFound) || orgId == caller.OrgId {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if assistantId != "" {
+		recordDenied(caller, action, "assistant", assistantId)
+	} else {
+		recordDenied(caller, action, "phone_number", phoneNumberId)
+	}
+	return ErrNotFound
+}
+
+// recordDenied stores a denied attempt in the caller's audit log.
+func recordDenied(caller Caller, action string, resourceType string, resourceId string) {
+	recordAudit(caller, action, resourceType, resourceId, mongodbTypes.AUDIT_DENIED, ErrNotFound.Error())
+}
+
+// recordAudit stores an action of the caller in its organization's audit log.
+// Failures are logged and don't change the outcome of the request.
+func recordAudit(caller Caller, action string, resourceType string, resourceId string, outcome mongodbTypes.AuditOutcome, reason string) {
+	log.Printf("[Audit] %s %s on %s %s for user %s of organization %s", outcome, action, resourceType, resourceId, caller.UserId, caller.OrgId)
+
+	_, err := mongodb.CreateAuditEntry(caller.OrgId, mongodbTypes.AuditEntry{
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceId:   resourceId,
+		UserId:       caller.UserId,
+		Outcome:      outcome,
+		Reason:       reason,
+		CreatedAt:    time.Now(),
+	})
+	if err != nil {
+		log.Printf("[Audit] Error recording %s: %v", action, err)
+	}
+}
+
+// GetAuditLog returns the most recent entries of the organization's audit log.
+func GetAuditLog(orgId string, outcome mongodbTypes.AuditOutcome, limit int) ([]mongodbTypes.AuditEntry, error) {
+	entries, err := mongodb.GetAuditEntries(orgId, outcome, limit)
+	if err != nil {
+		log.Printf("Error getting audit log: %v", err)
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// ownsCall reports whether a call was placed by one of the organization's assistants or phone numbers.
+func ownsCall(orgId string, assistantId string, phoneNumberId string) (bool, error) {
+	if _, err := ResolveAssistant(orgId, assistantId); err == nil {
+		return true, nil
+	} else if !errors.Is(err, ErrNotFound) {
+		return false, err
+	}
+
+	if _, err := ResolvePhoneNumber(orgId, phoneNumberId); err == nil {
+		return true, nil
+	} else if !errors.Is(err, ErrNotFound) {
+		return false, err
+	}
+
+	return false, nil
+}
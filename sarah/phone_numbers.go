@@ -0,0 +1,108 @@
+package sarah
+
+import (
+	"context"
+	"log"
+	"strconv"
+
+	"sarah/mongodb"
+	"sarah/phone"
+	mongodbTypes "sarah/types/mongodb"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// CreatePhoneNumber registers a VapiAI phone number for the caller's organization.
+// Returns ErrNotFound if the phone number doesn't exist in VapiAI or is registered by another organization,
+// and a *ValidationError if the number or one of its limits is invalid.
+func CreatePhoneNumber(caller Caller, phoneNumber mongodbTypes.PhoneNumber) (*mongo.InsertOneResult, error) {
+	if !ExistsPhoneNumber(phoneNumber.PhoneNumberId) {
+		return nil, ErrNotFound
+	}
+
+	if err := authorizeClaim(caller, "phone_numbers.create", "", phoneNumber.PhoneNumberId); err != nil {
+		return nil, err
+	}
+
+	// Local presence matches customers on the area code of the number, which needs it in E.164
+	invalid := &ValidationError{}
+	number, err := phone.Normalize(phoneNumber.PhoneNumber, organizationCountry(caller.OrgId))
+	if err != nil {
+		invalid.add("phone_number", phoneNumber.PhoneNumber, err)
+	} else {
+		phoneNumber.PhoneNumber = number.E164
+	}
+	validateLimits(invalid, phoneNumber)
+	if err := invalid.orNil(); err != nil {
+		return nil, err
+	}
+
+	result, err := mongodb.CreatePhoneNumber(caller.OrgId, phoneNumber)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// UpdatePhoneNumber updates the name, daily cap and concurrency limit of one of the caller's phone numbers.
+// Returns ErrNotFound if the organization hasn't registered the phone number,
+// and a *ValidationError if a limit is negative.
+func UpdatePhoneNumber(caller Caller, phoneNumber mongodbTypes.PhoneNumber) (*mongo.UpdateResult, error) {
+	if _, err := AuthorizePhoneNumber(caller, "phone_numbers.update", phoneNumber.PhoneNumberId); err != nil {
+		return nil, err
+	}
+
+	invalid := &ValidationError{}
+	validateLimits(invalid, phoneNumber)
+	if err := invalid.orNil(); err != nil {
+		return nil, err
+	}
+
+	result, err := mongodb.UpdatePhoneNumber(caller.OrgId, phoneNumber)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// ExistsPhoneNumber reports whether a phone number exists in the telephony provider.
+func ExistsPhoneNumber(phoneNumberId string) bool {
+	phoneNumber, err := Telephony.GetPhoneNumber(context.Background(), phoneNumberId)
+	if err != nil {
+		return false
+	}
+
+	return phoneNumber != nil
+}
+
+// validateLimits adds an error to invalid for each negative limit of a phone number.
+func validateLimits(invalid *ValidationError, phoneNumber mongodbTypes.PhoneNumber) {
+	if phoneNumber.DailyCap < 0 {
+		invalid.Errors = append(invalid.Errors, FieldError{Field: "daily_cap", Value: strconv.Itoa(phoneNumber.DailyCap), Message: "must be 0 (no limit) or more"})
+	}
+	if phoneNumber.MaxConcurrentCalls < 0 {
+		invalid.Errors = append(invalid.Errors, FieldError{Field: "max_concurrent_calls", Value: strconv.Itoa(phoneNumber.MaxConcurrentCalls), Message: "must be 0 (no limit) or more"})
+	}
+}
+
+// DeletePhoneNumber removes a VapiAI phone number from the caller's organization.
+// Returns ErrNotFound if the organization hasn't registered the phone number.
+func DeletePhoneNumber(caller Caller, phoneNumberId string) (*mongo.DeleteResult, error) {
+	if _, err := AuthorizePhoneNumber(caller, "phone_numbers.delete", phoneNumberId); err != nil {
+		return nil, err
+	}
+
+	result, err := mongodb.DeletePhoneNumber(caller.OrgId, phoneNumberId)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	callOrganizations.Delete("phone_number:" + phoneNumberId)
+
+	return result, nil
+}
@@ -0,0 +1,140 @@
+package sarah
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	vapiApi "github.com/VapiAI/server-sdk-go"
+	vapiclient "github.com/VapiAI/server-sdk-go/client"
+	"github.com/VapiAI/server-sdk-go/core"
+)
+
+// Provider is the telephony provider calls are placed with and assistants and phone numbers are managed in.
+// Requests and results use the VapiAI types, which the rest of Sarah is built on; a provider reports a missing
+// call, assistant or phone number with a *core.APIError whose status code is 404, as VapiAI does.
+type Provider interface {
+	// CreateCall places a call, or a batch of calls when the request lists several customers
+	CreateCall(ctx context.Context, request *vapiApi.CreateCallDto) (*vapiApi.CallsCreateResponse, error)
+
+	// GetCall returns a call
+	GetCall(ctx context.Context, callId string) (*vapiApi.Call, error)
+
+	// ListCalls returns the calls matching the request, newest first
+	ListCalls(ctx context.Context, request *vapiApi.CallsListRequest) ([]*vapiApi.Call, error)
+
+	// DeleteCall deletes a call, cancelling it if it was not dialed yet. The provider's record of the call is removed,
+	// so it is only used for calls that weren't dialed; active calls are hung up with EndCall
+	DeleteCall(ctx context.Context, callId string) error
+
+	// EndCall hangs up an active call, returning ErrCallNotControllable if the call can't be controlled
+	EndCall(ctx context.Context, call *vapiApi.Call) error
+
+	// CreateAssistant creates an assistant
+	CreateAssistant(ctx context.Context, request *vapiApi.CreateAssistantDto) (*vapiApi.Assistant, error)
+
+	// GetAssistant returns an assistant
+	GetAssistant(ctx context.Context, assistantId string) (*vapiApi.Assistant, error)
+
+	// UpdateAssistant updates the fields of an assistant set in the request
+	UpdateAssistant(ctx context.Context, assistantId string, request *vapiApi.UpdateAssistantDto) (*vapiApi.Assistant, error)
+
+	// DeleteAssistant deletes an assistant
+	DeleteAssistant(ctx context.Context, assistantId string) error
+
+	// GetPhoneNumber returns a phone number
+	GetPhoneNumber(ctx context.Context, phoneNumberId string) (*vapiApi.PhoneNumbersGetResponse, error)
+}
+
+// Telephony is the provider Sarah places calls with, VapiAI unless replaced, e.g. by a FakeProvider in tests.
+var Telephony Provider
+
+// VapiProvider is the Provider backed by the VapiAI API.
+type VapiProvider struct {
+	client *vapiclient.Client
+
+	// controlClient sends control messages to active calls
+	controlClient *http.Client
+}
+
+// NewVapiProvider returns a VapiProvider authenticated with the given VapiAI API key.
+func NewVapiProvider(apiKey string) *VapiProvider {
+	return &VapiProvider{
+		client:        createClient(apiKey),
+		controlClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *VapiProvider) CreateCall(ctx context.Context, request *vapiApi.CreateCallDto) (*vapiApi.CallsCreateResponse, error) {
+	return p.client.Calls.Create(ctx, request)
+}
+
+func (p *VapiProvider) GetCall(ctx context.Context, callId string) (*vapiApi.Call, error) {
+	return p.client.Calls.Get(ctx, callId)
+}
+
+func (p *VapiProvider) ListCalls(ctx context.Context, request *vapiApi.CallsListRequest) ([]*vapiApi.Call, error) {
+	return p.client.Calls.List(ctx, request)
+}
+
+// DeleteCall deletes a call in VapiAI. A call VapiAI hasn't dialed yet is cancelled; the record of any other call
+// is removed, along with its transcript and recording, so active calls are hung up with EndCall instead.
+func (p *VapiProvider) DeleteCall(ctx context.Context, callId string) error {
+	_, err := p.client.Calls.Delete(ctx, callId)
+	return err
+}
+
+// EndCall posts an end-call message to the control URL of the call, which VapiAI only
+// sets when the assistant's monitorPlan.controlEnabled is set.
+func (p *VapiProvider) EndCall(ctx context.Context, call *vapiApi.Call) error {
+	if call.Monitor == nil || derefString(call.Monitor.ControlUrl) == "" {
+		return ErrCallNotControllable
+	}
+
+	body, err := json.Marshal(map[string]string{"type": "end-call"})
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, *call.Monitor.ControlUrl, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := p.controlClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return core.NewAPIError(response.StatusCode, response.Header, fmt.Errorf("control URL returned %s", response.Status))
+	}
+
+	return nil
+}
+
+func (p *VapiProvider) CreateAssistant(ctx context.Context, request *vapiApi.CreateAssistantDto) (*vapiApi.Assistant, error) {
+	return p.client.Assistants.Create(ctx, request)
+}
+
+func (p *VapiProvider) GetAssistant(ctx context.Context, assistantId string) (*vapiApi.Assistant, error) {
+	return p.client.Assistants.Get(ctx, assistantId)
+}
+
+func (p *VapiProvider) UpdateAssistant(ctx context.Context, assistantId string, request *vapiApi.UpdateAssistantDto) (*vapiApi.Assistant, error) {
+	return p.client.Assistants.Update(ctx, assistantId, request)
+}
+
+func (p *VapiProvider) DeleteAssistant(ctx context.Context, assistantId string) error {
+	_, err := p.client.Assistants.Delete(ctx, assistantId)
+	return err
+}
+
+func (p *VapiProvider) GetPhoneNumber(ctx context.Context, phoneNumberId string) (*vapiApi.PhoneNumbersGetResponse, error) {
+	return p.client.PhoneNumbers.Get(ctx, phoneNumberId)
+}
@@ -0,0 +1,354 @@
+package sarah
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"sarah/clerk"
+	"sarah/mongodb"
+	mongodbTypes "sarah/types/mongodb"
+	vapiTypes "sarah/types/vapi"
+
+	vapiApi "github.com/VapiAI/server-sdk-go"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// ErrReconcileRunning is returned when a reconciliation of the organization's calls is already running.
+var ErrReconcileRunning = errors.New("the organization's calls are already being reconciled")
+
+// RECONCILE_STALE_AFTER is how long a call can go without an update before the reconciler fetches it from VapiAI
+const RECONCILE_STALE_AFTER = 30 * time.Minute
+
+// RECONCILE_BATCH_SIZE is the maximum number of calls of an organization checked in a single pass
+const RECONCILE_BATCH_SIZE = 100
+
+// RECONCILE_MANUAL_BATCH_SIZE is the maximum number of calls checked by a pass requested through the API,
+// which runs while the request waits
+const RECONCILE_MANUAL_BATCH_SIZE = 20
+
+// RECONCILE_MANUAL_TIME_LIMIT is how long a pass requested through the API keeps checking calls, so its
+// report is returned before the server's write timeout
+const RECONCILE_MANUAL_TIME_LIMIT = 5 * time.Second
+
+// RECONCILE_MISSING_REASON is the ended reason of calls VapiAI has no record of
+const RECONCILE_MISSING_REASON = "call-not-found-in-vapi"
+
+const (
+	// RECONCILE_TRIGGER_SCHEDULED identifies a pass of the CallReconciler
+	RECONCILE_TRIGGER_SCHEDULED = "scheduled"
+
+	// RECONCILE_TRIGGER_MANUAL identifies a pass requested through the API
+	RECONCILE_TRIGGER_MANUAL = "manual"
+)
+
+// reconciling records the organizations whose calls are being reconciled, so a manual pass and
+// a scheduled one never check the same calls at once
+var reconciling sync.Map
+
+// CallReconciler periodically fetches from VapiAI the calls that have not ended and were not updated
+// for a while, whose webhooks were most likely lost, and applies what VapiAI reports as if the webhook
+// had arrived: the call record, the call queue, campaign runs, contact updates, transcripts and recordings.
+type CallReconciler struct {
+	// Interval is the time between two passes, defaults to 5 minutes
+	Interval time.Duration
+
+	// StaleAfter is how long a call can go without an update before it is checked, defaults to RECONCILE_STALE_AFTER
+	StaleAfter time.Duration
+}
+
+func (c *CallReconciler) Start() {
+	if c.Interval <= 0 {
+		c.Interval = 5 * time.Minute
+	}
+	if c.StaleAfter <= 0 {
+		c.StaleAfter = RECONCILE_STALE_AFTER
+	}
+
+	go func() {
+		c.run()
+	}()
+}
+
+func (c *CallReconciler) run() {
+	for {
+		allOrgIDs, err := clerk.GetAllOrganizations()
+		if err != nil {
+			log.Printf("[CallReconciler] Error getting organizations: %v", err)
+		}
+
+		for _, id := range allOrgIDs {
+			_, err := reconcileOrganizationCalls(id, RECONCILE_TRIGGER_SCHEDULED, SYSTEM_CALL_RECONCILER, c.StaleAfter, RECONCILE_BATCH_SIZE, 0)
+			if errors.Is(err, ErrCircuitOpen) {
+				// Every organization would fail the same way, the next pass tries again
+				log.Printf("[CallReconciler] VapiAI is unavailable, reconciliation paused until the next pass")
+				break
+			} else if err != nil && !errors.Is(err, ErrReconcileRunning) {
+				log.Printf("[CallReconciler] Error reconciling calls for organization %s: %v", id, err)
+			}
+		}
+
+		time.Sleep(c.Interval)
+	}
+}
+
+// ReconcileCalls reconciles the stale calls of the caller's organization now, rather than waiting for the
+// next pass of the CallReconciler. The pass checks up to RECONCILE_MANUAL_BATCH_SIZE calls for at most
+// RECONCILE_MANUAL_TIME_LIMIT; calls left over are checked by the CallReconciler. Returns the report of
+// the pass, which is also stored, even if the pass stopped early because VapiAI became unavailable.
+// Returns ErrReconcileRunning if a pass is already running.
+func ReconcileCalls(caller Caller) (*mongodbTypes.Reconciliation, error) {
+	report, err := reconcileOrganizationCalls(caller.OrgId, RECONCILE_TRIGGER_MANUAL, caller.UserId, RECONCILE_STALE_AFTER,
+		RECONCILE_MANUAL_BATCH_SIZE, RECONCILE_MANUAL_TIME_LIMIT)
+	if report == nil {
+		return nil, err
+	}
+
+	recordAudit(caller, "calls.reconcile", "organization", caller.OrgId, mongodbTypes.AUDIT_ALLOWED,
+		fmt.Sprintf("%d checked, %d fixed, %d failed", report.Checked, report.Fixed, report.Failed))
+
+	// The circuit opening only stops the pass early, which the report explains
+	return report, nil
+}
+
+// GetReconciliations returns the latest reconciliation reports of the organization, newest first.
+func GetReconciliations(orgId string, limit int) ([]mongodbTypes.Reconciliation, error) {
+	reconciliations, err := mongodb.GetReconciliations(orgId, limit)
+	if err != nil {
+		log.Printf("Error getting reconciliations: %v", err)
+		return nil, err
+	}
+
+	return reconciliations, nil
+}
+
+// reconcileOrganizationCalls checks up to batchSize of the organization's calls that have not ended and were not
+// updated for staleAfter, least recently updated first. The pass stops early if VapiAI becomes unavailable, or once
+// it ran for timeLimit when set; other errors only fail the call they happened on. The report is stored if a call
+// was checked or the pass was requested through the API.
+func reconcileOrganizationCalls(orgId string, trigger string, triggeredBy string, staleAfter time.Duration, batchSize int, timeLimit time.Duration) (*mongodbTypes.Reconciliation, error) {
+	if _, running := reconciling.LoadOrStore(orgId, true); running {
+		return nil, ErrReconcileRunning
+	}
+	defer reconciling.Delete(orgId)
+
+	report := &mongodbTypes.Reconciliation{
+		Trigger:     trigger,
+		TriggeredBy: triggeredBy,
+		StaleBefore: time.Now().Add(-staleAfter),
+		Calls:       []mongodbTypes.ReconciledCall{},
+		StartedAt:   time.Now(),
+	}
+
+	calls, err := mongodb.GetStaleCalls(orgId, report.StaleBefore, batchSize)
+	if err != nil {
+		log.Printf("[CallReconciler] Error getting stale calls of organization %s: %v", orgId, err)
+		return nil, err
+	}
+
+	var stopErr error
+	for i, call := range calls {
+		if timeLimit > 0 && time.Since(report.StartedAt) >= timeLimit {
+			report.Error = fmt.Sprintf("stopped after %s, %d calls are left for the next pass", timeLimit, len(calls)-i)
+			break
+		}
+
+		result, err := reconcileCall(orgId, call)
+		if errors.Is(err, ErrCircuitOpen) {
+			stopErr = err
+			report.Error = err.Error()
+			break
+		}
+
+		report.Calls = append(report.Calls, result)
+		report.Checked++
+		switch result.Outcome {
+		case mongodbTypes.RECONCILE_ENDED, mongodbTypes.RECONCILE_UPDATED, mongodbTypes.RECONCILE_MISSING:
+			report.Fixed++
+		case mongodbTypes.RECONCILE_ERROR:
+			report.Failed++
+		}
+	}
+	report.FinishedAt = time.Now()
+
+	if report.Checked > 0 || trigger == RECONCILE_TRIGGER_MANUAL {
+		if _, err := mongodb.CreateReconciliation(orgId, *report); err != nil {
+			log.Printf("[CallReconciler] Error storing reconciliation of organization %s: %v", orgId, err)
+		}
+	}
+	if report.Checked > 0 {
+		log.Printf("[CallReconciler] Organization %s: %d checked, %d fixed, %d failed", orgId, report.Checked, report.Fixed, report.Failed)
+	}
+
+	return report, stopErr
+}
+
+// reconcileCall fetches a stale call from VapiAI and applies its current state. An ended call gets the
+// end-of-call report its webhook would have carried, a call that moved on gets a status update, and a
+// call VapiAI has no record of is ended locally. Returns ErrCircuitOpen if VapiAI is unavailable; any
+// other error is reported in the outcome of the call.
+func reconcileCall(orgId string, stored mongodbTypes.Call) (mongodbTypes.ReconciledCall, error) {
+	result := mongodbTypes.ReconciledCall{
+		VapiCallId:     stored.VapiCallId,
+		PreviousStatus: stored.Status,
+	}
+
+	vapiCall, err := Telephony.GetCall(context.Background(), stored.VapiCallId)
+	if isVapiNotFound(err) {
+		return reconcileMissingCall(orgId, stored, result), nil
+	} else if errors.Is(err, ErrCircuitOpen) {
+		return result, err
+	} else if err != nil {
+		log.Printf("[CallReconciler] Error getting call %s: %v", stored.VapiCallId, err)
+		return reconcileFailed(result, err), nil
+	}
+
+	result.Status = callStatus(vapiCall)
+	if vapiCall.EndedReason != nil {
+		result.EndedReason = string(*vapiCall.EndedReason)
+	}
+
+	switch {
+	case result.Status == mongodbTypes.CALL_STATUS_ENDED:
+		result.Outcome = mongodbTypes.RECONCILE_ENDED
+		err = applyReconciledMessage(orgId, vapiCall, vapiTypes.MESSAGE_END_OF_CALL_REPORT)
+	case result.Status != stored.Status:
+		result.Outcome = mongodbTypes.RECONCILE_UPDATED
+		err = applyReconciledMessage(orgId, vapiCall, vapiTypes.MESSAGE_STATUS_UPDATE)
+	default:
+		// Still in the same status, only the record is refreshed so the call isn't checked again until it goes stale
+		result.Outcome = mongodbTypes.RECONCILE_UNCHANGED
+		_, err = mongodb.UpdateCallStatus(orgId, callRecordFromVapi(vapiCall))
+	}
+	if err != nil {
+		log.Printf("[CallReconciler] Error reconciling call %s: %v", stored.VapiCallId, err)
+		return reconcileFailed(result, err), nil
+	}
+
+	return result, nil
+}
+
+// reconcileMissingCall ends a call VapiAI has no record of, e.g. one deleted from VapiAI. The end is streamed
+// like any other and the call is recorded as cancelled in its campaign run. The queued call it was placed for
+// is only retried, until it was tried QUEUE_MAX_ATTEMPTS times, if the stored call shows it was never dialed;
+// otherwise the customer may already have been called, so the queued call is failed for a human to review.
+func reconcileMissingCall(orgId string, stored mongodbTypes.Call, result mongodbTypes.ReconciledCall) mongodbTypes.ReconciledCall {
+	result.Outcome = mongodbTypes.RECONCILE_MISSING
+	result.Status = mongodbTypes.CALL_STATUS_ENDED
+	result.EndedReason = RECONCILE_MISSING_REASON
+
+	now := time.Now()
+	message := vapiTypes.ServerMessage{
+		Type:      vapiTypes.MESSAGE_STATUS_UPDATE,
+		Timestamp: float64(now.UnixMilli()),
+		Call: &vapiTypes.Call{
+			Id:            stored.VapiCallId,
+			AssistantId:   stored.AssistantId,
+			PhoneNumberId: stored.PhoneNumberId,
+			Status:        string(mongodbTypes.CALL_STATUS_ENDED),
+			EndedReason:   RECONCILE_MISSING_REASON,
+			EndedAt:       &now,
+		},
+		Status:      string(mongodbTypes.CALL_STATUS_ENDED),
+		EndedReason: RECONCILE_MISSING_REASON,
+	}
+	payload := map[string]interface{}{
+		"type":        message.Type,
+		"timestamp":   message.Timestamp,
+		"status":      message.Status,
+		"endedReason": message.EndedReason,
+		"call":        map[string]interface{}{"id": stored.VapiCallId},
+		"reconciled":  true,
+	}
+	if err := processCallMessage(orgId, message, payload); err != nil {
+		log.Printf("[CallReconciler] Error ending missing call %s: %v", stored.VapiCallId, err)
+		return reconcileFailed(result, err)
+	}
+
+	updated, err := mongodb.MarkCampaignRunCallCancelled(orgId, stored.VapiCallId, SYSTEM_CALL_RECONCILER)
+	if err != nil {
+		log.Printf("[CallReconciler] Error recording missing call %s in its campaign run: %v", stored.VapiCallId, err)
+	} else {
+		result.CampaignRunUpdated = updated.MatchedCount > 0
+	}
+
+	queued, err := mongodb.GetQueuedCallByVapiId(orgId, stored.VapiCallId)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		log.Printf("[CallReconciler] Error getting queued call of missing call %s: %v", stored.VapiCallId, err)
+	} else if err == nil && queued.Status == mongodbTypes.QUEUE_STATUS_DISPATCHED {
+		if neverDialed(stored) {
+			result.QueueStatus = releaseQueuedCall(orgId, *queued, errors.New("VapiAI has no record of call "+stored.VapiCallId))
+			if result.QueueStatus == mongodbTypes.QUEUE_STATUS_QUEUED {
+				wakeCallDispatcher(orgId)
+			}
+		} else {
+			reason := fmt.Sprintf("VapiAI has no record of call %s, which may have been dialed; review it before calling the customer again", stored.VapiCallId)
+			if err := mongodb.ReleaseQueuedCall(orgId, queued.Id, mongodbTypes.QUEUE_STATUS_FAILED, reason); err != nil {
+				log.Printf("[CallReconciler] Error failing queued call of missing call %s: %v", stored.VapiCallId, err)
+			}
+			result.QueueStatus = mongodbTypes.QUEUE_STATUS_FAILED
+		}
+	}
+
+	return result
+}
+
+// neverDialed reports whether a stored call never left the status VapiAI gives calls before dialing them.
+func neverDialed(stored mongodbTypes.Call) bool {
+	if stored.StartedAt != nil {
+		return false
+	}
+	return stored.Status == mongodbTypes.CALL_STATUS_SCHEDULED || stored.Status == mongodbTypes.CALL_STATUS_QUEUED
+}
+
+// applyReconciledMessage rebuilds the server message VapiAI would have sent about the current state of a call
+// and applies it like a webhook. The message is stored with "reconciled" set in its payload.
+func applyReconciledMessage(orgId string, call *vapiApi.Call, messageType vapiTypes.ServerMessageType) error {
+	// The server message and its call are subsets of the VapiAI call with the same JSON names
+	body, err := json.Marshal(call)
+	if err != nil {
+		return err
+	}
+	var message vapiTypes.ServerMessage
+	if err := json.Unmarshal(body, &message); err != nil {
+		return err
+	}
+	var messageCall vapiTypes.Call
+	if err := json.Unmarshal(body, &messageCall); err != nil {
+		return err
+	}
+	var callPayload map[string]interface{}
+	if err := json.Unmarshal(body, &callPayload); err != nil {
+		return err
+	}
+
+	message.Type = messageType
+	message.Timestamp = float64(time.Now().UnixMilli())
+	message.Call = &messageCall
+	message.ToolCallList = nil
+
+	payload := map[string]interface{}{
+		"type":       message.Type,
+		"timestamp":  message.Timestamp,
+		"call":       callPayload,
+		"reconciled": true,
+	}
+	for _, key := range []string{"status", "endedReason", "cost", "startedAt", "endedAt", "artifact", "analysis"} {
+		if value, ok := callPayload[key]; ok {
+			payload[key] = value
+		}
+	}
+
+	return processCallMessage(orgId, message, payload)
+}
+
+// reconcileFailed marks a reconciled call as failed because of err.
+func reconcileFailed(result mongodbTypes.ReconciledCall, err error) mongodbTypes.ReconciledCall {
+	result.Outcome = mongodbTypes.RECONCILE_ERROR
+	result.Error = err.Error()
+	return result
+}
@@ -0,0 +1,248 @@
+package sarah
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"sarah/mongodb"
+	mongodbTypes "sarah/types/mongodb"
+
+	vapiApi "github.com/VapiAI/server-sdk-go"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// placeRecordedCall places a call with the fake provider and records it as the webhooks would have left it.
+func placeRecordedCall(t *testing.T, orgId string, fake *FakeProvider, assistantId string, phoneNumberId string, customerNumber string) string {
+	t.Helper()
+
+	resp, err := fake.CreateCall(context.Background(), &vapiApi.CreateCallDto{
+		AssistantId:   vapiApi.String(assistantId),
+		PhoneNumberId: vapiApi.String(phoneNumberId),
+		Customer:      &vapiApi.CreateCustomerDto{Number: vapiApi.String(customerNumber)},
+	})
+	if err != nil {
+		t.Fatalf("placing call: %v", err)
+	}
+	recordCalls(orgId, callOrigin{}, resp)
+
+	return resp.Call.Id
+}
+
+// dispatchedQueuedCall stores a call VapiAI has no record of, dispatched from the call queue after one attempt.
+func dispatchedQueuedCall(t *testing.T, orgId string, record mongodbTypes.Call) mongodbTypes.QueuedCall {
+	t.Helper()
+
+	queued, err := mongodb.EnqueueCalls(orgId, []mongodbTypes.QueuedCall{{
+		AssistantId:   record.AssistantId,
+		PhoneNumberId: record.PhoneNumberId,
+		Customer:      mongodbTypes.Customer{PhoneNumber: record.CustomerNumber},
+		Status:        mongodbTypes.QUEUE_STATUS_QUEUED,
+		EnqueuedAt:    time.Now(),
+		UpdatedAt:     time.Now(),
+	}})
+	if err != nil {
+		t.Fatalf("queueing call: %v", err)
+	}
+	call := queued[0]
+	if claimed, err := mongodb.ClaimQueuedCall(orgId, call.Id); err != nil || !claimed {
+		t.Fatalf("claiming queued call: %v", err)
+	}
+	if err := mongodb.MarkQueuedCallDispatched(orgId, call.Id, record.VapiCallId); err != nil {
+		t.Fatalf("dispatching queued call: %v", err)
+	}
+	if _, err := mongodb.UpsertCall(orgId, record); err != nil {
+		t.Fatalf("recording call: %v", err)
+	}
+
+	return call
+}
+
+// storedCall reads a call record back from the database.
+func storedCall(t *testing.T, orgId string, vapiCallId string) mongodbTypes.Call {
+	t.Helper()
+
+	call, err := mongodb.GetCallByVapiId(orgId, vapiCallId)
+	if err != nil {
+		t.Fatalf("getting call %s: %v", vapiCallId, err)
+	}
+
+	return *call
+}
+
+// reconcileAll reconciles every call of the organization that hasn't ended, however recently it was updated.
+func reconcileAll(t *testing.T, orgId string) map[string]mongodbTypes.ReconciledCall {
+	t.Helper()
+
+	report, err := reconcileOrganizationCalls(orgId, RECONCILE_TRIGGER_MANUAL, "user_test", -time.Minute, RECONCILE_BATCH_SIZE, 0)
+	if err != nil {
+		t.Fatalf("reconciling calls: %v", err)
+	}
+
+	results := map[string]mongodbTypes.ReconciledCall{}
+	for _, result := range report.Calls {
+		results[result.VapiCallId] = result
+	}
+	return results
+}
+
+func TestReconcileOrganizationCallsOutcomes(t *testing.T) {
+	orgId := newTestOrganization(t)
+	fake, assistantId, phoneNumberId := newFakeTelephony(t)
+
+	// Each call is recorded as queued and then moved on in VapiAI without its webhooks arriving
+	ended := placeRecordedCall(t, orgId, fake, assistantId, phoneNumberId, "+15557654321")
+	fake.CompleteCalls()
+	updated := placeRecordedCall(t, orgId, fake, assistantId, phoneNumberId, "+15557654322")
+	fake.Advance()
+	unchanged := placeRecordedCall(t, orgId, fake, assistantId, phoneNumberId, "+15557654323")
+
+	results := reconcileAll(t, orgId)
+	if len(results) != 3 {
+		t.Fatalf("reconciled %d calls, want 3", len(results))
+	}
+
+	tests := []struct {
+		name       string
+		vapiCallId string
+		outcome    mongodbTypes.ReconcileOutcome
+		status     mongodbTypes.CallStatus
+	}{
+		{"ended", ended, mongodbTypes.RECONCILE_ENDED, mongodbTypes.CALL_STATUS_ENDED},
+		{"updated", updated, mongodbTypes.RECONCILE_UPDATED, mongodbTypes.CALL_STATUS_RINGING},
+		{"unchanged", unchanged, mongodbTypes.RECONCILE_UNCHANGED, mongodbTypes.CALL_STATUS_QUEUED},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := results[test.vapiCallId]
+			if result.Outcome != test.outcome || result.PreviousStatus != mongodbTypes.CALL_STATUS_QUEUED || result.Status != test.status {
+				t.Errorf("call went from %s to %s with outcome %q, want from queued to %s with outcome %q",
+					result.PreviousStatus, result.Status, result.Outcome, test.status, test.outcome)
+			}
+			if stored := storedCall(t, orgId, test.vapiCallId); stored.Status != test.status {
+				t.Errorf("stored call is %s, want %s", stored.Status, test.status)
+			}
+		})
+	}
+
+	// Ended calls aren't checked again
+	if results := reconcileAll(t, orgId); len(results) != 2 {
+		t.Errorf("second pass reconciled %d calls, want 2", len(results))
+	}
+}
+
+func TestReconcileMissingCalls(t *testing.T) {
+	orgId := newTestOrganization(t)
+	_, assistantId, phoneNumberId := newFakeTelephony(t)
+
+	startedAt := time.Now().Add(-time.Minute)
+	neverDialed := dispatchedQueuedCall(t, orgId, mongodbTypes.Call{
+		VapiCallId:     "call-never-dialed",
+		AssistantId:    assistantId,
+		PhoneNumberId:  phoneNumberId,
+		CustomerNumber: "+15557654321",
+		Status:         mongodbTypes.CALL_STATUS_QUEUED,
+	})
+	dialed := dispatchedQueuedCall(t, orgId, mongodbTypes.Call{
+		VapiCallId:     "call-dialed",
+		AssistantId:    assistantId,
+		PhoneNumberId:  phoneNumberId,
+		CustomerNumber: "+15557654322",
+		Status:         mongodbTypes.CALL_STATUS_IN_PROGRESS,
+		StartedAt:      &startedAt,
+	})
+
+	results := reconcileAll(t, orgId)
+
+	tests := []struct {
+		name        string
+		vapiCallId  string
+		queuedCall  mongodbTypes.QueuedCall
+		queueStatus mongodbTypes.QueueStatus
+	}{
+		// A call that never left the queue in VapiAI can be placed again
+		{"never dialed", "call-never-dialed", neverDialed, mongodbTypes.QUEUE_STATUS_QUEUED},
+		// The customer may have been called, so a human reviews the call before it is placed again
+		{"dialed", "call-dialed", dialed, mongodbTypes.QUEUE_STATUS_FAILED},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := results[test.vapiCallId]
+			if result.Outcome != mongodbTypes.RECONCILE_MISSING || result.EndedReason != RECONCILE_MISSING_REASON {
+				t.Errorf("call has outcome %q and ended reason %q, want %q and %q",
+					result.Outcome, result.EndedReason, mongodbTypes.RECONCILE_MISSING, RECONCILE_MISSING_REASON)
+			}
+			if result.QueueStatus != test.queueStatus {
+				t.Errorf("queued call was moved to %q, want %q", result.QueueStatus, test.queueStatus)
+			}
+
+			stored := storedCall(t, orgId, test.vapiCallId)
+			if stored.Status != mongodbTypes.CALL_STATUS_ENDED || stored.EndedReason != RECONCILE_MISSING_REASON {
+				t.Errorf("stored call is %s with ended reason %q, want ended with %q", stored.Status, stored.EndedReason, RECONCILE_MISSING_REASON)
+			}
+			if queued := storedQueuedCall(t, orgId, test.queuedCall); queued.Status != test.queueStatus {
+				t.Errorf("stored queued call is %s, want %s", queued.Status, test.queueStatus)
+			}
+		})
+	}
+}
+
+func TestReconcileCallErrors(t *testing.T) {
+	orgId := newTestOrganization(t)
+	fake := &flakyProvider{FakeProvider: NewFakeProvider()}
+	useTelephony(t, fake)
+	assistantId, phoneNumberId := addFakeLine(t, fake.FakeProvider)
+
+	vapiCallId := placeRecordedCall(t, orgId, fake.FakeProvider, assistantId, phoneNumberId, "+15557654321")
+	fake.getCallErrors = []error{apiError(http.StatusInternalServerError)}
+
+	report, err := reconcileOrganizationCalls(orgId, RECONCILE_TRIGGER_MANUAL, "user_test", -time.Minute, RECONCILE_BATCH_SIZE, 0)
+	if err != nil {
+		t.Fatalf("an error fetching one call stopped the pass: %v", err)
+	}
+	if report.Checked != 1 || report.Failed != 1 || report.Fixed != 0 {
+		t.Errorf("pass checked %d calls, failed %d and fixed %d, want 1, 1 and 0", report.Checked, report.Failed, report.Fixed)
+	}
+	if result := report.Calls[0]; result.Outcome != mongodbTypes.RECONCILE_ERROR || result.Error == "" {
+		t.Errorf("call has outcome %q and error %q, want %q with the error", result.Outcome, result.Error, mongodbTypes.RECONCILE_ERROR)
+	}
+	if stored := storedCall(t, orgId, vapiCallId); stored.Status != mongodbTypes.CALL_STATUS_QUEUED {
+		t.Errorf("stored call is %s, want queued", stored.Status)
+	}
+}
+
+func TestReconcileCallsReportsOpenCircuit(t *testing.T) {
+	orgId := newTestOrganization(t)
+	fake := NewFakeProvider()
+	assistantId, phoneNumberId := addFakeLine(t, fake)
+
+	breaker := &CircuitBreaker{Name: "test", FailureThreshold: 1, OpenDuration: time.Minute}
+	useTelephony(t, NewResilientProvider(fake, breaker))
+
+	placeRecordedCall(t, orgId, fake, assistantId, phoneNumberId, "+15557654321")
+	staleSince := time.Now().Add(-2 * RECONCILE_STALE_AFTER)
+	_, err := mongodb.Client.Database(orgId).Collection(os.Getenv("MONGO_COLLECTION_CALLS")).
+		UpdateMany(context.Background(), bson.M{}, bson.M{"$set": bson.M{"updated_at": staleSince}})
+	if err != nil {
+		t.Fatalf("making calls stale: %v", err)
+	}
+	breaker.Record(apiError(http.StatusServiceUnavailable))
+
+	report, err := ReconcileCalls(Caller{OrgId: orgId, UserId: "user_test"})
+	if err != nil {
+		t.Fatalf("ReconcileCalls returned %v, want the report", err)
+	}
+	if report.Checked != 0 || report.Error == "" {
+		t.Errorf("pass checked %d calls with error %q, want 0 with the circuit error", report.Checked, report.Error)
+	}
+
+	stored, err := mongodb.GetReconciliations(orgId, 1)
+	if err != nil {
+		t.Fatalf("getting reconciliations: %v", err)
+	}
+	if len(stored) != 1 || stored[0].Error != report.Error {
+		t.Errorf("stored %d reports, want the report of the stopped pass", len(stored))
+	}
+}
@@ -0,0 +1,263 @@
+package sarah
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	"sarah/clerk"
+	"sarah/mongodb"
+	"sarah/storage"
+	mongodbTypes "sarah/types/mongodb"
+
+	"github.com/joho/godotenv"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// RecordingStore is the blob store call recordings are archived to.
+// It is nil when the storage backend is misconfigured, in which case recordings are not archived.
+var RecordingStore storage.BlobStore
+
+const (
+	// maxRecordingAttempts is the number of times a recording download is attempted before giving up
+	maxRecordingAttempts = 5
+
+	// recordingDownloadTimeout bounds the download of a single recording
+	recordingDownloadTimeout = 10 * time.Minute
+)
+
+// recordingClient downloads recordings from VapiAI
+var recordingClient = &http.Client{Timeout: recordingDownloadTimeout}
+
+func init() {
+	if err := godotenv.Load(); err != nil {
+		log.Printf("Warning: .env file not found, using system environment variables")
+	}
+
+	store, err := storage.NewFromEnv()
+	if err != nil {
+		log.Printf("Warning: recording storage is not available, recordings will not be archived: %v", err)
+		return
+	}
+	RecordingStore = store
+}
+
+// ScheduleRecordingArchive schedules the archival of an ended call's recording and starts
+// downloading it in the background. Recordings that were already scheduled are left alone,
+// so the webhook and the call sync can both report the same recording.
+func ScheduleRecordingArchive(orgId string, vapiCallId string, sourceUrl string) error {
+	if sourceUrl == "" || RecordingStore == nil {
+		return nil
+	}
+
+	created, err := mongodb.CreatePendingRecording(orgId, vapiCallId, sourceUrl)
+	if err != nil {
+		log.Printf("Error scheduling recording of call %s: %v", vapiCallId, err)
+		return err
+	}
+
+	if created {
+		go archiveRecording(orgId, mongodbTypes.Recording{
+			VapiCallId: vapiCallId,
+			SourceUrl:  sourceUrl,
+		})
+	}
+
+	return nil
+}
+
+// OpenRecording opens the archived recording of one of the organization's calls.
+// Returns ErrNotFound if the call has no archived recording.
+// The caller must close the returned blob.
+func OpenRecording(orgId string, vapiCallId string) (*mongodbTypes.Recording, storage.Blob, error) {
+	if RecordingStore == nil {
+		return nil, nil, ErrNotFound
+	}
+
+	recording, err := mongodb.GetRecordingByVapiId(orgId, vapiCallId)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil, ErrNotFound
+	} else if err != nil {
+		log.Printf("Error getting recording of call %s: %v", vapiCallId, err)
+		return nil, nil, err
+	}
+
+	if recording.Status != mongodbTypes.RECORDING_STATUS_ARCHIVED {
+		return nil, nil, ErrNotFound
+	}
+
+	blob, err := RecordingStore.Open(context.Background(), recording.StorageKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil, ErrNotFound
+	} else if err != nil {
+		log.Printf("Error opening recording of call %s: %v", vapiCallId, err)
+		return nil, nil, err
+	}
+
+	return recording, blob, nil
+}
+
+// archiveRecording downloads a recording from VapiAI into the blob store, under the organization,
+// and saves the outcome. Failed downloads are retried by the RecordingArchiver.
+func archiveRecording(orgId string, recording mongodbTypes.Recording) {
+	recording.Attempts++
+
+	key, contentType, size, err := downloadRecording(orgId, recording)
+	if err != nil {
+		log.Printf("[RecordingArchiver] Error archiving recording of call %s (attempt %d): %v", recording.VapiCallId, recording.Attempts, err)
+		recording.Status = mongodbTypes.RECORDING_STATUS_FAILED
+		recording.Error = err.Error()
+	} else {
+		archivedAt := time.Now()
+		recording.Status = mongodbTypes.RECORDING_STATUS_ARCHIVED
+		recording.StorageKey = key
+		recording.ContentType = contentType
+		recording.SizeBytes = size
+		recording.ArchivedAt = &archivedAt
+		recording.Error = ""
+	}
+
+	if _, err := mongodb.UpdateRecording(orgId, recording); err != nil {
+		log.Printf("[RecordingArchiver] Error saving recording of call %s: %v", recording.VapiCallId, err)
+	}
+}
+
+// downloadRecording copies a recording into the blob store and returns its key, content type and size.
+func downloadRecording(orgId string, recording mongodbTypes.Recording) (string, string, int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), recordingDownloadTimeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, recording.SourceUrl, nil)
+	if err != nil {
+		return "", "", 0, err
+	}
+
+	response, err := recordingClient.Do(request)
+	if err != nil {
+		return "", "", 0, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return "", "", 0, fmt.Errorf("recording download returned %s", response.Status)
+	}
+
+	extension := recordingExtension(recording.SourceUrl)
+	contentType := response.Header.Get("Content-Type")
+	if contentType == "" || contentType == "application/octet-stream" {
+		contentType = mime.TypeByExtension(extension)
+	}
+
+	key := fmt.Sprintf("%s/recordings/%s%s", orgId, recording.VapiCallId, extension)
+	size, err := RecordingStore.Put(ctx, key, response.Body, contentType)
+	if err != nil {
+		return "", "", 0, err
+	}
+
+	return key, contentType, size, nil
+}
+
+// recordingExtension returns the file extension of a recording URL, defaulting to ".wav".
+func recordingExtension(sourceUrl string) string {
+	parsed, err := url.Parse(sourceUrl)
+	if err != nil {
+		return ".wav"
+	}
+
+	extension := path.Ext(parsed.Path)
+	if extension == "" || len(extension) > 5 {
+		return ".wav"
+	}
+	return extension
+}
+
+// RecordingArchiver periodically retries the recordings that failed to archive and deletes
+// the recordings older than each organization's retention period.
+type RecordingArchiver struct {
+	// Interval is the time between two runs, defaults to 1 hour
+	Interval time.Duration
+}
+
+func (a *RecordingArchiver) Start() {
+	if a.Interval <= 0 {
+		a.Interval = time.Hour
+	}
+
+	go func() {
+		a.run()
+	}()
+}
+
+func (a *RecordingArchiver) run() {
+	for {
+		if RecordingStore != nil {
+			allOrgIDs, err := clerk.GetAllOrganizations()
+			if err != nil {
+				log.Printf("[RecordingArchiver] Error getting organizations: %v", err)
+			}
+
+			for _, id := range allOrgIDs {
+				retryRecordings(id)
+				expireRecordings(id)
+			}
+		}
+
+		time.Sleep(a.Interval)
+	}
+}
+
+// retryRecordings archives the organization's pending and failed recordings again.
+// Recordings updated in the last few minutes may still be downloading and are skipped.
+func retryRecordings(orgId string) {
+	recordings, err := mongodb.GetRecordingsToArchive(orgId, maxRecordingAttempts, time.Now().Add(-recordingDownloadTimeout))
+	if err != nil {
+		log.Printf("[RecordingArchiver] Error getting recordings to archive for organization %s: %v", orgId, err)
+		return
+	}
+
+	for _, recording := range recordings {
+		archiveRecording(orgId, recording)
+	}
+}
+
+// expireRecordings deletes the organization's recordings archived before its retention period.
+func expireRecordings(orgId string) {
+	settings, err := mongodb.GetOrganizationSettings(orgId)
+	if err != nil {
+		log.Printf("[RecordingArchiver] Error getting settings for organization %s: %v", orgId, err)
+		return
+	}
+	if settings.RecordingRetentionDays <= 0 {
+		return
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -settings.RecordingRetentionDays)
+	recordings, err := mongodb.GetRecordingsArchivedBefore(orgId, cutoff)
+	if err != nil {
+		log.Printf("[RecordingArchiver] Error getting expired recordings for organization %s: %v", orgId, err)
+		return
+	}
+
+	for _, recording := range recordings {
+		if err := RecordingStore.Delete(context.Background(), recording.StorageKey); err != nil {
+			log.Printf("[RecordingArchiver] Error deleting recording of call %s: %v", recording.VapiCallId, err)
+			continue
+		}
+
+		recording.Status = mongodbTypes.RECORDING_STATUS_DELETED
+		recording.StorageKey = ""
+		if _, err := mongodb.UpdateRecording(orgId, recording); err != nil {
+			log.Printf("[RecordingArchiver] Error saving deleted recording of call %s: %v", recording.VapiCallId, err)
+		}
+	}
+
+	if len(recordings) > 0 {
+		log.Printf("[RecordingArchiver] Deleted %d recordings older than %d days for organization %s", len(recordings), settings.RecordingRetentionDays, orgId)
+	}
+}
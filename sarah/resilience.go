@@ -0,0 +1,421 @@
+package sarah
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	vapiApi "github.com/VapiAI/server-sdk-go"
+	"github.com/VapiAI/server-sdk-go/core"
+)
+
+// VAPI_MAX_RETRIES is the number of times a failed VapiAI request is retried
+const VAPI_MAX_RETRIES = 3
+
+// VAPI_RETRY_BASE_DELAY is the delay before the first retry, doubled for each following one
+const VAPI_RETRY_BASE_DELAY = 500 * time.Millisecond
+
+// VAPI_RETRY_MAX_DELAY is the longest delay between two attempts. A request VapiAI asks to retry
+// later than this through Retry-After fails instead of holding up its caller.
+const VAPI_RETRY_MAX_DELAY = 30 * time.Second
+
+// CIRCUIT_FAILURE_THRESHOLD is the number of consecutive failed VapiAI requests that opens the circuit
+const CIRCUIT_FAILURE_THRESHOLD = 5
+
+// CIRCUIT_OPEN_DURATION is how long the circuit stays open before a trial request is let through
+const CIRCUIT_OPEN_DURATION = 30 * time.Second
+
+// ErrCircuitOpen is returned without reaching VapiAI while the circuit is open,
+// after VapiAI failed CIRCUIT_FAILURE_THRESHOLD requests in a row.
+var ErrCircuitOpen = errors.New("VapiAI is unavailable, circuit is open")
+
+// CircuitState is the state of a circuit breaker.
+type CircuitState string
+
+const (
+	// CIRCUIT_CLOSED lets every request through
+	CIRCUIT_CLOSED CircuitState = "closed"
+
+	// CIRCUIT_OPEN rejects every request with ErrCircuitOpen
+	CIRCUIT_OPEN CircuitState = "open"
+
+	// CIRCUIT_HALF_OPEN lets a single trial request through, which closes the circuit if it succeeds
+	CIRCUIT_HALF_OPEN CircuitState = "half-open"
+)
+
+// CircuitStatus is a snapshot of a circuit breaker, as reported by the health endpoint.
+type CircuitStatus struct {
+	// State is the state of the circuit
+	State CircuitState `json:"state"`
+
+	// ConsecutiveFailures is the number of requests that failed since the last success
+	ConsecutiveFailures int `json:"consecutive_failures"`
+
+	// LastError is the error of the last failed request, empty if the last request succeeded
+	LastError string `json:"last_error,omitempty"`
+
+	// OpenedAt is when the circuit last opened, nil while it is closed
+	OpenedAt *time.Time `json:"opened_at,omitempty"`
+
+	// RetryAt is when the next trial request is let through, nil while the circuit is closed
+	RetryAt *time.Time `json:"retry_at,omitempty"`
+}
+
+// HealthStatus is the overall health reported by the health endpoint.
+type HealthStatus string
+
+const (
+	// HEALTH_OK means every dependency is available
+	HEALTH_OK HealthStatus = "ok"
+
+	// HEALTH_DEGRADED means VapiAI is unavailable: the API serves requests, but calls wait in the queue
+	HEALTH_DEGRADED HealthStatus = "degraded"
+)
+
+// Health is the health of Sarah and the services it depends on.
+type Health struct {
+	// Status is degraded while the VapiAI circuit is open, ok otherwise
+	Status HealthStatus `json:"status"`
+
+	// Vapi is the circuit breaker state of the requests sent to VapiAI
+	Vapi CircuitStatus `json:"vapi"`
+}
+
+// GetHealth returns the health of Sarah and the services it depends on.
+func GetHealth() Health {
+	health := Health{
+		Status: HEALTH_OK,
+		Vapi:   VapiCircuit.Status(),
+	}
+	if health.Vapi.State == CIRCUIT_OPEN {
+		health.Status = HEALTH_DEGRADED
+	}
+
+	return health
+}
+
+// CircuitBreaker stops requests to a failing service, so its callers fail fast and pause instead
+// of piling requests onto it. It opens after FailureThreshold consecutive failures, and lets a
+// trial request through every OpenDuration until one succeeds.
+type CircuitBreaker struct {
+	// Name identifies the service in logs
+	Name string
+
+	// FailureThreshold is the number of consecutive failures that opens the circuit
+	FailureThreshold int
+
+	// OpenDuration is how long the circuit stays open before a trial request
+	OpenDuration time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	lastError string
+	open      bool
+	openedAt  time.Time
+	trial     bool
+}
+
+// VapiCircuit is the circuit breaker of the requests sent to VapiAI
+var VapiCircuit = &CircuitBreaker{
+	Name:             "VapiAI",
+	FailureThreshold: CIRCUIT_FAILURE_THRESHOLD,
+	OpenDuration:     CIRCUIT_OPEN_DURATION,
+}
+
+// Allow returns ErrCircuitOpen if a request can't be sent now. When the circuit is half-open,
+// the request allowed is the trial, and every other request is rejected until it completes.
+func (b *CircuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.open {
+		return nil
+	}
+	if time.Since(b.openedAt) < b.OpenDuration || b.trial {
+		return ErrCircuitOpen
+	}
+
+	b.trial = true
+	return nil
+}
+
+// Record records the outcome of an allowed request. Server errors, rate limits and network errors
+// are failures; any other response, including client errors, shows the service is up.
+func (b *CircuitBreaker) Record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+
+	if !isServiceFailure(err) {
+		// Errors raised before a request is sent say nothing about the service
+		if err != nil && !isServiceResponse(err) {
+			return
+		}
+		if b.open {
+			log.Printf("[%s] Circuit closed after %d consecutive failures", b.Name, b.failures)
+		}
+		b.failures = 0
+		b.lastError = ""
+		b.open = false
+		return
+	}
+
+	b.failures++
+	b.lastError = err.Error()
+
+	if b.open {
+		// The trial failed, wait another OpenDuration
+		b.openedAt = time.Now()
+	} else if b.failures >= b.FailureThreshold {
+		b.open = true
+		b.openedAt = time.Now()
+		log.Printf("[%s] Circuit opened after %d consecutive failures: %v", b.Name, b.failures, err)
+	}
+}
+
+// State returns the state of the circuit.
+func (b *CircuitBreaker) State() CircuitState {
+	return b.Status().State
+}
+
+// Status returns a snapshot of the circuit.
+func (b *CircuitBreaker) Status() CircuitStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := CircuitStatus{
+		State:               CIRCUIT_CLOSED,
+		ConsecutiveFailures: b.failures,
+		LastError:           b.lastError,
+	}
+	if b.open {
+		openedAt := b.openedAt
+		retryAt := b.openedAt.Add(b.OpenDuration)
+		status.OpenedAt = &openedAt
+		status.RetryAt = &retryAt
+
+		status.State = CIRCUIT_OPEN
+		if !time.Now().Before(retryAt) {
+			status.State = CIRCUIT_HALF_OPEN
+		}
+	}
+
+	return status
+}
+
+// ResilientProvider wraps a Provider with retries and a circuit breaker. Failed requests are retried
+// with exponential backoff and jitter, waiting at least as long as VapiAI asks through Retry-After.
+// Requests that are safe to repeat are retried on server errors, rate limits and network errors;
+// calls and assistants are only created again when VapiAI rate limited the request, since another
+// failure may come after they were created.
+type ResilientProvider struct {
+	next    Provider
+	breaker *CircuitBreaker
+}
+
+// NewResilientProvider wraps a Provider with retries and the given circuit breaker.
+func NewResilientProvider(next Provider, breaker *CircuitBreaker) *ResilientProvider {
+	return &ResilientProvider{next: next, breaker: breaker}
+}
+
+func (p *ResilientProvider) CreateCall(ctx context.Context, request *vapiApi.CreateCallDto) (*vapiApi.CallsCreateResponse, error) {
+	var resp *vapiApi.CallsCreateResponse
+	err := p.do(ctx, "create call", false, func() (err error) {
+		resp, err = p.next.CreateCall(ctx, request)
+		return err
+	})
+	return resp, err
+}
+
+func (p *ResilientProvider) GetCall(ctx context.Context, callId string) (*vapiApi.Call, error) {
+	var call *vapiApi.Call
+	err := p.do(ctx, "get call", true, func() (err error) {
+		call, err = p.next.GetCall(ctx, callId)
+		return err
+	})
+	return call, err
+}
+
+func (p *ResilientProvider) ListCalls(ctx context.Context, request *vapiApi.CallsListRequest) ([]*vapiApi.Call, error) {
+	var calls []*vapiApi.Call
+	err := p.do(ctx, "list calls", true, func() (err error) {
+		calls, err = p.next.ListCalls(ctx, request)
+		return err
+	})
+	return calls, err
+}
+
+func (p *ResilientProvider) DeleteCall(ctx context.Context, callId string) error {
+	return p.do(ctx, "delete call", true, func() error {
+		return p.next.DeleteCall(ctx, callId)
+	})
+}
+
+func (p *ResilientProvider) EndCall(ctx context.Context, call *vapiApi.Call) error {
+	return p.do(ctx, "end call", true, func() error {
+		return p.next.EndCall(ctx, call)
+	})
+}
+
+func (p *ResilientProvider) CreateAssistant(ctx context.Context, request *vapiApi.CreateAssistantDto) (*vapiApi.Assistant, error) {
+	var assistant *vapiApi.Assistant
+	err := p.do(ctx, "create assistant", false, func() (err error) {
+		assistant, err = p.next.CreateAssistant(ctx, request)
+		return err
+	})
+	return assistant, err
+}
+
+func (p *ResilientProvider) GetAssistant(ctx context.Context, assistantId string) (*vapiApi.Assistant, error) {
+	var assistant *vapiApi.Assistant
+	err := p.do(ctx, "get assistant", true, func() (err error) {
+		assistant, err = p.next.GetAssistant(ctx, assistantId)
+		return err
+	})
+	return assistant, err
+}
+
+func (p *ResilientProvider) UpdateAssistant(ctx context.Context, assistantId string, request *vapiApi.UpdateAssistantDto) (*vapiApi.Assistant, error) {
+	var assistant *vapiApi.Assistant
+	err := p.do(ctx, "update assistant", true, func() (err error) {
+		assistant, err = p.next.UpdateAssistant(ctx, assistantId, request)
+		return err
+	})
+	return assistant, err
+}
+
+func (p *ResilientProvider) DeleteAssistant(ctx context.Context, assistantId string) error {
+	return p.do(ctx, "delete assistant", true, func() error {
+		return p.next.DeleteAssistant(ctx, assistantId)
+	})
+}
+
+func (p *ResilientProvider) GetPhoneNumber(ctx context.Context, phoneNumberId string) (*vapiApi.PhoneNumbersGetResponse, error) {
+	var phoneNumber *vapiApi.PhoneNumbersGetResponse
+	err := p.do(ctx, "get phone number", true, func() (err error) {
+		phoneNumber, err = p.next.GetPhoneNumber(ctx, phoneNumberId)
+		return err
+	})
+	return phoneNumber, err
+}
+
+// do sends a request through the circuit breaker, retrying it up to VAPI_MAX_RETRIES times while
+// it fails with a retryable error. idempotent tells whether the request is safe to repeat.
+func (p *ResilientProvider) do(ctx context.Context, operation string, idempotent bool, request func() error) error {
+	for attempt := 0; ; attempt++ {
+		if err := p.breaker.Allow(); err != nil {
+			return err
+		}
+
+		err := request()
+		p.breaker.Record(err)
+		if err == nil || attempt >= VAPI_MAX_RETRIES || !isRetryable(err, idempotent) {
+			return err
+		}
+
+		delay, ok := retryDelay(attempt, err)
+		if !ok {
+			return err
+		}
+		log.Printf("[VapiAI] Failed to %s (attempt %d of %d), retrying in %s: %v", operation, attempt+1, VAPI_MAX_RETRIES+1, delay.Round(time.Millisecond), err)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// isRetryable reports whether a failed request may succeed if sent again.
+func isRetryable(err error, idempotent bool) bool {
+	var apiErr *core.APIError
+	if errors.As(err, &apiErr) {
+		if apiErr.StatusCode == http.StatusTooManyRequests {
+			// The request was rejected before it was processed
+			return true
+		}
+		return idempotent && apiErr.StatusCode >= http.StatusInternalServerError
+	}
+
+	return idempotent && isServiceFailure(err)
+}
+
+// isRequestRejected reports whether a failed request certainly wasn't processed: the service answered it with
+// a client error, including a rate limit, or it was never sent because the circuit is open. A server or network
+// error may come after the service acted on the request.
+func isRequestRejected(err error) bool {
+	var apiErr *core.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode < http.StatusInternalServerError
+	}
+
+	return errors.Is(err, ErrCircuitOpen)
+}
+
+// isServiceFailure reports whether an error shows the service is unhealthy:
+// a server error, a rate limit, or a network error.
+func isServiceFailure(err error) bool {
+	if err == nil || errors.Is(err, ErrCircuitOpen) || errors.Is(err, ErrCallNotControllable) ||
+		errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var apiErr *core.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= http.StatusInternalServerError
+	}
+
+	return true
+}
+
+// isServiceResponse reports whether an error is a response of the service, as opposed to
+// an error raised before a request was sent.
+func isServiceResponse(err error) bool {
+	var apiErr *core.APIError
+	return errors.As(err, &apiErr)
+}
+
+// retryDelay returns how long to wait before retrying a request after the given attempt: an exponential
+// backoff with jitter, or the Retry-After of the response if longer. Returns false if the response
+// asks to wait longer than VAPI_RETRY_MAX_DELAY.
+func retryDelay(attempt int, err error) (time.Duration, bool) {
+	backoff := min(VAPI_RETRY_BASE_DELAY<<attempt, VAPI_RETRY_MAX_DELAY)
+	// Equal jitter keeps at least half the backoff, so clients failing together don't retry together
+	delay := backoff/2 + rand.N(backoff/2+1)
+
+	var apiErr *core.APIError
+	if errors.As(err, &apiErr) && apiErr.Header != nil {
+		if retryAfter, ok := parseRetryAfter(apiErr.Header.Get("Retry-After")); ok {
+			if retryAfter > VAPI_RETRY_MAX_DELAY {
+				return 0, false
+			}
+			delay = max(delay, retryAfter)
+		}
+	}
+
+	return delay, true
+}
+
+// parseRetryAfter parses a Retry-After header, given either in seconds or as an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,256 @@
+package sarah
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	vapiApi "github.com/VapiAI/server-sdk-go"
+)
+
+// flakyProvider is a FakeProvider whose GetCall and CreateCall fail with the queued errors before succeeding.
+type flakyProvider struct {
+	*FakeProvider
+
+	getCallErrors      []error
+	getCallAttempts    int
+	createCallErrors   []error
+	createCallAttempts int
+}
+
+func (p *flakyProvider) GetCall(ctx context.Context, callId string) (*vapiApi.Call, error) {
+	p.getCallAttempts++
+	if len(p.getCallErrors) > 0 {
+		err := p.getCallErrors[0]
+		p.getCallErrors = p.getCallErrors[1:]
+		return nil, err
+	}
+	return p.FakeProvider.GetCall(ctx, callId)
+}
+
+func (p *flakyProvider) CreateCall(ctx context.Context, request *vapiApi.CreateCallDto) (*vapiApi.CallsCreateResponse, error) {
+	p.createCallAttempts++
+	if len(p.createCallErrors) > 0 {
+		err := p.createCallErrors[0]
+		p.createCallErrors = p.createCallErrors[1:]
+		return nil, err
+	}
+	return p.FakeProvider.CreateCall(ctx, request)
+}
+
+// newFakeCall places a call from a new assistant and phone number of the fake provider.
+func newFakeCall(t *testing.T, fake *FakeProvider, customerNumber string) *vapiApi.Call {
+	t.Helper()
+
+	assistantId, phoneNumberId := addFakeLine(t, fake)
+	resp, err := fake.CreateCall(context.Background(), &vapiApi.CreateCallDto{
+		AssistantId:   vapiApi.String(assistantId),
+		PhoneNumberId: vapiApi.String(phoneNumberId),
+		Customer:      &vapiApi.CreateCustomerDto{Number: vapiApi.String(customerNumber)},
+	})
+	if err != nil {
+		t.Fatalf("creating call: %v", err)
+	}
+
+	return resp.Call
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		idempotent bool
+		want       bool
+	}{
+		{"rate limited read", apiError(http.StatusTooManyRequests), true, true},
+		{"rate limited create", apiError(http.StatusTooManyRequests), false, true},
+		{"server error on read", apiError(http.StatusServiceUnavailable), true, true},
+		{"server error on create", apiError(http.StatusInternalServerError), false, false},
+		{"wrapped server error", fmt.Errorf("get call: %w", apiError(http.StatusBadGateway)), true, true},
+		{"client error", apiError(http.StatusBadRequest), true, false},
+		{"not found", apiError(http.StatusNotFound), true, false},
+		{"network error on read", errors.New("connection reset by peer"), true, true},
+		{"network error on create", errors.New("connection reset by peer"), false, false},
+		{"circuit open", ErrCircuitOpen, true, false},
+		{"call not controllable", ErrCallNotControllable, true, false},
+		{"context canceled", context.Canceled, true, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := isRetryable(test.err, test.idempotent); got != test.want {
+				t.Errorf("isRetryable(%v, %t) = %t, want %t", test.err, test.idempotent, got, test.want)
+			}
+		})
+	}
+}
+
+func TestIsRequestRejected(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"client error", apiError(http.StatusBadRequest), true},
+		{"rate limited", apiError(http.StatusTooManyRequests), true},
+		{"circuit open", ErrCircuitOpen, true},
+		{"server error", apiError(http.StatusInternalServerError), false},
+		{"gateway timeout", fmt.Errorf("create call: %w", apiError(http.StatusGatewayTimeout)), false},
+		{"network error", errors.New("read: connection reset by peer"), false},
+		{"deadline exceeded", context.DeadlineExceeded, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := isRequestRejected(test.err); got != test.want {
+				t.Errorf("isRequestRejected(%v) = %t, want %t", test.err, got, test.want)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	breaker := &CircuitBreaker{Name: "test", FailureThreshold: 3, OpenDuration: 50 * time.Millisecond}
+
+	assertState := func(want CircuitState, failures int) {
+		t.Helper()
+		status := breaker.Status()
+		if status.State != want || status.ConsecutiveFailures != failures {
+			t.Fatalf("circuit is %s with %d failures, want %s with %d", status.State, status.ConsecutiveFailures, want, failures)
+		}
+	}
+
+	// Failures below the threshold keep the circuit closed
+	for i := 0; i < 2; i++ {
+		if err := breaker.Allow(); err != nil {
+			t.Fatalf("closed circuit rejected a request: %v", err)
+		}
+		breaker.Record(apiError(http.StatusInternalServerError))
+	}
+	assertState(CIRCUIT_CLOSED, 2)
+
+	// Errors raised before a request is sent are ignored
+	breaker.Record(ErrCallNotControllable)
+	assertState(CIRCUIT_CLOSED, 2)
+
+	// A client error shows the service is up
+	breaker.Record(apiError(http.StatusBadRequest))
+	assertState(CIRCUIT_CLOSED, 0)
+
+	for i := 0; i < 3; i++ {
+		breaker.Record(apiError(http.StatusServiceUnavailable))
+	}
+	assertState(CIRCUIT_OPEN, 3)
+	if err := breaker.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("open circuit allowed a request: %v", err)
+	}
+
+	// Once OpenDuration passed, a single trial request is let through
+	time.Sleep(60 * time.Millisecond)
+	assertState(CIRCUIT_HALF_OPEN, 3)
+	if err := breaker.Allow(); err != nil {
+		t.Fatalf("half-open circuit rejected the trial request: %v", err)
+	}
+	if err := breaker.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("half-open circuit allowed a second request during the trial: %v", err)
+	}
+
+	// A failed trial opens the circuit for another OpenDuration
+	breaker.Record(errors.New("connection refused"))
+	assertState(CIRCUIT_OPEN, 4)
+	if err := breaker.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("circuit allowed a request right after a failed trial: %v", err)
+	}
+
+	// A successful trial closes it
+	time.Sleep(60 * time.Millisecond)
+	if err := breaker.Allow(); err != nil {
+		t.Fatalf("half-open circuit rejected the trial request: %v", err)
+	}
+	breaker.Record(nil)
+	assertState(CIRCUIT_CLOSED, 0)
+	if status := breaker.Status(); status.LastError != "" || status.OpenedAt != nil {
+		t.Errorf("closed circuit kept its last error %q and opening time %v", status.LastError, status.OpenedAt)
+	}
+}
+
+func TestResilientProviderRetriesIdempotentRequests(t *testing.T) {
+	fake := &flakyProvider{FakeProvider: NewFakeProvider()}
+	call := newFakeCall(t, fake.FakeProvider, "+15557654321")
+	fake.getCallErrors = []error{apiError(http.StatusServiceUnavailable), errors.New("connection reset by peer")}
+
+	breaker := &CircuitBreaker{Name: "test", FailureThreshold: CIRCUIT_FAILURE_THRESHOLD, OpenDuration: time.Minute}
+	provider := NewResilientProvider(fake, breaker)
+
+	got, err := provider.GetCall(context.Background(), call.Id)
+	if err != nil {
+		t.Fatalf("GetCall failed after retries: %v", err)
+	}
+	if got.Id != call.Id {
+		t.Errorf("GetCall returned call %s, want %s", got.Id, call.Id)
+	}
+	if fake.getCallAttempts != 3 {
+		t.Errorf("GetCall was attempted %d times, want 3", fake.getCallAttempts)
+	}
+	if state := breaker.State(); state != CIRCUIT_CLOSED {
+		t.Errorf("circuit is %s after a successful retry, want closed", state)
+	}
+}
+
+func TestResilientProviderOnlyRetriesRateLimitedCreates(t *testing.T) {
+	fake := &flakyProvider{FakeProvider: NewFakeProvider()}
+	call := newFakeCall(t, fake.FakeProvider, "+15557654321")
+	request := &vapiApi.CreateCallDto{
+		AssistantId:   call.AssistantId,
+		PhoneNumberId: call.PhoneNumberId,
+		Customer:      &vapiApi.CreateCustomerDto{Number: vapiApi.String("+15557654322")},
+	}
+	breaker := &CircuitBreaker{Name: "test", FailureThreshold: CIRCUIT_FAILURE_THRESHOLD, OpenDuration: time.Minute}
+	provider := NewResilientProvider(fake, breaker)
+
+	// A server error may come after the call was placed, so the request isn't sent again
+	fake.createCallErrors = []error{apiError(http.StatusInternalServerError)}
+	if _, err := provider.CreateCall(context.Background(), request); err == nil {
+		t.Fatal("CreateCall succeeded, want the server error")
+	}
+	if fake.createCallAttempts != 1 {
+		t.Errorf("CreateCall was attempted %d times after a server error, want 1", fake.createCallAttempts)
+	}
+
+	// A rate limited request wasn't processed, so it is retried
+	fake.createCallAttempts = 0
+	fake.createCallErrors = []error{apiError(http.StatusTooManyRequests)}
+	resp, err := provider.CreateCall(context.Background(), request)
+	if err != nil {
+		t.Fatalf("CreateCall failed after a rate limit: %v", err)
+	}
+	if fake.createCallAttempts != 2 {
+		t.Errorf("CreateCall was attempted %d times after a rate limit, want 2", fake.createCallAttempts)
+	}
+	if len(createdCalls(resp)) != 1 {
+		t.Errorf("CreateCall created %d calls, want 1", len(createdCalls(resp)))
+	}
+}
+
+func TestResilientProviderFailsFastWhileOpen(t *testing.T) {
+	fake := &flakyProvider{FakeProvider: NewFakeProvider()}
+	call := newFakeCall(t, fake.FakeProvider, "+15557654321")
+	fake.getCallErrors = []error{apiError(http.StatusBadGateway)}
+
+	breaker := &CircuitBreaker{Name: "test", FailureThreshold: 1, OpenDuration: time.Minute}
+	provider := NewResilientProvider(fake, breaker)
+
+	// The first failure opens the circuit, so the retry is rejected without reaching the provider
+	if _, err := provider.GetCall(context.Background(), call.Id); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("GetCall returned %v, want ErrCircuitOpen", err)
+	}
+	if _, err := provider.GetCall(context.Background(), call.Id); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("GetCall returned %v while the circuit is open, want ErrCircuitOpen", err)
+	}
+	if fake.getCallAttempts != 1 {
+		t.Errorf("provider was reached %d times, want 1", fake.getCallAttempts)
+	}
+}
@@ -0,0 +1,81 @@
+package sarah
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"sarah/mongodb"
+	"sarah/phone"
+	mongodbTypes "sarah/types/mongodb"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// ErrInvalidSettings is returned when organization settings fail validation.
+var ErrInvalidSettings = errors.New("invalid settings")
+
+// GetOrganizationSettings returns the organization's settings, or the defaults if it never saved any.
+func GetOrganizationSettings(orgId string) (*mongodbTypes.OrganizationSettings, error) {
+	settings, err := mongodb.GetOrganizationSettings(orgId)
+	if err != nil {
+		log.Printf("Error getting organization settings: %v", err)
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+// UpdateOrganizationSettings validates and saves the settings given in the update.
+// Settings missing from the update are left unchanged.
+// Returns an error wrapping ErrInvalidSettings if a setting is out of range.
+func UpdateOrganizationSettings(orgId string, update mongodbTypes.OrganizationSettingsUpdate) (*mongo.UpdateResult, error) {
+	if update.RecordingRetentionDays != nil && *update.RecordingRetentionDays < 0 {
+		return nil, fmt.Errorf("%w: recording_retention_days must be 0 or more", ErrInvalidSettings)
+	}
+
+	if update.MaxConcurrentCalls != nil && *update.MaxConcurrentCalls < 0 {
+		return nil, fmt.Errorf("%w: max_concurrent_calls must be 0 or more", ErrInvalidSettings)
+	}
+
+	if update.AnalysisMappings != nil {
+		if err := validateAnalysisMappings(*update.AnalysisMappings); err != nil {
+			return nil, err
+		}
+	}
+
+	if update.Dispositions != nil {
+		if err := validateDispositions(*update.Dispositions); err != nil {
+			return nil, err
+		}
+	}
+
+	if update.DefaultCountry != nil {
+		country := strings.ToUpper(strings.TrimSpace(*update.DefaultCountry))
+		if country != "" && !phone.ValidCountry(country) {
+			return nil, fmt.Errorf("%w: default_country must be an ISO 3166-1 alpha-2 country code", ErrInvalidSettings)
+		}
+		update.DefaultCountry = &country
+	}
+
+	result, err := mongodb.UpdateOrganizationSettings(orgId, update)
+	if err != nil {
+		log.Printf("Error updating organization settings: %v", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// organizationCountry returns the default country phone numbers of the organization are read in.
+// Returns an empty country, which only accepts international numbers, if the settings can't be read.
+func organizationCountry(orgId string) string {
+	settings, err := mongodb.GetOrganizationSettings(orgId)
+	if err != nil {
+		log.Printf("Error getting default country of organization %s: %v", orgId, err)
+		return ""
+	}
+
+	return settings.DefaultCountry
+}
@@ -0,0 +1,262 @@
+package sarah
+
+import (
+	"html"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"sarah/mongodb"
+	mongodbTypes "sarah/types/mongodb"
+	vapiTypes "sarah/types/vapi"
+
+	vapiApi "github.com/VapiAI/server-sdk-go"
+)
+
+const (
+	// maxSnippetsPerMatch is the maximum number of snippets returned for a single call
+	maxSnippetsPerMatch = 3
+
+	// snippetContext is the number of characters kept on each side of the first match of a snippet
+	snippetContext = 80
+)
+
+// SearchTranscripts runs a full-text search over the organization's call transcripts and
+// returns the matching calls, most relevant first, each with up to three highlighted snippets.
+// The search string follows MongoDB $text syntax: words, "quoted phrases" and -negated words.
+func SearchTranscripts(orgId string, search string, filter mongodbTypes.TranscriptFilter, page int, limit int) (*mongodbTypes.TranscriptSearchPage, error) {
+	results, err := mongodb.SearchTranscripts(orgId, search, filter, page, limit)
+	if err != nil {
+		log.Printf("Error searching transcripts: %v", err)
+		return nil, err
+	}
+
+	highlight := searchHighlighter(search)
+	for i := range results.Matches {
+		results.Matches[i].Snippets = transcriptSnippets(results.Matches[i], highlight)
+	}
+
+	return results, nil
+}
+
+// recordTranscript stores the transcript of an ended call alongside the call's assistant,
+// campaign and creation date, which are read back from the call record so searches can be
+// filtered by them. Calls without any transcript are skipped.
+func recordTranscript(orgId string, vapiCallId string, transcript string, messages []mongodbTypes.TranscriptMessage) error {
+	if strings.TrimSpace(transcript) == "" && len(messages) == 0 {
+		return nil
+	}
+
+	call, err := mongodb.GetCallByVapiId(orgId, vapiCallId)
+	if err != nil {
+		log.Printf("Error getting call %s for its transcript: %v", vapiCallId, err)
+		return err
+	}
+
+	_, err = mongodb.UpsertTranscript(orgId, mongodbTypes.Transcript{
+		VapiCallId:     vapiCallId,
+		AssistantId:    call.AssistantId,
+		CampaignId:     call.CampaignId,
+		CustomerNumber: call.CustomerNumber,
+		Transcript:     transcript,
+		Messages:       messages,
+		CallCreatedAt:  call.CreatedAt,
+	})
+	if err != nil {
+		log.Printf("Error storing transcript of call %s: %v", vapiCallId, err)
+		return err
+	}
+
+	return nil
+}
+
+// transcriptMessagesFromServerMessage extracts the spoken turns of an end-of-call report.
+// System and tool messages are left out since customers and assistants never said them.
+func transcriptMessagesFromServerMessage(artifact *vapiTypes.Artifact) []mongodbTypes.TranscriptMessage {
+	messages := []mongodbTypes.TranscriptMessage{}
+	if artifact == nil {
+		return messages
+	}
+
+	for _, message := range artifact.Messages {
+		if message.Role != "user" && message.Role != "bot" {
+			continue
+		}
+		messages = append(messages, mongodbTypes.TranscriptMessage{
+			Role:             message.Role,
+			Message:          message.Message,
+			SecondsFromStart: message.SecondsFromStart,
+		})
+	}
+
+	return messages
+}
+
+// transcriptMessagesFromVapi extracts the spoken turns of a VapiAI call artifact.
+func transcriptMessagesFromVapi(artifact *vapiApi.Artifact) []mongodbTypes.TranscriptMessage {
+	messages := []mongodbTypes.TranscriptMessage{}
+	if artifact == nil {
+		return messages
+	}
+
+	for _, item := range artifact.Messages {
+		if item == nil {
+			continue
+		}
+		if item.UserMessage != nil {
+			messages = append(messages, mongodbTypes.TranscriptMessage{
+				Role:             item.UserMessage.Role,
+				Message:          item.UserMessage.Message,
+				SecondsFromStart: item.UserMessage.SecondsFromStart,
+			})
+		} else if item.BotMessage != nil {
+			messages = append(messages, mongodbTypes.TranscriptMessage{
+				Role:             item.BotMessage.Role,
+				Message:          item.BotMessage.Message,
+				SecondsFromStart: item.BotMessage.SecondsFromStart,
+			})
+		}
+	}
+
+	return messages
+}
+
+// searchPhrase matches the quoted phrases of a $text search string
+var searchPhrase = regexp.MustCompile(`"([^"]*)"`)
+
+// searchHighlighter builds a case-insensitive regular expression matching the terms of a
+// $text search string. MongoDB stems words, so each term also matches the word with any
+// ending (e.g., "cancel" highlights "cancelled"). Negated words are not highlighted.
+// Returns nil if the search has nothing to highlight.
+func searchHighlighter(search string) *regexp.Regexp {
+	patterns := []string{}
+
+	for _, phrase := range searchPhrase.FindAllStringSubmatch(search, -1) {
+		words := strings.Fields(phrase[1])
+		if len(words) == 0 {
+			continue
+		}
+		stems := []string{}
+		for _, word := range words {
+			stems = append(stems, regexp.QuoteMeta(searchStem(word))+`\w*`)
+		}
+		patterns = append(patterns, strings.Join(stems, `\W+`))
+	}
+
+	for _, word := range strings.Fields(searchPhrase.ReplaceAllString(search, " ")) {
+		if strings.HasPrefix(word, "-") {
+			continue
+		}
+		word = strings.Trim(word, `.,;:!?()'`)
+		if word == "" {
+			continue
+		}
+		patterns = append(patterns, regexp.QuoteMeta(searchStem(word))+`\w*`)
+	}
+
+	if len(patterns) == 0 {
+		return nil
+	}
+
+	// Longer patterns first so phrases win over the words they contain
+	sort.SliceStable(patterns, func(i, j int) bool { return len(patterns[i]) > len(patterns[j]) })
+
+	return regexp.MustCompile(`(?i)\b(?:` + strings.Join(patterns, "|") + `)`)
+}
+
+// searchStem trims common English endings from a search word so its variants are highlighted too.
+func searchStem(word string) string {
+	word = strings.ToLower(word)
+	for _, suffix := range []string{"ing", "ed", "es", "s", "y"} {
+		if strings.HasSuffix(word, suffix) && len(word)-len(suffix) >= 3 {
+			return strings.TrimSuffix(word, suffix)
+		}
+	}
+	return word
+}
+
+// transcriptSnippets returns the highlighted passages of a matched transcript. Messages are
+// preferred since they carry who spoke and when, otherwise the plain transcript is split into lines.
+func transcriptSnippets(match mongodbTypes.TranscriptMatch, highlight *regexp.Regexp) []mongodbTypes.TranscriptSnippet {
+	snippets := []mongodbTypes.TranscriptSnippet{}
+	if highlight == nil {
+		return snippets
+	}
+
+	passages := match.Messages
+	if len(passages) == 0 {
+		for _, line := range strings.Split(match.Transcript, "\n") {
+			passages = append(passages, mongodbTypes.TranscriptMessage{Message: line})
+		}
+	}
+
+	for _, passage := range passages {
+		text, ok := highlightPassage(passage.Message, highlight)
+		if !ok {
+			continue
+		}
+
+		snippets = append(snippets, mongodbTypes.TranscriptSnippet{
+			Role:             passage.Role,
+			SecondsFromStart: passage.SecondsFromStart,
+			Text:             text,
+		})
+		if len(snippets) == maxSnippetsPerMatch {
+			break
+		}
+	}
+
+	return snippets
+}
+
+// highlightPassage HTML-escapes a passage and wraps every match in <mark></mark>. Long passages
+// are cut to snippetContext characters around the first match. Reports false if nothing matched.
+func highlightPassage(passage string, highlight *regexp.Regexp) (string, bool) {
+	matches := highlight.FindAllStringIndex(passage, -1)
+	if len(matches) == 0 {
+		return "", false
+	}
+
+	start := runeBoundary(passage, matches[0][0]-snippetContext)
+	end := runeBoundary(passage, matches[0][1]+snippetContext)
+
+	var builder strings.Builder
+	if start > 0 {
+		builder.WriteString("…")
+	}
+
+	cursor := start
+	for _, match := range matches {
+		if match[0] < cursor || match[1] > end {
+			continue
+		}
+		builder.WriteString(html.EscapeString(passage[cursor:match[0]]))
+		builder.WriteString("<mark>")
+		builder.WriteString(html.EscapeString(passage[match[0]:match[1]]))
+		builder.WriteString("</mark>")
+		cursor = match[1]
+	}
+	builder.WriteString(html.EscapeString(passage[cursor:end]))
+
+	if end < len(passage) {
+		builder.WriteString("…")
+	}
+
+	return strings.TrimSpace(builder.String()), true
+}
+
+// runeBoundary clamps a byte offset to the passage and moves it back to the start of a rune.
+func runeBoundary(passage string, offset int) int {
+	if offset <= 0 {
+		return 0
+	}
+	if offset >= len(passage) {
+		return len(passage)
+	}
+	for offset > 0 && !utf8.RuneStart(passage[offset]) {
+		offset--
+	}
+	return offset
+}
@@ -1,15 +1,18 @@
 package sarah
 
 import (
+	"errors"
 	"net/http"
 	"time"
 
 	vapiclient "github.com/VapiAI/server-sdk-go/client"
+	"github.com/VapiAI/server-sdk-go/core"
 	"github.com/VapiAI/server-sdk-go/option"
 )
 
 // createClient initializes and returns a new VapiAI client with the provided API key.
-// The client is configured with a 30-second timeout for HTTP requests.
+// The client is configured with a 30-second timeout for HTTP requests. Its own retries are disabled,
+// since they also repeat requests that aren't idempotent; requests are retried by ResilientProvider instead.
 //
 // Parameters:
 //   - apiKey: The VapiAI API key for authentication
@@ -19,9 +22,24 @@ import (
 func createClient(apiKey string) *vapiclient.Client {
 	return vapiclient.NewClient(
 		option.WithToken(apiKey),
+		option.WithMaxAttempts(1),
 		option.WithHTTPClient(
 			&http.Client{
 				Timeout: 30 * time.Second,
 			}),
 	)
 }
+
+// derefString returns the value of a string pointer, or an empty string if it is nil.
+func derefString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+// isVapiNotFound reports whether a VapiAI API error is a 404 Not Found.
+func isVapiNotFound(err error) bool {
+	var apiErr *core.APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,168 @@
+package sarah
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"sarah/phone"
+	mongodbTypes "sarah/types/mongodb"
+)
+
+// FieldError describes why a single field of a request is invalid.
+type FieldError struct {
+	// Field is the path of the invalid field (e.g., "customers[2].phone_number")
+	Field string `json:"field"`
+
+	// Value is the rejected value
+	Value string `json:"value"`
+
+	// Message explains why the value was rejected
+	Message string `json:"message"`
+}
+
+// ValidationError is returned when one or more fields of a request are invalid.
+type ValidationError struct {
+	// Errors are the invalid fields
+	Errors []FieldError `json:"errors"`
+}
+
+func (e *ValidationError) Error() string {
+	fields := []string{}
+	for _, fieldError := range e.Errors {
+		fields = append(fields, fmt.Sprintf("%s: %s", fieldError.Field, fieldError.Message))
+	}
+	return "validation failed: " + strings.Join(fields, "; ")
+}
+
+// add records an invalid field.
+func (e *ValidationError) add(field string, value string, err error) {
+	e.Errors = append(e.Errors, FieldError{Field: field, Value: value, Message: phoneErrorMessage(err)})
+}
+
+// orNil returns the error if a field was invalid, nil otherwise.
+func (e *ValidationError) orNil() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
+
+// phoneErrorMessage returns the reason of a phone.Normalize error without the ErrInvalidNumber prefix.
+func phoneErrorMessage(err error) string {
+	return strings.TrimPrefix(err.Error(), phone.ErrInvalidNumber.Error()+": ")
+}
+
+// CUSTOMER_NAME_MAX_LENGTH is the maximum length of the name of a customer
+const CUSTOMER_NAME_MAX_LENGTH = 100
+
+// MAX_CUSTOMER_VARIABLES is the maximum number of variables of a customer
+const MAX_CUSTOMER_VARIABLES = 50
+
+// CUSTOMER_VARIABLE_MAX_LENGTH is the maximum length of the value of a customer variable
+const CUSTOMER_VARIABLE_MAX_LENGTH = 1000
+
+// customerVariablePattern matches the names of customer variables, usable as {{name}} in the assistant's prompts
+var customerVariablePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,63}$`)
+
+// normalizeCustomers converts the phone number of every customer to E.164 and sets its number type,
+// and checks the name, time zone and variables of every customer. field is the path of the customer list
+// in the request (e.g., "customers"). Returns the valid customers and a ValidationError for the others,
+// or nil if every customer is valid.
+func normalizeCustomers(customers []mongodbTypes.Customer, field string, defaultCountry string) ([]mongodbTypes.Customer, *ValidationError) {
+	valid := []mongodbTypes.Customer{}
+	invalid := &ValidationError{}
+
+	for i, customer := range customers {
+		customer, errs := normalizeCustomer(customer, fmt.Sprintf("%s[%d]", field, i), defaultCountry)
+		if len(errs) > 0 {
+			invalid.Errors = append(invalid.Errors, errs...)
+			continue
+		}
+		valid = append(valid, customer)
+	}
+
+	if len(invalid.Errors) == 0 {
+		return valid, nil
+	}
+	return valid, invalid
+}
+
+// normalizeCustomer converts the phone number of a customer to E.164, sets its number type and trims its name.
+// field is the path of the customer in the request (e.g., "customers[2]"). Returns the invalid fields of the
+// customer: a phone number that isn't valid, a name too long, a time zone that isn't an IANA time zone, or
+// variables with invalid names or values other than strings, numbers and booleans.
+func normalizeCustomer(customer mongodbTypes.Customer, field string, defaultCountry string) (mongodbTypes.Customer, []FieldError) {
+	invalid := &ValidationError{}
+
+	number, err := phone.Normalize(customer.PhoneNumber, defaultCountry)
+	if err != nil {
+		invalid.add(field+".phone_number", customer.PhoneNumber, err)
+	} else {
+		customer.PhoneNumber = number.E164
+		customer.PhoneNumberType = string(number.Type)
+	}
+
+	customer.Name = strings.TrimSpace(customer.Name)
+	if len([]rune(customer.Name)) > CUSTOMER_NAME_MAX_LENGTH {
+		invalid.Errors = append(invalid.Errors, FieldError{Field: field + ".name", Value: customer.Name, Message: fmt.Sprintf("must be at most %d characters", CUSTOMER_NAME_MAX_LENGTH)})
+	}
+
+	customer.TimeZone = strings.TrimSpace(customer.TimeZone)
+	if customer.TimeZone != "" {
+		if _, err := time.LoadLocation(customer.TimeZone); err != nil || strings.EqualFold(customer.TimeZone, "Local") {
+			invalid.Errors = append(invalid.Errors, FieldError{Field: field + ".timezone", Value: customer.TimeZone, Message: "must be an IANA time zone, e.g. America/New_York"})
+		}
+	}
+
+	if len(customer.Variables) > MAX_CUSTOMER_VARIABLES {
+		invalid.Errors = append(invalid.Errors, FieldError{Field: field + ".variables", Message: fmt.Sprintf("a customer can have at most %d variables", MAX_CUSTOMER_VARIABLES)})
+	}
+	for name, value := range customer.Variables {
+		variable := fmt.Sprintf("%s.variables.%s", field, name)
+		if !customerVariablePattern.MatchString(name) {
+			invalid.Errors = append(invalid.Errors, FieldError{Field: variable, Message: "names must be up to 64 letters, digits and underscores, not starting with a digit"})
+			continue
+		}
+
+		switch v := value.(type) {
+		case string:
+			if len([]rune(v)) > CUSTOMER_VARIABLE_MAX_LENGTH {
+				invalid.Errors = append(invalid.Errors, FieldError{Field: variable, Message: fmt.Sprintf("must be at most %d characters", CUSTOMER_VARIABLE_MAX_LENGTH)})
+			}
+		case float64, int32, int64, bool:
+		default:
+			invalid.Errors = append(invalid.Errors, FieldError{Field: variable, Value: fmt.Sprint(value), Message: "must be a string, a number or a boolean"})
+		}
+	}
+
+	// Map iteration order is random, so the errors of a customer are always reported in the same order
+	sort.SliceStable(invalid.Errors, func(i, j int) bool { return invalid.Errors[i].Field < invalid.Errors[j].Field })
+
+	return customer, invalid.Errors
+}
+
+// NormalizePhoneNumbers converts phone numbers sent by the organization to E.164, reading national
+// numbers in its default country. field is the path of the list in the request (e.g., "phoneNumbers").
+// Returns a *ValidationError listing every invalid number.
+func NormalizePhoneNumbers(orgId string, phoneNumbers []string, field string) ([]string, error) {
+	defaultCountry := organizationCountry(orgId)
+	normalized := []string{}
+	invalid := &ValidationError{}
+
+	for i, phoneNumber := range phoneNumbers {
+		number, err := phone.Normalize(phoneNumber, defaultCountry)
+		if err != nil {
+			invalid.add(fmt.Sprintf("%s[%d]", field, i), phoneNumber, err)
+			continue
+		}
+		normalized = append(normalized, number.E164)
+	}
+
+	if err := invalid.orNil(); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
@@ -0,0 +1,243 @@
+package sarah
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"sarah/clerk"
+	"sarah/mongodb"
+	mongodbTypes "sarah/types/mongodb"
+	vapiTypes "sarah/types/vapi"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// ErrOrganizationNotFound is returned when a VapiAI resource is not registered by any organization.
+var ErrOrganizationNotFound = errors.New("no organization owns the assistant or phone number")
+
+// callOrganizations caches which organization owns a VapiAI assistant or phone number,
+// keyed by "assistant:<id>" or "phone_number:<id>", so webhooks don't scan every organization.
+var callOrganizations sync.Map
+
+// HandleServerMessage processes a server message sent by VapiAI about a call.
+// The call is mapped back to its organization through the assistant and phone number
+// records, the message is stored as a call event, streamed to the organization's /calls/stream
+// clients if it is a lifecycle event, checked against the organization's alert rules, and the call record is updated.
+//
+// Parameters:
+//   - message: The decoded server message
+//   - payload: The raw message as sent by VapiAI, stored alongside the event
+//
+// Returns:
+//   - string: The organization ID the call belongs to
+//   - error: ErrOrganizationNotFound if the call can't be mapped to an organization
+func HandleServerMessage(message vapiTypes.ServerMessage, payload map[string]interface{}) (string, error) {
+	if message.Call == nil || message.Call.Id == "" {
+		return "", fmt.Errorf("server message %s has no call", message.Type)
+	}
+
+	orgId, err := ResolveCallOrganization(message.Call.AssistantId, message.Call.PhoneNumberId)
+	if err != nil {
+		log.Printf("[Webhook] Could not resolve organization for call %s: %v", message.Call.Id, err)
+		return "", err
+	}
+
+	return orgId, processCallMessage(orgId, message, payload)
+}
+
+// processCallMessage applies a server message about a call of the organization: the message is stored as a
+// call event, streamed and checked against the alert rules, and the call record and the state that follows
+// from it are updated. Used for the messages VapiAI sends and the ones the CallReconciler rebuilds.
+func processCallMessage(orgId string, message vapiTypes.ServerMessage, payload map[string]interface{}) error {
+	event := mongodbTypes.CallEvent{
+		VapiCallId:  message.Call.Id,
+		AssistantId: message.Call.AssistantId,
+		Type:        string(message.Type),
+		StreamEvent: streamEventName(message),
+		Status:      message.Status,
+		EndedReason: message.EndedReason,
+		Payload:     payload,
+		OccurredAt:  messageTime(message),
+		ReceivedAt:  time.Now(),
+	}
+	if event.StreamEvent != "" {
+		event.CampaignId = streamedCallCampaign(orgId, message.Call.Id)
+	}
+	if event.StreamEvent == STREAM_EVENT_TRANSCRIPT {
+		event.Role = message.Role
+		event.Transcript = message.Transcript
+	}
+
+	if _, err := mongodb.CreateCallEvent(orgId, event); err != nil {
+		log.Printf("[Webhook] Error storing %s event for call %s: %v", message.Type, message.Call.Id, err)
+		return err
+	}
+	if event.StreamEvent != "" {
+		notifyCallStreams(orgId)
+	}
+
+	// Alerts are raised before the call record is updated, so a human hears about the call as soon as possible
+	evaluateAlerts(orgId, message, event.CampaignId)
+
+	switch {
+	case message.Type == vapiTypes.MESSAGE_STATUS_UPDATE:
+		record := callRecordFromServerMessage(message)
+		if message.Status != "" {
+			record.Status = mongodbTypes.CallStatus(message.Status)
+		}
+		if _, err := mongodb.UpdateCallStatus(orgId, record); err != nil {
+			log.Printf("[Webhook] Error updating status of call %s: %v", message.Call.Id, err)
+			return err
+		}
+		if record.Status == mongodbTypes.CALL_STATUS_ENDED {
+			wakeCallDispatcher(orgId)
+		}
+	case message.Type == vapiTypes.MESSAGE_END_OF_CALL_REPORT:
+		record := callRecordFromServerMessage(message)
+		record.Status = mongodbTypes.CALL_STATUS_ENDED
+		if _, err := mongodb.UpsertCall(orgId, record); err != nil {
+			log.Printf("[Webhook] Error recording end of call %s: %v", message.Call.Id, err)
+			return err
+		}
+		// The call no longer holds a concurrency slot, so a queued call can take it
+		wakeCallDispatcher(orgId)
+		// Each step runs even if another fails, so a contact update failing doesn't lose the transcript or recording
+		var errs []error
+		if message.Artifact != nil {
+			if err := recordTranscript(orgId, message.Call.Id, message.Artifact.Transcript, transcriptMessagesFromServerMessage(message.Artifact)); err != nil {
+				log.Printf("[Webhook] Error recording transcript of call %s: %v", message.Call.Id, err)
+				errs = append(errs, err)
+			}
+			if err := ScheduleRecordingArchive(orgId, message.Call.Id, message.Artifact.RecordingUrl); err != nil {
+				log.Printf("[Webhook] Error scheduling recording archive of call %s: %v", message.Call.Id, err)
+				errs = append(errs, err)
+			}
+		}
+		if err := applyCallAnalysis(orgId, message); err != nil {
+			log.Printf("[Webhook] Error applying analysis of call %s: %v", message.Call.Id, err)
+			errs = append(errs, err)
+		}
+		if len(errs) > 0 {
+			return errors.Join(errs...)
+		}
+	case isTranscriptMessage(message.Type), message.Type == vapiTypes.MESSAGE_HANG:
+		// Stored as events only
+	default:
+		log.Printf("[Webhook] Stored unhandled server message type %s for call %s", message.Type, message.Call.Id)
+	}
+
+	return nil
+}
+
+// ResolveCallOrganization finds the organization that registered the given VapiAI assistant
+// or phone number. The assistant is checked first since every call has one.
+func ResolveCallOrganization(assistantId string, phoneNumberId string) (string, error) {
+	if orgId, ok := callOrganizations.Load("assistant:" + assistantId); ok && assistantId != "" {
+		return orgId.(string), nil
+	}
+	if orgId, ok := callOrganizations.Load("phone_number:" + phoneNumberId); ok && phoneNumberId != "" {
+		return orgId.(string), nil
+	}
+
+	orgIds, err := clerk.GetAllOrganizations()
+	if err != nil {
+		return "", err
+	}
+
+	for _, orgId := range orgIds {
+		if assistantId != "" {
+			_, err := mongodb.GetAssistantByVapiId(orgId, assistantId)
+			if err == nil {
+				callOrganizations.Store("assistant:"+assistantId, orgId)
+				return orgId, nil
+			}
+			if !errors.Is(err, mongo.ErrNoDocuments) {
+				return "", err
+			}
+		}
+
+		if phoneNumberId != "" {
+			_, err := mongodb.GetPhoneNumberByVapiId(orgId, phoneNumberId)
+			if err == nil {
+				callOrganizations.Store("phone_number:"+phoneNumberId, orgId)
+				return orgId, nil
+			}
+			if !errors.Is(err, mongo.ErrNoDocuments) {
+				return "", err
+			}
+		}
+	}
+
+	return "", ErrOrganizationNotFound
+}
+
+// callRecordFromServerMessage builds the call record carried by a server message.
+// Values reported at the top level of the message take precedence over the embedded call.
+func callRecordFromServerMessage(message vapiTypes.ServerMessage) mongodbTypes.Call {
+	call := message.Call
+
+	record := mongodbTypes.Call{
+		VapiCallId:    call.Id,
+		AssistantId:   call.AssistantId,
+		PhoneNumberId: call.PhoneNumberId,
+		Status:        mongodbTypes.CallStatus(call.Status),
+		EndedReason:   call.EndedReason,
+		CreatedAt:     call.CreatedAt,
+		StartedAt:     call.StartedAt,
+		EndedAt:       call.EndedAt,
+	}
+
+	if call.Customer != nil {
+		record.CustomerNumber = call.Customer.Number
+		record.CustomerName = call.Customer.Name
+	}
+	if call.Cost != nil {
+		record.Cost = *call.Cost
+	}
+
+	if message.EndedReason != "" {
+		record.EndedReason = message.EndedReason
+	}
+	if message.Cost != nil {
+		record.Cost = *message.Cost
+	}
+	if message.StartedAt != nil {
+		record.StartedAt = message.StartedAt
+	}
+	if message.EndedAt != nil {
+		record.EndedAt = message.EndedAt
+	}
+	if record.StartedAt != nil && record.EndedAt != nil {
+		record.DurationSeconds = record.EndedAt.Sub(*record.StartedAt).Seconds()
+	}
+	if message.Analysis != nil {
+		record.Summary = message.Analysis.Summary
+		if message.Analysis.SuccessEvaluation != nil {
+			record.SuccessEvaluation = fmt.Sprint(message.Analysis.SuccessEvaluation)
+			record.Successful = evaluationSuccessful(record.SuccessEvaluation)
+		}
+	}
+	if message.Artifact != nil {
+		record.Transcript = message.Artifact.Transcript
+	}
+
+	return record
+}
+
+// isTranscriptMessage reports whether the message type is a transcript,
+// including the `transcript[transcriptType="final"]` variant.
+func isTranscriptMessage(messageType vapiTypes.ServerMessageType) bool {
+	return strings.HasPrefix(string(messageType), string(vapiTypes.MESSAGE_TRANSCRIPT))
+}
+
+// messageTime returns when VapiAI emitted the message, falling back to now.
+func messageTime(message vapiTypes.ServerMessage) time.Time {
+	if message.Timestamp <= 0 {
+		return time.Now()
+	}
+	return time.UnixMilli(int64(message.Timestamp))
+}
@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when no blob is stored under the requested key.
+var ErrNotFound = errors.New("blob not found")
+
+// ErrInvalidKey is returned for keys that are empty, absolute or escape the store with "..".
+var ErrInvalidKey = errors.New("invalid blob key")
+
+// BlobStore stores opaque binary objects (e.g., call recordings) under slash-separated keys
+// such as "<orgId>/recordings/<callId>.wav". Implementations must be safe for concurrent use.
+type BlobStore interface {
+	// Put stores the content read from r under key, replacing any existing blob,
+	// and returns the number of bytes written
+	Put(ctx context.Context, key string, r io.Reader, contentType string) (int64, error)
+
+	// Open returns the blob stored under key, or ErrNotFound
+	Open(ctx context.Context, key string) (Blob, error)
+
+	// Delete removes the blob stored under key. Deleting a missing blob is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// Blob is a stored object opened for reading. It is seekable so it can be served with range requests.
+type Blob interface {
+	io.ReadSeekCloser
+
+	// Size is the length of the blob in bytes
+	Size() int64
+
+	// ModTime is when the blob was stored
+	ModTime() time.Time
+
+	// ContentType is the MIME type given when the blob was stored, empty if unknown
+	ContentType() string
+}
+
+// NewFromEnv creates the blob store configured by the STORAGE_BACKEND environment variable.
+// Only "local" (the default) is supported for now; it stores blobs under STORAGE_LOCAL_PATH,
+// which defaults to "./data/blobs".
+func NewFromEnv() (BlobStore, error) {
+	switch backend := os.Getenv("STORAGE_BACKEND"); backend {
+	case "", "local":
+		root := os.Getenv("STORAGE_LOCAL_PATH")
+		if root == "" {
+			root = "./data/blobs"
+		}
+		return NewLocalStore(root)
+	default:
+		return nil, fmt.Errorf("unsupported storage backend %q", backend)
+	}
+}
+
+// cleanKey validates a blob key and returns it in canonical form.
+func cleanKey(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+
+	cleaned := path.Clean(key)
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", ErrInvalidKey
+	}
+
+	return cleaned, nil
+}
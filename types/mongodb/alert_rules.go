@@ -0,0 +1,142 @@
+package mongodb
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// AlertConditionType is what an alert rule looks for in a call
+type AlertConditionType string
+
+const (
+	// ALERT_CONDITION_KEYWORD matches transcripts containing one of the rule's keywords as whole words, ignoring case
+	ALERT_CONDITION_KEYWORD AlertConditionType = "keyword"
+
+	// ALERT_CONDITION_REGEX matches transcripts matching the rule's regular expression
+	ALERT_CONDITION_REGEX AlertConditionType = "regex"
+
+	// ALERT_CONDITION_NEGATIVE_SCORE matches calls whose analysis score is at or below the rule's threshold
+	ALERT_CONDITION_NEGATIVE_SCORE AlertConditionType = "negative_score"
+)
+
+// AlertActionType is what happens when an alert rule triggers
+type AlertActionType string
+
+const (
+	// ALERT_ACTION_WEBHOOK posts the alert as JSON to a URL
+	ALERT_ACTION_WEBHOOK AlertActionType = "webhook"
+
+	// ALERT_ACTION_EMAIL emails the alert through the SMTP server configured with SMTP_HOST
+	ALERT_ACTION_EMAIL AlertActionType = "email"
+
+	// ALERT_ACTION_FLAG adds a flag to the call record
+	ALERT_ACTION_FLAG AlertActionType = "flag"
+)
+
+// AlertSource is the part of a call an alert rule matched
+type AlertSource string
+
+const (
+	// ALERT_SOURCE_LIVE_TRANSCRIPT is a transcript sent by VapiAI while the call is in progress
+	ALERT_SOURCE_LIVE_TRANSCRIPT AlertSource = "live_transcript"
+
+	// ALERT_SOURCE_FINAL_TRANSCRIPT is the full transcript of the end-of-call report
+	ALERT_SOURCE_FINAL_TRANSCRIPT AlertSource = "final_transcript"
+
+	// ALERT_SOURCE_ANALYSIS is the analysis of the end-of-call report
+	ALERT_SOURCE_ANALYSIS AlertSource = "analysis"
+
+	// ALERT_SOURCE_TEST is a sample sent to /alerts/test
+	ALERT_SOURCE_TEST AlertSource = "test"
+)
+
+// AlertRule is a rule of an organization raising an alert when a call's transcript or analysis matches its condition.
+// A rule triggers at most once per call.
+type AlertRule struct {
+	// Id is the unique MongoDB ObjectID for this rule
+	Id bson.ObjectID `json:"id" bson:"_id,omitempty"`
+
+	// Name describes the rule in alerts (e.g., "Legal threat")
+	Name string `json:"name" bson:"name"`
+
+	// Enabled is whether calls are checked against the rule
+	Enabled bool `json:"enabled" bson:"enabled"`
+
+	// Condition is what the rule looks for
+	Condition AlertCondition `json:"condition" bson:"condition"`
+
+	// Actions are run, in order, when the rule triggers
+	Actions []AlertAction `json:"actions" bson:"actions"`
+
+	// TriggerCount is the number of calls the rule triggered on
+	TriggerCount int64 `json:"trigger_count" bson:"trigger_count"`
+
+	// LastTriggeredAt is when the rule last triggered, nil if it never did
+	LastTriggeredAt *time.Time `json:"last_triggered_at" bson:"last_triggered_at"`
+
+	// CreatedAt is when the rule was created
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+
+	// UpdatedAt is when the rule was last changed
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// AlertCondition is what an alert rule looks for in a call.
+type AlertCondition struct {
+	// Type is the kind of condition
+	Type AlertConditionType `json:"type" bson:"type"`
+
+	// Keywords are the words or phrases matched by keyword conditions (e.g., ["lawyer", "complaint", "cancel"])
+	Keywords []string `json:"keywords,omitempty" bson:"keywords,omitempty"`
+
+	// Pattern is the regular expression matched by regex conditions, in RE2 syntax
+	Pattern string `json:"pattern,omitempty" bson:"pattern,omitempty"`
+
+	// Role only matches what this side of the call said, "user" for the customer or "assistant".
+	// Empty matches both. Only used by keyword and regex conditions.
+	Role string `json:"role,omitempty" bson:"role,omitempty"`
+
+	// Field is the analysis field holding the score of negative_score conditions:
+	// success_evaluation or structured_data.<key>
+	Field string `json:"field,omitempty" bson:"field,omitempty"`
+
+	// Threshold is the score at or below which negative_score conditions match
+	Threshold float64 `json:"threshold" bson:"threshold"`
+}
+
+// AlertAction is something done when an alert rule triggers.
+type AlertAction struct {
+	// Type is the kind of action
+	Type AlertActionType `json:"type" bson:"type"`
+
+	// Url is where webhook actions post the alert
+	Url string `json:"url,omitempty" bson:"url,omitempty"`
+
+	// Secret signs the body of webhook actions, sent as the hex HMAC-SHA256 in the X-Sarah-Signature header
+	Secret string `json:"secret,omitempty" bson:"secret,omitempty"`
+
+	// Recipients are the email addresses email actions send the alert to
+	Recipients []string `json:"recipients,omitempty" bson:"recipients,omitempty"`
+}
+
+// AlertTrigger records an alert rule triggering on a call, so the rule triggers once per call.
+type AlertTrigger struct {
+	// Id is the unique MongoDB ObjectID for this trigger
+	Id bson.ObjectID `json:"id" bson:"_id,omitempty"`
+
+	// RuleId is the rule that triggered
+	RuleId bson.ObjectID `json:"rule_id" bson:"rule_id"`
+
+	// VapiCallId is the VapiAI call the rule triggered on
+	VapiCallId string `json:"vapi_call_id" bson:"vapi_call_id"`
+
+	// Source is the part of the call that matched
+	Source AlertSource `json:"source" bson:"source"`
+
+	// Match is the text or score that matched
+	Match string `json:"match" bson:"match"`
+
+	// CreatedAt is when the rule triggered
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
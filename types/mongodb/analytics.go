@@ -0,0 +1,83 @@
+package mongodb
+
+import "time"
+
+// CampaignAnalytics is the aggregated view of every call placed by a campaign
+// within a date range, along with the same metrics split into time buckets.
+type CampaignAnalytics struct {
+	// CampaignId is the hex ObjectID of the campaign the analytics belong to
+	CampaignId string `json:"campaign_id" bson:"campaign_id"`
+
+	// From is the inclusive lower bound of the analyzed range, nil if unbounded
+	From *time.Time `json:"from" bson:"from"`
+
+	// To is the exclusive upper bound of the analyzed range, nil if unbounded
+	To *time.Time `json:"to" bson:"to"`
+
+	// Interval is the size of each bucket in Buckets
+	Interval AnalyticsInterval `json:"interval" bson:"interval"`
+
+	// Totals are the metrics computed over the whole range
+	Totals CallMetrics `json:"totals" bson:"totals"`
+
+	// EndedReasons counts calls per VapiAI ended reason, most frequent first
+	EndedReasons []EndedReasonCount `json:"ended_reasons" bson:"ended_reasons"`
+
+	// Buckets are the metrics computed per interval, oldest first
+	Buckets []AnalyticsBucket `json:"buckets" bson:"buckets"`
+}
+
+// CallMetrics are the counters computed for a set of calls.
+type CallMetrics struct {
+	// CustomersCalled is the number of distinct customer phone numbers called
+	CustomersCalled int `json:"customers_called" bson:"customers_called"`
+
+	// CallsPlaced is the number of calls created
+	CallsPlaced int `json:"calls_placed" bson:"calls_placed"`
+
+	// CallsAnswered is the number of calls where the customer spoke with the assistant
+	CallsAnswered int `json:"calls_answered" bson:"calls_answered"`
+
+	// SuccessfulCalls is the number of calls whose success evaluation was positive
+	SuccessfulCalls int `json:"successful_calls" bson:"successful_calls"`
+
+	// SuccessRate is SuccessfulCalls divided by CallsAnswered (0 when nothing was answered)
+	SuccessRate float64 `json:"success_rate" bson:"success_rate"`
+
+	// TotalDurationSeconds is the sum of all call durations
+	TotalDurationSeconds float64 `json:"total_duration_seconds" bson:"total_duration_seconds"`
+
+	// AverageDurationSeconds is the mean duration of answered calls
+	AverageDurationSeconds float64 `json:"average_duration_seconds" bson:"average_duration_seconds"`
+
+	// TotalCost is the sum of all call costs in USD
+	TotalCost float64 `json:"total_cost" bson:"total_cost"`
+}
+
+// AnalyticsBucket holds the metrics of the calls created within one interval.
+type AnalyticsBucket struct {
+	// Start is the beginning of the interval in the campaign's timezone
+	Start time.Time `json:"start" bson:"_id"`
+
+	CallMetrics `bson:",inline"`
+}
+
+// EndedReasonCount is the number of calls that ended for a given reason.
+type EndedReasonCount struct {
+	// Reason is the VapiAI ended reason
+	Reason string `json:"reason" bson:"_id"`
+
+	// Count is the number of calls that ended with Reason
+	Count int `json:"count" bson:"count"`
+}
+
+// AnalyticsInterval defines the size of the buckets analytics are grouped into.
+type AnalyticsInterval string
+
+const (
+	// INTERVAL_DAY groups calls by calendar day
+	INTERVAL_DAY AnalyticsInterval = "day"
+
+	// INTERVAL_WEEK groups calls by calendar week
+	INTERVAL_WEEK AnalyticsInterval = "week"
+)
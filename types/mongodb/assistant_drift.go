@@ -0,0 +1,81 @@
+package mongodb
+
+import "time"
+
+// ASSISTANT_DRIFT_REPORT_ID is the _id of the single assistant drift report of an organization
+const ASSISTANT_DRIFT_REPORT_ID = "latest"
+
+// AssistantDriftReport compares the assistant records of an organization with the assistants in VapiAI.
+// Assistants renamed or deleted in the VapiAI dashboard leave the records stale; the report lists every
+// difference found and the repairs that can fix it. Only the latest report of an organization is kept.
+type AssistantDriftReport struct {
+	// Id is always ASSISTANT_DRIFT_REPORT_ID
+	Id string `json:"-" bson:"_id"`
+
+	// Trigger is what produced the report: "scheduled" for the background job, "manual" for the API
+	Trigger string `json:"trigger" bson:"trigger"`
+
+	// Checked is the number of assistants looked up in VapiAI
+	Checked int `json:"checked" bson:"checked"`
+
+	// Issues are the differences found, empty when the records match VapiAI
+	Issues []AssistantDrift `json:"issues" bson:"issues"`
+
+	// Error explains why the check stopped early, e.g. because VapiAI is unavailable
+	Error string `json:"error,omitempty" bson:"error,omitempty"`
+
+	// CheckedAt is when the check ran
+	CheckedAt time.Time `json:"checked_at" bson:"checked_at"`
+}
+
+// AssistantDrift is a single difference between an organization's assistant records and VapiAI.
+type AssistantDrift struct {
+	// Kind is what differs
+	Kind AssistantDriftKind `json:"kind" bson:"kind"`
+
+	// VapiAssistantId is the VapiAI assistant the difference is about
+	VapiAssistantId string `json:"vapi_assistant_id" bson:"vapi_assistant_id"`
+
+	// Name is the name in the assistant record, empty for orphans
+	Name string `json:"name" bson:"name"`
+
+	// VapiName is the name of the assistant in VapiAI, empty for deleted assistants
+	VapiName string `json:"vapi_name" bson:"vapi_name"`
+
+	// CampaignIds are the hex ObjectIDs of the organization's campaigns using the assistant
+	CampaignIds []string `json:"campaign_ids" bson:"campaign_ids"`
+
+	// Repairs are the actions that fix the difference
+	Repairs []AssistantRepair `json:"repairs" bson:"repairs"`
+}
+
+// AssistantDriftKind is the kind of difference between an assistant record and VapiAI.
+type AssistantDriftKind string
+
+const (
+	// DRIFT_DELETED indicates the assistant is registered but was deleted in VapiAI
+	DRIFT_DELETED AssistantDriftKind = "deleted"
+
+	// DRIFT_NAME indicates the assistant was renamed in VapiAI, or its record was
+	DRIFT_NAME AssistantDriftKind = "name"
+
+	// DRIFT_ORPHAN indicates campaigns use an assistant that exists in VapiAI but isn't registered
+	DRIFT_ORPHAN AssistantDriftKind = "orphan"
+)
+
+// AssistantRepair is an action that fixes a difference between an assistant record and VapiAI.
+type AssistantRepair string
+
+const (
+	// REPAIR_PULL_NAME copies the VapiAI name of the assistant into its record
+	REPAIR_PULL_NAME AssistantRepair = "pull_name"
+
+	// REPAIR_PUSH_NAME renames the assistant in VapiAI after its record
+	REPAIR_PUSH_NAME AssistantRepair = "push_name"
+
+	// REPAIR_UNREGISTER deletes the record of an assistant deleted in VapiAI
+	REPAIR_UNREGISTER AssistantRepair = "unregister"
+
+	// REPAIR_REGISTER registers an orphan assistant with its VapiAI name
+	REPAIR_REGISTER AssistantRepair = "register"
+)
@@ -0,0 +1,49 @@
+package mongodb
+
+import "time"
+
+// AssistantTemplate is a parameterized VapiAI assistant configuration an assistant can be created from.
+// Templates are either built into Sarah and shared by every organization, or stored by an organization.
+// Strings of the assistant can contain {{placeholders}} naming the template's parameters, which are
+// replaced by the values given when the template is rendered. Other placeholders are left for VapiAI,
+// which fills them in from the variables of each call.
+type AssistantTemplate struct {
+	// Id identifies the template: a hex ObjectID for organization templates, a slug for built-in ones
+	Id string `json:"id" bson:"_id"`
+
+	// Name describes the template (e.g., "Insurance renewal reminder")
+	Name string `json:"name" bson:"name"`
+
+	// Description explains what assistants created from the template do
+	Description string `json:"description" bson:"description"`
+
+	// Parameters are the placeholders of the template
+	Parameters []TemplateParameter `json:"parameters" bson:"parameters"`
+
+	// Assistant is the VapiAI create assistant request, in the format of POST /assistants/create, with placeholders
+	Assistant map[string]interface{} `json:"assistant" bson:"assistant"`
+
+	// Builtin indicates the template is built into Sarah and can't be changed
+	Builtin bool `json:"builtin" bson:"-"`
+
+	// CreatedBy is the Clerk user ID that created an organization template
+	CreatedBy string `json:"created_by,omitempty" bson:"created_by"`
+
+	// CreatedAt is when an organization template was created
+	CreatedAt time.Time `json:"created_at,omitzero" bson:"created_at"`
+
+	// UpdatedAt is when an organization template was last changed
+	UpdatedAt time.Time `json:"updated_at,omitzero" bson:"updated_at"`
+}
+
+// TemplateParameter is a placeholder of an assistant template.
+type TemplateParameter struct {
+	// Name is the placeholder, used as {{name}} in the assistant (e.g., "company_name")
+	Name string `json:"name" bson:"name"`
+
+	// Description explains the value expected
+	Description string `json:"description" bson:"description"`
+
+	// Default is used when no value is given; parameters without a default are required
+	Default string `json:"default,omitempty" bson:"default,omitempty"`
+}
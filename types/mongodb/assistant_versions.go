@@ -0,0 +1,83 @@
+package mongodb
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// AssistantVersion is a snapshot of the full VapiAI configuration of an assistant.
+// A version is stored every time the assistant is created, registered, updated or rolled back,
+// so a bad edit can be compared with earlier configurations and undone.
+type AssistantVersion struct {
+	// Id is the unique MongoDB ObjectID for this version
+	Id bson.ObjectID `json:"id" bson:"_id,omitempty"`
+
+	// VapiAssistantId is the VapiAI assistant the snapshot is of
+	VapiAssistantId string `json:"vapi_assistant_id" bson:"vapi_assistant_id"`
+
+	// Version numbers the snapshots of an assistant, starting at 1
+	Version int `json:"version" bson:"version"`
+
+	// Action is the change that produced the snapshot
+	Action AssistantVersionAction `json:"action" bson:"action"`
+
+	// Config is the assistant as returned by VapiAI
+	Config map[string]interface{} `json:"config" bson:"config"`
+
+	// AuthorId is the Clerk user ID that made the change, or the system caller that made it
+	AuthorId string `json:"author_id" bson:"author_id"`
+
+	// RestoredVersion is the version re-applied by a rollback
+	RestoredVersion int `json:"restored_version,omitempty" bson:"restored_version,omitempty"`
+
+	// CreatedAt is when the snapshot was taken
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+// AssistantVersionAction is the change that produced an assistant version.
+type AssistantVersionAction string
+
+const (
+	// VERSION_CREATE indicates the assistant was created through the API
+	VERSION_CREATE AssistantVersionAction = "create"
+
+	// VERSION_REGISTER indicates an existing VapiAI assistant was registered
+	VERSION_REGISTER AssistantVersionAction = "register"
+
+	// VERSION_UPDATE indicates the assistant was updated
+	VERSION_UPDATE AssistantVersionAction = "update"
+
+	// VERSION_ROLLBACK indicates an earlier version was re-applied
+	VERSION_ROLLBACK AssistantVersionAction = "rollback"
+
+	// VERSION_BASELINE is the configuration found in VapiAI before the first change of an assistant without versions
+	VERSION_BASELINE AssistantVersionAction = "baseline"
+)
+
+// AssistantVersionDiff lists the configuration fields that differ between two versions of an assistant.
+type AssistantVersionDiff struct {
+	// VapiAssistantId is the VapiAI assistant compared
+	VapiAssistantId string `json:"vapi_assistant_id"`
+
+	// From is the older version compared
+	From int `json:"from"`
+
+	// To is the newer version compared
+	To int `json:"to"`
+
+	// Changes are the differing fields, sorted by path
+	Changes []AssistantConfigChange `json:"changes"`
+}
+
+// AssistantConfigChange is a configuration field that differs between two versions of an assistant.
+type AssistantConfigChange struct {
+	// Path is the field, with nested fields separated by dots and list items indexed, e.g. model.messages[0].content
+	Path string `json:"path"`
+
+	// Old is the value in the older version, absent if the field was added
+	Old interface{} `json:"old,omitempty"`
+
+	// New is the value in the newer version, absent if the field was removed
+	New interface{} `json:"new,omitempty"`
+}
@@ -0,0 +1,45 @@
+package mongodb
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// AuditEntry records an action attempted by a user or a background job on an organization resource.
+type AuditEntry struct {
+	// Id is the unique MongoDB ObjectID for this entry
+	Id bson.ObjectID `json:"id" bson:"_id,omitempty"`
+
+	// Action is what was attempted (e.g., "assistants.update", "calls.create")
+	Action string `json:"action" bson:"action"`
+
+	// ResourceType is the kind of resource the action targeted (e.g., "assistant", "phone_number")
+	ResourceType string `json:"resource_type" bson:"resource_type"`
+
+	// ResourceId is the identifier of the targeted resource as provided by the caller
+	ResourceId string `json:"resource_id" bson:"resource_id"`
+
+	// UserId is the Clerk user ID of the caller, or the name of the background job
+	UserId string `json:"user_id" bson:"user_id"`
+
+	// Outcome is the result of the attempt
+	Outcome AuditOutcome `json:"outcome" bson:"outcome"`
+
+	// Reason explains the outcome (e.g., "resource not found")
+	Reason string `json:"reason" bson:"reason"`
+
+	// CreatedAt is when the attempt was made
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+// AuditOutcome defines the possible results of an audited action.
+type AuditOutcome string
+
+const (
+	// AUDIT_ALLOWED indicates the action was performed
+	AUDIT_ALLOWED AuditOutcome = "allowed"
+
+	// AUDIT_DENIED indicates the action was rejected because the caller doesn't own the resource
+	AUDIT_DENIED AuditOutcome = "denied"
+)
@@ -0,0 +1,52 @@
+package mongodb
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// CallEvent represents a server message received from VapiAI about a call.
+// Events are stored as they arrive so call outcomes can be reconstructed
+// and reprocessed later.
+type CallEvent struct {
+	// Id is the unique MongoDB ObjectID for this event
+	Id bson.ObjectID `json:"id" bson:"_id,omitempty"`
+
+	// VapiCallId is the VapiAI call the event is about
+	VapiCallId string `json:"vapi_call_id" bson:"vapi_call_id"`
+
+	// AssistantId is the VapiAI assistant that handled the call
+	AssistantId string `json:"assistant_id" bson:"assistant_id"`
+
+	// CampaignId is the hex ObjectID of the campaign that placed the call, if the call was recorded by then
+	CampaignId string `json:"campaign_id" bson:"campaign_id"`
+
+	// Type is the VapiAI server message type (e.g., "status-update", "end-of-call-report")
+	Type string `json:"type" bson:"type"`
+
+	// StreamEvent is the name the event is streamed under on /calls/stream (e.g., "ringing", "transcript"),
+	// empty for events that aren't streamed
+	StreamEvent string `json:"stream_event,omitempty" bson:"stream_event,omitempty"`
+
+	// Status is the call status reported by the event, if any
+	Status string `json:"status" bson:"status"`
+
+	// EndedReason is the ended reason reported by the event, if any
+	EndedReason string `json:"ended_reason" bson:"ended_reason"`
+
+	// Role is who spoke ("user" or "assistant"), set on final transcript events
+	Role string `json:"role,omitempty" bson:"role,omitempty"`
+
+	// Transcript is the transcribed speech, set on final transcript events
+	Transcript string `json:"transcript,omitempty" bson:"transcript,omitempty"`
+
+	// Payload is the full message as sent by VapiAI
+	Payload map[string]interface{} `json:"payload" bson:"payload"`
+
+	// OccurredAt is when VapiAI emitted the event
+	OccurredAt time.Time `json:"occurred_at" bson:"occurred_at"`
+
+	// ReceivedAt is when Sarah received the event
+	ReceivedAt time.Time `json:"received_at" bson:"received_at"`
+}
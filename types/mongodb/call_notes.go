@@ -0,0 +1,25 @@
+package mongodb
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// CallNote is a free-text note an agent wrote on a call when reviewing it.
+type CallNote struct {
+	// Id is the unique MongoDB ObjectID for this note
+	Id bson.ObjectID `json:"id" bson:"_id,omitempty"`
+
+	// VapiCallId is the VapiAI call the note is about
+	VapiCallId string `json:"vapi_call_id" bson:"vapi_call_id"`
+
+	// AuthorId is the Clerk user ID of the agent who wrote the note
+	AuthorId string `json:"author_id" bson:"author_id"`
+
+	// Text is the content of the note
+	Text string `json:"text" bson:"text"`
+
+	// CreatedAt is when the note was written
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
@@ -0,0 +1,126 @@
+package mongodb
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// QueuedCall is an outbound call waiting in an organization's call queue.
+// Calls are queued when they are created and sent to VapiAI one by one by the call dispatcher,
+// as the concurrency limits of the organization, the phone number and the platform allow.
+type QueuedCall struct {
+	// Id is the unique MongoDB ObjectID for this queued call
+	Id bson.ObjectID `json:"id" bson:"_id,omitempty"`
+
+	// AssistantId is the VapiAI assistant that will handle the call
+	AssistantId string `json:"assistant_id" bson:"assistant_id"`
+
+	// PhoneNumberId is the VapiAI phone number the call will be placed from
+	PhoneNumberId string `json:"phone_number_id" bson:"phone_number_id"`
+
+	// Customer is the customer to call, with its number in E.164 format
+	Customer Customer `json:"customer" bson:"customer"`
+
+	// CampaignId is the hex ObjectID of the campaign that queued the call, empty for calls created through the API
+	CampaignId string `json:"campaign_id" bson:"campaign_id"`
+
+	// RunId is the campaign run the call belongs to, nil for calls created through the API
+	RunId *bson.ObjectID `json:"run_id" bson:"run_id"`
+
+	// CallerIdStrategy is how the phone number was chosen from the campaign's pool, empty without a pool
+	CallerIdStrategy CallerIdStrategy `json:"caller_id_strategy" bson:"caller_id_strategy"`
+
+	// Status is where the call is in the queue
+	Status QueueStatus `json:"status" bson:"status"`
+
+	// VapiCallId is the VapiAI call placed for this entry, set once it is dispatched
+	VapiCallId string `json:"vapi_call_id" bson:"vapi_call_id"`
+
+	// Attempts is the number of times the call was sent to VapiAI
+	Attempts int `json:"attempts" bson:"attempts"`
+
+	// Error is the last error VapiAI returned for the call
+	Error string `json:"error" bson:"error"`
+
+	// EnqueuedAt is when the call was queued
+	EnqueuedAt time.Time `json:"enqueued_at" bson:"enqueued_at"`
+
+	// DispatchedAt is when the call was sent to VapiAI, nil until it is
+	DispatchedAt *time.Time `json:"dispatched_at" bson:"dispatched_at"`
+
+	// UpdatedAt is when the entry last changed status
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// QueueStatus defines the states of a queued call.
+type QueueStatus string
+
+const (
+	// QUEUE_STATUS_QUEUED indicates the call is waiting for a free slot
+	QUEUE_STATUS_QUEUED QueueStatus = "queued"
+
+	// QUEUE_STATUS_DISPATCHING indicates the dispatcher is sending the call to VapiAI
+	QUEUE_STATUS_DISPATCHING QueueStatus = "dispatching"
+
+	// QUEUE_STATUS_DISPATCHED indicates VapiAI accepted the call
+	QUEUE_STATUS_DISPATCHED QueueStatus = "dispatched"
+
+	// QUEUE_STATUS_FAILED indicates VapiAI rejected the call on every attempt, or that the call may have
+	// been placed without Sarah knowing its VapiAI call, and needs a review before the customer is called again
+	QUEUE_STATUS_FAILED QueueStatus = "failed"
+
+	// QUEUE_STATUS_CANCELLED indicates the call was removed from the queue before it was dispatched
+	QUEUE_STATUS_CANCELLED QueueStatus = "cancelled"
+)
+
+// QueuedCallPage is a single page of an organization's call queue, oldest first.
+type QueuedCallPage struct {
+	// Calls are the queued calls on this page
+	Calls []QueuedCall `json:"calls"`
+
+	// Total is the number of queued calls matching the filter
+	Total int64 `json:"total"`
+
+	// Page is the 1-based page number
+	Page int `json:"page"`
+
+	// Limit is the maximum number of calls per page
+	Limit int `json:"limit"`
+}
+
+// QueueStats is the state of an organization's call queue and of the calls it has in flight.
+type QueueStats struct {
+	// Depth is the number of calls waiting to be dispatched
+	Depth int64 `json:"depth"`
+
+	// ByStatus is the number of queued calls in each status
+	ByStatus map[QueueStatus]int64 `json:"by_status"`
+
+	// DepthByPhoneNumber is the number of calls waiting to be dispatched from each phone number
+	DepthByPhoneNumber map[string]int64 `json:"depth_by_phone_number"`
+
+	// OldestEnqueuedAt is when the longest waiting call was queued, nil if none is waiting
+	OldestEnqueuedAt *time.Time `json:"oldest_enqueued_at"`
+
+	// Active is the number of calls of the organization VapiAI has not ended yet
+	Active int `json:"active"`
+
+	// ActiveByPhoneNumber is the number of active calls placed from each phone number
+	ActiveByPhoneNumber map[string]int `json:"active_by_phone_number"`
+
+	// Limits are the concurrency limits that apply to the organization
+	Limits QueueLimits `json:"limits"`
+}
+
+// QueueLimits are the maximum numbers of concurrent calls, 0 meaning no limit.
+type QueueLimits struct {
+	// Global is the limit shared by every organization
+	Global int `json:"global"`
+
+	// Organization is the limit of the organization
+	Organization int `json:"organization"`
+
+	// PhoneNumbers are the limits of the organization's phone numbers that have one
+	PhoneNumbers map[string]int `json:"phone_numbers"`
+}
@@ -0,0 +1,89 @@
+package mongodb
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Callback is a call a customer asked to receive later, registered by the assistant during a call
+// through the callback tool. Once it is due, the callback scheduler queues it with the assistant
+// and phone number of the original call.
+type Callback struct {
+	// Id is the unique MongoDB ObjectID for this callback
+	Id bson.ObjectID `json:"id" bson:"_id,omitempty"`
+
+	// ToolCallId is the VapiAI tool call that registered the callback, so a retried tool call registers it once
+	ToolCallId string `json:"tool_call_id" bson:"tool_call_id"`
+
+	// VapiCallId is the VapiAI call during which the customer asked for the callback
+	VapiCallId string `json:"vapi_call_id" bson:"vapi_call_id"`
+
+	// ContactId is the contact with the customer's phone number, nil if the organization has none
+	ContactId *bson.ObjectID `json:"contact_id" bson:"contact_id"`
+
+	// AssistantId is the VapiAI assistant of the original call, which also handles the callback
+	AssistantId string `json:"assistant_id" bson:"assistant_id"`
+
+	// PhoneNumberId is the VapiAI phone number of the original call, which the callback is placed from
+	PhoneNumberId string `json:"phone_number_id" bson:"phone_number_id"`
+
+	// Customer is the customer to call back, with its number in E.164 format
+	Customer Customer `json:"customer" bson:"customer"`
+
+	// CustomerName is the customer's name as known to VapiAI, if any
+	CustomerName string `json:"customer_name" bson:"customer_name"`
+
+	// ScheduledAt is when the customer asked to be called back
+	ScheduledAt time.Time `json:"scheduled_at" bson:"scheduled_at"`
+
+	// Reason is why the customer asked to be called back, as summarized by the assistant
+	Reason string `json:"reason" bson:"reason"`
+
+	// Status is where the callback is in its lifecycle
+	Status CallbackStatus `json:"status" bson:"status"`
+
+	// QueuedCallId is the call queue entry of the callback, set once it is queued
+	QueuedCallId *bson.ObjectID `json:"queued_call_id" bson:"queued_call_id"`
+
+	// Error is why the callback could not be queued
+	Error string `json:"error" bson:"error"`
+
+	// CreatedAt is when the callback was registered
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+
+	// UpdatedAt is when the callback last changed status
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// CallbackStatus defines the states of a callback.
+type CallbackStatus string
+
+const (
+	// CALLBACK_STATUS_SCHEDULED indicates the callback is waiting for its time
+	CALLBACK_STATUS_SCHEDULED CallbackStatus = "scheduled"
+
+	// CALLBACK_STATUS_QUEUED indicates the callback was added to the call queue
+	CALLBACK_STATUS_QUEUED CallbackStatus = "queued"
+
+	// CALLBACK_STATUS_FAILED indicates the callback could not be queued
+	CALLBACK_STATUS_FAILED CallbackStatus = "failed"
+
+	// CALLBACK_STATUS_CANCELLED indicates the callback was cancelled before it was queued
+	CALLBACK_STATUS_CANCELLED CallbackStatus = "cancelled"
+)
+
+// CallbackPage is a single page of an organization's callbacks, soonest first.
+type CallbackPage struct {
+	// Callbacks are the callbacks on this page
+	Callbacks []Callback `json:"callbacks"`
+
+	// Total is the number of callbacks matching the filter
+	Total int64 `json:"total"`
+
+	// Page is the 1-based page number
+	Page int `json:"page"`
+
+	// Limit is the maximum number of callbacks per page
+	Limit int `json:"limit"`
+}
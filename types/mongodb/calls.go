@@ -0,0 +1,207 @@
+package mongodb
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Call represents a call placed through VapiAI and stored in the organization's database.
+// Keeping a local record of every call allows campaigns to be measured and aggregated
+// without fetching the full call history from VapiAI on every request.
+type Call struct {
+	// Id is the unique MongoDB ObjectID for this call record
+	Id bson.ObjectID `json:"id" bson:"_id,omitempty"`
+
+	// VapiCallId is the unique identifier for the call in VapiAI
+	VapiCallId string `json:"vapi_call_id" bson:"vapi_call_id"`
+
+	// AssistantId is the VapiAI assistant ID that handled the call
+	AssistantId string `json:"assistant_id" bson:"assistant_id"`
+
+	// PhoneNumberId is the VapiAI phone number ID the call was placed from
+	PhoneNumberId string `json:"phone_number_id" bson:"phone_number_id"`
+
+	// CampaignId is the hex ObjectID of the campaign that placed the call
+	// Empty for calls created directly through the API
+	CampaignId string `json:"campaign_id" bson:"campaign_id"`
+
+	// CallerIdStrategy is how PhoneNumberId was chosen from the campaign's phone number pool
+	// Empty for calls placed from a single phone number
+	CallerIdStrategy CallerIdStrategy `json:"caller_id_strategy" bson:"caller_id_strategy"`
+
+	// CustomerNumber is the phone number of the customer that was called
+	CustomerNumber string `json:"customer_number" bson:"customer_number"`
+
+	// CustomerName is the name of the customer that was called, if known
+	CustomerName string `json:"customer_name" bson:"customer_name"`
+
+	// Status is the last known VapiAI status of the call
+	Status CallStatus `json:"status" bson:"status"`
+
+	// EndedReason is the VapiAI reason the call ended (e.g., "customer-ended-call")
+	EndedReason string `json:"ended_reason" bson:"ended_reason"`
+
+	// Cost is the total cost of the call in USD as reported by VapiAI
+	Cost float64 `json:"cost" bson:"cost"`
+
+	// DurationSeconds is the time between the call starting and ending
+	DurationSeconds float64 `json:"duration_seconds" bson:"duration_seconds"`
+
+	// SuccessEvaluation is the VapiAI analysis success evaluation (e.g., "true", "false", "8")
+	SuccessEvaluation string `json:"success_evaluation" bson:"success_evaluation"`
+
+	// Successful is SuccessEvaluation normalized across VapiAI rubrics, nil if the call wasn't evaluated
+	// or its evaluation isn't recognized
+	Successful *bool `json:"successful" bson:"successful"`
+
+	// Summary is the VapiAI analysis summary of the conversation
+	Summary string `json:"summary" bson:"summary"`
+
+	// Transcript is the full transcript of the conversation
+	Transcript string `json:"transcript" bson:"transcript"`
+
+	// Flags are the alerts raised on the call by alert rules with a flag action
+	Flags []CallFlag `json:"flags" bson:"flags,omitempty"`
+
+	// Tags are the labels agents gave the call when reviewing it, lowercase
+	Tags []string `json:"tags" bson:"tags,omitempty"`
+
+	// Disposition is the code of the outcome agents gave the call, from the organization's disposition taxonomy
+	// Empty until a disposition is set
+	Disposition string `json:"disposition" bson:"disposition,omitempty"`
+
+	// DispositionBy is the Clerk user ID of the agent who last set the disposition
+	DispositionBy string `json:"disposition_by" bson:"disposition_by,omitempty"`
+
+	// DispositionAt is when the disposition was last set, nil if it never was
+	DispositionAt *time.Time `json:"disposition_at" bson:"disposition_at,omitempty"`
+
+	// NoteCount is the number of notes agents wrote on the call
+	NoteCount int `json:"note_count" bson:"note_count,omitempty"`
+
+	// CreatedAt is when the call was created in VapiAI
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+
+	// StartedAt is when the call started, nil if it never started
+	StartedAt *time.Time `json:"started_at" bson:"started_at"`
+
+	// EndedAt is when the call ended, nil if it has not ended yet
+	EndedAt *time.Time `json:"ended_at" bson:"ended_at"`
+
+	// UpdatedAt is when the call record was last updated
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// CallFilter narrows down the calls returned when listing an organization's calls.
+// Empty fields are ignored.
+type CallFilter struct {
+	// AssistantId only matches calls handled by this VapiAI assistant
+	AssistantId string `json:"assistant_id"`
+
+	// PhoneNumberId only matches calls placed from this VapiAI phone number
+	PhoneNumberId string `json:"phone_number_id"`
+
+	// CampaignId only matches calls placed by this campaign
+	CampaignId string `json:"campaign_id"`
+
+	// CustomerNumber only matches calls to this customer phone number
+	CustomerNumber string `json:"customer_number"`
+
+	// Status only matches calls in this status
+	Status CallStatus `json:"status"`
+
+	// EndedReason only matches calls that ended for this reason
+	EndedReason string `json:"ended_reason"`
+
+	// From only matches calls created at or after this date
+	From *time.Time `json:"from"`
+
+	// To only matches calls created before this date
+	To *time.Time `json:"to"`
+
+	// Flagged only matches calls flagged by an alert rule
+	Flagged bool `json:"flagged"`
+
+	// Tags only matches calls with every one of these tags
+	Tags []string `json:"tags"`
+
+	// Disposition only matches calls with this disposition code
+	Disposition string `json:"disposition"`
+
+	// HasNotes only matches calls with at least one note
+	HasNotes bool `json:"has_notes"`
+}
+
+// CallFlag is an alert raised on a call by an alert rule with a flag action.
+type CallFlag struct {
+	// RuleId is the alert rule that flagged the call
+	RuleId bson.ObjectID `json:"rule_id" bson:"rule_id"`
+
+	// RuleName is the name of the rule when it flagged the call
+	RuleName string `json:"rule_name" bson:"rule_name"`
+
+	// Source is the part of the call that matched
+	Source AlertSource `json:"source" bson:"source"`
+
+	// Match is the text or score that matched
+	Match string `json:"match" bson:"match"`
+
+	// FlaggedAt is when the call was flagged
+	FlaggedAt time.Time `json:"flagged_at" bson:"flagged_at"`
+}
+
+// CallPage is a single page of an organization's calls, newest first.
+type CallPage struct {
+	// Calls are the calls on this page
+	Calls []Call `json:"calls"`
+
+	// Total is the number of calls matching the filter across all pages
+	Total int64 `json:"total"`
+
+	// Page is the 1-based page number
+	Page int `json:"page"`
+
+	// Limit is the maximum number of calls per page
+	Limit int `json:"limit"`
+}
+
+// CallStatus mirrors the lifecycle states of a VapiAI call.
+type CallStatus string
+
+const (
+	// CALL_STATUS_SCHEDULED indicates the call is scheduled for a later time in VapiAI
+	CALL_STATUS_SCHEDULED CallStatus = "scheduled"
+
+	// CALL_STATUS_QUEUED indicates the call has been accepted by VapiAI but not dialed yet
+	CALL_STATUS_QUEUED CallStatus = "queued"
+
+	// CALL_STATUS_RINGING indicates the customer's phone is ringing
+	CALL_STATUS_RINGING CallStatus = "ringing"
+
+	// CALL_STATUS_IN_PROGRESS indicates the customer answered and the call is ongoing
+	CALL_STATUS_IN_PROGRESS CallStatus = "in-progress"
+
+	// CALL_STATUS_FORWARDING indicates the call is being transferred
+	CALL_STATUS_FORWARDING CallStatus = "forwarding"
+
+	// CALL_STATUS_ENDED indicates the call has finished
+	CALL_STATUS_ENDED CallStatus = "ended"
+)
+
+// ENDED_REASON_MANUALLY_CANCELED is the VapiAI ended reason of calls cancelled before completing
+const ENDED_REASON_MANUALLY_CANCELED = "manually-canceled"
+
+// UnansweredEndedReasons lists the VapiAI ended reasons that mean the customer never
+// spoke with the assistant. Calls ending with any of these are not counted as answered.
+var UnansweredEndedReasons = []string{
+	"customer-busy",
+	"customer-did-not-answer",
+	"customer-did-not-give-microphone-permission",
+	"voicemail",
+	"twilio-failed-to-connect-call",
+	"vonage-failed-to-connect-call",
+	"vonage-rejected",
+	"call.start.error-get-transport",
+	ENDED_REASON_MANUALLY_CANCELED,
+}
@@ -0,0 +1,71 @@
+package mongodb
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// CampaignRun records a single execution of a campaign by the scheduler: the calls it placed
+// and the ones that were later cancelled, so the history of a campaign can be reviewed.
+type CampaignRun struct {
+	// Id is the unique MongoDB ObjectID for this run
+	Id bson.ObjectID `json:"id" bson:"_id,omitempty"`
+
+	// CampaignId is the hex ObjectID of the campaign that ran
+	CampaignId string `json:"campaign_id" bson:"campaign_id"`
+
+	// CustomersTargeted is the number of customers the run called
+	CustomersTargeted int `json:"customers_targeted" bson:"customers_targeted"`
+
+	// InvalidNumbers are the customer numbers that were not called because they are not valid phone numbers
+	InvalidNumbers []string `json:"invalid_numbers" bson:"invalid_numbers"`
+
+	// SuppressedNumbers are the customer numbers that were not called because they are on a Do-Not-Call list
+	SuppressedNumbers []string `json:"suppressed_numbers" bson:"suppressed_numbers"`
+
+	// CappedNumbers are the customer numbers that were not called because every number of the
+	// campaign's phone number pool reached its daily cap
+	CappedNumbers []string `json:"capped_numbers" bson:"capped_numbers"`
+
+	// QueuedCalls is the number of calls the run added to the call queue
+	QueuedCalls int `json:"queued_calls" bson:"queued_calls"`
+
+	// CallIds are the VapiAI call IDs placed by the run, added as the call dispatcher sends its queued calls
+	CallIds []string `json:"call_ids" bson:"call_ids"`
+
+	// CancelledCallIds are the calls of the run that were cancelled or ended before completing
+	CancelledCallIds []string `json:"cancelled_call_ids" bson:"cancelled_call_ids"`
+
+	// Status is the outcome of the run
+	Status CampaignRunStatus `json:"status" bson:"status"`
+
+	// Error is why the run failed, empty otherwise
+	Error string `json:"error" bson:"error"`
+
+	// StartedAt is when the run placed its calls
+	StartedAt time.Time `json:"started_at" bson:"started_at"`
+
+	// CancelledAt is when a call of the run was last cancelled, nil if none was
+	CancelledAt *time.Time `json:"cancelled_at" bson:"cancelled_at"`
+
+	// CancelledBy is the Clerk user ID that last cancelled a call of the run
+	CancelledBy string `json:"cancelled_by" bson:"cancelled_by"`
+}
+
+// CampaignRunStatus defines the possible outcomes of a campaign run.
+type CampaignRunStatus string
+
+const (
+	// RUN_STATUS_PLACED indicates the run queued its calls
+	RUN_STATUS_PLACED CampaignRunStatus = "placed"
+
+	// RUN_STATUS_FAILED indicates the run could not place its calls
+	RUN_STATUS_FAILED CampaignRunStatus = "failed"
+
+	// RUN_STATUS_PARTIALLY_CANCELLED indicates some of the calls of the run were cancelled
+	RUN_STATUS_PARTIALLY_CANCELLED CampaignRunStatus = "partially_cancelled"
+
+	// RUN_STATUS_CANCELLED indicates every call of the run was cancelled
+	RUN_STATUS_CANCELLED CampaignRunStatus = "cancelled"
+)
@@ -23,8 +23,16 @@ type Campaign struct {
 	AssistantId string `json:"assistant_id" bson:"assistant_id"`
 
 	// PhoneNumberId is the VapiAI phone number ID to use for outbound calls
+	// Ignored when PhoneNumberPool is set
 	PhoneNumberId string `json:"phone_number_id" bson:"phone_number_id"`
 
+	// PhoneNumberPool are the VapiAI phone number IDs calls are rotated across, so a high-volume
+	// campaign doesn't call every customer from the same number
+	PhoneNumberPool []string `json:"phone_number_pool" bson:"phone_number_pool"`
+
+	// CallerIdStrategy is how a number of the pool is chosen for each customer
+	CallerIdStrategy CallerIdStrategy `json:"caller_id_strategy" bson:"caller_id_strategy"`
+
 	// SchedulePlan defines when and how often the campaign should run
 	SchedulePlan *SchedulePlan `json:"schedule_plan" bson:"schedule_plan"`
 
@@ -70,6 +78,19 @@ type Customer struct {
 	// PhoneNumber is the customer's contact phone number in E.164 format (e.g., "+1234567890")
 	PhoneNumber string `json:"phone_number" bson:"phone_number"`
 
+	// PhoneNumberType is the kind of line of the phone number (e.g., "mobile"), set when the number is validated
+	PhoneNumberType string `json:"phone_number_type" bson:"phone_number_type"`
+
+	// Name is the customer's name, sent to VapiAI with the call
+	Name string `json:"name" bson:"name,omitempty"`
+
+	// TimeZone is the customer's IANA time zone (e.g., "America/New_York"), given to the assistant as the timezone variable
+	TimeZone string `json:"timezone" bson:"timezone,omitempty"`
+
+	// Variables are the values of the assistant's template variables for this customer (e.g., {{plan}})
+	// Values are strings, numbers or booleans
+	Variables map[string]interface{} `json:"variables,omitempty" bson:"variables,omitempty"`
+
 	// DayNumber is the day of the month when this customer's calls should be scheduled
 	// This is typically used for monthly or yearly campaigns
 	DayNumber int `json:"day_number" bson:"day_number"`
@@ -83,6 +104,20 @@ type Customer struct {
 	YearNumber int `json:"year_number" bson:"year_number"`
 }
 
+// CallerIdStrategy defines how the caller ID of each call is chosen from a campaign's phone number pool.
+type CallerIdStrategy string
+
+const (
+	// CALLER_ID_ROUND_ROBIN uses the numbers of the pool in turn
+	CALLER_ID_ROUND_ROBIN CallerIdStrategy = "round_robin"
+
+	// CALLER_ID_LEAST_USED uses the number that placed the fewest calls today
+	CALLER_ID_LEAST_USED CallerIdStrategy = "least_used"
+
+	// CALLER_ID_LOCAL_PRESENCE uses a number with the customer's area code, or the least used one if there is none
+	CALLER_ID_LOCAL_PRESENCE CallerIdStrategy = "local_presence"
+)
+
 // CampaignType defines the different types of campaign recurrence patterns.
 type CampaignType string
 
@@ -20,6 +20,9 @@ type Contact struct {
 	// PhoneNumber is the contact's phone number in E.164 format (e.g., "+1234567890")
 	PhoneNumber string `json:"phone_number" bson:"phone_number"`
 
+	// PhoneNumberType is the kind of line of the phone number (e.g., "mobile"), set when the number is validated
+	PhoneNumberType string `json:"phone_number_type" bson:"phone_number_type"`
+
 	// Company is the name of the company the contact works for
 	Company string `json:"company" bson:"company"`
 
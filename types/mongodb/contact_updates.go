@@ -0,0 +1,55 @@
+package mongodb
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// ContactUpdate records the metadata of a contact written from the analysis of a call.
+type ContactUpdate struct {
+	// Id is the unique MongoDB ObjectID for this update
+	Id bson.ObjectID `json:"id" bson:"_id,omitempty"`
+
+	// ContactId is the contact that was updated
+	ContactId bson.ObjectID `json:"contact_id" bson:"contact_id"`
+
+	// VapiCallId is the VapiAI call whose analysis was written to the contact
+	VapiCallId string `json:"vapi_call_id" bson:"vapi_call_id"`
+
+	// Changes are the metadata keys that were written
+	Changes []ContactChange `json:"changes" bson:"changes"`
+
+	// CreatedAt is when the contact was updated
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+// ContactChange is a single contact metadata key written from a call's analysis.
+type ContactChange struct {
+	// Field is the analysis field the value was read from (e.g., "structured_data.renewal_confirmed")
+	Field string `json:"field" bson:"field"`
+
+	// MetadataKey is the contact metadata key that was written (e.g., "renewed")
+	MetadataKey string `json:"metadata_key" bson:"metadata_key"`
+
+	// OldValue is the value of the key before the update, nil if it was not set
+	OldValue interface{} `json:"old_value" bson:"old_value"`
+
+	// NewValue is the value written to the key
+	NewValue interface{} `json:"new_value" bson:"new_value"`
+}
+
+// ContactUpdatePage is a single page of a contact's update history, newest first.
+type ContactUpdatePage struct {
+	// Updates are the contact updates on this page
+	Updates []ContactUpdate `json:"updates"`
+
+	// Total is the number of updates of the contact
+	Total int64 `json:"total"`
+
+	// Page is the 1-based page number
+	Page int `json:"page"`
+
+	// Limit is the maximum number of updates per page
+	Limit int `json:"limit"`
+}
@@ -0,0 +1,55 @@
+package mongodb
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// DncEntry is a phone number on an organization's Do-Not-Call list.
+// Calls to numbers on the list are suppressed before they reach VapiAI.
+type DncEntry struct {
+	// Id is the unique MongoDB ObjectID for this entry
+	Id bson.ObjectID `json:"id" bson:"_id,omitempty"`
+
+	// PhoneNumber is the suppressed number in E.164 format (e.g., "+14155552671")
+	PhoneNumber string `json:"phone_number" bson:"phone_number"`
+
+	// Reason is why the number was added (e.g., "customer opted out"), optional
+	Reason string `json:"reason" bson:"reason"`
+
+	// Source is how the number was added to the list
+	Source DncSource `json:"source" bson:"source"`
+
+	// AddedBy is the Clerk user ID that added the number
+	AddedBy string `json:"added_by" bson:"added_by"`
+
+	// CreatedAt is when the number was added to the list
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+// DncSource defines how a number was added to a Do-Not-Call list.
+type DncSource string
+
+const (
+	// DNC_SOURCE_MANUAL indicates the number was added through the API
+	DNC_SOURCE_MANUAL DncSource = "manual"
+
+	// DNC_SOURCE_IMPORT indicates the number was added by a CSV import
+	DNC_SOURCE_IMPORT DncSource = "import"
+)
+
+// DncPage is a single page of an organization's Do-Not-Call list, newest first.
+type DncPage struct {
+	// Entries are the entries on this page
+	Entries []DncEntry `json:"entries"`
+
+	// Total is the number of entries on the list
+	Total int64 `json:"total"`
+
+	// Page is the 1-based page number
+	Page int `json:"page"`
+
+	// Limit is the maximum number of entries per page
+	Limit int `json:"limit"`
+}
@@ -0,0 +1,22 @@
+package mongodb
+
+import "go.mongodb.org/mongo-driver/v2/bson"
+
+// PhoneNumberUsage counts the calls placed from a phone number on a single day,
+// so phone number daily caps can be enforced and pools balanced.
+type PhoneNumberUsage struct {
+	// Id is the unique MongoDB ObjectID for this counter
+	Id bson.ObjectID `json:"id" bson:"_id,omitempty"`
+
+	// PhoneNumberId is the VapiAI phone number ID the calls were placed from
+	PhoneNumberId string `json:"phone_number_id" bson:"phone_number_id"`
+
+	// Date is the UTC day the calls were placed on (e.g., "2024-01-01")
+	Date string `json:"date" bson:"date"`
+
+	// Calls is the number of calls placed
+	Calls int `json:"calls" bson:"calls"`
+}
+
+// USAGE_DATE_FORMAT is the layout of PhoneNumberUsage.Date
+const USAGE_DATE_FORMAT = "2006-01-02"
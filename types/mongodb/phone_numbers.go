@@ -19,4 +19,12 @@ type PhoneNumber struct {
 	// PhoneNumber is the actual phone number in E.164 format (e.g., "+1987654321")
 	// This is the number that will be displayed to recipients during calls
 	PhoneNumber string `json:"phone_number" bson:"phone_number"`
+
+	// DailyCap is the maximum number of calls placed from this number per day (UTC)
+	// 0 places calls without a limit
+	DailyCap int `json:"daily_cap" bson:"daily_cap"`
+
+	// MaxConcurrentCalls is the maximum number of calls in flight from this number at once
+	// 0 places calls without a limit of its own
+	MaxConcurrentCalls int `json:"max_concurrent_calls" bson:"max_concurrent_calls"`
 }
@@ -0,0 +1,93 @@
+package mongodb
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Reconciliation is the report of a pass of the call reconciler over an organization's calls.
+// Calls whose webhooks never arrived stay in a non-terminal status; the reconciler fetches them
+// from VapiAI and records what it found and fixed.
+type Reconciliation struct {
+	// Id is the unique MongoDB ObjectID for this report
+	Id bson.ObjectID `json:"id" bson:"_id,omitempty"`
+
+	// Trigger is what started the pass: "scheduled" for the background job, "manual" for the API
+	Trigger string `json:"trigger" bson:"trigger"`
+
+	// TriggeredBy is the Clerk user ID that started a manual pass, or the system caller of a scheduled one
+	TriggeredBy string `json:"triggered_by" bson:"triggered_by"`
+
+	// StaleBefore is the cutoff of the pass: calls not updated since are checked
+	StaleBefore time.Time `json:"stale_before" bson:"stale_before"`
+
+	// Checked is the number of calls fetched from VapiAI
+	Checked int `json:"checked" bson:"checked"`
+
+	// Fixed is the number of calls whose stored status was out of date
+	Fixed int `json:"fixed" bson:"fixed"`
+
+	// Failed is the number of calls that couldn't be reconciled
+	Failed int `json:"failed" bson:"failed"`
+
+	// Error explains why the pass stopped early, e.g. because VapiAI is unavailable
+	Error string `json:"error,omitempty" bson:"error,omitempty"`
+
+	// Calls are the outcomes of the checked calls, oldest update first
+	Calls []ReconciledCall `json:"calls" bson:"calls"`
+
+	// StartedAt is when the pass started
+	StartedAt time.Time `json:"started_at" bson:"started_at"`
+
+	// FinishedAt is when the pass finished
+	FinishedAt time.Time `json:"finished_at" bson:"finished_at"`
+}
+
+// ReconciledCall is the outcome of a single call checked by the call reconciler.
+type ReconciledCall struct {
+	// VapiCallId is the VapiAI call that was checked
+	VapiCallId string `json:"vapi_call_id" bson:"vapi_call_id"`
+
+	// Outcome is what the reconciler did with the call
+	Outcome ReconcileOutcome `json:"outcome" bson:"outcome"`
+
+	// PreviousStatus is the status stored before the call was checked
+	PreviousStatus CallStatus `json:"previous_status" bson:"previous_status"`
+
+	// Status is the status of the call in VapiAI, empty if VapiAI has no such call
+	Status CallStatus `json:"status" bson:"status"`
+
+	// EndedReason is why the call ended, set on ended and missing calls
+	EndedReason string `json:"ended_reason,omitempty" bson:"ended_reason,omitempty"`
+
+	// CampaignRunUpdated indicates the call was recorded as cancelled in the run of its campaign
+	CampaignRunUpdated bool `json:"campaign_run_updated,omitempty" bson:"campaign_run_updated,omitempty"`
+
+	// QueueStatus is the status the queued call of a missing call was moved to: queued when it is retried,
+	// failed when the call may have been dialed and needs a review
+	QueueStatus QueueStatus `json:"queue_status,omitempty" bson:"queue_status,omitempty"`
+
+	// Error explains why the call couldn't be reconciled
+	Error string `json:"error,omitempty" bson:"error,omitempty"`
+}
+
+// ReconcileOutcome is what the call reconciler did with a stale call.
+type ReconcileOutcome string
+
+const (
+	// RECONCILE_ENDED indicates the call had ended in VapiAI and its end-of-call report was applied
+	RECONCILE_ENDED ReconcileOutcome = "ended"
+
+	// RECONCILE_UPDATED indicates the call moved to another non-terminal status in VapiAI
+	RECONCILE_UPDATED ReconcileOutcome = "updated"
+
+	// RECONCILE_UNCHANGED indicates the stored status was still right
+	RECONCILE_UNCHANGED ReconcileOutcome = "unchanged"
+
+	// RECONCILE_MISSING indicates VapiAI has no such call, so it was ended locally
+	RECONCILE_MISSING ReconcileOutcome = "missing"
+
+	// RECONCILE_ERROR indicates the call couldn't be fetched or updated
+	RECONCILE_ERROR ReconcileOutcome = "error"
+)
@@ -0,0 +1,65 @@
+package mongodb
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Recording tracks the archived copy of a call recording. VapiAI recording URLs are
+// not under our control and may expire, so every ended call's recording is downloaded
+// into the blob store and served from there.
+type Recording struct {
+	// Id is the unique MongoDB ObjectID for this recording
+	Id bson.ObjectID `json:"id" bson:"_id,omitempty"`
+
+	// VapiCallId is the VapiAI call the recording belongs to
+	VapiCallId string `json:"vapi_call_id" bson:"vapi_call_id"`
+
+	// SourceUrl is the VapiAI URL the recording is downloaded from
+	SourceUrl string `json:"source_url" bson:"source_url"`
+
+	// StorageKey is the blob store key of the archived recording, empty until archived
+	StorageKey string `json:"storage_key" bson:"storage_key"`
+
+	// ContentType is the MIME type of the recording (e.g., "audio/wav")
+	ContentType string `json:"content_type" bson:"content_type"`
+
+	// SizeBytes is the size of the archived recording
+	SizeBytes int64 `json:"size_bytes" bson:"size_bytes"`
+
+	// Status is where the recording is in its archival lifecycle
+	Status RecordingStatus `json:"status" bson:"status"`
+
+	// Attempts is the number of times the download was attempted
+	Attempts int `json:"attempts" bson:"attempts"`
+
+	// Error is the last archival error, empty once archived
+	Error string `json:"error" bson:"error"`
+
+	// ArchivedAt is when the recording was stored, nil until archived
+	ArchivedAt *time.Time `json:"archived_at" bson:"archived_at"`
+
+	// CreatedAt is when the recording was scheduled for archival
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+
+	// UpdatedAt is when the recording was last updated
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// RecordingStatus defines the archival states of a call recording.
+type RecordingStatus string
+
+const (
+	// RECORDING_STATUS_PENDING indicates the recording is waiting to be downloaded
+	RECORDING_STATUS_PENDING RecordingStatus = "pending"
+
+	// RECORDING_STATUS_ARCHIVED indicates the recording is stored and can be served
+	RECORDING_STATUS_ARCHIVED RecordingStatus = "archived"
+
+	// RECORDING_STATUS_FAILED indicates the last download attempt failed, it is retried later
+	RECORDING_STATUS_FAILED RecordingStatus = "failed"
+
+	// RECORDING_STATUS_DELETED indicates the recording was removed by the retention policy
+	RECORDING_STATUS_DELETED RecordingStatus = "deleted"
+)
@@ -0,0 +1,72 @@
+package mongodb
+
+import "time"
+
+// ORGANIZATION_SETTINGS_ID is the _id of the single settings document of an organization
+const ORGANIZATION_SETTINGS_ID = "organization"
+
+// OrganizationSettings holds the configuration an organization can change for itself.
+// Each organization database holds a single settings document; missing settings use their defaults.
+type OrganizationSettings struct {
+	// Id is always ORGANIZATION_SETTINGS_ID
+	Id string `json:"-" bson:"_id"`
+
+	// RecordingRetentionDays is how long archived call recordings are kept
+	// 0 keeps recordings forever
+	RecordingRetentionDays int `json:"recording_retention_days" bson:"recording_retention_days"`
+
+	// DefaultCountry is the ISO 3166-1 alpha-2 code (e.g., "US") national phone numbers are read in
+	// Empty only accepts phone numbers in international format
+	DefaultCountry string `json:"default_country" bson:"default_country"`
+
+	// MaxConcurrentCalls is the maximum number of calls of the organization in flight at once
+	// 0 only applies the global and phone number limits
+	MaxConcurrentCalls int `json:"max_concurrent_calls" bson:"max_concurrent_calls"`
+
+	// AnalysisMappings copy fields of the VapiAI end-of-call analysis to the metadata of the called contact
+	AnalysisMappings []AnalysisMapping `json:"analysis_mappings" bson:"analysis_mappings"`
+
+	// Dispositions are the outcomes agents can give calls when reviewing them
+	Dispositions []Disposition `json:"dispositions" bson:"dispositions"`
+
+	// UpdatedAt is when the settings were last changed
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// OrganizationSettingsUpdate holds the settings an update changes. Settings left nil keep their stored value.
+type OrganizationSettingsUpdate struct {
+	// RecordingRetentionDays is how long archived call recordings are kept, 0 keeps recordings forever
+	RecordingRetentionDays *int `json:"recording_retention_days"`
+
+	// DefaultCountry is the ISO 3166-1 alpha-2 code national phone numbers are read in
+	DefaultCountry *string `json:"default_country"`
+
+	// MaxConcurrentCalls is the maximum number of calls of the organization in flight at once
+	MaxConcurrentCalls *int `json:"max_concurrent_calls"`
+
+	// AnalysisMappings replace the organization's analysis mappings
+	AnalysisMappings *[]AnalysisMapping `json:"analysis_mappings"`
+
+	// Dispositions replace the organization's disposition taxonomy
+	Dispositions *[]Disposition `json:"dispositions"`
+}
+
+// AnalysisMapping copies one field of a call's analysis to a key of the called contact's metadata.
+type AnalysisMapping struct {
+	// Field is the analysis field to copy: "summary", "success_evaluation",
+	// or "structured_data.<key>" for a key of the structured data (nested keys are separated by dots)
+	Field string `json:"field" bson:"field"`
+
+	// MetadataKey is the contact metadata key the value is written to (e.g., "renewed")
+	// Nested keys are separated by dots
+	MetadataKey string `json:"metadata_key" bson:"metadata_key"`
+}
+
+// Disposition is an outcome of the organization's disposition taxonomy.
+type Disposition struct {
+	// Code identifies the disposition on calls (e.g., "resolved"), lowercase letters, digits, underscores and hyphens
+	Code string `json:"code" bson:"code"`
+
+	// Label is the name of the disposition shown to agents (e.g., "Resolved on call")
+	Label string `json:"label" bson:"label"`
+}
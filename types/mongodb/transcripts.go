@@ -0,0 +1,121 @@
+package mongodb
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Transcript is the conversation of a call, stored once the call has ended.
+// Transcripts live in their own collection so they can be full-text indexed
+// without weighing on the calls collection used for listings and analytics.
+type Transcript struct {
+	// Id is the unique MongoDB ObjectID for this transcript
+	Id bson.ObjectID `json:"id" bson:"_id,omitempty"`
+
+	// VapiCallId is the VapiAI call the transcript belongs to
+	VapiCallId string `json:"vapi_call_id" bson:"vapi_call_id"`
+
+	// AssistantId is the VapiAI assistant that handled the call
+	AssistantId string `json:"assistant_id" bson:"assistant_id"`
+
+	// CampaignId is the hex ObjectID of the campaign that placed the call, empty for API calls
+	CampaignId string `json:"campaign_id" bson:"campaign_id"`
+
+	// CustomerNumber is the phone number of the customer that was called
+	CustomerNumber string `json:"customer_number" bson:"customer_number"`
+
+	// Transcript is the full transcript of the conversation as plain text
+	Transcript string `json:"transcript" bson:"transcript"`
+
+	// Messages are the turns of the conversation, in order
+	Messages []TranscriptMessage `json:"messages" bson:"messages"`
+
+	// CallCreatedAt is when the call was created, used to filter searches by date
+	CallCreatedAt time.Time `json:"call_created_at" bson:"call_created_at"`
+
+	// UpdatedAt is when the transcript was last stored
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// TranscriptMessage is a single turn of a call conversation.
+type TranscriptMessage struct {
+	// Role is who spoke, "user" for the customer and "bot" for the assistant
+	Role string `json:"role" bson:"role"`
+
+	// Message is what was said
+	Message string `json:"message" bson:"message"`
+
+	// SecondsFromStart is when the turn started, relative to the start of the call
+	SecondsFromStart float64 `json:"seconds_from_start" bson:"seconds_from_start"`
+}
+
+// TranscriptFilter narrows down a transcript search. Empty fields are ignored.
+type TranscriptFilter struct {
+	// AssistantId only matches calls handled by this VapiAI assistant
+	AssistantId string `json:"assistant_id"`
+
+	// CampaignId only matches calls placed by this campaign
+	CampaignId string `json:"campaign_id"`
+
+	// From only matches calls created at or after this date
+	From *time.Time `json:"from"`
+
+	// To only matches calls created before this date
+	To *time.Time `json:"to"`
+}
+
+// TranscriptMatch is a call whose transcript matched a search, along with the passages that matched.
+type TranscriptMatch struct {
+	// VapiCallId is the VapiAI call that matched
+	VapiCallId string `json:"vapi_call_id" bson:"vapi_call_id"`
+
+	// AssistantId is the VapiAI assistant that handled the call
+	AssistantId string `json:"assistant_id" bson:"assistant_id"`
+
+	// CampaignId is the campaign that placed the call, empty for API calls
+	CampaignId string `json:"campaign_id" bson:"campaign_id"`
+
+	// CustomerNumber is the phone number of the customer that was called
+	CustomerNumber string `json:"customer_number" bson:"customer_number"`
+
+	// CallCreatedAt is when the call was created
+	CallCreatedAt time.Time `json:"call_created_at" bson:"call_created_at"`
+
+	// Score is the MongoDB text search relevance, higher is better
+	Score float64 `json:"score" bson:"score"`
+
+	// Snippets are the passages of the conversation containing the search terms
+	Snippets []TranscriptSnippet `json:"snippets" bson:"-"`
+
+	// Transcript and Messages are loaded to compute the snippets and are not returned
+	Transcript string              `json:"-" bson:"transcript"`
+	Messages   []TranscriptMessage `json:"-" bson:"messages"`
+}
+
+// TranscriptSnippet is a passage of a conversation with the search terms highlighted.
+type TranscriptSnippet struct {
+	// Role is who said the passage, empty when the snippet comes from the plain transcript
+	Role string `json:"role"`
+
+	// SecondsFromStart is when the passage was said, relative to the start of the call
+	SecondsFromStart float64 `json:"seconds_from_start"`
+
+	// Text is the HTML-escaped passage with every matched term wrapped in <mark></mark>
+	Text string `json:"text"`
+}
+
+// TranscriptSearchPage is a single page of transcript search results, most relevant first.
+type TranscriptSearchPage struct {
+	// Matches are the calls on this page
+	Matches []TranscriptMatch `json:"matches"`
+
+	// Total is the number of calls matching the search across all pages
+	Total int64 `json:"total"`
+
+	// Page is the 1-based page number
+	Page int `json:"page"`
+
+	// Limit is the maximum number of calls per page
+	Limit int `json:"limit"`
+}
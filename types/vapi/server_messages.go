@@ -0,0 +1,162 @@
+// Package vapi contains the data structures VapiAI sends to Sarah's server URL.
+// Only the fields Sarah uses are declared, so payload changes on VapiAI's side
+// that don't affect those fields never break decoding.
+package vapi
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ServerMessageRequest is the envelope of every request VapiAI posts to the server URL.
+type ServerMessageRequest struct {
+	// Message is the server message being delivered
+	Message ServerMessage `json:"message"`
+}
+
+// ServerMessage is a single message sent by VapiAI about a call.
+// Which fields are set depends on Type.
+type ServerMessage struct {
+	// Type identifies the kind of message (e.g., "status-update", "end-of-call-report")
+	Type ServerMessageType `json:"type"`
+
+	// Timestamp is when VapiAI emitted the message, in milliseconds since the Unix epoch
+	Timestamp float64 `json:"timestamp"`
+
+	// Call is the call the message is about
+	Call *Call `json:"call"`
+
+	// Status is the new call status, set on "status-update" messages
+	Status string `json:"status"`
+
+	// EndedReason is why the call ended, set on "end-of-call-report" and final "status-update" messages
+	EndedReason string `json:"endedReason"`
+
+	// Role is who spoke ("user" or "assistant"), set on "transcript" messages
+	Role string `json:"role"`
+
+	// TranscriptType is "partial" or "final", set on "transcript" messages
+	TranscriptType string `json:"transcriptType"`
+
+	// Transcript is the transcribed speech on "transcript" messages
+	Transcript string `json:"transcript"`
+
+	// Cost is the total cost of the call in USD, set on "end-of-call-report" messages
+	Cost *float64 `json:"cost"`
+
+	// StartedAt is when the call started, set on "end-of-call-report" messages
+	StartedAt *time.Time `json:"startedAt"`
+
+	// EndedAt is when the call ended, set on "end-of-call-report" messages
+	EndedAt *time.Time `json:"endedAt"`
+
+	// Artifact holds the recording and transcript of the call, set on "end-of-call-report" messages
+	Artifact *Artifact `json:"artifact"`
+
+	// Analysis holds the post-call analysis, set on "end-of-call-report" messages
+	Analysis *Analysis `json:"analysis"`
+
+	// ToolCallList holds the tools the assistant invoked, set on "tool-calls" messages
+	ToolCallList []ToolCall `json:"toolCallList"`
+}
+
+// ServerMessageType defines the server message types Sarah handles.
+type ServerMessageType string
+
+const (
+	// MESSAGE_STATUS_UPDATE is sent every time the call status changes
+	MESSAGE_STATUS_UPDATE ServerMessageType = "status-update"
+
+	// MESSAGE_END_OF_CALL_REPORT is sent once the call ended and its analysis is ready
+	MESSAGE_END_OF_CALL_REPORT ServerMessageType = "end-of-call-report"
+
+	// MESSAGE_HANG is sent when the assistant fails to reply for a while
+	MESSAGE_HANG ServerMessageType = "hang"
+
+	// MESSAGE_TRANSCRIPT is sent for every partial and final transcript
+	// VapiAI may also send it as `transcript[transcriptType="final"]`
+	MESSAGE_TRANSCRIPT ServerMessageType = "transcript"
+
+	// MESSAGE_TOOL_CALLS is sent to a tool's server URL when the assistant invokes it during a call
+	MESSAGE_TOOL_CALLS ServerMessageType = "tool-calls"
+)
+
+// Call is the subset of the VapiAI call object included in server messages.
+type Call struct {
+	Id            string     `json:"id"`
+	OrgId         string     `json:"orgId"`
+	Type          string     `json:"type"`
+	Status        string     `json:"status"`
+	EndedReason   string     `json:"endedReason"`
+	AssistantId   string     `json:"assistantId"`
+	PhoneNumberId string     `json:"phoneNumberId"`
+	Customer      *Customer  `json:"customer"`
+	Cost          *float64   `json:"cost"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+	StartedAt     *time.Time `json:"startedAt"`
+	EndedAt       *time.Time `json:"endedAt"`
+	Monitor       *Monitor   `json:"monitor"`
+}
+
+// Customer is the customer a call was placed to.
+type Customer struct {
+	Number string `json:"number"`
+	Name   string `json:"name"`
+}
+
+// Monitor holds the URLs used to listen to and control a live call.
+type Monitor struct {
+	ListenUrl  string `json:"listenUrl"`
+	ControlUrl string `json:"controlUrl"`
+}
+
+// Artifact holds what VapiAI recorded during a call.
+type Artifact struct {
+	Transcript         string            `json:"transcript"`
+	RecordingUrl       string            `json:"recordingUrl"`
+	StereoRecordingUrl string            `json:"stereoRecordingUrl"`
+	Messages           []ArtifactMessage `json:"messages"`
+}
+
+// ArtifactMessage is a single turn of the call conversation.
+type ArtifactMessage struct {
+	Role             string  `json:"role"`
+	Message          string  `json:"message"`
+	Time             float64 `json:"time"`
+	SecondsFromStart float64 `json:"secondsFromStart"`
+}
+
+// Analysis is the post-call analysis computed by VapiAI.
+type Analysis struct {
+	Summary           string                 `json:"summary"`
+	StructuredData    map[string]interface{} `json:"structuredData"`
+	SuccessEvaluation interface{}            `json:"successEvaluation"`
+}
+
+// ToolCall is a single invocation of a function tool by the assistant.
+type ToolCall struct {
+	Id       string           `json:"id"`
+	Type     string           `json:"type"`
+	Function ToolCallFunction `json:"function"`
+}
+
+// ToolCallFunction is the function the assistant invoked and its arguments.
+// VapiAI sends the arguments as a JSON object, or as a string holding one for some models.
+type ToolCallFunction struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// ToolCallsResponse is the reply to a "tool-calls" message, with one result per tool call.
+type ToolCallsResponse struct {
+	Results []ToolCallResult `json:"results"`
+}
+
+// ToolCallResult is what the assistant is told about a tool call.
+// Result is set when the tool succeeded and Error when it failed.
+type ToolCallResult struct {
+	ToolCallId string `json:"toolCallId"`
+	Result     string `json:"result,omitempty"`
+	Error      string `json:"error,omitempty"`
+}